@@ -2,17 +2,20 @@ package stremio
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	netpprof "net/http/pprof"
 	"net/url"
-	"os"
-	"os/signal"
 	"reflect"
 	"runtime/pprof"
-	"strconv"
-	"syscall"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/gofiber/fiber/v3"
@@ -20,6 +23,7 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/recover"
 	"github.com/gofiber/fiber/v3/middleware/static"
 	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/pkg/recording"
 	"github.com/xybydy/go-stremio/types"
 	"go.uber.org/zap"
 )
@@ -38,6 +42,8 @@ import (
 type ManifestCallback func(ctx context.Context, manifest *types.Manifest, userData any) int
 
 // CatalogHandler is the callback for catalog requests for a specific type (like "movie").
+// The context parameter contains a meta object (see GetMetaFromContext) if PutMetaInContext and MetaForCatalogs were set to true in the addon options
+// and the catalog ID is an IMDb ID.
 // The id parameter is the catalog ID that you specified yourself in the CatalogItem objects in the Manifest.
 // The userData parameter depends on whether you called `RegisterUserData()` before:
 // If not, a simple string will be passed. It's empty if the user didn't provide user data.
@@ -51,7 +57,7 @@ type ManifestCallback func(ctx context.Context, manifest *types.Manifest, userDa
 type CatalogHandler func(ctx context.Context, id string, extra url.Values, userData any) ([]types.MetaPreviewItem, error)
 
 // StreamHandler is the callback for stream requests for a specific type (like "movie").
-// The context parameter contains a meta object under the key "meta" if PutMetaInContext was set to true in the addon options.
+// The context parameter contains a meta object (see GetMetaFromContext) if PutMetaInContext was set to true in the addon options.
 // The id parameter can be for example an IMDb ID if your addon handles the "movie" type.
 // The userData parameter depends on whether you called `RegisterUserData()` before:
 // If not, a simple string will be passed. It's empty if the user didn't provide user data.
@@ -59,7 +65,7 @@ type CatalogHandler func(ctx context.Context, id string, extra url.Values, userD
 type StreamHandler func(ctx context.Context, id string, userData any) ([]types.StreamItem, error)
 
 // MetaHandler is the callback for metadata requests for a specific type (like "movie").
-// The context parameter contains a meta object under the key "meta" if PutMetaInContext was set to true in the addon options.
+// The context parameter contains a meta object (see GetMetaFromContext) if PutMetaInContext was set to true in the addon options.
 // The id parameter can be for example an IMDb ID if your addon handles the "movie" type.
 // The userData parameter depends on whether you called `RegisterUserData()` before:
 // If not, a simple string will be passed. It's empty if the user didn't provide user data.
@@ -69,7 +75,7 @@ type StreamHandler func(ctx context.Context, id string, userData any) ([]types.S
 type MetaHandler func(ctx context.Context, id string, userData any) (types.MetaItem, error)
 
 // SubtitleHandler is the callback for subtitle requests for a specific type (like "movie").
-// The context parameter contains a meta object under the key "meta" if PutMetaInContext was set to true in the addon options.
+// The context parameter contains a meta object (see GetMetaFromContext) if PutMetaInContext was set to true in the addon options.
 // The id parameter can be for example an "videoId" if your addon handles the "movie" type.
 // The userData parameter depends on whether you called `RegisterUserData()` before:
 // If not, a simple string will be passed. It's empty if the user didn't provide user data.
@@ -88,59 +94,152 @@ type MetaFetcher interface {
 	GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error)
 }
 
+// TypedMetaFetcher is an optional extension of MetaFetcher for other types than movies and TV shows, like "channel" and "tv",
+// and for other ID schemes than IMDb, like "kitsu:1" or "tmdb:550".
+// When the MetaClient implements it, the meta middleware uses GetMeta for such requests.
+// The ID is the one from the request, so it can contain a season and episode, like "kitsu:1:5".
+// The Cinemeta and TMDB clients implement it.
+type TypedMetaFetcher interface {
+	MetaFetcher
+	GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error)
+}
+
 // Addon represents a remote addon.
 // You can create one with NewAddon() and then run it with Run().
 type Addon struct {
-	manifest          types.Manifest
-	catalogHandlers   map[string]CatalogHandler
-	streamHandlers    map[string]StreamHandler
-	metaHandlers      map[string]MetaHandler
-	subtitleHandlers  map[string]SubtitleHandler
-	opts              Options
-	logger            *zap.Logger
-	customMiddlewares []customMiddleware
-	customEndpoints   []customEndpoint
-	manifestCallback  ManifestCallback
-	userDataType      reflect.Type
-	metaClient        MetaFetcher
+	manifest             types.Manifest
+	catalogHandlers      *liveHandlers
+	streamHandlers       *liveHandlers
+	metaHandlers         *liveHandlers
+	subtitleHandlers     *liveHandlers
+	opts                 Options
+	logger               *zap.Logger
+	customMiddlewares    []customMiddleware
+	customEndpoints      []customEndpoint
+	manifestCallback     ManifestCallback
+	unmarshalUserData    userDataUnmarshaler
+	metaClient           MetaFetcher
+	recorder             *recording.Recorder
+	userDataCache        *userDataCache
+	ipFilter             *ipFilter
+	languages            *languages
+	healthChecks         *healthChecks
+	killSwitches         *killSwitches
+	adminStats           *adminStats
+	recentConfigs        *recentConfigs
+	manifestVariants     *manifestVariants
+	manifestTranslations *manifestTranslations
+	// Declared extras of the catalogs by "type/ID", guarded by the manifest lock
+	catalogExtras map[string]CatalogExtras
+	// Current manifest with its pre-marshalled bodies, which UpdateManifest swaps. The lock guards the manifest field for updates.
+	manifestSnapshot *atomic.Pointer[manifestSnapshot]
+	manifestLock     *sync.Mutex
+	// State of the running Run, nil when it's not running
+	running *atomic.Pointer[runControl]
+	// Checks the LatestVersionURL while the server is running, nil without one
+	versionChecker   *versionChecker
+	stopVersionCheck context.CancelFunc
 }
 
 // NewAddon creates a new Addon object that can be started with Run().
 // A proper manifest must be supplied, but manifestCallback and all but one handler can be nil in case you only want to handle specific requests and opts can be the zero value of Options.
 func NewAddon(manifest types.Manifest, catalogHandlers map[string]CatalogHandler, streamHandlers map[string]StreamHandler, metaHandlers map[string]MetaHandler, subtitleHandlers map[string]SubtitleHandler, opts Options) (*Addon, error) {
-	// Precondition checks
-	switch {
-	case manifest.ID == "" || manifest.Name == "" || manifest.Description == "" || manifest.Version == "":
-		return nil, errors.New("an empty manifest was passed")
-	case catalogHandlers == nil && streamHandlers == nil && metaHandlers == nil && subtitleHandlers == nil:
-		return nil, errors.New("no handler was passed")
-	case (opts.CachePublicCatalogs && opts.CacheAgeCatalogs == 0) ||
-		(opts.CachePublicStreams && opts.CacheAgeStreams == 0) ||
-		(opts.CachePublicMeta && opts.CacheAgeMeta == 0):
-		return nil, errors.New("enabling public caching only makes sense when also setting a cache age")
-	case (opts.StaleRevalidateCatalogs != 0 && opts.CacheAgeCatalogs == 0) ||
-		(opts.StaleRevalidateStreams != 0 && opts.CacheAgeStreams == 0):
-		return nil, errors.New("to enable stale-while-revalidate you must also set cache age")
-	case (opts.StaleErrorCatalogs != 0 && opts.CacheAgeCatalogs == 0) ||
-		(opts.StaleErrorStreams != 0 && opts.CacheAgeStreams == 0):
-		return nil, errors.New("to enable stale-if-error you must also set cache age")
-	case (opts.HandleEtagCatalogs && opts.CacheAgeCatalogs == 0) ||
-		(opts.HandleEtagStreams && opts.CacheAgeStreams == 0):
-		return nil, errors.New(`ETag handling only makes sense when also setting a cache age`)
-	case opts.DisableRequestLogging && (opts.LogIPs || opts.LogUserAgent):
-		return nil, errors.New("enabling IP or user agent logging doesn't make sense when disabling request logging")
-	case opts.Logger != nil && opts.LoggingLevel != "":
-		return nil, errors.New("setting a logging level in the options doesn't make sense when you already set a custom logger")
-	case opts.DisableRequestLogging && opts.LogMediaName:
-		return nil, errors.New("enabling media name logging doesn't make sense when disabling request logging")
-	case opts.MetaClient != nil && !opts.LogMediaName && !opts.PutMetaInContext:
-		return nil, errors.New("setting a meta client when neither logging the media name nor putting it in the context doesn't make sense")
-	case opts.MetaClient != nil && opts.MetaTimeout != 0:
-		return nil, errors.New("setting a MetaClient timeout doesn't make sense when you already set a meta client")
-	case manifest.BehaviorHints.ConfigurationRequired && !manifest.BehaviorHints.Configurable:
-		return nil, errors.New("requiring a configuration only makes sense when also making the addon configurable")
-	case opts.ConfigureHTMLfs != nil && !manifest.BehaviorHints.Configurable:
-		return nil, errors.New("setting a ConfigureHTMLfs only makes sense when also making the addon configurable")
+	// Precondition checks. All violations are collected, so a misconfigured deployment can be fixed in one go.
+	var errs []error
+	for _, check := range []struct {
+		violated bool
+		err      error
+	}{
+		{manifest.ID == "" || manifest.Name == "" || manifest.Description == "" || manifest.Version == "", errors.New("an empty manifest was passed")},
+		{catalogHandlers == nil && streamHandlers == nil && metaHandlers == nil && subtitleHandlers == nil, errors.New("no handler was passed")},
+		{(opts.CachePublicCatalogs && opts.CacheAgeCatalogs == 0) ||
+			(opts.CachePublicStreams && opts.CacheAgeStreams == 0) ||
+			(opts.CachePublicMeta && opts.CacheAgeMeta == 0), errors.New("enabling public caching only makes sense when also setting a cache age")},
+		{(opts.StaleRevalidateCatalogs != 0 && opts.CacheAgeCatalogs == 0) ||
+			(opts.StaleRevalidateStreams != 0 && opts.CacheAgeStreams == 0), errors.New("to enable stale-while-revalidate you must also set cache age")},
+		{(opts.StaleErrorCatalogs != 0 && opts.CacheAgeCatalogs == 0) ||
+			(opts.StaleErrorStreams != 0 && opts.CacheAgeStreams == 0), errors.New("to enable stale-if-error you must also set cache age")},
+		{(opts.HandleEtagCatalogs && opts.CacheAgeCatalogs == 0) ||
+			(opts.HandleEtagStreams && opts.CacheAgeStreams == 0), errors.New(`ETag handling only makes sense when also setting a cache age`)},
+		{opts.DisableRequestLogging && (opts.LogIPs || opts.LogUserAgent), errors.New("enabling IP or user agent logging doesn't make sense when disabling request logging")},
+		{opts.Logger != nil && opts.LoggingLevel != "", errors.New("setting a logging level in the options doesn't make sense when you already set a custom logger")},
+		{opts.DisableRequestLogging && opts.LogMediaName, errors.New("enabling media name logging doesn't make sense when disabling request logging")},
+		{opts.MetaClient != nil && !opts.LogMediaName && !opts.PutMetaInContext, errors.New("setting a meta client when neither logging the media name nor putting it in the context doesn't make sense")},
+		{opts.MetaForCatalogs && !opts.LogMediaName && !opts.PutMetaInContext, errors.New("enabling meta for catalogs doesn't make sense when neither logging the media name nor putting it in the context")},
+		{opts.MetaClient != nil && opts.MetaTimeout != 0, errors.New("setting a MetaClient timeout doesn't make sense when you already set a meta client")},
+		{opts.MetaClient != nil && !reflect.ValueOf(opts.CinemetaOptions).IsZero(), errors.New("setting Cinemeta options doesn't make sense when you already set a meta client")},
+		{opts.MaxConcurrentMetaFetches != 0 && !opts.LogMediaName && !opts.PutMetaInContext, errors.New("limiting concurrent meta fetches doesn't make sense when neither logging the media name nor putting it in the context")},
+		{opts.MetaTimeout != 0 && opts.CinemetaOptions.Timeout != 0, errors.New("setting both MetaTimeout and CinemetaOptions.Timeout is ambiguous, only set one of them")},
+		{manifest.BehaviorHints.ConfigurationRequired && !manifest.BehaviorHints.Configurable, errors.New("requiring a configuration only makes sense when also making the addon configurable")},
+		{opts.ConfigureHTMLfs != nil && !manifest.BehaviorHints.Configurable, errors.New("setting a ConfigureHTMLfs only makes sense when also making the addon configurable")},
+		{opts.StreamProxy && opts.URLSigner == nil, errors.New("the stream proxy requires a URLSigner")},
+		{opts.SubtitleConversion && opts.URLSigner == nil, errors.New("the subtitle conversion requires a URLSigner")},
+		{(opts.MaxProxyConnections != 0 || opts.MaxProxyConnectionsPerUser != 0 || opts.MaxProxyBandwidthPerUser != 0) && !opts.StreamProxy, errors.New("setting proxy limits only makes sense when also enabling the stream proxy")},
+		{opts.LandingPage && opts.RedirectURL != "", errors.New("a landing page can't be used together with a RedirectURL, as both are served at the root")},
+		{opts.LandingPageTemplate != nil && !opts.LandingPage, errors.New("setting a LandingPageTemplate only makes sense when also enabling the LandingPage")},
+		{opts.ConfigurePage && !manifest.BehaviorHints.Configurable, errors.New("enabling the ConfigurePage only makes sense when also making the addon configurable")},
+		{opts.ConfigurePage && opts.ConfigureHTMLfs != nil, errors.New("the ConfigurePage can't be used together with a ConfigureHTMLfs, as both are served at \"/configure\"")},
+		{opts.ConfigurePage && len(manifest.Config) == 0, errors.New("the ConfigurePage requires config items in the manifest")},
+		{opts.UserDataSigningKey != nil && len(opts.UserDataSigningKey) < MinUserDataSigningKeyLength, fmt.Errorf("the UserDataSigningKey must be at least %v bytes long", MinUserDataSigningKeyLength)},
+		{opts.UserDataJWT != nil && (opts.UserDataIsBase64 || opts.UserDataSigningKey != nil), errors.New("using a UserDataJWT can't be combined with UserDataIsBase64 or a UserDataSigningKey, as the token already is Base64-encoded and signed")},
+		{opts.UserDataJWT != nil && len(opts.UserDataJWT.Keys) == 0, errors.New("using a UserDataJWT requires at least one key")},
+		{opts.UserDataJWT != nil && slices.ContainsFunc(opts.UserDataJWT.Keys, func(key []byte) bool { return len(key) < MinUserDataSigningKeyLength }), fmt.Errorf("the UserDataJWT keys must be at least %v bytes long", MinUserDataSigningKeyLength)},
+		{opts.ShutdownTimeout < 0, errors.New("the ShutdownTimeout can't be negative")},
+		{opts.MaxUserDataLength < 0, errors.New("the MaxUserDataLength can't be negative")},
+		{opts.UserDataCacheSize < 0, errors.New("the UserDataCacheSize can't be negative")},
+		{opts.StreamingThreshold < 0, errors.New("the StreamingThreshold can't be negative")},
+		{opts.ConfigStore != nil && (opts.UserDataIsBase64 || opts.UserDataSigningKey != nil || opts.UserDataJWT != nil), errors.New("using a ConfigStore can't be combined with UserDataIsBase64, a UserDataSigningKey or a UserDataJWT, as the URL only contains a random token")},
+		{opts.ConfigSchema && len(manifest.Config) == 0, errors.New("the ConfigSchema requires config items in the manifest")},
+		{opts.ConfigurePageTemplate != nil && !opts.ConfigurePage, errors.New("setting a ConfigurePageTemplate only makes sense when also enabling the ConfigurePage")},
+		{opts.PageCSS != "" && !opts.LandingPage && !opts.ConfigurePage && !opts.AdminDashboard, errors.New("setting PageCSS only makes sense when also enabling a generated page like the LandingPage, ConfigurePage or AdminDashboard")},
+		{opts.ProfilingAuth != nil && !opts.Profiling, errors.New("setting ProfilingAuth only makes sense when also enabling Profiling")},
+		{opts.MetricsAuth != nil && !opts.Metrics, errors.New("setting MetricsAuth only makes sense when also enabling Metrics")},
+		{(opts.ProfilingAuth != nil && (opts.ProfilingAuth.Username == "" || opts.ProfilingAuth.Password == "")) ||
+			(opts.MetricsAuth != nil && (opts.MetricsAuth.Username == "" || opts.MetricsAuth.Password == "")), errors.New("basic auth credentials require a username and password")},
+		{opts.AdminDashboard && opts.APIKey == "", errors.New("the AdminDashboard requires an APIKey")},
+		{opts.AdminAPI && opts.APIKey == "", errors.New("the AdminAPI requires an APIKey")},
+		{opts.InstallStore != nil && opts.InstallCallback == nil && opts.InstallWebhookURL == "", errors.New("setting an InstallStore only makes sense when also setting an InstallCallback or InstallWebhookURL")},
+		{opts.InstallNotificationsPerMinute < 0, errors.New("InstallNotificationsPerMinute can't be negative")},
+		{opts.SecurityTxt != nil && len(opts.SecurityTxt.Contact) == 0, errors.New("the SecurityTxt requires at least one Contact")},
+		{opts.HealthPath != "" && !strings.HasPrefix(opts.HealthPath, "/"), errors.New(`the HealthPath must start with "/"`)},
+		{opts.HealthPath != "" && opts.DisableHealthEndpoint, errors.New("setting a HealthPath doesn't make sense when disabling the health endpoint")},
+		{opts.CatalogFeeds && catalogHandlers == nil, errors.New("enabling CatalogFeeds requires catalog handlers")},
+		{opts.LanguageConfigKey != "" && len(opts.Languages) == 0, errors.New("setting a LanguageConfigKey only makes sense when also setting Languages")},
+		{len(opts.TrustedProxies) > 0 && len(opts.AllowedIPs) == 0 && len(opts.DeniedIPs) == 0, errors.New("setting TrustedProxies only makes sense when also setting AllowedIPs or DeniedIPs")},
+	} {
+		if check.violated {
+			errs = append(errs, check.err)
+		}
+	}
+	// Invalid values are collected as well
+	if opts.LandingPageTemplate != nil {
+		var err error
+		if opts.LandingPageTemplate, err = withStyleTemplate(opts.LandingPageTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("invalid LandingPageTemplate: %w", err))
+		}
+	}
+	if opts.ConfigurePageTemplate != nil {
+		var err error
+		if opts.ConfigurePageTemplate, err = withStyleTemplate(opts.ConfigurePageTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("invalid ConfigurePageTemplate: %w", err))
+		}
+	}
+	var filter *ipFilter
+	if len(opts.AllowedIPs) > 0 || len(opts.DeniedIPs) > 0 {
+		var err error
+		if filter, err = newIPFilter(opts.AllowedIPs, opts.DeniedIPs, opts.TrustedProxies); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var langs *languages
+	if len(opts.Languages) > 0 {
+		var err error
+		if langs, err = newLanguages(opts.Languages, opts.LanguageConfigKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
 	// Set default values
@@ -156,9 +255,15 @@ func NewAddon(manifest types.Manifest, catalogHandlers map[string]CatalogHandler
 	if opts.LogEncoding == "" {
 		opts.LogEncoding = DefaultOptions.LogEncoding
 	}
+	if opts.HealthPath == "" && !opts.DisableHealthEndpoint {
+		opts.HealthPath = DefaultOptions.HealthPath
+	}
 	if opts.MetaTimeout == 0 {
 		opts.MetaTimeout = DefaultOptions.MetaTimeout
 	}
+	if opts.InstallNotificationsPerMinute == 0 {
+		opts.InstallNotificationsPerMinute = DefaultOptions.InstallNotificationsPerMinute
+	}
 
 	// Configure logger if no custom one is set
 	if opts.Logger == nil {
@@ -169,34 +274,103 @@ func NewAddon(manifest types.Manifest, catalogHandlers map[string]CatalogHandler
 	}
 	// Configure Cinemeta client if no custom MetaFetcher is set
 	if opts.MetaClient == nil && (opts.LogMediaName || opts.PutMetaInContext) {
-		cinemetaCache := cinemeta.NewInMemoryCache()
-		cinemetaOpts := cinemeta.ClientOptions{
-			Timeout: opts.MetaTimeout,
+		// Bounded, so long-running addons don't grow without bound. 10,000 meta objects take a few dozen MB at most.
+		cinemetaCache := cinemeta.NewLRUCache(10_000)
+		cinemetaOpts := opts.CinemetaOptions
+		if cinemetaOpts.Timeout == 0 {
+			cinemetaOpts.Timeout = opts.MetaTimeout
+		}
+		if cinemetaOpts.TTLJitter == 0 {
+			cinemetaOpts.TTLJitter = 24 * time.Hour
 		}
 		opts.MetaClient = cinemeta.NewClient(cinemetaOpts, cinemetaCache, opts.Logger)
 	}
 
+	var cache *userDataCache
+	if opts.UserDataCacheSize > 0 {
+		cache = newUserDataCache(opts.UserDataCacheSize)
+	}
+
+	snapshot := &atomic.Pointer[manifestSnapshot]{}
+	var checker *versionChecker
+	if opts.LatestVersionURL != "" {
+		checker = newVersionChecker(opts.LatestVersionURL, manifest.ID, snapshot, opts.Logger)
+	}
+
 	// Create and return addon
 	return &Addon{
-		manifest:         manifest,
-		catalogHandlers:  catalogHandlers,
-		streamHandlers:   streamHandlers,
-		metaHandlers:     metaHandlers,
-		subtitleHandlers: subtitleHandlers,
-		opts:             opts,
-		logger:           opts.Logger,
-		metaClient:       opts.MetaClient,
+		manifest:             manifest,
+		catalogHandlers:      newLiveHandlers("catalog", catalogHandlers, convertCatalogHandler),
+		streamHandlers:       newLiveHandlers("stream", streamHandlers, convertStreamHandler),
+		metaHandlers:         newLiveHandlers("meta", metaHandlers, convertMetaHandler),
+		subtitleHandlers:     newLiveHandlers("subtitles", subtitleHandlers, convertSubtitleHandler),
+		opts:                 opts,
+		logger:               opts.Logger,
+		metaClient:           opts.MetaClient,
+		userDataCache:        cache,
+		ipFilter:             filter,
+		languages:            langs,
+		healthChecks:         newHealthChecks(),
+		killSwitches:         newKillSwitches(),
+		adminStats:           newAdminStats(),
+		recentConfigs:        newRecentConfigs(),
+		manifestVariants:     &manifestVariants{},
+		manifestTranslations: &manifestTranslations{},
+		catalogExtras:        map[string]CatalogExtras{},
+		manifestSnapshot:     snapshot,
+		manifestLock:         &sync.Mutex{},
+		running:              &atomic.Pointer[runControl]{},
+		versionChecker:       checker,
 	}, nil
 }
 
+// resourceMiddlewares returns the middlewares for the routes of a resource, see createKillSwitchMiddleware for the jsonArrayKey.
+func (a *Addon) resourceMiddlewares(resource, jsonArrayKey string, logger *zap.Logger) []fiber.Handler {
+	var mws []fiber.Handler
+	varyHeaders := a.opts.VaryHeaders
+	if a.languages != nil {
+		varyHeaders = append(slices.Clone(varyHeaders), fiber.HeaderAcceptLanguage)
+	}
+	if len(varyHeaders) > 0 {
+		mws = append(mws, createVaryMiddleware(varyHeaders))
+	}
+	mws = append(mws, createKillSwitchMiddleware(a.killSwitches, resource, jsonArrayKey, logger), createAcceptLanguageMiddleware())
+	if a.languages != nil {
+		mws = append(mws, createLanguageMiddleware(a.languages, resource == "catalog", a.opts.UserDataIsBase64, logger))
+	}
+	if a.opts.Analytics != nil {
+		mws = append(mws, createAnalyticsMiddleware(a.opts.Analytics, resource, logger))
+	}
+	if a.opts.AdminDashboard {
+		mws = append(mws, createAdminIDMiddleware(a.adminStats, resource))
+	}
+	return mws
+}
+
 // RegisterUserData registers the type of userData, so the addon can automatically unmarshal user data into an object of this type
 // and pass the object into the manifest callback or catalog and stream handlers.
+// The object is created with reflection for every request. RegisterUserDataType does the same without reflection.
 func (a *Addon) RegisterUserData(userDataObject any) {
 	t := reflect.TypeOf(userDataObject)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-	a.userDataType = t
+	a.unmarshalUserData = reflectUserDataUnmarshaler(t)
+}
+
+// RegisterUserDataType is like RegisterUserData, so handlers get a *T as userData, but it doesn't use reflection for every request,
+// which saves CPU time for addons where most requests have user data.
+// Use it like `stremio.RegisterUserDataType[customer](addon)`.
+func RegisterUserDataType[T any](a *Addon) {
+	a.unmarshalUserData = unmarshalUserDataJSON[T]
+}
+
+// RegisterUserDataDecoder registers a function that unmarshals the user data instead of encoding/json,
+// for example generated unmarshalling code or a faster JSON library.
+// The function gets the user data JSON after it was Base64-decoded or URL-unescaped, and its result is passed into the handlers as userData.
+// Errors lead to a "400 Bad Request" response. The function must not keep a reference to the byte slice.
+func (a *Addon) RegisterUserDataDecoder(decode func(userDataJSON []byte) (any, error)) {
+	a.unmarshalUserData = decode
 }
 
 // DecodeUserData decodes the request's user data and returns the result.
@@ -204,9 +378,63 @@ func (a *Addon) RegisterUserData(userDataObject any) {
 // like the ManifestCallback, CatalogHandler and StreamHandler have.
 // The param value must match the URL parameter you used when creating the custom endpoint,
 // for example when using `AddEndpoint("GET", "/:userData/ping", customEndpoint)` you must pass "userData".
+// User data that's longer than MaxUserDataLength leads to ErrUserDataTooLong.
+// When signing user data or using JWTs, the user data is verified first, and an invalid one leads to ErrInvalidUserDataSignature or ErrUserDataExpired.
+// When using a ConfigStore, the token is resolved first, and an unknown one leads to ErrUnknownConfigToken.
 func (a *Addon) DecodeUserData(param string, c fiber.Ctx) (any, error) {
 	data := c.Params(param, "")
-	return decodeUserData(data, a.userDataType, a.logger, a.opts.UserDataIsBase64)
+	if a.opts.MaxUserDataLength != 0 && len(data) > a.opts.MaxUserDataLength {
+		return nil, ErrUserDataTooLong
+	}
+	data, err := a.verifyUserData(c.Context(), data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserData(data, a.unmarshalUserData, a.logger, a.opts.UserDataIsBase64)
+}
+
+// EncodeUserData encodes user data the way the addon expects it in URLs, so it's the counterpart of DecodeUserData.
+// It's useful for building install and configure links, and for tests.
+// The value is marshalled to JSON and then either Base64-encoded (when using UserDataIsBase64) or URL-escaped,
+// and signed when using UserDataSigningKey. When using UserDataJWT, it's issued as JWT instead.
+// When using a ConfigStore, it's stored under a new token, which is returned instead.
+func (a *Addon) EncodeUserData(userData any) (string, error) {
+	if a.opts.ConfigStore != nil {
+		return storeConfig(context.Background(), a.opts.ConfigStore, "", userData)
+	}
+	if a.opts.UserDataJWT != nil {
+		return issueUserDataJWT(userData, a.opts.UserDataJWT)
+	}
+	encoded, err := encodeUserData(userData, a.opts.UserDataIsBase64)
+	if err != nil || a.opts.UserDataSigningKey == nil {
+		return encoded, err
+	}
+	return signUserData(encoded, a.opts.UserDataSigningKey), nil
+}
+
+// verifyUserData verifies the signature or JWT of the user data or resolves its config token, if the addon uses either,
+// and returns the user data in the encoding that decodeUserData expects.
+func (a *Addon) verifyUserData(ctx context.Context, userData string) (string, error) {
+	switch {
+	case a.opts.ConfigStore != nil:
+		return resolveConfigToken(ctx, a.opts.ConfigStore, userData)
+	case a.opts.UserDataSigningKey != nil:
+		return verifyUserData(userData, a.opts.UserDataSigningKey)
+	case a.opts.UserDataJWT != nil:
+		return verifyUserDataJWT(userData, a.opts.UserDataJWT)
+	}
+	return userData, nil
+}
+
+func encodeUserData(userData any, userDataIsBase64 bool) (string, error) {
+	userDataJSON, err := json.Marshal(userData)
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal user data: %w", err)
+	}
+	if userDataIsBase64 {
+		return base64.RawURLEncoding.EncodeToString(userDataJSON), nil
+	}
+	return url.PathEscape(string(userDataJSON)), nil
 }
 
 // AddMiddleware appends a custom middleware to the chain of existing middlewares.
@@ -221,15 +449,36 @@ func (a *Addon) AddMiddleware(path string, middleware fiber.Handler) {
 }
 
 // AddEndpoint adds a custom endpoint (a route and its handler).
+// The method can be a comma-separated list of methods, like "GET,POST", for handling them with the same handler.
 // If you want to be able to access custom user data, you can use a path like this:
 // "/:userData/foo" and then either deal with the data yourself
 // by using `c.Params("userData", "")` in the handler,
 // or use the convenience method `DecodeUserData("userData", c)`.
+// For several endpoints below a common path prefix with shared middlewares, see Group.
 func (a *Addon) AddEndpoint(method, path string, handler fiber.Handler) {
+	a.addEndpoint(method, path, handler, false, nil)
+}
+
+// AddProtectedEndpoint adds a custom endpoint like AddEndpoint, but requires the APIKey of the options for accessing it,
+// for example for admin or stats endpoints that aren't meant for Stremio.
+// Without an APIKey it's the same as AddEndpoint.
+func (a *Addon) AddProtectedEndpoint(method, path string, handler fiber.Handler) {
+	a.addEndpoint(method, path, handler, true, nil)
+}
+
+func (a *Addon) addEndpoint(method, path string, handler fiber.Handler, protected bool, group *Group) {
+	var methods []string
+	for _, m := range strings.Split(method, ",") {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			methods = append(methods, m)
+		}
+	}
 	customEndpoint := customEndpoint{
-		method:  method,
-		path:    path,
-		handler: handler,
+		methods:   methods,
+		path:      path,
+		handler:   handler,
+		protected: protected,
+		group:     group,
 	}
 	a.customEndpoints = append(a.customEndpoints, customEndpoint)
 }
@@ -239,24 +488,12 @@ func (a *Addon) SetManifestCallback(callback ManifestCallback) {
 	a.manifestCallback = callback
 }
 
-// Run starts the remote addon. It sets up an HTTP server that handles requests to "/manifest.json" etc. and gracefully handles shutdowns.
-// The call is *blocking*, so use the stoppingChan param if you want to be notified when the addon is about to shut down
-// because of a system signal like Ctrl+C or `docker stop`. It should be a buffered channel with a capacity of 1.
-func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
+// App creates the Fiber app with all middlewares and endpoints of the addon, without starting a server.
+// Run uses it, but it's also useful for testing the addon in-process (see the stremiotest package)
+// or for serving it with a custom server setup. Pass nil for the fiberConf to use go-stremio's default config.
+func (a *Addon) App(fiberConf *fiber.Config) *fiber.App {
 	logger := a.logger
 
-	defer func() {
-		err := logger.Sync()
-		if err != nil {
-			logger.Error("Failed to sync logger", zap.Error(err))
-		}
-	}()
-
-	// Make sure the passed channel is buffered, so we can send a message before shutting down and not be blocked by the channel.
-	if stoppingChan != nil && cap(stoppingChan) < 1 {
-		logger.Fatal("The passed stopping channel isn't buffered")
-	}
-
 	if fiberConf == nil {
 		fiberConf = &fiber.Config{
 			ErrorHandler: func(c fiber.Ctx, err error) error {
@@ -288,18 +525,76 @@ func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
 	if !a.opts.DisableRequestLogging {
 		app.Use(createLoggingMiddleware(logger, a.opts.LogIPs, a.opts.LogUserAgent, a.opts.LogMediaName))
 	}
+	// Reject blocked clients before doing any other work for them
+	if a.ipFilter != nil {
+		app.Use(createIPFilterMiddleware(a.ipFilter, a.opts.LogIPs, logger))
+	}
 	if a.opts.Metrics {
-		app.Use(createMetricsMiddleware())
+		app.Use(createMetricsMiddleware(a.opts.HealthPath))
+	}
+	if a.opts.AdminDashboard {
+		app.Use(createAdminStatsMiddleware(a.adminStats))
+	}
+	if a.opts.RecordFile != "" {
+		if a.recorder == nil {
+			recorder, err := recording.NewRecorder(a.opts.RecordFile, recording.RecorderOptions{KeepUserData: a.opts.RecordUserData})
+			if err != nil {
+				logger.Fatal("Couldn't create recorder", zap.Error(err))
+			}
+			a.recorder = recorder
+		}
+		app.Use(createRecordingMiddleware(a.recorder, logger))
 	}
 	app.Use(corsMiddleware()) // Stremio doesn't show stream responses when no CORS middleware is used!
 	// Filter some requests (like for requests without user data when the addon requires configuration, or for missing type or id URL parameters) and put some request info in the context
 	addRouteMatcherMiddleware(app, a.manifest.BehaviorHints.ConfigurationRequired, a.opts.StreamIDregex, logger)
-	metaMw := createMetaMiddleware(a.metaClient, a.opts.PutMetaInContext, a.opts.LogMediaName, logger)
-	// Meta middleware only works for stream requests.
+	// Reject oversized user data before decoding it
+	if a.opts.MaxUserDataLength != 0 {
+		lengthMw := createUserDataLengthMiddleware(a.opts.MaxUserDataLength, logger)
+		for _, route := range userDataRoutes {
+			app.Use("/:userData/"+route, lengthMw)
+		}
+	}
+	// Reject modified or expired user data, and resolve config tokens
+	if a.opts.UserDataSigningKey != nil || a.opts.UserDataJWT != nil || a.opts.ConfigStore != nil {
+		verify := a.verifyUserData
+		// Remember config tokens for the admin API
+		if a.opts.AdminAPI && a.opts.ConfigStore != nil {
+			verify = func(ctx context.Context, userData string) (string, error) {
+				verified, err := a.verifyUserData(ctx, userData)
+				if err == nil {
+					a.recentConfigs.seen(userData)
+				}
+				return verified, err
+			}
+		}
+		verificationMw := createUserDataVerificationMiddleware(verify, logger)
+		for _, route := range userDataRoutes {
+			app.Use("/:userData/"+route, verificationMw)
+		}
+	}
+	// Notify about new users
+	if a.opts.InstallCallback != nil || a.opts.InstallWebhookURL != "" {
+		app.Use("/:userData/manifest.json", createInstallTrackingMiddleware(newInstallTracker(a)))
+	}
+	metaFetcher := newSharedMetaFetcher(a.metaClient, a.opts.MaxConcurrentMetaFetches, a.opts.MetaTimeout, logger)
+	metaMw := createMetaMiddleware(metaFetcher, a.opts.PutMetaInContext, a.opts.LogMediaName, false, logger)
+	// Meta middleware works for stream and meta requests, and optionally for catalog requests with an IMDb ID.
 	if !a.manifest.BehaviorHints.ConfigurationRequired {
 		app.Use("/stream/:type/:id.json", metaMw)
+		app.Use("/meta/:type/:id.json", metaMw)
 	}
 	app.Use("/:userData/stream/:type/:id.json", metaMw)
+	app.Use("/:userData/meta/:type/:id.json", metaMw)
+	if a.opts.MetaForCatalogs {
+		catalogMetaMw := createMetaMiddleware(metaFetcher, a.opts.PutMetaInContext, a.opts.LogMediaName, true, logger)
+		if !a.manifest.BehaviorHints.ConfigurationRequired {
+			app.Use("/catalog/:type/:id.json", catalogMetaMw)
+			app.Use("/catalog/:type/:id/:extras", catalogMetaMw)
+		}
+		app.Use("/:userData/catalog/:type/:id.json", catalogMetaMw)
+		app.Use("/:userData/catalog/:type/:id/:extras", catalogMetaMw)
+	}
 	// Custom middlewares
 	for _, customMW := range a.customMiddlewares {
 		app.Use(customMW.path, customMW.mw)
@@ -307,10 +602,25 @@ func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
 
 	// Extra endpoints
 
-	app.Get("/health", createHealthHandler(logger))
+	if !a.opts.DisableHealthEndpoint {
+		app.Get(a.opts.HealthPath, createHealthHandler(a.healthChecks, logger))
+	}
+	if a.opts.VersionEndpoint {
+		app.Get("/version", createVersionHandler(a.manifestSnapshot, a.versionChecker, logger))
+	}
+	// Endpoints that aren't meant for Stremio optionally require an API key
+	var protectedMws []fiber.Handler
+	if a.opts.APIKey != "" {
+		protectedMws = append(protectedMws, createAPIKeyMiddleware(a.opts.APIKey, a.opts.LogIPs, logger))
+	}
 	// Optional profiling
 	if a.opts.Profiling {
-		group := app.Group("/debug/pprof")
+		var group fiber.Router
+		if a.opts.ProfilingAuth != nil {
+			group = app.Group("/debug/pprof", createBasicAuthMiddleware(*a.opts.ProfilingAuth, "Profiling", a.opts.LogIPs, logger))
+		} else {
+			group = app.Group("/debug/pprof", protectedMws...)
+		}
 
 		group.Get("/", func(c fiber.Ctx) error {
 			c.Set(fiber.HeaderContentType, fiber.MIMETextHTML)
@@ -325,55 +635,131 @@ func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
 	}
 	// Optional metrics
 	if a.opts.Metrics {
+		metricsMws := protectedMws
+		if a.opts.MetricsAuth != nil {
+			metricsMws = []fiber.Handler{createBasicAuthMiddleware(*a.opts.MetricsAuth, "Metrics", a.opts.LogIPs, logger)}
+		}
 		app.Get("/metrics", adaptor.HTTPHandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			metrics.WritePrometheus(w, true)
-		}))
+		}), metricsMws...)
+	}
+
+	// Optional OpenAPI document
+	if a.opts.OpenAPI {
+		doc, err := a.OpenAPI()
+		if err != nil {
+			logger.Fatal("Couldn't create OpenAPI document", zap.Error(err))
+		}
+		app.Get("/openapi.json", createOpenAPIHandler(doc, logger))
+	}
+	// Optional JSON Schema of the config items
+	if a.opts.ConfigSchema {
+		schema, err := a.ConfigSchema()
+		if err != nil {
+			logger.Fatal("Couldn't create config schema", zap.Error(err))
+		}
+		app.Get("/config.schema.json", createConfigSchemaHandler(schema, logger))
 	}
 
 	// Stremio endpoints
 
-	// In Fiber optional parameters don't work at the beginning of the URL, so we have to register two routes each
-	manifestHandler := createManifestHandler(a.manifest, logger, a.manifestCallback, a.userDataType, a.opts.UserDataIsBase64)
+	// In Fiber optional parameters don't work at the beginning of the URL, so we have to register two routes each.
+	// HEAD is answered like GET without the body, as monitoring systems and some proxies probe with it.
+	getAndHead := []string{fiber.MethodGet, fiber.MethodHead}
+	a.manifestLock.Lock()
+	snapshot, err := newManifestSnapshot(a.manifest, a.manifestVariants, a.manifestTranslations)
+	if err != nil {
+		logger.Fatal("Couldn't prepare manifest", zap.Error(err))
+	}
+	a.manifestSnapshot.Store(snapshot)
+	a.manifestLock.Unlock()
+	manifestHandler := createManifestHandler(a.manifestSnapshot, logger, a.manifestCallback, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache, a.manifestVariants, a.manifestTranslations)
 	// We always register this route, because even if BehaviorHints.ConfigurationRequired is true, this endpoint is required for the addon to be listed in Stremio's community addons.
-	app.Get("/manifest.json", manifestHandler)
-	app.Get("/:userData/manifest.json", manifestHandler)
+	var manifestMws []fiber.Handler
+	manifestVaryHeaders := a.opts.VaryHeaders
+	if len(a.manifestTranslations.translations) > 0 {
+		// Shared caches must not serve a translated manifest to users with other languages
+		manifestVaryHeaders = append(slices.Clone(manifestVaryHeaders), fiber.HeaderAcceptLanguage)
+	}
+	if len(manifestVaryHeaders) > 0 {
+		manifestMws = append(manifestMws, createVaryMiddleware(manifestVaryHeaders))
+	}
+	app.Add(getAndHead, "/manifest.json", manifestHandler, manifestMws...)
+	app.Add(getAndHead, "/:userData/manifest.json", manifestHandler, manifestMws...)
 	if a.catalogHandlers != nil {
-		catalogHandler := createCatalogHandler(a.catalogHandlers, a.opts.CacheAgeCatalogs, a.opts.StaleRevalidateCatalogs, a.opts.StaleErrorCatalogs, a.opts.CachePublicCatalogs, a.opts.HandleEtagCatalogs, logger, a.userDataType, a.opts.UserDataIsBase64)
+		catalogHandler := createCatalogHandler(a.catalogHandlers, a.opts.CacheAgeCatalogs, a.opts.StaleRevalidateCatalogs, a.opts.StaleErrorCatalogs, a.opts.CachePublicCatalogs, a.opts.HandleEtagCatalogs, a.opts.StreamingThreshold, logger, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache)
+		catalogMws := a.resourceMiddlewares("catalog", "metas", logger)
 		if !a.manifest.BehaviorHints.ConfigurationRequired {
-			app.Get("/catalog/:type/:id.json", catalogHandler)
-			app.Get("/catalog/:type/:id/:extras", catalogHandler)
+			app.Add(getAndHead, "/catalog/:type/:id.json", catalogHandler, catalogMws...)
+			app.Add(getAndHead, "/catalog/:type/:id/:extras", catalogHandler, catalogMws...)
 		}
 		// We always register this route, because we don't know if the addon developer wants to use user data or not, as BehaviorHints.Configurable only indicates the configurability *via Stremio*
-		app.Get("/:userData/catalog/:type/:id.json", catalogHandler)
-		app.Get("/:userData/catalog/:type/:id/:extras", catalogHandler)
+		app.Add(getAndHead, "/:userData/catalog/:type/:id.json", catalogHandler, catalogMws...)
+		app.Add(getAndHead, "/:userData/catalog/:type/:id/:extras", catalogHandler, catalogMws...)
+		// Feed readers aren't Stremio, so the feeds don't go through the resource's middlewares like analytics
+		if a.opts.CatalogFeeds {
+			for _, format := range []string{feedFormatRSS, feedFormatJSONFeed} {
+				feedHandler := createFeedHandler(format, a.manifestSnapshot, a.catalogHandlers, a.killSwitches, logger, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache)
+				if !a.manifest.BehaviorHints.ConfigurationRequired {
+					app.Add(getAndHead, "/feed/:type/:id."+format, feedHandler)
+				}
+				app.Add(getAndHead, "/:userData/feed/:type/:id."+format, feedHandler)
+			}
+		}
 	}
 
 	if a.streamHandlers != nil {
-		streamHandler := createStreamHandler(a.streamHandlers, a.opts.CacheAgeStreams, a.opts.StaleRevalidateStreams, a.opts.StaleErrorStreams, a.opts.CachePublicStreams, a.opts.HandleEtagStreams, logger, a.userDataType, a.opts.UserDataIsBase64)
+		streamHandler := createStreamHandler(a.streamHandlers, a.opts.CacheAgeStreams, a.opts.StaleRevalidateStreams, a.opts.StaleErrorStreams, a.opts.CachePublicStreams, a.opts.HandleEtagStreams, a.opts.StreamingThreshold, logger, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache)
+		streamMws := a.resourceMiddlewares("stream", "streams", logger)
 		if !a.manifest.BehaviorHints.ConfigurationRequired {
-			app.Get("/stream/:type/:id.json", streamHandler)
+			app.Add(getAndHead, "/stream/:type/:id.json", streamHandler, streamMws...)
 		}
 		// We always register this route, because we don't know if the addon developer wants to use user data or not, as BehaviorHints.Configurable only indicates the configurability *via Stremio*
-		app.Get("/:userData/stream/:type/:id.json", streamHandler)
+		app.Add(getAndHead, "/:userData/stream/:type/:id.json", streamHandler, streamMws...)
 	}
 
 	if a.metaHandlers != nil {
-		metaHandler := createMetaHandler(a.metaHandlers, a.opts.CacheAgeMeta, a.opts.StaleRevalidateMeta, a.opts.StaleErrorMeta, a.opts.CachePublicMeta, a.opts.HandleEtagMeta, logger, a.userDataType, a.opts.UserDataIsBase64)
+		metaHandler := createMetaHandler(a.metaHandlers, a.opts.CacheAgeMeta, a.opts.StaleRevalidateMeta, a.opts.StaleErrorMeta, a.opts.CachePublicMeta, a.opts.HandleEtagMeta, logger, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache)
+		metaMws := a.resourceMiddlewares("meta", "", logger)
 		if !a.manifest.BehaviorHints.ConfigurationRequired {
-			app.Get("/meta/:type/:id.json", metaHandler)
+			app.Add(getAndHead, "/meta/:type/:id.json", metaHandler, metaMws...)
 		}
 		// We always register this route, because we don't know if the addon developer wants to use user data or not, as BehaviorHints.Configurable only indicates the configurability *via Stremio*
-		app.Get("/:userData/meta/:type/:id.json", metaHandler)
+		app.Add(getAndHead, "/:userData/meta/:type/:id.json", metaHandler, metaMws...)
 	}
 
 	if a.subtitleHandlers != nil {
-		subtitleHandler := createSubtitleHandler(a.subtitleHandlers, a.opts.CacheAgeStreams, a.opts.StaleRevalidateStreams, a.opts.StaleErrorStreams, a.opts.CachePublicStreams, a.opts.HandleEtagStreams, logger, a.userDataType, a.opts.UserDataIsBase64)
+		subtitleHandler := createSubtitleHandler(a.subtitleHandlers, a.opts.CacheAgeStreams, a.opts.StaleRevalidateStreams, a.opts.StaleErrorStreams, a.opts.CachePublicStreams, a.opts.HandleEtagStreams, a.opts.StreamingThreshold, logger, a.unmarshalUserData, a.opts.UserDataIsBase64, a.userDataCache)
+		subtitleMws := a.resourceMiddlewares("subtitles", "subtitles", logger)
 		if !a.manifest.BehaviorHints.ConfigurationRequired {
-			app.Get("/subtitles/:type/:id.json", subtitleHandler)
+			app.Add(getAndHead, "/subtitles/:type/:id.json", subtitleHandler, subtitleMws...)
 		}
-		app.Get("/:userData/subtitles/:type/:id.json", subtitleHandler)
+		app.Add(getAndHead, "/:userData/subtitles/:type/:id.json", subtitleHandler, subtitleMws...)
 	}
 
+	configurePage := configurePage{
+		manifest:         a.manifest,
+		css:              a.opts.PageCSS,
+		hasAssets:        a.opts.PageAssets != nil,
+		userDataIsBase64: a.opts.UserDataIsBase64,
+		encodeUserData:   a.EncodeUserData,
+		store:            a.opts.ConfigStore,
+		logger:           logger,
+	}
+	if a.opts.ConfigurePage {
+		configurePage.tmpl = configureTemplate
+		if a.opts.ConfigurePageTemplate != nil {
+			configurePage.tmpl = a.opts.ConfigurePageTemplate
+		}
+		configureHandler := createConfigureHandler(configurePage)
+		app.Get("/configure", configureHandler)
+		// Reconfiguring an installed addon leads to this endpoint, which prefills the form with the existing configuration.
+		app.Get("/:userData/configure", configureHandler)
+	}
+	// Custom configure pages can also submit their forms here, as long as the manifest has config items to validate against.
+	if (a.opts.ConfigurePage || a.opts.ConfigureHTMLfs != nil) && len(a.manifest.Config) > 0 {
+		app.Post("/configure", createConfigureSubmitHandler(configurePage))
+	}
 	if a.opts.ConfigureHTMLfs != nil {
 		fsConfig := static.Config{
 			FS: a.opts.ConfigureHTMLfs,
@@ -393,47 +779,84 @@ func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
 
 	// Additional endpoints
 
-	// Root redirects to website
+	// Signed upstream URLs
+	if a.opts.URLSigner != nil {
+		app.Get("/resolve/:token", createResolveHandler(a.opts.URLSigner, logger))
+	}
+	// SRT to WebVTT conversion
+	if a.opts.SubtitleConversion {
+		app.Get("/vtt/:token.vtt", createVTTHandler(a.opts.URLSigner, logger))
+	}
+	// Stream proxy
+	if a.opts.StreamProxy {
+		app.Get("/proxy/:token", createProxyHandler(a.opts.URLSigner, a.opts.MaxProxyConnections, a.opts.MaxProxyConnectionsPerUser, a.opts.MaxProxyBandwidthPerUser, logger))
+	}
+
+	// Root redirects to website or shows the landing page
 	if a.opts.RedirectURL != "" {
 		app.Get("/", createRootHandler(a.opts.RedirectURL, logger))
 	}
+	if a.opts.LandingPage {
+		tmpl := landingTemplate
+		if a.opts.LandingPageTemplate != nil {
+			tmpl = a.opts.LandingPageTemplate
+		}
+		app.Get("/", createLandingHandler(a.manifest, tmpl, a.opts.PageCSS, a.opts.PageAssets != nil, a.opts.ConfigurePage || a.opts.ConfigureHTMLfs != nil, a.opts.InstallQRCode, logger))
+	}
+	if a.opts.ManifestImages {
+		images := newManifestImages(a.manifestSnapshot, logger)
+		app.Get("/logo", createManifestImageHandler(images, "logo", logger))
+		app.Get("/background", createManifestImageHandler(images, "background", logger))
+		app.Get("/favicon.ico", createManifestImageHandler(images, "favicon", logger))
+	}
+	if a.opts.RobotsTxt != "" {
+		app.Get("/robots.txt", createRobotsTxtHandler(a.opts.RobotsTxt, logger))
+	}
+	if a.opts.SecurityTxt != nil {
+		app.Get("/.well-known/security.txt", createSecurityTxtHandler(*a.opts.SecurityTxt, logger))
+	}
+	if a.opts.PageAssets != nil {
+		app.Use("/assets", static.New("", static.Config{FS: a.opts.PageAssets}))
+	}
+	if a.opts.InstallQRCode {
+		qrCodeHandler := createQRCodeHandler(a.manifest, logger)
+		app.Get("/install-qr.png", qrCodeHandler)
+		app.Get("/:userData/install-qr.png", qrCodeHandler)
+	}
+
+	// Optional admin dashboard
+	if a.opts.AdminDashboard {
+		app.Get("/admin", createAdminHandler(a.manifest, a.adminStats, a.userDataCache, a.opts.PageCSS, logger), protectedMws...)
+	}
+	// Optional admin API
+	if a.opts.AdminAPI {
+		group := app.Group("/admin/api", protectedMws...)
+		group.Get("/userdata", createInspectUserDataHandler(a.verifyUserData, a.opts.MaxUserDataLength, a.opts.UserDataIsBase64, logger))
+		if a.opts.ConfigStore != nil {
+			group.Get("/configs", createListConfigsHandler(a.recentConfigs, logger))
+			group.Delete("/configs/:token", createRevokeConfigHandler(a.opts.ConfigStore, a.recentConfigs, logger))
+		}
+		if a.catalogHandlers != nil {
+			group.Get("/catalogs/:type/:id", createExportCatalogHandler(a.manifestSnapshot, a.catalogHandlers, a.verifyUserData, a.unmarshalUserData, a.opts.UserDataIsBase64, logger))
+		}
+	}
+	// Optional usage statistics
+	if a.opts.Analytics != nil && a.opts.APIKey != "" {
+		app.Get("/analytics.json", createAnalyticsHandler(a.opts.Analytics, logger), protectedMws...)
+	}
 
 	// Custom endpoints
 	for _, customEndpoint := range a.customEndpoints {
-		app.Add([]string{customEndpoint.method}, customEndpoint.path, customEndpoint.handler)
+		var mws []fiber.Handler
+		if customEndpoint.protected {
+			mws = append(mws, protectedMws...)
+		}
+		// The API key is checked before the group's middlewares do any work
+		mws = append(mws, customEndpoint.group.handlers()...)
+		app.Add(customEndpoint.methods, customEndpoint.path, customEndpoint.handler, mws...)
 	}
 
 	logger.Info("Finished setting up server")
 
-	stopping := false
-	stoppingPtr := &stopping
-
-	addr := a.opts.BindAddr + ":" + strconv.Itoa(a.opts.Port)
-	logger.Info("Starting server", zap.String("address", addr))
-	go func() {
-		if err := app.Listen(addr, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
-			if !*stoppingPtr {
-				logger.Fatal("Couldn't start server", zap.Error(err))
-			} else {
-				logger.Fatal("Error in srv.ListenAndServe() during server shutdown (probably context deadline expired before the server could shutdown cleanly)", zap.Error(err))
-			}
-		}
-	}()
-
-	// Graceful shutdown
-
-	c := make(chan os.Signal, 1)
-	// Accept SIGINT (Ctrl+C) and SIGTERM (`docker stop`)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	sig := <-c
-	logger.Info("Received signal, shutting down server...", zap.Stringer("signal", sig))
-	*stoppingPtr = true
-	if stoppingChan != nil {
-		stoppingChan <- true
-	}
-	// Graceful shutdown, waiting for all current requests to finish without accepting new ones.
-	if err := app.Shutdown(); err != nil {
-		logger.Fatal("Error shutting down server", zap.Error(err))
-	}
-	logger.Info("Finished shutting down server")
+	return app
 }
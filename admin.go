@@ -0,0 +1,233 @@
+package stremio
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/url"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/pkg/recording"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+var adminTemplate = template.Must(withStyleTemplate(template.Must(template.ParseFS(templatesFS, "templates/admin.html"))))
+
+const (
+	// Max number of distinct IDs that are counted for the top IDs, so the memory usage doesn't grow without bound.
+	adminMaxIDs = 1000
+	// Number of top IDs and recent errors that the dashboard shows.
+	adminTopIDs       = 10
+	adminRecentErrors = 20
+	// Window for the request and error rates, in seconds.
+	adminRateWindowSec = 60
+)
+
+// adminStats are the live statistics for the admin dashboard. They're kept in memory and reset when the addon restarts.
+// It's safe for concurrent use.
+type adminStats struct {
+	started      time.Time
+	requests     int64
+	clientErrors int64
+	serverErrors int64
+	// Requests and server errors per second of the last minute, indexed by Unix second modulo the window
+	seconds      [adminRateWindowSec]adminSecond
+	ids          map[string]int64
+	recentErrors []adminError
+	lock         *sync.Mutex
+}
+
+type adminSecond struct {
+	unix     int64
+	requests int64
+	errors   int64
+}
+
+type adminError struct {
+	Time   time.Time
+	Method string
+	Path   string
+	Status int
+}
+
+type adminCount struct {
+	Key   string
+	Count int64
+}
+
+func newAdminStats() *adminStats {
+	return &adminStats{
+		started: time.Now(),
+		ids:     map[string]int64{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (s *adminStats) recordRequest(method, path string, status int) {
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests++
+	second := &s.seconds[now.Unix()%adminRateWindowSec]
+	if second.unix != now.Unix() {
+		*second = adminSecond{unix: now.Unix()}
+	}
+	second.requests++
+	switch {
+	case status >= 500:
+		s.serverErrors++
+		second.errors++
+		s.recentErrors = append(s.recentErrors, adminError{Time: now, Method: method, Path: path, Status: status})
+		if len(s.recentErrors) > adminRecentErrors {
+			s.recentErrors = s.recentErrors[1:]
+		}
+	case status >= 400:
+		s.clientErrors++
+	}
+}
+
+func (s *adminStats) recordID(resource, mediaType, id string) {
+	key := resource + "/" + mediaType + "/" + id
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.ids[key]; ok || len(s.ids) < adminMaxIDs {
+		s.ids[key]++
+	}
+}
+
+// adminDashboardData is the data that the admin dashboard template is executed with.
+type adminDashboardData struct {
+	Manifest     types.Manifest
+	CSS          template.CSS
+	Uptime       time.Duration
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	// Requests per second and percentage of server errors in the last minute
+	RequestRate float64
+	ErrorRate   float64
+	TopIDs      []adminCount
+	// Hits and misses of the user data cache, nil if there's no cache
+	UserDataCache *adminCacheStats
+	Goroutines    int
+	HeapAllocMB   float64
+	SysMB         float64
+	// Most recent first
+	RecentErrors []adminError
+}
+
+type adminCacheStats struct {
+	Hits     int64
+	Misses   int64
+	HitRatio float64
+}
+
+func (s *adminStats) dashboardData() adminDashboardData {
+	now := time.Now()
+	s.lock.Lock()
+	data := adminDashboardData{
+		Uptime:       now.Sub(s.started).Round(time.Second),
+		Requests:     s.requests,
+		ClientErrors: s.clientErrors,
+		ServerErrors: s.serverErrors,
+	}
+	var windowRequests, windowErrors int64
+	for _, second := range s.seconds {
+		if now.Unix()-second.unix < adminRateWindowSec {
+			windowRequests += second.requests
+			windowErrors += second.errors
+		}
+	}
+	for key, count := range s.ids {
+		data.TopIDs = append(data.TopIDs, adminCount{Key: key, Count: count})
+	}
+	for i := len(s.recentErrors) - 1; i >= 0; i-- {
+		data.RecentErrors = append(data.RecentErrors, s.recentErrors[i])
+	}
+	s.lock.Unlock()
+
+	data.RequestRate = float64(windowRequests) / adminRateWindowSec
+	if windowRequests > 0 {
+		data.ErrorRate = 100 * float64(windowErrors) / float64(windowRequests)
+	}
+	sort.Slice(data.TopIDs, func(i, j int) bool {
+		if data.TopIDs[i].Count != data.TopIDs[j].Count {
+			return data.TopIDs[i].Count > data.TopIDs[j].Count
+		}
+		return data.TopIDs[i].Key < data.TopIDs[j].Key
+	})
+	if len(data.TopIDs) > adminTopIDs {
+		data.TopIDs = data.TopIDs[:adminTopIDs]
+	}
+	return data
+}
+
+// createAdminStatsMiddleware creates a middleware that counts all requests and their errors for the admin dashboard.
+func createAdminStatsMiddleware(stats *adminStats) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberError *fiber.Error
+			if errors.As(err, &fiberError) {
+				status = fiberError.Code
+			}
+		}
+		// The user data can contain credentials of the users
+		stats.recordRequest(c.Method(), recording.RedactUserData(c.Path()), status)
+		return err
+	}
+}
+
+// createAdminIDMiddleware creates a middleware that counts the successful requests of a resource by ID for the admin dashboard.
+func createAdminIDMiddleware(stats *adminStats, resource string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+		if id, err := url.PathUnescape(c.Params("id")); err == nil {
+			stats.recordID(resource, c.Params("type"), id)
+		}
+		return nil
+	}
+}
+
+func createAdminHandler(manifest types.Manifest, stats *adminStats, cache *userDataCache, css string, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("adminHandler called")
+
+		data := stats.dashboardData()
+		data.Manifest = manifest
+		data.CSS = template.CSS(css)
+		if cache != nil {
+			hits, misses := cache.hits.Load(), cache.misses.Load()
+			data.UserDataCache = &adminCacheStats{Hits: hits, Misses: misses}
+			if hits+misses > 0 {
+				data.UserDataCache.HitRatio = 100 * float64(hits) / float64(hits+misses)
+			}
+		}
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		data.Goroutines = runtime.NumGoroutine()
+		data.HeapAllocMB = float64(memStats.HeapAlloc) / (1 << 20)
+		data.SysMB = float64(memStats.Sys) / (1 << 20)
+
+		var buf bytes.Buffer
+		if err := adminTemplate.Execute(&buf, data); err != nil {
+			logger.Error("Couldn't execute admin template", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Send(buf.Bytes())
+	}
+}
@@ -0,0 +1,136 @@
+package stremio
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// recentConfigsMax is the max number of config tokens that the admin API remembers.
+const recentConfigsMax = 1000
+
+// recentConfigs remembers when config tokens of the ConfigStore were last seen, for the admin API.
+// It's safe for concurrent use.
+type recentConfigs struct {
+	lastSeen map[string]time.Time
+	lock     *sync.Mutex
+}
+
+// RecentConfig is a config token that the admin API lists, see Options.AdminAPI.
+type RecentConfig struct {
+	Token    string    `json:"token"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+func newRecentConfigs() *recentConfigs {
+	return &recentConfigs{
+		lastSeen: map[string]time.Time{},
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (r *recentConfigs) seen(token string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.lastSeen[token]; !ok && len(r.lastSeen) >= recentConfigsMax {
+		var oldestToken string
+		var oldest time.Time
+		for t, lastSeen := range r.lastSeen {
+			if oldestToken == "" || lastSeen.Before(oldest) {
+				oldestToken, oldest = t, lastSeen
+			}
+		}
+		delete(r.lastSeen, oldestToken)
+	}
+	// Fiber's params point into the request buffer, which is reused for other requests.
+	r.lastSeen[strings.Clone(token)] = time.Now()
+}
+
+func (r *recentConfigs) remove(token string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.lastSeen, token)
+}
+
+// list returns the tokens, most recently seen first.
+func (r *recentConfigs) list() []RecentConfig {
+	r.lock.Lock()
+	configs := make([]RecentConfig, 0, len(r.lastSeen))
+	for token, lastSeen := range r.lastSeen {
+		configs = append(configs, RecentConfig{Token: token, LastSeen: lastSeen})
+	}
+	r.lock.Unlock()
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].LastSeen.After(configs[j].LastSeen)
+	})
+	return configs
+}
+
+// configDeleter is implemented by stores that can delete values, like the ones of the pkg/store packages.
+type configDeleter interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// UserDataInspection is the admin API's response for inspecting user data, see Options.AdminAPI.
+type UserDataInspection struct {
+	// Whether the user data can be used, meaning its signature or JWT is valid, its config token is known and it can be decoded.
+	Valid bool `json:"valid"`
+	// Reason why the user data is invalid.
+	Error string `json:"error,omitempty"`
+	// Decoded user data, as generic JSON value.
+	UserData any `json:"userData,omitempty"`
+}
+
+func createInspectUserDataHandler(verify func(ctx context.Context, userData string) (string, error), maxLength int, userDataIsBase64 bool, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("inspectUserDataHandler called")
+
+		data := c.Query("data")
+		if data == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("missing \"data\" query parameter")
+		}
+		if maxLength != 0 && len(data) > maxLength {
+			return c.JSON(UserDataInspection{Error: ErrUserDataTooLong.Error()})
+		}
+		verified, err := verify(c.Context(), data)
+		if err != nil {
+			return c.JSON(UserDataInspection{Error: err.Error()})
+		}
+		userData, err := decodeUserData(verified, unmarshalUserDataJSON[any], logger, userDataIsBase64)
+		if err != nil {
+			return c.JSON(UserDataInspection{Error: err.Error()})
+		}
+		return c.JSON(UserDataInspection{Valid: true, UserData: userData})
+	}
+}
+
+func createListConfigsHandler(configs *recentConfigs, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("listConfigsHandler called")
+		return c.JSON(configs.list())
+	}
+}
+
+func createRevokeConfigHandler(configStore ConfigStore, configs *recentConfigs, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("revokeConfigHandler called")
+
+		deleter, ok := configStore.(configDeleter)
+		if !ok {
+			return c.Status(fiber.StatusNotImplemented).SendString("the ConfigStore can't delete configurations")
+		}
+		token := c.Params("token")
+		if err := deleter.Delete(c.Context(), configStoreKeyPrefix+token); err != nil {
+			logger.Error("Couldn't revoke config token", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		configs.remove(token)
+		logger.Info("Revoked config token")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
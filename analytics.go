@@ -0,0 +1,64 @@
+package stremio
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/pkg/analytics"
+	"go.uber.org/zap"
+)
+
+// createAnalyticsMiddleware creates a middleware that counts the successful requests of a resource.
+func createAnalyticsMiddleware(collector *analytics.Collector, resource string, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		// HEAD requests are probes, not users
+		if c.Response().StatusCode() != fiber.StatusOK || c.Method() == fiber.MethodHead {
+			return nil
+		}
+		id, err := url.PathUnescape(c.Params("id"))
+		if err != nil {
+			logger.Warn("Couldn't unescape ID", zap.Error(err))
+			return nil
+		}
+		collector.Record(resource, c.Params("type"), id, userDataParam(c))
+		return nil
+	}
+}
+
+// analyticsDefaultDays is the number of days that the analytics endpoint returns without a "from" query parameter.
+const analyticsDefaultDays = 7
+
+func createAnalyticsHandler(collector *analytics.Collector, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("analyticsHandler called")
+
+		to := time.Now().UTC()
+		if s := c.Query("to"); s != "" {
+			var err error
+			if to, err = time.Parse(time.DateOnly, s); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("invalid \"to\" date")
+			}
+		}
+		from := to.AddDate(0, 0, -(analyticsDefaultDays - 1))
+		if s := c.Query("from"); s != "" {
+			var err error
+			if from, err = time.Parse(time.DateOnly, s); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("invalid \"from\" date")
+			}
+		}
+
+		days, err := collector.Export(c.Context(), from, to)
+		if err != nil {
+			logger.Error("Couldn't export analytics", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if days == nil {
+			days = []analytics.Day{}
+		}
+		return c.JSON(days)
+	}
+}
@@ -0,0 +1,99 @@
+package stremio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// canary is an alternative handler for a resource and type that gets a percentage of the requests.
+type canary struct {
+	handler    handler
+	percentage float64
+}
+
+// handlerMetrics are the metrics of the primary or canary handler of a resource and type.
+type handlerMetrics struct {
+	requests *metrics.Counter
+	errors   *metrics.Counter
+	duration *metrics.Histogram
+}
+
+func newHandlerMetrics(resource, mediaType, variant string) handlerMetrics {
+	labels := `{resource="` + resource + `",type="` + mediaType + `",variant="` + variant + `"}`
+	return handlerMetrics{
+		requests: metrics.GetOrCreateCounter("handler_requests_total" + labels),
+		errors:   metrics.GetOrCreateCounter("handler_errors_total" + labels),
+		duration: metrics.GetOrCreateHistogram("handler_duration_seconds" + labels),
+	}
+}
+
+func (m handlerMetrics) call(h handler, ctx context.Context, id string, extra url.Values, userData any) (any, error) {
+	start := time.Now()
+	res, err := h(ctx, id, extra, userData)
+	m.duration.UpdateDuration(start)
+	m.requests.Inc()
+	// Unknown IDs and bad requests aren't the handler's fault
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrBadRequest) {
+		m.errors.Inc()
+	}
+	return res, err
+}
+
+// withCanaries returns the handlers, with the ones that have a canary replaced by a handler that sends the canary's percentage of requests to it.
+func withCanaries(resource string, handlers map[string]handler, canaries map[string]canary) map[string]handler {
+	for mediaType, c := range canaries {
+		primary, ok := handlers[mediaType]
+		if !ok {
+			continue
+		}
+		primaryMetrics := newHandlerMetrics(resource, mediaType, "primary")
+		canaryMetrics := newHandlerMetrics(resource, mediaType, "canary")
+		handlers[mediaType] = func(ctx context.Context, id string, extra url.Values, userData any) (any, error) {
+			if rand.Float64()*100 < c.percentage {
+				return canaryMetrics.call(c.handler, ctx, id, extra, userData)
+			}
+			return primaryMetrics.call(primary, ctx, id, extra, userData)
+		}
+	}
+	return handlers
+}
+
+func addCanary(l *liveHandlers, resource, mediaType string, percentage float64, h handler) error {
+	switch {
+	case !l.has(mediaType):
+		return fmt.Errorf("there's no %v handler for the type %q", resource, mediaType)
+	case percentage < 0 || percentage > 100:
+		return errors.New("the canary percentage must be between 0 and 100")
+	}
+	l.addCanary(mediaType, canary{handler: h, percentage: percentage})
+	return nil
+}
+
+// AddCanaryCatalogHandler adds a canary for the catalog handler of the type, which gets the given percentage of the requests,
+// for validating a rewritten handler in production. The primary and canary handlers get separate metrics:
+// "handler_requests_total", "handler_errors_total" and "handler_duration_seconds" with the labels "resource", "type" and "variant",
+// which is either "primary" or "canary". Like AddEndpoint, it must be called before running the addon.
+func (a *Addon) AddCanaryCatalogHandler(mediaType string, percentage float64, h CatalogHandler) error {
+	return addCanary(a.catalogHandlers, "catalog", mediaType, percentage, convertCatalogHandler(h))
+}
+
+// AddCanaryStreamHandler adds a canary for the stream handler of the type, like AddCanaryCatalogHandler.
+func (a *Addon) AddCanaryStreamHandler(mediaType string, percentage float64, h StreamHandler) error {
+	return addCanary(a.streamHandlers, "stream", mediaType, percentage, convertStreamHandler(h))
+}
+
+// AddCanaryMetaHandler adds a canary for the meta handler of the type, like AddCanaryCatalogHandler.
+func (a *Addon) AddCanaryMetaHandler(mediaType string, percentage float64, h MetaHandler) error {
+	return addCanary(a.metaHandlers, "meta", mediaType, percentage, convertMetaHandler(h))
+}
+
+// AddCanarySubtitleHandler adds a canary for the subtitle handler of the type, like AddCanaryCatalogHandler.
+func (a *Addon) AddCanarySubtitleHandler(mediaType string, percentage float64, h SubtitleHandler) error {
+	return addCanary(a.subtitleHandlers, "subtitles", mediaType, percentage, convertSubtitleHandler(h))
+}
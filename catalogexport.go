@@ -0,0 +1,109 @@
+package stremio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// catalogExportMaxItems limits the catalog export, in case a handler ignores the "skip" extra and returns the same page forever.
+const catalogExportMaxItems = 100_000
+
+func createExportCatalogHandler(manifest *atomic.Pointer[manifestSnapshot], handlers *liveHandlers, verify func(ctx context.Context, userData string) (string, error), unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("exportCatalogHandler called")
+
+		// Fiber's params point into the request buffer, which is reused before the response is streamed.
+		catalogType, id := strings.Clone(c.Params("type")), strings.Clone(c.Params("id"))
+		catalogs := manifest.Load().bodies.manifest.Catalogs
+		i := slices.IndexFunc(catalogs, func(catalog types.CatalogItem) bool {
+			return catalog.Type == catalogType && catalog.ID == id
+		})
+		reqHandler, ok := handlers.get(catalogType)
+		if i == -1 || !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		pagination := slices.ContainsFunc(catalogs[i].Extra, func(extra types.ExtraItem) bool { return extra.Name == types.ExtraSkip })
+
+		// The other query parameters except the API key are passed as extras, like "genre"
+		extra := url.Values{}
+		for key, value := range c.Queries() {
+			if key != "data" && key != "api_key" && key != types.ExtraSkip {
+				extra.Set(strings.Clone(key), strings.Clone(value))
+			}
+		}
+		var userData any
+		data := c.Query("data")
+		switch {
+		case unmarshalUserData == nil:
+			userData = strings.Clone(data)
+		case data != "":
+			verified, err := verify(c.Context(), data)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+			}
+			if userData, err = decodeUserData(verified, unmarshalUserData, logger, userDataIsBase64); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+			}
+		}
+
+		// The first page is fetched before responding, so errors lead to a proper status code
+		ctx := c.Context()
+		res, err := reqHandler(ctx, id, extra, userData)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNotFound):
+				return c.SendStatus(fiber.StatusNotFound)
+			case errors.Is(err, ErrBadRequest):
+				return c.SendStatus(fiber.StatusBadRequest)
+			default:
+				logger.Error("Addon returned error", zap.Error(err), zap.String("requestedType", catalogType), zap.String("requestedID", id))
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		metas, _ := res.([]types.MetaPreviewItem)
+
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		return c.SendStreamWriter(func(w *bufio.Writer) {
+			encoder := json.NewEncoder(w)
+			exported := 0
+			for {
+				for _, meta := range metas {
+					if err := encoder.Encode(meta); err != nil {
+						return
+					}
+				}
+				// Flushing fails when the client is gone
+				if err := w.Flush(); err != nil {
+					return
+				}
+				exported += len(metas)
+				if !pagination || len(metas) == 0 {
+					return
+				}
+				if exported >= catalogExportMaxItems {
+					logger.Warn("Stopped catalog export at the max number of items", zap.String("requestedType", catalogType), zap.String("requestedID", id))
+					return
+				}
+				extra.Set(types.ExtraSkip, strconv.Itoa(exported))
+				res, err := reqHandler(ctx, id, extra, userData)
+				if err != nil {
+					// The status was already sent, so the export just ends early
+					logger.Error("Couldn't export catalog page", zap.Error(err), zap.String("requestedType", catalogType), zap.String("requestedID", id), zap.Int("skip", exported))
+					return
+				}
+				metas, _ = res.([]types.MetaPreviewItem)
+			}
+		})
+	}
+}
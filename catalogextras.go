@@ -0,0 +1,114 @@
+package stremio
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// CatalogExtras are the extras that the CatalogHandler supports for a catalog, see SetCatalogExtras.
+type CatalogExtras struct {
+	// Whether the handler supports the "search" extra, see types.CatalogItem.WithSearch
+	Search bool
+	// Whether the catalog can only be searched, see types.CatalogItem.WithRequiredSearch. Implies Search.
+	SearchRequired bool
+	// Genres for the "genre" extra, see types.CatalogItem.WithGenres. Nil means the handler doesn't filter by genre.
+	Genres []string
+	// Whether a genre must be selected, see types.CatalogItem.WithRequiredGenre. Requires Genres.
+	GenreRequired bool
+	// Whether the handler supports the "skip" extra for pagination, see types.CatalogItem.WithSkip
+	Skip bool
+}
+
+// apply returns a copy of the catalog with exactly the declared extras of the ones that Stremio knows. Custom extras are kept.
+func (e CatalogExtras) apply(catalog types.CatalogItem) types.CatalogItem {
+	catalog.Extra = slices.DeleteFunc(slices.Clone(catalog.Extra), func(extra types.ExtraItem) bool {
+		return extra.Name == types.ExtraSearch || extra.Name == types.ExtraGenre || extra.Name == types.ExtraSkip
+	})
+	switch {
+	case e.SearchRequired:
+		catalog = catalog.WithRequiredSearch()
+	case e.Search:
+		catalog = catalog.WithSearch()
+	}
+	switch {
+	case e.GenreRequired:
+		catalog = catalog.WithRequiredGenre(e.Genres...)
+	case e.Genres != nil:
+		catalog = catalog.WithGenres(e.Genres...)
+	}
+	if e.Skip {
+		catalog = catalog.WithSkip()
+	}
+	return catalog
+}
+
+// applyCatalogExtras sets the declared extras in the manifest's catalogs. The manifest lock must be held.
+func (a *Addon) applyCatalogExtras(manifest *types.Manifest) {
+	for i, catalog := range manifest.Catalogs {
+		if extras, ok := a.catalogExtras[catalog.Type+"/"+catalog.ID]; ok {
+			manifest.Catalogs[i] = extras.apply(catalog)
+		}
+	}
+}
+
+// SetCatalogExtras declares the extras that the CatalogHandler supports for the catalog with the type and ID,
+// and sets the matching ExtraItems in the manifest's catalog, replacing the "search", "genre" and "skip" extras it had.
+// Declaring them next to the handler prevents the manifest and the handler from disagreeing, for example when the handler
+// gets pagination but the manifest doesn't announce it, so Stremio never loads a second page.
+// The declared extras are kept when the manifest is changed with UpdateManifest.
+// The catalog must be in the manifest and there must be a CatalogHandler for its type.
+// Like AddEndpoint, it must be called before running the addon.
+func (a *Addon) SetCatalogExtras(catalogType, id string, extras CatalogExtras) error {
+	a.manifestLock.Lock()
+	defer a.manifestLock.Unlock()
+
+	switch {
+	case !slices.ContainsFunc(a.manifest.Catalogs, func(catalog types.CatalogItem) bool { return catalog.Type == catalogType && catalog.ID == id }):
+		return fmt.Errorf("the manifest has no catalog with the type %q and ID %q", catalogType, id)
+	case !a.catalogHandlers.has(catalogType):
+		return fmt.Errorf("there's no CatalogHandler for the type %q", catalogType)
+	case extras.GenreRequired && len(extras.Genres) == 0:
+		return errors.New("requiring a genre only makes sense when also setting Genres")
+	}
+	a.catalogExtras[catalogType+"/"+id] = extras
+	a.applyCatalogExtras(&a.manifest)
+	return nil
+}
+
+// UpdateCatalogGenres changes the options of the "genre" extra of the catalog with the type and ID while the addon is running,
+// for genres that are derived from the catalog's items with types.Genres, so the Discover sidebar always matches the data.
+// The catalog gets a "genre" extra if it doesn't have one yet, and declared extras (see SetCatalogExtras) are changed as well.
+// Like UpdateManifest it's safe for concurrent use.
+func (a *Addon) UpdateCatalogGenres(catalogType, id string, genres []string) error {
+	found := false
+	// The update function is called with the manifest lock held, which also guards the declared extras
+	err := a.UpdateManifest(func(manifest *types.Manifest) {
+		i := slices.IndexFunc(manifest.Catalogs, func(catalog types.CatalogItem) bool { return catalog.Type == catalogType && catalog.ID == id })
+		if i == -1 {
+			return
+		}
+		found = true
+		key := catalogType + "/" + id
+		if extras, ok := a.catalogExtras[key]; ok {
+			extras.Genres = slices.Clone(genres)
+			a.catalogExtras[key] = extras
+		}
+		catalog := manifest.Catalogs[i]
+		genre := types.ExtraItem{Name: types.ExtraGenre, OptionsLimit: 1}
+		if j := slices.IndexFunc(catalog.Extra, func(extra types.ExtraItem) bool { return extra.Name == types.ExtraGenre }); j != -1 {
+			genre = catalog.Extra[j]
+		}
+		genre.Options = slices.Clone(genres)
+		manifest.Catalogs[i] = catalog.WithExtra(genre)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("the manifest has no catalog with the type %q and ID %q", catalogType, id)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// Command go-stremio is a tool for developing Stremio addons with go-stremio.
+//
+// Usage:
+//
+//	go-stremio new [-module path] [-id id] [-name name] [-resources list] [-types list] [-configurable] <directory>
+//	go-stremio check [-id type=id]... [-userdata value] [-json] <addon URL>
+//	go-stremio publish [-id type=id]... [-skip-check] <manifest URL>
+//	go-stremio replay [-v] <recording file> <addon URL>
+//	go-stremio loadtest [-c n] [-d duration] [-rate n] [-id type=id]... [-userdata value] [-baseline file] [-json] <addon URL>
+//
+// The new command generates a ready-to-run addon skeleton with a manifest, handlers, tests and a Dockerfile.
+// The check command checks the endpoints of a running addon like a linter and exits with status 1 if a check failed.
+// The publish command checks an addon and submits it to Stremio's community addon collection.
+// The loadtest command generates realistic Stremio traffic against an addon and reports latency percentiles.
+// With a baseline report of a previous run (created with -json) it also prints the change of the p99 latency and error rate.
+// The replay command re-issues the requests of a recording (see the RecordFile option) and exits with status 1 if a response differs.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xybydy/go-stremio/internal/scaffold"
+	"github.com/xybydy/go-stremio/pkg/addoncheck"
+	"github.com/xybydy/go-stremio/pkg/loadtest"
+	"github.com/xybydy/go-stremio/pkg/publish"
+	"github.com/xybydy/go-stremio/pkg/recording"
+)
+
+const usage = `go-stremio is a tool for developing Stremio addons with go-stremio.
+
+Usage:
+
+	go-stremio <command> [arguments]
+
+Commands:
+
+	new      generate a new addon
+	check    check the endpoints of a running addon like a linter
+	publish  check an addon and submit it to Stremio's community addon collection
+	replay   replay recorded requests against an addon and compare the responses
+	loadtest generate realistic traffic against an addon and report latency percentiles
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "new":
+		os.Exit(newAddon(os.Args[2:]))
+	case "check":
+		os.Exit(check(os.Args[2:]))
+	case "publish":
+		os.Exit(publishAddon(os.Args[2:]))
+	case "replay":
+		os.Exit(replay(os.Args[2:]))
+	case "loadtest":
+		os.Exit(loadTest(os.Args[2:]))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%v", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+func newAddon(args []string) int {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	module := fs.String("module", "", "Go module path (default: the directory name)")
+	id := fs.String("id", "", `addon ID (default: "com.example." + the directory name)`)
+	name := fs.String("name", "", "addon name (default: the directory name)")
+	resources := fs.String("resources", "stream", "comma-separated resources to generate handlers for ("+strings.Join(scaffold.Resources, ", ")+")")
+	types := fs.String("types", "movie,series", "comma-separated types")
+	configurable := fs.Bool("configurable", false, "generate user data and a configure page")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-stremio new [-module path] [-id id] [-name name] [-resources list] [-types list] [-configurable] <directory>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dir := fs.Arg(0)
+	base := filepath.Base(filepath.Clean(dir))
+	opts := scaffold.Options{
+		Module:       *module,
+		ID:           *id,
+		Name:         *name,
+		Resources:    splitList(*resources),
+		Types:        splitList(*types),
+		Configurable: *configurable,
+	}
+	if opts.Module == "" {
+		opts.Module = base
+	}
+	if opts.ID == "" {
+		opts.ID = "com.example." + base
+	}
+	if opts.Name == "" {
+		opts.Name = base
+	}
+	files, err := scaffold.Generate(dir, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't generate addon:", err)
+		return 1
+	}
+	for _, file := range files {
+		fmt.Println("created", filepath.Join(dir, file))
+	}
+	fmt.Printf("\nNext steps:\n\n\tcd %v\n\tgo get github.com/xybydy/go-stremio@latest && go mod tidy\n\tgo test ./...\n\tgo run .\n", dir)
+	return 0
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// sampleIDs is a flag for sample IDs like "movie=tt1254207", which can be repeated.
+type sampleIDs map[string]string
+
+func (s sampleIDs) String() string {
+	return fmt.Sprint(map[string]string(s))
+}
+
+func (s sampleIDs) Set(value string) error {
+	t, id, ok := strings.Cut(value, "=")
+	if !ok || t == "" || id == "" {
+		return fmt.Errorf("expected type=id, got %q", value)
+	}
+	s[t] = id
+	return nil
+}
+
+func check(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	ids := sampleIDs{}
+	for t, id := range addoncheck.DefaultOptions.SampleIDs {
+		ids[t] = id
+	}
+	fs.Var(ids, "id", "sample ID for stream, meta and subtitle requests, as type=id (repeatable)")
+	userData := fs.String("userdata", "", "user data for addons that require configuration, as it's used in the URL")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-stremio check [-id type=id]... [-userdata value] [-json] <addon URL>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	report := addoncheck.Check(context.Background(), fs.Arg(0), addoncheck.Options{
+		SampleIDs: ids,
+		UserData:  *userData,
+	})
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		fmt.Print(report)
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+func publishAddon(args []string) int {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	ids := sampleIDs{}
+	for t, id := range addoncheck.DefaultOptions.SampleIDs {
+		ids[t] = id
+	}
+	fs.Var(ids, "id", "sample ID for checking stream, meta and subtitle requests, as type=id (repeatable)")
+	skipCheck := fs.Bool("skip-check", false, "don't check the addon before publishing")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-stremio publish [-id type=id]... [-skip-check] <manifest URL>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	report, err := publish.Publish(context.Background(), fs.Arg(0), publish.Options{
+		CheckOptions: addoncheck.Options{SampleIDs: ids},
+		SkipCheck:    *skipCheck,
+	})
+	if errors.Is(err, publish.ErrCheckFailed) {
+		fmt.Print(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't publish addon:", err)
+		return 1
+	}
+	fmt.Println("Published", fs.Arg(0))
+	return 0
+}
+
+func replay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "print the recorded and the actual body of differing responses")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-stremio replay [-v] <recording file> <addon URL>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	entries, err := recording.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't read recording:", err)
+		return 1
+	}
+	failed := 0
+	for _, result := range recording.Replay(context.Background(), fs.Arg(1), entries, nil) {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Printf("ERROR %v %v: %v\n", result.Entry.Method, result.Entry.Path, result.Err)
+		case !result.Match:
+			failed++
+			fmt.Printf("DIFF  %v %v: status %d, recorded %d\n", result.Entry.Method, result.Entry.Path, result.Status, result.Entry.Status)
+			if *verbose {
+				fmt.Printf("\trecorded: %v\n\tactual:   %v\n", result.Entry.ResponseBody, result.Body)
+			}
+		default:
+			fmt.Printf("OK    %v %v\n", result.Entry.Method, result.Entry.Path)
+		}
+	}
+	fmt.Printf("\n%d of %d responses differ\n", failed, len(entries))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// sampleIDLists is a flag for sample IDs like "movie=tt1254207", which can be repeated, also for the same type.
+type sampleIDLists map[string][]string
+
+func (s sampleIDLists) String() string {
+	return fmt.Sprint(map[string][]string(s))
+}
+
+func (s sampleIDLists) Set(value string) error {
+	t, id, ok := strings.Cut(value, "=")
+	if !ok || t == "" || id == "" {
+		return fmt.Errorf("expected type=id, got %q", value)
+	}
+	s[t] = append(s[t], id)
+	return nil
+}
+
+func loadTest(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	concurrency := fs.Int("c", loadtest.DefaultOptions.Concurrency, "number of virtual users")
+	duration := fs.Duration("d", loadtest.DefaultOptions.Duration, "duration of the load test")
+	rate := fs.Int("rate", 0, "max number of requests per second, 0 means no limit")
+	ids := sampleIDLists{}
+	fs.Var(ids, "id", "sample ID for stream requests, as type=id, without season and episode for TV shows (repeatable, default: "+fmt.Sprint(loadtest.DefaultOptions.SampleIDs)+")")
+	userData := fs.String("userdata", "", "user data for addons that require configuration, as it's used in the URL")
+	baselineFile := fs.String("baseline", "", "JSON report of a previous run to compare with")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-stremio loadtest [-c n] [-d duration] [-rate n] [-id type=id]... [-userdata value] [-baseline file] [-json] <addon URL>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var baseline *loadtest.Report
+	if *baselineFile != "" {
+		b, err := os.ReadFile(*baselineFile)
+		if err == nil {
+			err = json.Unmarshal(b, &baseline)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't read baseline report:", err)
+			return 1
+		}
+	}
+	opts := loadtest.Options{
+		Duration:    *duration,
+		Concurrency: *concurrency,
+		Rate:        *rate,
+		UserData:    *userData,
+	}
+	if len(ids) > 0 {
+		opts.SampleIDs = ids
+	}
+	report, err := loadtest.Run(context.Background(), fs.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't run load test:", err)
+		return 1
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+		return 0
+	}
+	fmt.Print(report)
+	if baseline != nil {
+		p99Change, errorRateChange, err := loadtest.Compare(*baseline, report)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't compare with baseline:", err)
+			return 1
+		}
+		fmt.Printf("\nCompared to the baseline: p99 latency %+.1f%%, error rate %+.2f percentage points\n", p99Change*100, errorRateChange*100)
+	}
+	return 0
+}
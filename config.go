@@ -1,9 +1,14 @@
 package stremio
 
 import (
+	"context"
+	"html/template"
 	"io/fs"
 	"time"
 
+	"github.com/xybydy/go-stremio/pkg/analytics"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
 	"go.uber.org/zap"
 )
 
@@ -46,16 +51,156 @@ type Options struct {
 	// When no value is set, it will lead to a "404 Not Found" response.
 	// Default "".
 	RedirectURL string
+	// Flag for indicating whether to serve a landing page at the root of the handler.
+	// It shows the addon's name, logo, description and version from the manifest, and buttons for installing and configuring the addon,
+	// so you don't need to write any HTML. Can't be used together with RedirectURL.
+	// Default false.
+	LandingPage bool
+	// Flag for indicating whether to show a QR code of the "stremio://" install URL on the landing page,
+	// so users can install the addon on Android TV devices by scanning it with their phone.
+	// It also registers the "/install-qr.png" and "/:userData/install-qr.png" endpoints,
+	// which configure pages can use to show a QR code for the configured addon.
+	// Default false.
+	InstallQRCode bool
+	// Custom template for the landing page, for branding it while still generating it from the manifest.
+	// It's executed with a LandingPageData. It can include the built-in "style" template for the default styling.
+	// Only makes sense when also setting LandingPage.
+	// Default nil, meaning the built-in template.
+	LandingPageTemplate *template.Template
+	// Flag for indicating whether to serve a configure page at "/configure" that's generated from the manifest's config items,
+	// so simple addons don't need to write any HTML. The form is submitted to "POST /configure", which validates the values
+	// and redirects to the install link with the values as user data, encoded like the addon expects it,
+	// with checkboxes as booleans and numbers as numbers.
+	// Requires the addon to be configurable and can't be used together with ConfigureHTMLfs.
+	// Default false.
+	ConfigurePage bool
+	// Custom template for the configure page, like LandingPageTemplate. It's executed with a ConfigurePageData.
+	// Only makes sense when also setting ConfigurePage.
+	// Default nil, meaning the built-in template.
+	ConfigurePageTemplate *template.Template
+	// Flag for indicating whether to serve a JSON Schema of the manifest's config items at "/config.schema.json",
+	// for external configurator UIs and validation tooling. See Addon.ConfigSchema.
+	// Default false.
+	ConfigSchema bool
+	// Custom CSS for the generated pages, which is added after the built-in styling so it can override it.
+	// Default "".
+	PageCSS string
+	// Files like images, fonts or stylesheets for the generated pages, which are served at "/assets/".
+	// Templates can refer to them with the AssetsURL of their data.
+	// Default nil.
+	PageAssets fs.FS
+	// Flag for indicating whether to serve the manifest's Logo and Background at "/logo" and "/background", and a favicon
+	// derived from the logo at "/favicon.ico", so browsers don't get "404 Not Found" for the favicon of the generated pages.
+	// The images are fetched when they're first requested and kept in memory for an hour, and browsers may cache them for a day.
+	// Default false.
+	ManifestImages bool
+	// Flag for indicating whether to serve the catalogs as RSS at "/feed/{type}/{id}.rss" and as JSON Feed at "/feed/{type}/{id}.json",
+	// so users can follow new additions in a feed reader outside of Stremio. With user data they're also served below "/{userData}".
+	// The feeds contain the first page of the CatalogHandler's items, linking to their pages in Stremio Web.
+	// Catalogs that require an extra, like search-only ones, have no feed.
+	// Default false.
+	CatalogFeeds bool
+	// Content of "/robots.txt", as public addons get crawled constantly. DefaultRobotsTxt disallows crawling the whole addon.
+	// Default "" (meaning no robots.txt is served).
+	RobotsTxt string
+	// Content of "/.well-known/security.txt" (RFC 9116), with the contact for reporting security issues to the operator of the addon.
+	// Default nil (meaning no security.txt is served).
+	SecurityTxt *SecurityTxt
+	// Path of the health endpoint, which responds with "200 OK" when the addon and its health checks are healthy.
+	// Change it when the path is already reserved, like by an infrastructure sidecar.
+	// Default "/health".
+	HealthPath string
+	// Flag for indicating whether to not serve the health endpoint, for example when the health is checked via the manifest.
+	// Default false.
+	DisableHealthEndpoint bool
 	// Flag for indicating whether you want to expose URL handlers for the Go profiler.
 	// The URLs are be the standard ones: "/debug/pprof/...".
+	// They're protected by the APIKey, if set, or by ProfilingAuth.
 	// Default false.
 	Profiling bool
+	// Credentials for protecting the profiling endpoints with HTTP basic authentication, so browsers can access them.
+	// When set, they're used instead of the APIKey for these endpoints.
+	// Only makes sense when also setting Profiling.
+	// Default nil.
+	ProfilingAuth *BasicAuth
+	// Path of a file to record requests and responses to, in the JSON Lines format of the recording package.
+	// Recordings can be replayed against a new build with the "go-stremio replay" command, for debugging issues that only some users run into.
+	// Sensitive headers like "Authorization" and "X-Forwarded-For" aren't recorded, and neither is the user data in the URL unless RecordUserData is set.
+	// Only meant for debugging, as every request leads to a disk write.
+	// Default "" (meaning nothing is recorded).
+	RecordFile string
+	// Flag for indicating whether to keep the user data in the recorded URLs.
+	// Only relevant when using RecordFile. Note that user data can contain credentials of your users.
+	// Default false.
+	RecordUserData bool
 	// Flag for indicating whether you want to collect and expose Prometheus metrics.
 	// The URL is the standard one: "/metrics".
-	// There's no credentials required for accessing it. If you expose xybydy-stremio to the public,
-	// you might want to protect the metrics route in your reverse proxy.
+	// There's no credentials required for accessing it, unless you set an APIKey or MetricsAuth. If you expose xybydy-stremio to the public,
+	// you might want to protect the metrics route with them, or in your reverse proxy.
 	// Default false.
 	Metrics bool
+	// Credentials for protecting the metrics endpoint with HTTP basic authentication, which Prometheus supports in its scrape config.
+	// When set, they're used instead of the APIKey for this endpoint.
+	// Only makes sense when also setting Metrics.
+	// Default nil.
+	MetricsAuth *BasicAuth
+	// IP addresses and CIDR ranges (like "10.0.0.0/8") of the clients that may access the addon, for private addons.
+	// Requests from other clients are rejected with "403 Forbidden" before any other work is done for them.
+	// Default nil (meaning all clients may access the addon).
+	AllowedIPs []string
+	// IP addresses and CIDR ranges of clients that may not access the addon, for blocking abusive scrapers.
+	// They take precedence over AllowedIPs.
+	// Default nil.
+	DeniedIPs []string
+	// IP addresses and CIDR ranges of reverse proxies in front of the addon, whose "X-Forwarded-For" header is trusted
+	// for determining the client IP for AllowedIPs and DeniedIPs. Requests from other addresses are judged by their own address,
+	// so clients can't spoof the header.
+	// Only makes sense when also setting AllowedIPs or DeniedIPs.
+	// Default nil (meaning the header is ignored).
+	TrustedProxies []string
+	// Flag for indicating whether to serve an admin dashboard at "/admin" with live statistics of the addon,
+	// like request and error rates, top requested IDs, the user data cache hit ratio, memory usage and recent errors.
+	// The statistics are kept in memory, so they're reset when the addon restarts.
+	// Requires an APIKey, which browsers can send in the "api_key" query parameter, like "/admin?api_key=...".
+	// Default false.
+	AdminDashboard bool
+	// Flag for indicating whether to serve an admin API for supporting users who report broken installs, at "/admin/api/...":
+	// "GET /admin/api/userdata?data=..." decodes and verifies user data and responds with a UserDataInspection.
+	// When using a ConfigStore, "GET /admin/api/configs" lists the recently seen config tokens as RecentConfig objects,
+	// and "DELETE /admin/api/configs/:token" revokes a token, if the store can delete values (like the ones of the pkg/store packages).
+	// "GET /admin/api/catalogs/:type/:id" exports all pages of a catalog as NDJSON (one MetaPreviewItem per line), streamed while
+	// the CatalogHandler is called with increasing "skip" extras, for QA, search indexing and diffing catalogs between deployments.
+	// Its "data" query parameter is the user data for the handler and the other ones except "api_key" are passed as extras, like "genre".
+	// Requires an APIKey.
+	// Default false.
+	AdminAPI bool
+	// Collector for usage statistics, which counts successful catalog, stream, meta and subtitle requests
+	// per resource, type and ID and per user (identified by a hash of the user data).
+	// When also setting an APIKey, the statistics are served at "/analytics.json", for the days from the "from" to the "to" query parameter
+	// (in the format "2006-01-02", defaulting to the last 7 days).
+	// Closing the collector when the addon stops is up to you.
+	// Default nil.
+	Analytics *analytics.Collector
+	// Shared secret that's required for accessing the endpoints that aren't meant for Stremio,
+	// like "/metrics", "/debug/pprof/..." and endpoints added with AddProtectedEndpoint.
+	// Clients send it in the "X-API-Key" header, as bearer token in the "Authorization" header or in the "api_key" query parameter.
+	// Requests without the correct key are rejected with "401 Unauthorized" and logged as warning.
+	// Default "" (meaning the endpoints are accessible without a key).
+	APIKey string
+	// Flag for indicating whether to serve an OpenAPI 3 document that describes the addon's endpoints at "/openapi.json".
+	// It's derived from the manifest and the registered handlers and endpoints, which is useful for API gateways and client generators.
+	// Default false.
+	OpenAPI bool
+	// Flag for indicating whether to serve the version of the addon at "/version", as JSON VersionInfo with the manifest's version
+	// and the build info that the Go toolchain stamps into the binary, like the Git commit SHA, for checking what's deployed.
+	// Default false.
+	VersionEndpoint bool
+	// URL that responds with the latest version of the addon, as plain text like "1.2.3" or as JSON with a "version" or "tag_name" field,
+	// like GitHub's API for the latest release ("https://api.github.com/repos/OWNER/REPO/releases/latest").
+	// While the addon is running it's checked every 6 hours, and when the manifest's version is older, a warning is logged,
+	// the VersionEndpoint shows it and with Metrics the "addon_outdated" gauge is 1, so self-hosters notice outdated deployments.
+	// Default "" (meaning the latest version isn't checked).
+	LatestVersionURL string
 	// Duration of client/proxy-side cache for responses from the catalog endpoint.
 	// Helps reducing number of requsts and transferred data volume to/from the server.
 	// The result is not cached by the SDK on the server side, so if two *separate* users make a reqeust,
@@ -97,26 +242,99 @@ type Options struct {
 	// Flag for indicating whether the "ETag" header should be set and the "If-None-Match" header checked.
 	// Helps reducing the transferred data volume from the server even further.
 	// Only makes sense when setting a non-zero CacheAgeCatalogs.
-	// Leads to a slight computational overhead due to every CatalogHandler result being hashed.
+	// Leads to a slight computational overhead due to every CatalogHandler result being hashed, unless the handler supplies the ETag with SetETag.
 	// Default false.
 	HandleEtagCatalogs bool
 	// Same as HandleEtagCatalogs, but for streams.
 	HandleEtagStreams bool
 	// Same as HandleEtagCatalogs, but for metas.
 	HandleEtagMeta bool
+	// Request headers that the responses of the manifest, catalog, stream, meta and subtitles endpoints depend on besides the URL,
+	// like "Accept-Language" when a middleware localizes them. They're sent in the "Vary" header, also for "304 Not Modified" responses,
+	// so shared caches like CDNs keep a response per header value instead of serving one to clients that should get another.
+	// User data doesn't need it, as it's part of the URL. Responses that are compressed by a middleware already vary by "Accept-Encoding".
+	// Default nil.
+	VaryHeaders []string
+	// Number of items from which catalog, stream and subtitles responses are encoded item by item into a chunked response,
+	// instead of marshalling the whole response in memory first. It reduces the peak memory for very large catalogs on small hosts.
+	// Responses of endpoints with ETag handling are marshalled as a whole, as the ETag is the hash of the whole body,
+	// unless the handler supplies the ETag with SetETag.
+	// Chunked responses have no Content-Length, except for HEAD requests, for which the items are encoded only to count the bytes.
+	// Default 0 (meaning responses are always marshalled as a whole).
+	StreamingThreshold int
+	// Languages the addon supports, like "en", "de" and "pt-BR", the first one being the fallback.
+	// For each catalog, stream, meta and subtitles request, the user's language is put into the handler's context, see LanguageFromContext.
+	// It's the language of a per-language catalog (see types.CatalogItem.ForLanguages), the value of the LanguageConfigKey config item
+	// or the best match of the "Accept-Language" header, in this order. As responses may then depend on the header, it's added to "Vary".
+	// Default nil (meaning no language is put into the context).
+	Languages []string
+	// Key of the config item whose value is the user's language, like a "select" with the Languages as options.
+	// It lets users choose another language than the one of their device.
+	// Default "" (meaning only per-language catalogs and the "Accept-Language" header are used).
+	LanguageConfigKey string
 	// Flag for indicating whether user data is Base64-encoded.
 	// As the user data is in the URL it needs to be the URL-safe Base64 encoding described in RFC 4648.
 	// When true, go-stremio first decodes the value before passing or unmarshalling it.
 	// Default false.
 	UserDataIsBase64 bool
+	// Key for signing the user data with HMAC-SHA256, so users can't modify it, for example to set flags like "premium" that the configure flow never issued.
+	// EncodeUserData (and thus InstallLinks and the configure page) append the signature to the encoded user data,
+	// and requests with a missing or wrong signature are rejected with "400 Bad Request".
+	// Must be at least MinUserDataSigningKeyLength bytes long and the same for all instances of your addon.
+	// Default nil (meaning the user data isn't signed).
+	UserDataSigningKey []byte
+	// Options for issuing user data as compact JWT with the configuration in the "cfg" claim, plus issuer and expiry,
+	// so you can invalidate old configurations and rotate keys. EncodeUserData (and thus InstallLinks and the configure page) issue the tokens,
+	// and requests with invalid or expired tokens are rejected with "400 Bad Request".
+	// Can't be used together with UserDataIsBase64 or UserDataSigningKey, as the token already is Base64-encoded and signed.
+	// Default nil (meaning the user data isn't a JWT).
+	UserDataJWT *JWTOptions
+	// Maximum number of decoded user data objects to keep in an LRU cache, keyed by the encoded user data in the URL,
+	// so the user data of active users isn't decoded and unmarshalled on every request.
+	// Note that the handlers then get the same user data object for the same user, so they must not modify it.
+	// Default 0 (meaning user data isn't cached).
+	UserDataCacheSize int
+	// Store for keeping configurations server-side. The URL then only contains a short random token instead of the encoded user data,
+	// which the addon resolves before calling the handlers. The configure page (and EncodeUserData) store the configuration,
+	// and reconfiguring the addon updates the existing configuration, so users don't need to reinstall the addon.
+	// Can't be used together with UserDataIsBase64, UserDataSigningKey or UserDataJWT.
+	// Default nil (meaning the configuration is encoded in the URL).
+	ConfigStore ConfigStore
+	// Callback for when a manifest request arrives with user data that wasn't seen before, for tracking installs
+	// or provisioning per-user resources. It's called in the background, so it doesn't slow down the installation.
+	// Default nil.
+	InstallCallback InstallCallback
+	// URL that an InstallEvent is POSTed to for every new user, like InstallCallback.
+	// The event only contains a hash of the user data, as the user data can contain credentials of your users.
+	// Default "".
+	InstallWebhookURL string
+	// Store for remembering which user data was seen before, for InstallCallback and InstallWebhookURL.
+	// Like for the ConfigStore, the pkg/store packages can be used.
+	// Only makes sense when also setting InstallCallback or InstallWebhookURL.
+	// Default nil (meaning an in-memory store of the 100,000 most recent users, so users are reported again after a restart).
+	InstallStore ConfigStore
+	// Max number of new users per minute that InstallCallback and InstallWebhookURL are notified about.
+	// Further ones are dropped with a warning and not remembered, so they're reported on their next installation,
+	// but a client making up user data can't flood the callback or webhook.
+	// Only makes sense when also setting InstallCallback or InstallWebhookURL.
+	// Default 60.
+	InstallNotificationsPerMinute int
+	// Maximum length of the user data in the URL, in bytes.
+	// Longer user data is rejected with "414 URI Too Long" before it's decoded, protecting the JSON decoder from abusive multi-kilobyte URLs.
+	// Default 0 (meaning no limit).
+	MaxUserDataLength int
 	// Flag for indicating whether to look up the movie / TV show name by its IMDb ID and put it into the context.
-	// Only works for stream requests.
+	// Only works for stream and meta requests, and for catalog requests when using MetaForCatalogs.
 	// Default false.
 	PutMetaInContext bool
 	// Flag for indicating whether to include the movie / TV show name (and year) in the request log.
-	// Only works for stream requests.
+	// Only works for stream and meta requests, and for catalog requests when using MetaForCatalogs.
 	// Default false.
 	LogMediaName bool
+	// Flag for indicating whether PutMetaInContext and LogMediaName also apply to catalog requests.
+	// Only catalog requests where the catalog ID is an IMDb ID are enriched, like for "similar titles" catalogs.
+	// Default false.
+	MetaForCatalogs bool
 	// Meta client for fetching movie and TV show info.
 	// Only relevant when using PutMetaInContext or LogMediaName.
 	// You can set it if you have already created one to share its in-memory cache for example,
@@ -128,7 +346,21 @@ type Options struct {
 	// Note that each response is cached for 30 days, so waiting a bit once per movie / TV show per 30 days is acceptable.
 	// Default 2 seconds.
 	MetaTimeout time.Duration
+	// Options for the Cinemeta client that go-stremio creates when no MetaClient is set,
+	// for example to use a self-hosted Cinemeta mirror as BaseURL, or a different TTL.
+	// Only relevant when using PutMetaInContext or LogMediaName.
+	// Unset fields fall back to the defaults of the cinemeta package, except for the Timeout, which falls back to MetaTimeout.
+	// Default empty.
+	CinemetaOptions cinemeta.ClientOptions
+	// Max number of concurrent MetaClient calls by the meta middleware, to protect the upstream (like Cinemeta) and the addon's memory during load spikes.
+	// Concurrent requests for the same meta share a single call, so they only count once.
+	// MetaTimeout bounds the waiting for a slot and the fetch together, so requests that don't get their meta within MetaTimeout are handled without it.
+	// Only relevant when using PutMetaInContext or LogMediaName. 0 means no limit.
+	// Default 0.
+	MaxConcurrentMetaFetches int
 	// Should implement fs.FS interface
+	// Its forms can be submitted to "POST /configure", which validates the values against the manifest's config items
+	// and redirects to the install link, so no JavaScript is needed for building it.
 	// Default nil.
 	ConfigureHTMLfs fs.FS
 	// Regex for accepted stream IDs.
@@ -139,14 +371,80 @@ type Options struct {
 	// IMDb example: "^tt\\d{7,8}$" or `^tt\d{7,8}$`
 	// Default "".
 	StreamIDregex string
+	// Signer for the "/resolve/:token" endpoint.
+	// When set, the endpoint is registered and redirects to the upstream URL of valid and unexpired tokens.
+	// Your stream handlers can then return URLs created with the same signer's `URL()` method
+	// to hide the real upstream URLs (and credentials in them) from users.
+	// Default nil.
+	URLSigner *signedurl.Signer
+	// Flag for indicating whether to register the "/vtt/:token.vtt" endpoint, which fetches the SRT subtitle of a token and serves it as WebVTT.
+	// Stremio Web can only play WebVTT subtitles. Create the subtitle URLs with URLSigner's `VTTURL()` method.
+	// Requires URLSigner to be set.
+	// Default false.
+	SubtitleConversion bool
+	// Flag for indicating whether to register the "/proxy/:token" endpoint, which streams the upstream of a token through the addon.
+	// It's for upstream hosts that Stremio can't reach directly or that require headers like "Referer" or "Authorization".
+	// Create the stream URLs with URLSigner's `ProxyURL()` method. Range requests are forwarded, so seeking works.
+	// Requires URLSigner to be set.
+	// Default false.
+	StreamProxy bool
+	// Max number of concurrently proxied streams. Requests beyond this limit get a 503 response.
+	// Only relevant when using StreamProxy. 0 means no limit.
+	// Default 0.
+	MaxProxyConnections int
+	// Max number of concurrently proxied streams per user. Requests beyond this limit get a 429 response.
+	// Users are identified by the user key in the token (see the URLSigner's `ProxyURLForUser()` method) or their IP otherwise.
+	// Only relevant when using StreamProxy. 0 means no limit.
+	// Default 0.
+	MaxProxyConnectionsPerUser int
+	// Max bandwidth per user in bytes per second, shared by all of the user's proxied streams.
+	// This prevents a single user from saturating the addon's uplink. Users are identified like for MaxProxyConnectionsPerUser.
+	// Only relevant when using StreamProxy. 0 means no limit.
+	// Default 0.
+	MaxProxyBandwidthPerUser int
+	// Max duration that Run waits for the current requests to finish when shutting down.
+	// On SIGINT or SIGTERM the server first stops accepting new connections and closes idle ones,
+	// then it waits for the current requests to finish. After the timeout the remaining requests are abandoned,
+	// so Run can return and the AfterShutdown hook is called. "docker stop" kills the process after 10 seconds,
+	// so a value below that, like 8 seconds, is recommended for Docker. 0 means waiting without limit.
+	// Default 0.
+	ShutdownTimeout time.Duration
+	// Flag for indicating whether to listen with the SO_REUSEPORT socket option, for zero-downtime deploys on bare metal.
+	// It allows a new version of the addon to start on the same port while the old one is still running,
+	// with the kernel distributing new connections between them. Then send SIGTERM to the old one,
+	// so it stops accepting connections and finishes the current requests (see ShutdownTimeout) without dropping any.
+	// Not supported on Windows.
+	// Default false.
+	ReusePort bool
+	// Callback that's called by Run after the server's listener is up and before requests are served,
+	// for tying resources like DB pools, schedulers and upstream sessions to the addon's lifecycle.
+	// An error stops the addon from starting.
+	// Default nil.
+	OnStart func(ctx context.Context) error
+	// Callback that's called by Run when the addon received a shutdown signal, before the server stops accepting requests
+	// and waits for the current ones to finish, for example for stopping schedulers. Errors are logged.
+	// Default nil.
+	OnShutdown func(ctx context.Context) error
+	// Callback that's called by Run after the server shut down and all requests finished, for example for closing DB pools.
+	// Errors are logged.
+	// Default nil.
+	AfterShutdown func(ctx context.Context) error
+}
+
+// BasicAuth are the credentials for HTTP basic authentication, see Options.ProfilingAuth and Options.MetricsAuth.
+type BasicAuth struct {
+	Username string
+	Password string
 }
 
 // DefaultOptions is an Options object with default values.
 // For fields that aren't set here the zero value is the default value.
 var DefaultOptions = Options{
-	BindAddr:     "localhost",
-	Port:         8080,
-	LoggingLevel: "info",
-	LogEncoding:  "console",
-	MetaTimeout:  2 * time.Second,
+	BindAddr:                      "localhost",
+	Port:                          8080,
+	LoggingLevel:                  "info",
+	LogEncoding:                   "console",
+	HealthPath:                    "/health",
+	MetaTimeout:                   2 * time.Second,
+	InstallNotificationsPerMinute: 60,
 }
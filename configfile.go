@@ -0,0 +1,175 @@
+package stremio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// OptionsFromFile loads Options from a YAML (".yaml" or ".yml") or TOML (".toml") file, so deployment config can live outside the binary.
+// The keys are the environment variables of OptionsFromEnv in lower case, like "cache_age_streams", and the values have their
+// native types, with durations like "1h30m" or a number of seconds. The basic auth credentials are set with "profiling_auth"
+// and "metrics_auth" sections with "username" and "password" keys. For example in YAML:
+//
+//	port: 8080
+//	cache_age_streams: 1h
+//	metrics: true
+//	metrics_auth:
+//	  username: admin
+//	  password: secret
+//	tmdb:
+//	  api_key: abc
+//
+// The other keys are custom sections for the addon author, which are decoded into custom, a pointer to a struct
+// with "yaml" or "toml" tags, like `yaml:"tmdb"` for the section above. With a nil custom they're not allowed.
+// Options that can't be set in the file, like the Logger or handlers, can be set on the returned Options.
+// An error is returned for invalid values and unknown keys, listing all of them with their key.
+func OptionsFromFile(path string, custom any) (Options, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Options{}, fmt.Errorf("couldn't read config file: %w", err)
+	}
+	var values map[string]any
+	var decodeCustom func(sections map[string]any) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+		decodeCustom = func(sections map[string]any) error {
+			sectionsYAML, err := yaml.Marshal(sections)
+			if err != nil {
+				return err
+			}
+			dec := yaml.NewDecoder(bytes.NewReader(sectionsYAML))
+			dec.KnownFields(true)
+			return dec.Decode(custom)
+		}
+	case ".toml":
+		_, err = toml.Decode(string(data), &values)
+		decodeCustom = func(sections map[string]any) error {
+			sectionsTOML, err := toml.Marshal(sections)
+			if err != nil {
+				return err
+			}
+			md, err := toml.Decode(string(sectionsTOML), custom)
+			if err != nil {
+				return err
+			}
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				return fmt.Errorf("unknown key %q", undecoded[0].String())
+			}
+			return nil
+		}
+	default:
+		return Options{}, fmt.Errorf("unsupported config file extension %q, use \".yaml\", \".yml\" or \".toml\"", filepath.Ext(path))
+	}
+	if err != nil {
+		return Options{}, fmt.Errorf("couldn't parse %v: %w", path, err)
+	}
+
+	var opts Options
+	var errs []error
+	for _, option := range optionFields {
+		key := strings.ToLower(option.name)
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		delete(values, key)
+		if err := setFileOption(option.field(&opts), value); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v: %w", path, key, err))
+		}
+	}
+	for _, auth := range []struct {
+		key   string
+		field **BasicAuth
+	}{{"profiling_auth", &opts.ProfilingAuth}, {"metrics_auth", &opts.MetricsAuth}} {
+		value, ok := values[auth.key]
+		if !ok {
+			continue
+		}
+		delete(values, auth.key)
+		var err error
+		if *auth.field, err = basicAuthFromFile(value); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v: %w", path, auth.key, err))
+		}
+	}
+	if len(values) > 0 {
+		if custom == nil {
+			for _, key := range slices.Sorted(maps.Keys(values)) {
+				errs = append(errs, fmt.Errorf("%v: %v: unknown option", path, key))
+			}
+		} else if err := decodeCustom(values); err != nil {
+			errs = append(errs, fmt.Errorf("%v: invalid custom section: %w", path, err))
+		}
+	}
+	return opts, errors.Join(errs...)
+}
+
+func setFileOption(field any, value any) error {
+	switch value := value.(type) {
+	case string:
+		return setEnvOption(field, value)
+	case bool:
+		if field, ok := field.(*bool); ok {
+			*field = value
+			return nil
+		}
+	case int:
+		return setFileOption(field, int64(value))
+	case int64:
+		switch field := field.(type) {
+		case *int:
+			*field = int(value)
+			return nil
+		case *time.Duration:
+			*field = time.Duration(value) * time.Second
+			return nil
+		}
+	case []any:
+		if field, ok := field.(*[]string); ok {
+			for _, item := range value {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("expected a list of strings, got %v", item)
+				}
+				*field = append(*field, s)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %v, got %v", fieldKind(field), value)
+}
+
+// fieldKind describes the type of an option field for error messages.
+func fieldKind(field any) string {
+	switch field.(type) {
+	case *int:
+		return "an integer"
+	case *bool:
+		return "a boolean"
+	case *time.Duration:
+		return "a duration"
+	case *[]string:
+		return "a list of strings"
+	}
+	return "a string"
+}
+
+func basicAuthFromFile(value any) (*BasicAuth, error) {
+	section, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a section with username and password, got %v", value)
+	}
+	username, _ := section["username"].(string)
+	password, _ := section["password"].(string)
+	return &BasicAuth{Username: username, Password: password}, nil
+}
@@ -0,0 +1,71 @@
+package stremio
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// ConfigSchema returns a JSON Schema (draft 2020-12) in JSON format that describes the user data of the manifest's config items,
+// in the format that the configure page submits it: Checkboxes are booleans, numbers are numbers and all other types are strings.
+// It's useful for external configurator UIs and validation tooling.
+// With the ConfigSchema option the addon serves the same document at "/config.schema.json".
+func (a *Addon) ConfigSchema() ([]byte, error) {
+	properties := make(map[string]any, len(a.manifest.Config))
+	required := []string{}
+	for _, item := range a.manifest.Config {
+		property := map[string]any{}
+		if item.ConfTitle != "" {
+			property["title"] = item.ConfTitle
+		}
+		switch item.ConfType {
+		case "checkbox":
+			property["type"] = "boolean"
+			property["default"] = item.ConfDefault == "checked"
+		case "number":
+			property["type"] = "number"
+			if item.ConfMin != nil {
+				property["minimum"] = *item.ConfMin
+			}
+			if item.ConfMax != nil {
+				property["maximum"] = *item.ConfMax
+			}
+			if number, err := strconv.ParseFloat(item.ConfDefault, 64); err == nil {
+				property["default"] = number
+			}
+		case "select":
+			property["type"] = "string"
+			property["enum"] = item.ConfOptions
+		case "password":
+			property["type"] = "string"
+			property["format"] = "password"
+			property["writeOnly"] = true
+		default:
+			property["type"] = "string"
+		}
+		if _, ok := property["default"]; !ok && item.ConfDefault != "" {
+			property["default"] = item.ConfDefault
+		}
+		if item.ConfRequired && item.ConfType != "checkbox" {
+			required = append(required, item.ConfKey)
+		}
+		properties[item.ConfKey] = property
+	}
+	return json.Marshal(map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      a.manifest.Name + " configuration",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+}
+
+func createConfigSchemaHandler(schema []byte, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("configSchemaHandler called")
+		c.Set(fiber.HeaderContentType, "application/schema+json")
+		return c.Send(schema)
+	}
+}
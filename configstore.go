@@ -0,0 +1,66 @@
+package stremio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrUnknownConfigToken signals that there's no configuration for the token in the ConfigStore.
+var ErrUnknownConfigToken = errors.New("unknown config token")
+
+// ConfigStore stores configurations server-side, see Options.ConfigStore.
+// It's a subset of store.Store, so the in-memory, bbolt and Redis stores of the pkg/store packages can be used.
+// Implementations must be safe for concurrent use.
+type ConfigStore interface {
+	// Get returns the value of the key. The boolean return value signals if the key was found.
+	// An error signals a problem with the store itself.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key. A TTL of 0 means the value doesn't expire.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// configStoreKeyPrefix namespaces the keys, so stores can be shared with other data.
+const configStoreKeyPrefix = "config:"
+
+// newConfigToken returns a random, URL-safe token that can't be guessed.
+func newConfigToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("couldn't generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// storeConfig marshals the user data and stores it under the token, or a new token if it's empty, and returns the token.
+func storeConfig(ctx context.Context, store ConfigStore, token string, userData any) (string, error) {
+	userDataJSON, err := json.Marshal(userData)
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal user data: %w", err)
+	}
+	if token == "" {
+		if token, err = newConfigToken(); err != nil {
+			return "", err
+		}
+	}
+	if err = store.Set(ctx, configStoreKeyPrefix+token, userDataJSON, 0); err != nil {
+		return "", fmt.Errorf("couldn't store config: %w", err)
+	}
+	return token, nil
+}
+
+// resolveConfigToken returns the configuration of the token, URL-escaped like user data without Base64 encoding.
+func resolveConfigToken(ctx context.Context, store ConfigStore, token string) (string, error) {
+	userDataJSON, found, err := store.Get(ctx, configStoreKeyPrefix+token)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get config: %w", err)
+	} else if !found {
+		return "", ErrUnknownConfigToken
+	}
+	return url.PathEscape(string(userDataJSON)), nil
+}
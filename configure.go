@@ -0,0 +1,244 @@
+package stremio
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+var configureTemplate = template.Must(withStyleTemplate(template.Must(template.ParseFS(templatesFS, "templates/configure.html"))))
+
+// ConfigureField is a config item of the manifest with its current value, for rendering a form field.
+type ConfigureField struct {
+	types.ConfigItem
+	// Value of text, number, password and select fields.
+	Value string
+	// Whether a checkbox is checked.
+	Checked bool
+	// Validation error of a submitted value, like "is required".
+	Error string
+}
+
+// ConfigurePageData is the data that the configure page template is executed with.
+type ConfigurePageData struct {
+	Manifest types.Manifest
+	// Form fields for the manifest's config items, with their default, existing or submitted values.
+	Fields []ConfigureField
+	// Base URL of the addon, like "https://example.com", for building the install links.
+	BaseURL string
+	// Same as the UserDataIsBase64 option, for encoding the user data like the addon expects it.
+	UserDataIsBase64 bool
+	// Same as the WebInstallBaseURL constant.
+	WebInstallBaseURL string
+	// Custom CSS from the PageCSS option.
+	CSS template.CSS
+	// URL of the PageAssets, like "https://example.com/assets", empty if there are none.
+	AssetsURL string
+	// Token of the configuration that's being changed, when using a ConfigStore. Forms must submit it as "configToken",
+	// so the existing configuration is updated and the user doesn't need to reinstall the addon.
+	ConfigToken string
+}
+
+// configurePage renders the configure page and handles its submissions.
+type configurePage struct {
+	manifest types.Manifest
+	// nil when the addon uses a ConfigureHTMLfs instead of the generated page.
+	tmpl             *template.Template
+	css              string
+	hasAssets        bool
+	userDataIsBase64 bool
+	encodeUserData   func(userData any) (string, error)
+	// nil when the configuration is encoded in the URL.
+	store  ConfigStore
+	logger *zap.Logger
+}
+
+// configureFields returns the form fields for the config items, with their default values.
+func configureFields(config []types.ConfigItem) []ConfigureField {
+	fields := make([]ConfigureField, 0, len(config))
+	for _, item := range config {
+		field := ConfigureField{ConfigItem: item}
+		if item.ConfType == "checkbox" {
+			field.Checked = item.ConfDefault == "checked"
+		} else {
+			field.Value = item.ConfDefault
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// prefilledConfigureFields returns the form fields for the config items, with the values of the user data.
+// Config items that aren't in the user data, for example because they were added after the user installed the addon, get their default values.
+func prefilledConfigureFields(config []types.ConfigItem, userData map[string]any) []ConfigureField {
+	fields := configureFields(config)
+	for i, field := range fields {
+		value, ok := userData[field.ConfKey]
+		if !ok {
+			continue
+		}
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		}
+		if field.ConfType == "checkbox" {
+			fields[i].Checked = s == "true" || s == "checked" || s == "on"
+		} else {
+			fields[i].Value = s
+		}
+	}
+	return fields
+}
+
+// parseConfigForm validates the submitted values against the config items and returns them as user data,
+// with checkboxes as booleans and numbers as numbers. Empty optional values are left out.
+// It also returns the fields with the submitted values and their validation errors, and whether all values are valid.
+func parseConfigForm(config []types.ConfigItem, formValue func(key string) string) (map[string]any, []ConfigureField, bool) {
+	userData := make(map[string]any, len(config))
+	fields := make([]ConfigureField, 0, len(config))
+	valid := true
+	for _, item := range config {
+		field := ConfigureField{ConfigItem: item}
+		value := strings.TrimSpace(formValue(item.ConfKey))
+		switch {
+		case item.ConfType == "checkbox":
+			// Browsers only submit checked checkboxes, with the value "on" by default.
+			field.Checked = value != ""
+			userData[item.ConfKey] = field.Checked
+		case value == "":
+			if item.ConfRequired {
+				field.Error = "is required"
+			}
+		case item.ConfType == "number":
+			field.Value = value
+			number, err := strconv.ParseFloat(value, 64)
+			switch {
+			case err != nil:
+				field.Error = "must be a number"
+			case item.ConfMin != nil && number < *item.ConfMin:
+				field.Error = "must be at least " + strconv.FormatFloat(*item.ConfMin, 'f', -1, 64)
+			case item.ConfMax != nil && number > *item.ConfMax:
+				field.Error = "must be at most " + strconv.FormatFloat(*item.ConfMax, 'f', -1, 64)
+			default:
+				userData[item.ConfKey] = number
+			}
+		case item.ConfType == "select" && !slices.Contains(item.ConfOptions, value):
+			field.Value = value
+			field.Error = "must be one of " + strings.Join(item.ConfOptions, ", ")
+		default:
+			field.Value = value
+			userData[item.ConfKey] = value
+		}
+		if field.Error != "" {
+			valid = false
+		}
+		fields = append(fields, field)
+	}
+	return userData, fields, valid
+}
+
+func (p configurePage) render(c fiber.Ctx, fields []ConfigureField, configToken string) error {
+	baseURL := c.BaseURL()
+	data := ConfigurePageData{
+		Manifest:          p.manifest,
+		Fields:            fields,
+		BaseURL:           baseURL,
+		UserDataIsBase64:  p.userDataIsBase64,
+		WebInstallBaseURL: WebInstallBaseURL,
+		CSS:               template.CSS(p.css),
+		ConfigToken:       configToken,
+	}
+	if p.hasAssets {
+		data.AssetsURL = baseURL + "/assets"
+	}
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		p.logger.Error("Couldn't execute configure page template", zap.Error(err))
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(buf.Bytes())
+}
+
+// createConfigureHandler creates a handler for the configure page.
+// When the route has user data, like when reconfiguring an installed addon, the fields are prefilled with its values.
+func createConfigureHandler(page configurePage) fiber.Handler {
+	fields := configureFields(page.manifest.Config)
+	return func(c fiber.Ctx) error {
+		page.logger.Debug("configureHandler called")
+
+		userData := userDataParam(c)
+		if userData == "" {
+			return page.render(c, fields, "")
+		}
+		var configToken string
+		if page.store != nil {
+			configToken = c.Params("userData")
+		}
+		// Decoding already logs errors, and the user can still configure the addon from scratch.
+		decoded, err := decodeUserData(userData, unmarshalUserDataMap, page.logger, page.userDataIsBase64)
+		if err != nil {
+			return page.render(c, fields, "")
+		}
+		return page.render(c, prefilledConfigureFields(page.manifest.Config, *decoded.(*map[string]any)), configToken)
+	}
+}
+
+// createConfigureSubmitHandler creates a handler for configure forms, which validates the submitted values against the manifest's config items,
+// encodes them like the addon expects its user data and redirects to the "stremio://" install link.
+// Invalid submissions lead to the configure page with the errors, or a plain text error response when the page isn't generated.
+func createConfigureSubmitHandler(page configurePage) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		page.logger.Debug("configureSubmitHandler called")
+
+		userData, fields, valid := parseConfigForm(page.manifest.Config, func(key string) string { return c.FormValue(key) })
+		configToken := c.FormValue("configToken")
+		if !valid {
+			c.Status(fiber.StatusBadRequest)
+			if page.tmpl != nil {
+				return page.render(c, fields, configToken)
+			}
+			var errs []string
+			for _, field := range fields {
+				if field.Error != "" {
+					errs = append(errs, field.ConfKey+" "+field.Error)
+				}
+			}
+			return c.SendString("Invalid configuration: " + strings.Join(errs, "; "))
+		}
+		var encoded string
+		var err error
+		if page.store != nil {
+			// Only existing tokens can be updated, so clients can't choose their own.
+			if configToken != "" {
+				if _, err = resolveConfigToken(c.Context(), page.store, configToken); errors.Is(err, ErrUnknownConfigToken) {
+					configToken = ""
+				} else if err != nil {
+					page.logger.Error("Couldn't get config", zap.Error(err))
+					return c.SendStatus(fiber.StatusInternalServerError)
+				}
+			}
+			encoded, err = storeConfig(c.Context(), page.store, configToken, userData)
+		} else {
+			encoded, err = page.encodeUserData(userData)
+		}
+		if err != nil {
+			page.logger.Error("Couldn't encode user data", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderLocation, StremioURL(c.BaseURL(), encoded))
+		return c.SendStatus(fiber.StatusSeeOther)
+	}
+}
@@ -0,0 +1,163 @@
+package stremio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optionFields are the environment variables of OptionsFromEnv and the option fields they're mapped onto.
+// OptionsFromFile uses the same names in lower case.
+var optionFields = []struct {
+	name  string
+	field func(opts *Options) any
+}{
+	{"BIND_ADDR", func(opts *Options) any { return &opts.BindAddr }},
+	{"PORT", func(opts *Options) any { return &opts.Port }},
+	{"LOG_LEVEL", func(opts *Options) any { return &opts.LoggingLevel }},
+	{"LOG_ENCODING", func(opts *Options) any { return &opts.LogEncoding }},
+	{"DISABLE_REQUEST_LOGGING", func(opts *Options) any { return &opts.DisableRequestLogging }},
+	{"LOG_IPS", func(opts *Options) any { return &opts.LogIPs }},
+	{"LOG_USER_AGENT", func(opts *Options) any { return &opts.LogUserAgent }},
+	{"LOG_MEDIA_NAME", func(opts *Options) any { return &opts.LogMediaName }},
+	{"REDIRECT_URL", func(opts *Options) any { return &opts.RedirectURL }},
+	{"LANDING_PAGE", func(opts *Options) any { return &opts.LandingPage }},
+	{"INSTALL_QR_CODE", func(opts *Options) any { return &opts.InstallQRCode }},
+	{"CONFIGURE_PAGE", func(opts *Options) any { return &opts.ConfigurePage }},
+	{"CONFIG_SCHEMA", func(opts *Options) any { return &opts.ConfigSchema }},
+	{"OPENAPI", func(opts *Options) any { return &opts.OpenAPI }},
+	{"MANIFEST_IMAGES", func(opts *Options) any { return &opts.ManifestImages }},
+	{"CATALOG_FEEDS", func(opts *Options) any { return &opts.CatalogFeeds }},
+	{"VERSION_ENDPOINT", func(opts *Options) any { return &opts.VersionEndpoint }},
+	{"LATEST_VERSION_URL", func(opts *Options) any { return &opts.LatestVersionURL }},
+	{"ROBOTS_TXT", func(opts *Options) any { return &opts.RobotsTxt }},
+	{"HEALTH_PATH", func(opts *Options) any { return &opts.HealthPath }},
+	{"DISABLE_HEALTH_ENDPOINT", func(opts *Options) any { return &opts.DisableHealthEndpoint }},
+	{"PROFILING", func(opts *Options) any { return &opts.Profiling }},
+	{"METRICS", func(opts *Options) any { return &opts.Metrics }},
+	{"RECORD_FILE", func(opts *Options) any { return &opts.RecordFile }},
+	{"RECORD_USER_DATA", func(opts *Options) any { return &opts.RecordUserData }},
+	{"API_KEY", func(opts *Options) any { return &opts.APIKey }},
+	{"ADMIN_DASHBOARD", func(opts *Options) any { return &opts.AdminDashboard }},
+	{"ADMIN_API", func(opts *Options) any { return &opts.AdminAPI }},
+	{"ALLOWED_IPS", func(opts *Options) any { return &opts.AllowedIPs }},
+	{"DENIED_IPS", func(opts *Options) any { return &opts.DeniedIPs }},
+	{"TRUSTED_PROXIES", func(opts *Options) any { return &opts.TrustedProxies }},
+	{"CACHE_AGE_CATALOGS", func(opts *Options) any { return &opts.CacheAgeCatalogs }},
+	{"STALE_REVALIDATE_CATALOGS", func(opts *Options) any { return &opts.StaleRevalidateCatalogs }},
+	{"STALE_ERROR_CATALOGS", func(opts *Options) any { return &opts.StaleErrorCatalogs }},
+	{"CACHE_PUBLIC_CATALOGS", func(opts *Options) any { return &opts.CachePublicCatalogs }},
+	{"HANDLE_ETAG_CATALOGS", func(opts *Options) any { return &opts.HandleEtagCatalogs }},
+	{"CACHE_AGE_STREAMS", func(opts *Options) any { return &opts.CacheAgeStreams }},
+	{"STALE_REVALIDATE_STREAMS", func(opts *Options) any { return &opts.StaleRevalidateStreams }},
+	{"STALE_ERROR_STREAMS", func(opts *Options) any { return &opts.StaleErrorStreams }},
+	{"CACHE_PUBLIC_STREAMS", func(opts *Options) any { return &opts.CachePublicStreams }},
+	{"HANDLE_ETAG_STREAMS", func(opts *Options) any { return &opts.HandleEtagStreams }},
+	{"CACHE_AGE_META", func(opts *Options) any { return &opts.CacheAgeMeta }},
+	{"STALE_REVALIDATE_META", func(opts *Options) any { return &opts.StaleRevalidateMeta }},
+	{"STALE_ERROR_META", func(opts *Options) any { return &opts.StaleErrorMeta }},
+	{"CACHE_PUBLIC_META", func(opts *Options) any { return &opts.CachePublicMeta }},
+	{"HANDLE_ETAG_META", func(opts *Options) any { return &opts.HandleEtagMeta }},
+	{"VARY_HEADERS", func(opts *Options) any { return &opts.VaryHeaders }},
+	{"STREAMING_THRESHOLD", func(opts *Options) any { return &opts.StreamingThreshold }},
+	{"LANGUAGES", func(opts *Options) any { return &opts.Languages }},
+	{"LANGUAGE_CONFIG_KEY", func(opts *Options) any { return &opts.LanguageConfigKey }},
+	{"USER_DATA_IS_BASE64", func(opts *Options) any { return &opts.UserDataIsBase64 }},
+	{"USER_DATA_SIGNING_KEY", func(opts *Options) any { return &opts.UserDataSigningKey }},
+	{"USER_DATA_CACHE_SIZE", func(opts *Options) any { return &opts.UserDataCacheSize }},
+	{"MAX_USER_DATA_LENGTH", func(opts *Options) any { return &opts.MaxUserDataLength }},
+	{"INSTALL_WEBHOOK_URL", func(opts *Options) any { return &opts.InstallWebhookURL }},
+	{"INSTALL_NOTIFICATIONS_PER_MINUTE", func(opts *Options) any { return &opts.InstallNotificationsPerMinute }},
+	{"PUT_META_IN_CONTEXT", func(opts *Options) any { return &opts.PutMetaInContext }},
+	{"META_FOR_CATALOGS", func(opts *Options) any { return &opts.MetaForCatalogs }},
+	{"META_TIMEOUT", func(opts *Options) any { return &opts.MetaTimeout }},
+	{"MAX_CONCURRENT_META_FETCHES", func(opts *Options) any { return &opts.MaxConcurrentMetaFetches }},
+	{"STREAM_ID_REGEX", func(opts *Options) any { return &opts.StreamIDregex }},
+	{"SUBTITLE_CONVERSION", func(opts *Options) any { return &opts.SubtitleConversion }},
+	{"STREAM_PROXY", func(opts *Options) any { return &opts.StreamProxy }},
+	{"MAX_PROXY_CONNECTIONS", func(opts *Options) any { return &opts.MaxProxyConnections }},
+	{"MAX_PROXY_CONNECTIONS_PER_USER", func(opts *Options) any { return &opts.MaxProxyConnectionsPerUser }},
+	{"MAX_PROXY_BANDWIDTH_PER_USER", func(opts *Options) any { return &opts.MaxProxyBandwidthPerUser }},
+	{"SHUTDOWN_TIMEOUT", func(opts *Options) any { return &opts.ShutdownTimeout }},
+	{"REUSE_PORT", func(opts *Options) any { return &opts.ReusePort }},
+}
+
+// OptionsFromEnv returns Options with the values of environment variables, so containers can be configured without code changes.
+// The variables are named like the options in upper snake case, like "CACHE_AGE_STREAMS" for CacheAgeStreams,
+// except "LOG_LEVEL" for LoggingLevel. Supported are the options that are strings, numbers, booleans, durations and lists:
+//
+//	BIND_ADDR, PORT, LOG_LEVEL, LOG_ENCODING, DISABLE_REQUEST_LOGGING, LOG_IPS, LOG_USER_AGENT, LOG_MEDIA_NAME,
+//	REDIRECT_URL, LANDING_PAGE, INSTALL_QR_CODE, CONFIGURE_PAGE, CONFIG_SCHEMA, OPENAPI, VERSION_ENDPOINT, LATEST_VERSION_URL,
+//	MANIFEST_IMAGES, CATALOG_FEEDS, ROBOTS_TXT, HEALTH_PATH, DISABLE_HEALTH_ENDPOINT, PROFILING, METRICS,
+//	RECORD_FILE, RECORD_USER_DATA, API_KEY, ADMIN_DASHBOARD, ADMIN_API, ALLOWED_IPS, DENIED_IPS, TRUSTED_PROXIES,
+//	CACHE_AGE_{CATALOGS,STREAMS,META}, STALE_REVALIDATE_{CATALOGS,STREAMS,META}, STALE_ERROR_{CATALOGS,STREAMS,META},
+//	CACHE_PUBLIC_{CATALOGS,STREAMS,META}, HANDLE_ETAG_{CATALOGS,STREAMS,META}, VARY_HEADERS, STREAMING_THRESHOLD, LANGUAGES, LANGUAGE_CONFIG_KEY,
+//	USER_DATA_IS_BASE64, USER_DATA_SIGNING_KEY, USER_DATA_CACHE_SIZE, MAX_USER_DATA_LENGTH, INSTALL_WEBHOOK_URL, INSTALL_NOTIFICATIONS_PER_MINUTE,
+//	PUT_META_IN_CONTEXT, META_FOR_CATALOGS, META_TIMEOUT, MAX_CONCURRENT_META_FETCHES, STREAM_ID_REGEX,
+//	SUBTITLE_CONVERSION, STREAM_PROXY, MAX_PROXY_CONNECTIONS, MAX_PROXY_CONNECTIONS_PER_USER, MAX_PROXY_BANDWIDTH_PER_USER,
+//	SHUTDOWN_TIMEOUT, REUSE_PORT
+//
+// Booleans accept the values of strconv.ParseBool, like "true" and "1". Durations accept the values of time.ParseDuration,
+// like "1h30m", or a number of seconds. Lists are comma-separated. The basic auth credentials are set with
+// "PROFILING_USERNAME" and "PROFILING_PASSWORD", and "METRICS_USERNAME" and "METRICS_PASSWORD".
+// Unset and empty variables keep the zero value, so NewAddon uses the default value.
+// Options that can't be set from the environment, like the Logger or handlers, can be set on the returned Options.
+// An error is returned for invalid values, listing all of them.
+func OptionsFromEnv() (Options, error) {
+	var opts Options
+	var errs []error
+	for _, envOption := range optionFields {
+		value := os.Getenv(envOption.name)
+		if value == "" {
+			continue
+		}
+		if err := setEnvOption(envOption.field(&opts), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %v: %w", envOption.name, err))
+		}
+	}
+	opts.ProfilingAuth = basicAuthFromEnv("PROFILING")
+	opts.MetricsAuth = basicAuthFromEnv("METRICS")
+	return opts, errors.Join(errs...)
+}
+
+func setEnvOption(field any, value string) error {
+	var err error
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *int:
+		*field, err = strconv.Atoi(value)
+	case *bool:
+		*field, err = strconv.ParseBool(value)
+	case *time.Duration:
+		if seconds, atoiErr := strconv.Atoi(value); atoiErr == nil {
+			*field = time.Duration(seconds) * time.Second
+		} else {
+			*field, err = time.ParseDuration(value)
+		}
+	case *[]string:
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	case *[]byte:
+		*field = []byte(value)
+	default:
+		panic(fmt.Sprintf("unsupported option type %T", field))
+	}
+	return err
+}
+
+// basicAuthFromEnv returns the basic auth credentials of the "{prefix}_USERNAME" and "{prefix}_PASSWORD" environment variables,
+// or nil if neither is set.
+func basicAuthFromEnv(prefix string) *BasicAuth {
+	username, password := os.Getenv(prefix+"_USERNAME"), os.Getenv(prefix+"_PASSWORD")
+	if username == "" && password == "" {
+		return nil
+	}
+	return &BasicAuth{Username: username, Password: password}
+}
@@ -11,6 +11,10 @@ var (
 	// ErrNotFound signals that the catalog/meta/stream was not found.
 	// It leads to a "404 Not Found" response.
 	ErrNotFound = errors.New("not found")
+	// ErrUserDataTooLong signals that the user data is longer than the MaxUserDataLength option.
+	ErrUserDataTooLong = errors.New("user data too long")
+	// ErrNotRunning signals that Stop or Restart was called while the addon isn't running.
+	ErrNotRunning = errors.New("addon isn't running")
 
 	ErrNoMeta = errors.New("no meta in context")
 )
@@ -0,0 +1,32 @@
+package stremio
+
+import "context"
+
+type suppliedETagKey struct{}
+
+// suppliedETag is put into the handler's context by createHandler when handling ETags, for SetETag.
+type suppliedETag struct {
+	ifNoneMatch string
+	eTag        string
+}
+
+// SetETag sets the ETag of the handler's result, like the version or modification time of the data that the result is based on,
+// which is usually cheaper than the hash of the response body that the SDK uses otherwise.
+// It's only used when the HandleEtag option for the handler's resource is set (for example HandleEtagStreams), otherwise it does nothing.
+// It returns true if the client already has the result with this ETag. The handler can then return nil instead of building the result,
+// because the SDK responds with "304 Not Modified" anyway. Either way the result is only marshalled when the client doesn't have it.
+// The ETag must be different for every different result, including results for different user data or languages.
+// Supplied ETags also allow streaming large results, see Options.StreamingThreshold.
+func SetETag(ctx context.Context, eTag string) bool {
+	supplied, ok := ctx.Value(suppliedETagKey{}).(*suppliedETag)
+	if !ok {
+		return false
+	}
+	supplied.eTag = eTag
+	return eTagMatches(supplied.ifNoneMatch, eTag)
+}
+
+// eTagMatches returns true if the client's "If-None-Match" header matches the ETag of the response.
+func eTagMatches(ifNoneMatch, eTag string) bool {
+	return ifNoneMatch == "*" || ifNoneMatch == eTag
+}
@@ -115,8 +115,8 @@ func main() {
 		logger.Fatal("Couldn't create new addon", zap.Error(err))
 	}
 
-	// Register the user data type
-	addon.RegisterUserData(customer{})
+	// Register the user data type, so handlers get a *customer
+	stremio.RegisterUserDataType[customer](addon)
 
 	// Add a custom middleware that blocks unauthorized requests, but only for selected endpoints.
 	// This allows requests to:
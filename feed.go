@@ -0,0 +1,173 @@
+package stremio
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"slices"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// Formats of the catalog feeds, see Options.CatalogFeeds
+const (
+	feedFormatRSS      = "rss"
+	feedFormatJSONFeed = "json"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// jsonFeed is a feed in the JSON Feed 1.1 format, see https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Icon        string         `json:"icon,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	ContentText string   `json:"content_text"`
+	Image       string   `json:"image,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// stremioWebDetailURL returns the URL of the item's page in Stremio Web, which works in any feed reader, unlike "stremio://" links.
+func stremioWebDetailURL(metaType, id string) string {
+	return "https://web.stremio.com/#/detail/" + url.PathEscape(metaType) + "/" + url.PathEscape(id)
+}
+
+func createFeedHandler(format string, manifest *atomic.Pointer[manifestSnapshot], handlers *liveHandlers, switches *killSwitches, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("feedHandler called")
+
+		requestedType := c.Params("type")
+		requestedID, err := url.PathUnescape(c.Params("id"))
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		snapshot := manifest.Load()
+		i := slices.IndexFunc(snapshot.bodies.manifest.Catalogs, func(catalog types.CatalogItem) bool {
+			return catalog.Type == requestedType && catalog.ID == requestedID
+		})
+		// Catalogs that require an extra, like search-only ones, have no list of items to follow
+		if i == -1 || slices.ContainsFunc(snapshot.bodies.manifest.Catalogs[i].Extra, func(extra types.ExtraItem) bool { return extra.IsRequired }) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		catalog := snapshot.bodies.manifest.Catalogs[i]
+		reqHandler, ok := handlers.get(requestedType)
+		if !ok || !switches.enabled("catalog", requestedType) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		var userData any
+		userDataString := userDataParam(c)
+		switch {
+		case unmarshalUserData == nil:
+			userData = userDataString
+		case userDataString == "":
+			userData = nil
+		default:
+			if userData, err = decodeUserDataCached(userDataCache, userDataString, unmarshalUserData, logger, userDataIsBase64); err != nil {
+				return c.SendStatus(fiber.StatusBadRequest)
+			}
+		}
+
+		res, err := reqHandler(c.Context(), requestedID, nil, userData)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNotFound):
+				return c.SendStatus(fiber.StatusNotFound)
+			case errors.Is(err, ErrBadRequest):
+				return c.SendStatus(fiber.StatusBadRequest)
+			default:
+				logger.Error("Addon returned error", zap.Error(err), zap.String("requestedType", requestedType), zap.String("requestedID", requestedID))
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		metas, _ := res.([]types.MetaPreviewItem)
+
+		title := snapshot.bodies.manifest.Name + " - " + catalog.Name
+		homePageURL := c.BaseURL() + "/"
+		if format == feedFormatJSONFeed {
+			feed := jsonFeed{
+				Version:     "https://jsonfeed.org/version/1.1",
+				Title:       title,
+				HomePageURL: homePageURL,
+				FeedURL:     c.BaseURL() + c.OriginalURL(),
+				Description: snapshot.bodies.manifest.Description,
+				Icon:        snapshot.bodies.manifest.Logo,
+				Items:       make([]jsonFeedItem, len(metas)),
+			}
+			for i, meta := range metas {
+				feed.Items[i] = jsonFeedItem{
+					ID:          meta.ID,
+					URL:         stremioWebDetailURL(meta.Type, meta.ID),
+					Title:       meta.Name,
+					ContentText: meta.Description,
+					Image:       meta.Poster,
+					Tags:        meta.Genres,
+				}
+			}
+			return c.JSON(feed, "application/feed+json; charset=utf-8")
+		}
+
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:       title,
+				Link:        homePageURL,
+				Description: snapshot.bodies.manifest.Description,
+				Items:       make([]rssItem, len(metas)),
+			},
+		}
+		for i, meta := range metas {
+			feed.Channel.Items[i] = rssItem{
+				Title:       meta.Name,
+				Link:        stremioWebDetailURL(meta.Type, meta.ID),
+				Description: meta.Description,
+				Categories:  meta.Genres,
+				GUID:        rssGUID{Value: meta.ID},
+			}
+		}
+		body, err := xml.Marshal(feed)
+		if err != nil {
+			logger.Error("Couldn't marshal feed", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, "application/rss+xml; charset=utf-8")
+		return c.Send(append([]byte(xml.Header), body...))
+	}
+}
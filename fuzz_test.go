@@ -0,0 +1,59 @@
+package stremio
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type fuzzUserData struct {
+	Token   string   `json:"token"`
+	Quality []string `json:"quality"`
+	Limit   int      `json:"limit"`
+}
+
+func FuzzDecodeUserData(f *testing.F) {
+	f.Add(`{"token":"abc","quality":["1080p"],"limit":5}`)
+	f.Add(`%7B%22token%22%3A%22abc%22%7D`)
+	f.Add(`eyJ0b2tlbiI6ImFiYyJ9`)
+	f.Add(`eyJ0b2tlbiI6ImFiYyJ9==`)
+	f.Add(`%`)
+	f.Add(`{"limit":1e999}`)
+	unmarshalReflect := reflectUserDataUnmarshaler(reflect.TypeOf(fuzzUserData{}))
+	logger := zap.NewNop()
+	f.Fuzz(func(t *testing.T, data string) {
+		for _, isBase64 := range []bool{false, true} {
+			userData, err := decodeUserData(data, unmarshalReflect, logger, isBase64)
+			if err == nil && userData == nil {
+				t.Fatalf("no error, but nil user data for %q", data)
+			}
+			// RegisterUserDataType must behave exactly like RegisterUserData
+			genericUserData, genericErr := decodeUserData(data, unmarshalUserDataJSON[fuzzUserData], logger, isBase64)
+			if (err == nil) != (genericErr == nil) || !reflect.DeepEqual(userData, genericUserData) {
+				t.Fatalf("reflection-free decoding differs for %q: %v (%v) vs. %v (%v)", data, genericUserData, genericErr, userData, err)
+			}
+		}
+	})
+}
+
+func FuzzParseExtras(f *testing.F) {
+	f.Add("search=big%20buck&skip=100.json")
+	f.Add("genre=Action&genre=Drama")
+	f.Add("%zz=1")
+	f.Add(";;&&==")
+	f.Fuzz(func(t *testing.T, extraString string) {
+		extra, err := parseExtras(extraString)
+		if err != nil {
+			return
+		}
+		// Each value needs at least one byte of input, so the result can't be larger than the input.
+		n := 0
+		for key, values := range extra {
+			n += len(key) + len(values)
+		}
+		if n > len(extraString)+1 {
+			t.Fatalf("%v keys and values for %v bytes of input", n, len(extraString))
+		}
+	})
+}
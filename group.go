@@ -0,0 +1,58 @@
+package stremio
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Group is a group of custom endpoints below a common path prefix that share middlewares,
+// for keeping larger custom APIs like configure backends or admin APIs organized. Create one with Addon.Group.
+// Like the addon's endpoints, the group's endpoints and middlewares must be added before running the addon.
+type Group struct {
+	addon       *Addon
+	parent      *Group
+	prefix      string
+	middlewares []fiber.Handler
+}
+
+// Group returns a group for the path prefix, like "/api", with optional middlewares for all of its endpoints.
+func (a *Addon) Group(prefix string, middlewares ...fiber.Handler) *Group {
+	return &Group{
+		addon:       a,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+	}
+}
+
+// Group returns a subgroup for the path prefix below the group's prefix.
+// The subgroup's endpoints go through the group's middlewares first and then the subgroup's own.
+func (g *Group) Group(prefix string, middlewares ...fiber.Handler) *Group {
+	sub := g.addon.Group(g.prefix+prefix, middlewares...)
+	sub.parent = g
+	return sub
+}
+
+// Use adds a middleware for all endpoints of the group and its subgroups, including the ones that were added before.
+// Unlike Addon.AddMiddleware it's only called for the group's endpoints, not for every request below the prefix.
+func (g *Group) Use(middleware fiber.Handler) {
+	g.middlewares = append(g.middlewares, middleware)
+}
+
+// AddEndpoint adds a custom endpoint below the group's prefix, see Addon.AddEndpoint.
+func (g *Group) AddEndpoint(method, path string, handler fiber.Handler) {
+	g.addon.addEndpoint(method, g.prefix+path, handler, false, g)
+}
+
+// AddProtectedEndpoint adds a custom endpoint below the group's prefix that requires the APIKey, see Addon.AddProtectedEndpoint.
+func (g *Group) AddProtectedEndpoint(method, path string, handler fiber.Handler) {
+	g.addon.addEndpoint(method, g.prefix+path, handler, true, g)
+}
+
+// handlers returns the middlewares of the group's endpoints, the ones of the parent groups first.
+func (g *Group) handlers() []fiber.Handler {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.handlers(), g.middlewares...)
+}
@@ -1,6 +1,8 @@
 package stremio
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -12,68 +14,66 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/gofiber/fiber/v3"
-	"github.com/xybydy/go-stremio/types"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
+	"github.com/xybydy/go-stremio/pkg/subtitles"
 	"go.uber.org/zap"
 )
 
 type customEndpoint struct {
-	method  string
+	methods []string
 	path    string
 	handler fiber.Handler
+	// Whether the endpoint requires the APIKey.
+	protected bool
+	// Group whose middlewares are called before the handler, nil for endpoints that aren't in a group.
+	group *Group
 }
 
-func createHealthHandler(logger *zap.Logger) fiber.Handler {
-	return func(c fiber.Ctx) error {
-		logger.Debug("healthHandler called")
-		return c.SendString("OK")
-	}
-}
-
-func createManifestHandler(manifest types.Manifest, logger *zap.Logger, manifestCallback ManifestCallback, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
-	// When there's user data we want Stremio to show the "Install" button, which it only does when "configurationRequired" is false.
-	// To not change the boolean value of the manifest object on the fly and thus mess with a single object across concurrent goroutines, we copy it and return two different objects.
-	// Note that this manifest copy has some values shallowly copied, but `BehaviorHints.ConfigurationRequired` is a simple type and thus a real copy.
-	configuredManifest := manifest
-	configuredManifest.BehaviorHints.ConfigurationRequired = false
-
-	manifestBody, err := json.Marshal(manifest)
-	if err != nil {
-		logger.Fatal("Couldn't marshal manifest", zap.Error(err))
-	}
-	configuredManifestBody, err := json.Marshal(configuredManifest)
-	if err != nil {
-		logger.Fatal("Couldn't marshal configured manifest", zap.Error(err))
-	}
-
+func createManifestHandler(manifest *atomic.Pointer[manifestSnapshot], logger *zap.Logger, manifestCallback ManifestCallback, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache, variants *manifestVariants, translations *manifestTranslations) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		logger.Debug("manifestHandler called")
 
 		// First call the callback so the SDK user can prevent further processing
 		var userData any
-		userDataString := c.Params("userData")
+		var err error
+		userDataString := userDataParam(c)
 		configured := false
 		if userDataString == "" {
-			if userDataType == nil {
+			if unmarshalUserData == nil {
 				userData = ""
 			} else {
 				userData = nil
 			}
 		} else {
 			configured = true
-			if userDataType == nil {
+			if unmarshalUserData == nil {
 				userData = userDataString
 			} else {
-				if userData, err = decodeUserData(userDataString, userDataType, logger, userDataIsBase64); err != nil {
+				if userData, err = decodeUserDataCached(userDataCache, userDataString, unmarshalUserData, logger, userDataIsBase64); err != nil {
 					return c.SendStatus(fiber.StatusBadRequest)
 				}
 			}
 		}
+		snapshot := manifest.Load()
+		bodies := snapshot.bodies
+		if len(snapshot.variants) > 0 {
+			if name := variants.choose(c.Context(), userDataString, c.IP()); name != "" {
+				bodies = snapshot.variants[name]
+			}
+		}
+		if len(bodies.translations) > 0 {
+			if tag, ok := translations.choose(parseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))); ok {
+				bodies = bodies.translations[tag]
+			}
+		}
 		if manifestCallback != nil {
-			manifestClone := manifest.Clone()
+			manifestClone := bodies.manifest.Clone()
 			if status := manifestCallback(c.Context(), &manifestClone, userData); status >= http.StatusBadRequest {
 				return c.SendStatus(status)
 			}
@@ -92,23 +92,19 @@ func createManifestHandler(manifest types.Manifest, logger *zap.Logger, manifest
 		}
 
 		if configured {
-			logger.Debug("Responding", zap.ByteString("body", configuredManifestBody))
+			logger.Debug("Responding", zap.ByteString("body", bodies.configuredBody))
 			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
-			return c.Send(configuredManifestBody)
+			return c.Send(bodies.configuredBody)
 		}
 
-		logger.Debug("Responding", zap.ByteString("body", manifestBody))
+		logger.Debug("Responding", zap.ByteString("body", bodies.body))
 		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
-		return c.Send(manifestBody)
+		return c.Send(bodies.body)
 	}
 }
 
-func createCatalogHandler(catalogHandlers map[string]CatalogHandler, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
-	handlers := make(map[string]handler, len(catalogHandlers))
-	for k, v := range catalogHandlers {
-		handlers[k] = convertCatalogHandler(v)
-	}
-	return createHandler("catalog", handlers, []byte("metas"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, logger, userDataType, userDataIsBase64)
+func createCatalogHandler(handlers *liveHandlers, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, streamingThreshold int, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
+	return createHandler("catalog", handlers, []byte("metas"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, streamingThreshold, logger, unmarshalUserData, userDataIsBase64, userDataCache)
 }
 
 func convertCatalogHandler(h CatalogHandler) handler {
@@ -117,12 +113,8 @@ func convertCatalogHandler(h CatalogHandler) handler {
 	}
 }
 
-func createStreamHandler(streamHandlers map[string]StreamHandler, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
-	handlers := make(map[string]handler, len(streamHandlers))
-	for k, v := range streamHandlers {
-		handlers[k] = convertStreamHandler(v)
-	}
-	return createHandler("stream", handlers, []byte("streams"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, logger, userDataType, userDataIsBase64)
+func createStreamHandler(handlers *liveHandlers, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, streamingThreshold int, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
+	return createHandler("stream", handlers, []byte("streams"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, streamingThreshold, logger, unmarshalUserData, userDataIsBase64, userDataCache)
 }
 
 func convertStreamHandler(h StreamHandler) handler {
@@ -131,12 +123,8 @@ func convertStreamHandler(h StreamHandler) handler {
 	}
 }
 
-func createMetaHandler(metaHandlers map[string]MetaHandler, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
-	handlers := make(map[string]handler, len(metaHandlers))
-	for k, v := range metaHandlers {
-		handlers[k] = convertMetaHandler(v)
-	}
-	return createHandler("meta", handlers, []byte("meta"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, logger, userDataType, userDataIsBase64)
+func createMetaHandler(handlers *liveHandlers, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
+	return createHandler("meta", handlers, []byte("meta"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, 0, logger, unmarshalUserData, userDataIsBase64, userDataCache)
 }
 
 func convertMetaHandler(h MetaHandler) handler {
@@ -145,12 +133,8 @@ func convertMetaHandler(h MetaHandler) handler {
 	}
 }
 
-func createSubtitleHandler(subtitleHandlers map[string]SubtitleHandler, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
-	handlers := make(map[string]handler, len(subtitleHandlers))
-	for k, v := range subtitleHandlers {
-		handlers[k] = convertSubtitleHandler(v)
-	}
-	return createHandler("subtitle", handlers, []byte("subtitles"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, logger, userDataType, userDataIsBase64)
+func createSubtitleHandler(handlers *liveHandlers, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, streamingThreshold int, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
+	return createHandler("subtitle", handlers, []byte("subtitles"), cacheAge, staleRevalidateAge, staleErrorAge, cachePublic, handleEtag, streamingThreshold, logger, unmarshalUserData, userDataIsBase64, userDataCache)
 }
 
 func convertSubtitleHandler(h SubtitleHandler) handler {
@@ -159,10 +143,50 @@ func convertSubtitleHandler(h SubtitleHandler) handler {
 	}
 }
 
+// maxPooledResponseBufferSize is the max capacity of the buffers that are put back into the responseBufferPool,
+// so a few very large responses don't keep their memory allocated forever.
+const maxPooledResponseBufferSize = 1 << 20
+
+// responseBuffer is a buffer for marshalling responses, which hashes the marshalled result for the ETag while it's written,
+// so the body doesn't have to be read a second time.
+// Only Write is hashed, so the JSON object around the result can be written with the other methods of the buffer.
+type responseBuffer struct {
+	bytes.Buffer
+	digest  xxhash.Digest
+	hashing bool
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.hashing {
+		_, _ = b.digest.Write(p)
+	}
+	return b.Buffer.Write(p)
+}
+
+// reset empties the buffer and starts a new hash if hashing is true.
+func (b *responseBuffer) reset(hashing bool) {
+	b.Buffer.Reset()
+	b.digest.Reset()
+	b.hashing = hashing
+}
+
+// responseBufferPool contains the buffers for marshalling responses in createHandler.
+var responseBufferPool = sync.Pool{
+	New: func() any {
+		return new(responseBuffer)
+	},
+}
+
+func putResponseBuffer(buf *responseBuffer) {
+	if buf.Cap() <= maxPooledResponseBufferSize {
+		responseBufferPool.Put(buf)
+	}
+}
+
 // Common handler (same signature as both catalog and stream handler).
 type handler func(ctx context.Context, id string, extra url.Values, userData any) (any, error)
 
-func createHandler(handlerName string, handlers map[string]handler, jsonArrayKey []byte, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, logger *zap.Logger, userDataType reflect.Type, userDataIsBase64 bool) fiber.Handler {
+func createHandler(handlerName string, handlers *liveHandlers, jsonArrayKey []byte, cacheAge, staleRevalidateAge, staleErrorAge time.Duration, cachePublic, handleEtag bool, streamingThreshold int, logger *zap.Logger, unmarshalUserData userDataUnmarshaler, userDataIsBase64 bool, userDataCache *userDataCache) fiber.Handler {
 	handlerName += "Handler"
 	handlerLogMsg := handlerName + " called"
 
@@ -177,14 +201,12 @@ func createHandler(handlerName string, handlers map[string]handler, jsonArrayKey
 		}
 	}
 
-	var staleHeader string
-	if staleRevalidateAge != 0 {
-		staleHeader = "stale-while-revalidate=" + strconv.FormatFloat(math.Round(staleRevalidateAge.Seconds()), 'f', 0, 64)
+	// The stale directives are part of the same Cache-Control header, setting them separately would overwrite the max-age.
+	if cacheHeaderVal != "" && staleRevalidateAge != 0 {
+		cacheHeaderVal += ", stale-while-revalidate=" + strconv.FormatFloat(math.Round(staleRevalidateAge.Seconds()), 'f', 0, 64)
 	}
-
-	var staleErrorHeader string
-	if staleRevalidateAge != 0 {
-		staleErrorHeader = "stale-if-error=" + strconv.FormatFloat(math.Round(staleErrorAge.Seconds()), 'f', 0, 64)
+	if cacheHeaderVal != "" && staleErrorAge != 0 {
+		cacheHeaderVal += ", stale-if-error=" + strconv.FormatFloat(math.Round(staleErrorAge.Seconds()), 'f', 0, 64)
 	}
 
 	logger = logger.With(zap.String("handler", handlerName))
@@ -203,7 +225,7 @@ func createHandler(handlerName string, handlers map[string]handler, jsonArrayKey
 		zapLogType, zapLogID := zap.String("requestedType", requestedType), zap.String("requestedID", requestedID)
 
 		// Check if we have a reqHandler for the type
-		reqHandler, ok := handlers[requestedType]
+		reqHandler, ok := handlers.get(requestedType)
 		if !ok {
 			logger.Warn("Got request for unhandled type; returning 404")
 			return c.SendStatus(fiber.StatusNotFound)
@@ -211,31 +233,34 @@ func createHandler(handlerName string, handlers map[string]handler, jsonArrayKey
 
 		// Decode user data
 		var userData any
-		userDataString := c.Params("userData")
+		userDataString := userDataParam(c)
 		switch {
-		case userDataType == nil:
+		case unmarshalUserData == nil:
 			userData = userDataString
 		case userDataString == "":
 			userData = nil
 		default:
 			var err error
-			if userData, err = decodeUserData(userDataString, userDataType, logger, userDataIsBase64); err != nil {
+			if userData, err = decodeUserDataCached(userDataCache, userDataString, unmarshalUserData, logger, userDataIsBase64); err != nil {
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
 		}
 
 		// Get extra arguments
-		var extra url.Values
-		extraString := c.Params("extras")
-		extraString = strings.ReplaceAll(extraString, ".json", "")
-		if extraString != "" {
-			extra, err = url.ParseQuery(extraString)
-			if err != nil {
-				return c.SendStatus(fiber.StatusBadRequest)
-			}
+		extra, err := parseExtras(c.Params("extras"))
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
 		}
 
-		res, err := reqHandler(c.Context(), requestedID, extra, userData)
+		// Handlers can supply the ETag themselves, see SetETag
+		ctx := c.Context()
+		var supplied *suppliedETag
+		if handleEtag {
+			supplied = &suppliedETag{ifNoneMatch: c.Get(fiber.HeaderIfNoneMatch)}
+			ctx = context.WithValue(ctx, suppliedETagKey{}, supplied)
+		}
+
+		res, err := reqHandler(ctx, requestedID, extra, userData)
 		if err != nil {
 			switch {
 			case errors.Is(err, ErrNotFound):
@@ -250,62 +275,131 @@ func createHandler(handlerName string, handlers map[string]handler, jsonArrayKey
 			}
 		}
 
-		resBody, err := json.Marshal(res)
-		if err != nil {
+		respondNotModified := func(ifNoneMatch, eTag string) error {
+			if ce := logger.Check(zap.DebugLevel, "ETag matches, responding with 304"); ce != nil {
+				ce.Write(zap.String("If-None-Match", ifNoneMatch), zap.String("ETag", eTag), zapLogType, zapLogID)
+			}
+			c.Set(fiber.HeaderCacheControl, cacheHeaderVal) // Required according to https://tools.ietf.org/html/rfc7232#section-4.1
+			c.Set(fiber.HeaderETag, eTag)                   // We set it to make sure a client doesn't overwrite its cached ETag with an empty string or so.
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		// A supplied ETag is known before marshalling, so the result is only marshalled when the client doesn't have it yet.
+		var eTag string
+		if supplied != nil && supplied.eTag != "" {
+			eTag = supplied.eTag
+			if eTagMatches(supplied.ifNoneMatch, eTag) {
+				return respondNotModified(supplied.ifNoneMatch, eTag)
+			}
+		}
+
+		// Large results are encoded item by item into the response, so their JSON is never in memory as a whole.
+		// That's not possible with ETags that are the hash of the whole body, but with supplied ones.
+		if streamingThreshold > 0 && (!handleEtag || eTag != "") {
+			if items := reflect.ValueOf(res); items.Kind() == reflect.Slice && items.Len() >= streamingThreshold {
+				if ce := logger.Check(zap.DebugLevel, "Responding with streamed body"); ce != nil {
+					ce.Write(zap.Int("items", items.Len()), zapLogType, zapLogID)
+				}
+				c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				if cacheHeaderVal != "" {
+					c.Set(fiber.HeaderCacheControl, cacheHeaderVal)
+					if eTag != "" {
+						c.Set(fiber.HeaderETag, eTag)
+					}
+				}
+				// HEAD responses have no body, but should have the Content-Length of the GET response,
+				// which is only known after encoding the items. Count the bytes instead of keeping them.
+				if c.Method() == fiber.MethodHead {
+					var counter byteCounter
+					if err := writeJSONArray(bufio.NewWriter(&counter), jsonArrayKey, items); err != nil {
+						logger.Error("Couldn't marshal response", zap.Error(err), zapLogType, zapLogID)
+						return c.SendStatus(fiber.StatusInternalServerError)
+					}
+					c.Response().Header.SetContentLength(int(counter))
+					return nil
+				}
+				return c.SendStreamWriter(func(w *bufio.Writer) {
+					if err := writeJSONArray(w, jsonArrayKey, items); err != nil {
+						// The status was already sent, so the client gets a truncated body
+						logger.Error("Couldn't stream response", zap.Error(err), zapLogType, zapLogID)
+					}
+				})
+			}
+		}
+
+		// The response is marshalled into a pooled buffer, directly wrapped in the JSON object, so it isn't allocated and copied per request.
+		// Without a supplied ETag, the result is hashed while it's marshalled.
+		buf := responseBufferPool.Get().(*responseBuffer)
+		defer putResponseBuffer(buf)
+		buf.reset(handleEtag && eTag == "")
+		if len(jsonArrayKey) > 0 {
+			buf.WriteString(`{"`)
+			buf.Buffer.Write(jsonArrayKey)
+			buf.WriteString(`":`)
+		}
+		if err := json.NewEncoder(buf).Encode(res); err != nil {
 			logger.Error("Couldn't marshal response", zap.Error(err), zapLogType, zapLogID)
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
+		// Unlike json.Marshal, the encoder appends a newline
+		buf.Truncate(buf.Len() - 1)
 
 		// Handle ETag
-		var eTag string
-		if handleEtag {
-			hash := xxhash.Sum64(resBody)
-			eTag = strconv.FormatUint(hash, 16)
-			ifNoneMatch := c.Get("If-None-Match")
-			zapLogIfNoneMatch, zapLogETagServer := zap.String("If-None-Match", ifNoneMatch), zap.String("ETag", eTag)
-			modified := false
-			switch {
-			case ifNoneMatch == "*":
-				logger.Debug("If-None-Match is \"*\", responding with 304", zapLogIfNoneMatch, zapLogETagServer, zapLogType, zapLogID)
-			case ifNoneMatch != eTag:
-				logger.Debug("If-None-Match != ETag", zapLogIfNoneMatch, zapLogETagServer, zapLogType, zapLogID)
-				modified = true
-			default:
-				logger.Debug("ETag matches, responding with 304", zapLogIfNoneMatch, zapLogETagServer, zapLogType, zapLogID)
-			}
-			if !modified {
-				c.Set(fiber.HeaderCacheControl, cacheHeaderVal) // Required according to https://tools.ietf.org/html/rfc7232#section-4.1
-				c.Set(fiber.HeaderETag, eTag)                   // We set it to make sure a client doesn't overwrite its cached ETag with an empty string or so.
-				c.Set(fiber.HeaderCacheControl, staleHeader)
-				c.Set(fiber.HeaderCacheControl, staleErrorHeader)
-				return c.SendStatus(fiber.StatusNotModified)
+		if buf.hashing {
+			eTag = strconv.FormatUint(buf.digest.Sum64(), 16)
+			if ifNoneMatch := supplied.ifNoneMatch; eTagMatches(ifNoneMatch, eTag) {
+				return respondNotModified(ifNoneMatch, eTag)
 			}
 		}
 
 		if len(jsonArrayKey) > 0 {
-			prefix := append([]byte(`{"`), jsonArrayKey...)
-			prefix = append(prefix, '"', ':')
-			resBody = append(prefix, resBody...)
-			resBody = append(resBody, '}')
+			buf.WriteByte('}')
 		}
+		resBody := buf.Bytes()
 
-		logger.Debug("Responding", zap.ByteString("body", resBody), zapLogType, zapLogID)
+		// Checking the level first prevents allocating the fields for every request when debug logging is off
+		if ce := logger.Check(zap.DebugLevel, "Responding"); ce != nil {
+			ce.Write(zap.ByteString("body", resBody), zapLogType, zapLogID)
+		}
 		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
 		if cacheHeaderVal != "" {
 			c.Set(fiber.HeaderCacheControl, cacheHeaderVal)
 			if handleEtag {
 				c.Set(fiber.HeaderETag, eTag)
 			}
-			if staleHeader != "" {
-				c.Set(fiber.HeaderCacheControl, staleHeader)
-			}
-			if staleErrorHeader != "" {
-				c.Set(fiber.HeaderCacheControl, staleErrorHeader)
-			}
 		}
 
-		return c.Send(resBody)
+		// Unlike Send, SetBody copies the body. The buffer is reused by other requests after returning,
+		// while the response is only written after the remaining middlewares ran.
+		c.Response().SetBody(resBody)
+		return nil
+	}
+}
+
+// writeJSONArray writes the items as JSON object with the items array under the key, like `{"metas":[...]}`, encoding one item at a time.
+func writeJSONArray(w *bufio.Writer, jsonArrayKey []byte, items reflect.Value) error {
+	w.WriteString(`{"`)
+	w.Write(jsonArrayKey)
+	w.WriteString(`":[`)
+	encoder := json.NewEncoder(w)
+	for i := range items.Len() {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		if err := encoder.Encode(items.Index(i).Interface()); err != nil {
+			return err
+		}
 	}
+	w.WriteString("]}")
+	return w.Flush()
+}
+
+// byteCounter is a writer that only counts the bytes written to it.
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
 }
 
 func createRootHandler(redirectURL string, logger *zap.Logger) fiber.Handler {
@@ -318,15 +412,106 @@ func createRootHandler(redirectURL string, logger *zap.Logger) fiber.Handler {
 	}
 }
 
-func decodeUserData(data string, t reflect.Type, logger *zap.Logger, userDataIsBase64 bool) (any, error) {
-	logger.Debug("Decoding user data", zap.String("userData", data))
+func createResolveHandler(signer *signedurl.Signer, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("resolveHandler called")
+
+		target, err := signer.Verify(c.Params("token"))
+		switch {
+		case errors.Is(err, signedurl.ErrExpired):
+			logger.Debug("Got request with expired token; returning 410")
+			return c.SendStatus(fiber.StatusGone)
+		case err != nil:
+			logger.Warn("Got request with invalid token; returning 403", zap.Error(err))
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+
+		logger.Debug("Responding with redirect to upstream")
+		c.Set(fiber.HeaderLocation, target.URL)
+		// Tokens expire, so clients must not cache the redirect.
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.SendStatus(fiber.StatusTemporaryRedirect)
+	}
+}
+
+func createVTTHandler(signer *signedurl.Signer, logger *zap.Logger) fiber.Handler {
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	return func(c fiber.Ctx) error {
+		logger.Debug("vttHandler called")
+
+		target, err := signer.Verify(c.Params("token"))
+		switch {
+		case errors.Is(err, signedurl.ErrExpired):
+			logger.Debug("Got request with expired token; returning 410")
+			return c.SendStatus(fiber.StatusGone)
+		case err != nil:
+			logger.Warn("Got request with invalid token; returning 403", zap.Error(err))
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+
+		vtt, err := subtitles.FetchVTT(c.Context(), httpClient, target.URL)
+		if err != nil {
+			logger.Warn("Couldn't fetch subtitle; returning 502", zap.Error(err))
+			return c.SendStatus(fiber.StatusBadGateway)
+		}
+
+		c.Set(fiber.HeaderContentType, subtitles.MIMETypeVTT)
+		// The subtitle for a token doesn't change, so it can be cached until the token expires.
+		if maxAge := target.Expires - time.Now().Unix(); maxAge > 0 {
+			c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.FormatInt(maxAge, 10))
+		}
+		return c.Send(vtt)
+	}
+}
+
+// parseExtras parses the extra arguments of catalog requests, like "search=foo&skip=100.json".
+// It returns nil for an empty string.
+func parseExtras(extraString string) (url.Values, error) {
+	extraString = strings.ReplaceAll(extraString, ".json", "")
+	if extraString == "" {
+		return nil, nil
+	}
+	return url.ParseQuery(extraString)
+}
+
+// userDataUnmarshaler unmarshals the user data JSON after it was Base64-decoded or URL-unescaped.
+// The result is what handlers get as userData.
+type userDataUnmarshaler func(userDataJSON []byte) (any, error)
+
+// unmarshalUserDataJSON is the userDataUnmarshaler for user data of type T, see RegisterUserDataType.
+// Handlers get a *T, like with RegisterUserData.
+func unmarshalUserDataJSON[T any](userDataJSON []byte) (any, error) {
+	userData := new(T)
+	if err := json.Unmarshal(userDataJSON, userData); err != nil {
+		return nil, err
+	}
+	return userData, nil
+}
+
+// reflectUserDataUnmarshaler returns a userDataUnmarshaler for user data of the type t, for when the type is only known at runtime, see RegisterUserData.
+func reflectUserDataUnmarshaler(t reflect.Type) userDataUnmarshaler {
+	return func(userDataJSON []byte) (any, error) {
+		userData := reflect.New(t).Interface()
+		if err := json.Unmarshal(userDataJSON, userData); err != nil {
+			return nil, err
+		}
+		return userData, nil
+	}
+}
+
+func decodeUserData(data string, unmarshalUserData userDataUnmarshaler, logger *zap.Logger, userDataIsBase64 bool) (any, error) {
+	if ce := logger.Check(zap.DebugLevel, "Decoding user data"); ce != nil {
+		ce.Write(zap.String("userData", data))
+	}
 
 	var userDataDecoded []byte
 	var err error
 	if userDataIsBase64 {
 		// Remove padding so that both Base64URL values with and without padding work.
-		data = strings.TrimSuffix(data, "=")
-		userDataDecoded, err = base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(data)
+		data = strings.TrimRight(data, "=")
+		userDataDecoded, err = base64.RawURLEncoding.DecodeString(data)
 	} else {
 		var userDataDecodedString string
 		userDataDecodedString, err = url.PathUnescape(data)
@@ -338,11 +523,14 @@ func decodeUserData(data string, t reflect.Type, logger *zap.Logger, userDataIsB
 		return nil, err
 	}
 
-	userData := reflect.New(t).Interface()
-	if err := json.Unmarshal(userDataDecoded, userData); err != nil {
+	userData, err := unmarshalUserData(userDataDecoded)
+	if err != nil {
 		logger.Warn("Couldn't unmarshal user data", zap.Error(err))
 		return nil, err
 	}
-	logger.Debug("Decoded user data", zap.String("userData", fmt.Sprintf("%+v", userData)))
+	// Formatting the user data is only worth it when it's logged
+	if ce := logger.Check(zap.DebugLevel, "Decoded user data"); ce != nil {
+		ce.Write(zap.String("userData", fmt.Sprintf("%+v", userData)))
+	}
 	return userData, nil
 }
@@ -0,0 +1,134 @@
+package stremio
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// liveHandlers are the handlers of a resource by type, which can be swapped while the addon is running.
+// Requests load the current handlers atomically, and changes replace them as a whole.
+// It's safe for concurrent use.
+type liveHandlers struct {
+	resource string
+	// Handlers with their canaries, for the requests
+	current *atomic.Pointer[map[string]handler]
+	// Handlers without their canaries, guarded by the lock
+	primaries map[string]handler
+	canaries  map[string]canary
+	lock      *sync.Mutex
+}
+
+// newLiveHandlers converts the handlers of a resource, like the ones that are passed to NewAddon. It returns nil for nil handlers,
+// as the routes of a resource are only added when there are handlers for it.
+func newLiveHandlers[T any](resource string, handlers map[string]T, convert func(T) handler) *liveHandlers {
+	if handlers == nil {
+		return nil
+	}
+	l := &liveHandlers{
+		resource:  resource,
+		current:   &atomic.Pointer[map[string]handler]{},
+		primaries: make(map[string]handler, len(handlers)),
+		canaries:  map[string]canary{},
+		lock:      &sync.Mutex{},
+	}
+	for mediaType, h := range handlers {
+		l.primaries[mediaType] = convert(h)
+	}
+	l.update()
+	return l
+}
+
+// update stores the current handlers with their canaries. The lock must be held, except when creating the handlers.
+func (l *liveHandlers) update() {
+	handlers := withCanaries(l.resource, maps.Clone(l.primaries), l.canaries)
+	l.current.Store(&handlers)
+}
+
+func (l *liveHandlers) get(mediaType string) (handler, bool) {
+	h, ok := (*l.current.Load())[mediaType]
+	return h, ok
+}
+
+// has returns whether there's a handler for the type. It's false for nil handlers.
+func (l *liveHandlers) has(mediaType string) bool {
+	if l == nil {
+		return false
+	}
+	_, ok := l.get(mediaType)
+	return ok
+}
+
+// types returns the sorted types that have a handler.
+func (l *liveHandlers) types() []string {
+	return slices.Sorted(maps.Keys(*l.current.Load()))
+}
+
+// set replaces the handler for the type, or removes it when the handler is nil.
+func (l *liveHandlers) set(mediaType string, h handler) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if h == nil {
+		delete(l.primaries, mediaType)
+	} else {
+		l.primaries[mediaType] = h
+	}
+	l.update()
+}
+
+func (l *liveHandlers) addCanary(mediaType string, c canary) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.canaries[mediaType] = c
+	l.update()
+}
+
+func setHandler(l *liveHandlers, resource, mediaType string, h handler) error {
+	if l == nil {
+		return fmt.Errorf("the addon has no %v routes, as no %v handlers were passed to NewAddon", resource, resource)
+	}
+	l.set(mediaType, h)
+	return nil
+}
+
+// SetCatalogHandler sets the catalog handler for the type, replacing an existing one, or removes it when the handler is nil.
+// Unlike AddEndpoint it can also be called while the addon is running, for example for plugins that are loaded at runtime.
+// Requests that are already being handled finish with the previous handler. Canaries (see AddCanaryCatalogHandler) and
+// kill switches (see DisableResource) keep applying to the type. As the routes of a resource are only added when handlers
+// for it were passed to NewAddon, it returns an error otherwise. It's safe for concurrent use.
+func (a *Addon) SetCatalogHandler(mediaType string, h CatalogHandler) error {
+	var converted handler
+	if h != nil {
+		converted = convertCatalogHandler(h)
+	}
+	return setHandler(a.catalogHandlers, "catalog", mediaType, converted)
+}
+
+// SetStreamHandler sets the stream handler for the type, like SetCatalogHandler.
+func (a *Addon) SetStreamHandler(mediaType string, h StreamHandler) error {
+	var converted handler
+	if h != nil {
+		converted = convertStreamHandler(h)
+	}
+	return setHandler(a.streamHandlers, "stream", mediaType, converted)
+}
+
+// SetMetaHandler sets the meta handler for the type, like SetCatalogHandler.
+func (a *Addon) SetMetaHandler(mediaType string, h MetaHandler) error {
+	var converted handler
+	if h != nil {
+		converted = convertMetaHandler(h)
+	}
+	return setHandler(a.metaHandlers, "meta", mediaType, converted)
+}
+
+// SetSubtitleHandler sets the subtitle handler for the type, like SetCatalogHandler.
+func (a *Addon) SetSubtitleHandler(mediaType string, h SubtitleHandler) error {
+	var converted handler
+	if h != nil {
+		converted = convertSubtitleHandler(h)
+	}
+	return setHandler(a.subtitleHandlers, "subtitle", mediaType, converted)
+}
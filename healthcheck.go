@@ -0,0 +1,96 @@
+package stremio
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// HealthCheck checks a dependency of the addon, like whether a scraper session is still valid or there's enough disk space.
+// It returns an error if the dependency is unhealthy. It should return quickly, as it's called for every health check request.
+type HealthCheck func(ctx context.Context) error
+
+// healthCheckTimeout limits how long the health endpoint waits for the checks, so slow checks don't make it hang.
+const healthCheckTimeout = 5 * time.Second
+
+// healthChecks are the registered health checks. They can be registered while the addon is running.
+type healthChecks struct {
+	checks map[string]HealthCheck
+	lock   *sync.RWMutex
+}
+
+func newHealthChecks() *healthChecks {
+	return &healthChecks{
+		checks: map[string]HealthCheck{},
+		lock:   &sync.RWMutex{},
+	}
+}
+
+func (h *healthChecks) register(name string, check HealthCheck) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.checks[name] = check
+}
+
+// run runs all checks concurrently and returns the errors of the failed ones by name.
+func (h *healthChecks) run(ctx context.Context) map[string]error {
+	h.lock.RLock()
+	checks := make(map[string]HealthCheck, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.lock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	var errsLock sync.Mutex
+	errs := map[string]error{}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := check(ctx); err != nil {
+				errsLock.Lock()
+				errs[name] = err
+				errsLock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// RegisterHealthCheck registers a health check that's run on every request to the health endpoint, see HealthPath in the options.
+// When a check fails, the endpoint responds with "503 Service Unavailable" and the names and errors of the failed checks.
+// Registering a check with an existing name replaces that check.
+func (a *Addon) RegisterHealthCheck(name string, check HealthCheck) {
+	a.healthChecks.register(name, check)
+}
+
+func createHealthHandler(checks *healthChecks, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("healthHandler called")
+		errs := checks.run(c.Context())
+		if len(errs) == 0 {
+			return c.SendString("OK")
+		}
+
+		names := make([]string, 0, len(errs))
+		for name := range errs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var body strings.Builder
+		for _, name := range names {
+			logger.Warn("Health check failed", zap.String("check", name), zap.Error(errs[name]))
+			body.WriteString(name + ": " + errs[name].Error() + "\n")
+		}
+		return c.Status(fiber.StatusServiceUnavailable).SendString(body.String())
+	}
+}
@@ -0,0 +1,40 @@
+package stremio
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+)
+
+// AddHTTPEndpoint adds a custom endpoint like AddEndpoint, but with a net/http handler, for plugging in existing handlers
+// like OAuth callbacks or webhook receivers without learning Fiber. The route's parameters, like "userData" in "/:userData/callback",
+// are available via the request's PathValue method.
+func (a *Addon) AddHTTPEndpoint(method, path string, handler http.Handler) {
+	a.AddEndpoint(method, path, httpHandler(handler))
+}
+
+// AddHTTPEndpoint adds a custom endpoint below the group's prefix with a net/http handler, see Addon.AddHTTPEndpoint.
+func (g *Group) AddHTTPEndpoint(method, path string, handler http.Handler) {
+	g.AddEndpoint(method, path, httpHandler(handler))
+}
+
+// httpHandler converts the net/http handler to a Fiber handler that passes the route's parameters as path values.
+func httpHandler(handler http.Handler) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		names := c.Route().Params
+		if len(names) == 0 {
+			return adaptor.HTTPHandler(handler)(c)
+		}
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = c.Params(name)
+		}
+		return adaptor.HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i, name := range names {
+				r.SetPathValue(name, values[i])
+			}
+			handler.ServeHTTP(w, r)
+		})(c)
+	}
+}
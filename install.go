@@ -0,0 +1,171 @@
+package stremio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/pkg/store"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+// InstallCallback is called when a manifest request arrives with user data that wasn't seen before, see Options.InstallCallback.
+// The user data is decoded like for the handlers. The user hash identifies the user without revealing the user data,
+// as it's the hex-encoded SHA-256 hash of it.
+type InstallCallback func(ctx context.Context, userHash string, userData any)
+
+// InstallEvent is the JSON body that's POSTed to the Options.InstallWebhookURL.
+type InstallEvent struct {
+	AddonID      string    `json:"addonId"`
+	AddonVersion string    `json:"addonVersion"`
+	UserHash     string    `json:"userHash"`
+	Time         time.Time `json:"time"`
+}
+
+// installKeyPrefix namespaces the keys, so stores can be shared with other data.
+const installKeyPrefix = "install:"
+
+// installNotifyTimeout limits how long the callback and webhook may take, as they run in the background.
+const installNotifyTimeout = 10 * time.Second
+
+// defaultInstallStoreSize is the max number of users that the default InstallStore remembers,
+// as the user data can be made up by clients.
+const defaultInstallStoreSize = 100_000
+
+// installTracker detects new users and notifies the InstallCallback and InstallWebhookURL about them.
+type installTracker struct {
+	store             ConfigStore
+	callback          InstallCallback
+	webhookURL        string
+	httpClient        *http.Client
+	addonID           string
+	addonVersion      string
+	unmarshalUserData userDataUnmarshaler
+	userDataIsBase64  bool
+	logger            *zap.Logger
+	// Deduplicates concurrent first requests of the same user
+	group *singleflight.Group
+	// Limits the notifications, see Options.InstallNotificationsPerMinute
+	limiter *rate.Limiter
+}
+
+func newInstallTracker(a *Addon) *installTracker {
+	installStore := a.opts.InstallStore
+	if installStore == nil {
+		installStore = store.NewMemoryWithLimit(defaultInstallStoreSize)
+	}
+	return &installTracker{
+		store:             installStore,
+		callback:          a.opts.InstallCallback,
+		webhookURL:        a.opts.InstallWebhookURL,
+		httpClient:        &http.Client{Timeout: installNotifyTimeout},
+		addonID:           a.manifest.ID,
+		addonVersion:      a.manifest.Version,
+		unmarshalUserData: a.unmarshalUserData,
+		userDataIsBase64:  a.opts.UserDataIsBase64,
+		logger:            a.logger,
+		group:             &singleflight.Group{},
+		limiter:           rate.NewLimiter(rate.Every(time.Minute/time.Duration(a.opts.InstallNotificationsPerMinute)), a.opts.InstallNotificationsPerMinute),
+	}
+}
+
+// track checks if the user data was seen before, and if not, remembers it and notifies about the new user in the background.
+func (t *installTracker) track(ctx context.Context, userData string) {
+	sum := sha256.Sum256([]byte(userData))
+	userHash := hex.EncodeToString(sum[:])
+	_, _, _ = t.group.Do(userHash, func() (any, error) {
+		_, seen, err := t.store.Get(ctx, installKeyPrefix+userHash)
+		if err != nil {
+			t.logger.Error("Couldn't check if user was seen before", zap.Error(err))
+			return nil, nil
+		} else if seen {
+			return nil, nil
+		}
+		// Not remembering the user means it's reported on its next installation
+		if !t.limiter.Allow() {
+			t.logger.Warn("Too many new users, dropping install notification")
+			return nil, nil
+		}
+		if err = t.store.Set(ctx, installKeyPrefix+userHash, []byte(time.Now().UTC().Format(time.RFC3339)), 0); err != nil {
+			t.logger.Error("Couldn't remember user", zap.Error(err))
+			return nil, nil
+		}
+		go t.notify(userHash, userData)
+		return nil, nil
+	})
+}
+
+func (t *installTracker) notify(userHash, userDataString string) {
+	ctx, cancel := context.WithTimeout(context.Background(), installNotifyTimeout)
+	defer cancel()
+
+	if t.callback != nil {
+		var userData any = userDataString
+		if t.unmarshalUserData != nil {
+			var err error
+			if userData, err = decodeUserData(userDataString, t.unmarshalUserData, t.logger, t.userDataIsBase64); err != nil {
+				// Already logged
+				return
+			}
+		}
+		t.callback(ctx, userHash, userData)
+	}
+	if t.webhookURL != "" {
+		if err := t.sendWebhook(ctx, userHash); err != nil {
+			t.logger.Warn("Couldn't send install webhook", zap.Error(err))
+		}
+	}
+}
+
+func (t *installTracker) sendWebhook(ctx context.Context, userHash string) error {
+	body, err := json.Marshal(InstallEvent{
+		AddonID:      t.addonID,
+		AddonVersion: t.addonVersion,
+		UserHash:     userHash,
+		Time:         time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't marshal event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't send request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("bad response status: %v", res.Status)
+	}
+	return nil
+}
+
+// createInstallTrackingMiddleware creates a middleware that tracks the user data of successful manifest requests.
+func createInstallTrackingMiddleware(tracker *installTracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		// HEAD requests are probes, not installations
+		if c.Response().StatusCode() != fiber.StatusOK || c.Method() == fiber.MethodHead {
+			return nil
+		}
+		// The param points into the request buffer, which is reused after the request
+		if userData := strings.Clone(userDataParam(c)); userData != "" {
+			tracker.track(c.Context(), userData)
+		}
+		return nil
+	}
+}
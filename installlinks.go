@@ -0,0 +1,63 @@
+package stremio
+
+import (
+	"net/url"
+	"strings"
+)
+
+// WebInstallBaseURL is the URL of Stremio Web's addon page, which installs the addon in its "addon" query parameter.
+const WebInstallBaseURL = "https://web.strem.io/#/addons"
+
+// InstallLinks are the links for installing an addon.
+type InstallLinks struct {
+	// URL of the manifest, like "https://example.com/abc/manifest.json".
+	ManifestURL string `json:"manifestUrl"`
+	// Deep link that opens the Stremio app, like "stremio://example.com/abc/manifest.json".
+	StremioURL string `json:"stremioUrl"`
+	// Link that opens Stremio Web, for users without the app.
+	WebURL string `json:"webUrl"`
+}
+
+// ManifestURL returns the URL of the manifest of the addon at the base URL, like "https://example.com".
+// The user data must already be encoded like in the URL (see Addon.EncodeUserData) and can be empty.
+func ManifestURL(baseURL, userData string) string {
+	manifestURL := strings.TrimSuffix(strings.TrimSuffix(baseURL, "/manifest.json"), "/")
+	if userData != "" {
+		manifestURL += "/" + userData
+	}
+	return manifestURL + "/manifest.json"
+}
+
+// StremioURL returns the "stremio://" deep link for installing the addon at the base URL, like "https://example.com".
+// The user data must already be encoded like in the URL (see Addon.EncodeUserData) and can be empty.
+// Stremio always uses HTTPS for deep links, except for localhost.
+func StremioURL(baseURL, userData string) string {
+	manifestURL := ManifestURL(baseURL, userData)
+	if i := strings.Index(manifestURL, "://"); i >= 0 {
+		manifestURL = manifestURL[i+len("://"):]
+	}
+	return "stremio://" + manifestURL
+}
+
+// WebInstallURL returns the Stremio Web URL for installing the addon at the base URL, like "https://example.com".
+// The user data must already be encoded like in the URL (see Addon.EncodeUserData) and can be empty.
+func WebInstallURL(baseURL, userData string) string {
+	return WebInstallBaseURL + "?addon=" + url.QueryEscape(ManifestURL(baseURL, userData))
+}
+
+// InstallLinks returns the links for installing the addon at the public base URL, like "https://example.com".
+// The user data is encoded like with EncodeUserData. It can be nil for addons without user data.
+func (a *Addon) InstallLinks(baseURL string, userData any) (InstallLinks, error) {
+	encoded := ""
+	if userData != nil {
+		var err error
+		if encoded, err = a.EncodeUserData(userData); err != nil {
+			return InstallLinks{}, err
+		}
+	}
+	return InstallLinks{
+		ManifestURL: ManifestURL(baseURL, encoded),
+		StremioURL:  StremioURL(baseURL, encoded),
+		WebURL:      WebInstallURL(baseURL, encoded),
+	}, nil
+}
@@ -0,0 +1,11 @@
+// Package metactx contains the context key for meta objects,
+// so the stremio and cinemeta packages read and write the same one.
+package metactx
+
+type key struct{}
+
+// Key is the context key for meta objects.
+var Key = key{}
+
+// LegacyKey is the string key that was used before Key and is still read for backward compatibility.
+const LegacyKey = "meta"
@@ -0,0 +1,115 @@
+// Package scaffold generates the skeleton of a new addon for the "go-stremio new" command.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Resources that can be generated.
+var Resources = []string{"catalog", "meta", "stream", "subtitles"}
+
+// Options are the options for a new addon.
+type Options struct {
+	// Go module path, like "github.com/user/my-addon". Required.
+	Module string
+	// Addon ID, like "com.example.my-addon". Required.
+	ID string
+	// Addon name, like "My addon". Required.
+	Name string
+	// Resources to generate handlers for, see Resources. Required.
+	Resources []string
+	// Types like "movie" and "series". Required.
+	Types []string
+	// Whether to generate user data and a configure page.
+	Configurable bool
+}
+
+// data is the data for the templates.
+type data struct {
+	Options
+}
+
+func (d data) Has(resource string) bool {
+	return slices.Contains(d.Resources, resource)
+}
+
+// Generate writes the files of a new addon into the directory, which must not exist or be empty.
+// It returns the paths of the written files, relative to the directory.
+func Generate(dir string, opts Options) ([]string, error) {
+	switch {
+	case opts.Module == "" || opts.ID == "" || opts.Name == "":
+		return nil, errors.New("the module, ID and name must not be empty")
+	case len(opts.Resources) == 0 || len(opts.Types) == 0:
+		return nil, errors.New("at least one resource and one type are required")
+	}
+	for _, r := range opts.Resources {
+		if !slices.Contains(Resources, r) {
+			return nil, fmt.Errorf("unknown resource %q, must be one of %v", r, strings.Join(Resources, ", "))
+		}
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("directory %v isn't empty", dir)
+	}
+
+	funcs := template.FuncMap{
+		"quote": strconv.Quote,
+		"quoteList": func(values []string) string {
+			quoted := make([]string, len(values))
+			for i, v := range values {
+				quoted[i] = strconv.Quote(v)
+			}
+			return strings.Join(quoted, ", ")
+		},
+	}
+	var written []string
+	err := fs.WalkDir(templates, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "templates/"), ".tmpl")
+		if strings.HasPrefix(name, "web/") && !opts.Configurable {
+			return nil
+		}
+		tmpl, err := template.New(filepath.Base(path)).Funcs(funcs).ParseFS(templates, path)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, data{opts}); err != nil {
+			return fmt.Errorf("couldn't execute template %v: %w", name, err)
+		}
+		content := buf.Bytes()
+		if strings.HasSuffix(name, ".go") {
+			if content, err = format.Source(content); err != nil {
+				return fmt.Errorf("generated invalid Go code in %v: %w", name, err)
+			}
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(target, content, 0o644); err != nil {
+			return err
+		}
+		written = append(written, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
@@ -0,0 +1,111 @@
+package stremio
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// ipFilter decides which clients may access the addon, based on Options.AllowedIPs, DeniedIPs and TrustedProxies.
+type ipFilter struct {
+	allowed        []netip.Prefix
+	denied         []netip.Prefix
+	trustedProxies []netip.Prefix
+}
+
+// newIPFilter parses the IP addresses and CIDR ranges of the options.
+func newIPFilter(allowed, denied, trustedProxies []string) (*ipFilter, error) {
+	var f ipFilter
+	var err error
+	if f.allowed, err = parsePrefixes(allowed); err != nil {
+		return nil, fmt.Errorf("invalid AllowedIPs: %w", err)
+	}
+	if f.denied, err = parsePrefixes(denied); err != nil {
+		return nil, fmt.Errorf("invalid DeniedIPs: %w", err)
+	}
+	if f.trustedProxies, err = parsePrefixes(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TrustedProxies: %w", err)
+	}
+	return &f, nil
+}
+
+// parsePrefixes parses CIDR ranges like "10.0.0.0/8", and single IP addresses as ranges that only contain them.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP address of the client.
+// When the request comes from a trusted proxy, the X-Forwarded-For header is walked from right to left,
+// skipping trusted proxies, so clients can't spoof their address by sending the header themselves.
+func (f *ipFilter) clientIP(c fiber.Ctx) netip.Addr {
+	addr, _ := netip.AddrFromSlice(c.RequestCtx().RemoteIP())
+	addr = addr.Unmap()
+	if !containsAddr(f.trustedProxies, addr) {
+		return addr
+	}
+	hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Everything left of an invalid entry can't be trusted
+			break
+		}
+		addr = hop.Unmap()
+		if !containsAddr(f.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// allows reports whether the client may access the addon. Denied IPs take precedence over allowed ones.
+func (f *ipFilter) allows(addr netip.Addr) bool {
+	if containsAddr(f.denied, addr) {
+		return false
+	}
+	return len(f.allowed) == 0 || containsAddr(f.allowed, addr)
+}
+
+// createIPFilterMiddleware creates a middleware that rejects requests from clients that the filter doesn't allow.
+func createIPFilterMiddleware(f *ipFilter, logIPs bool, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if addr := f.clientIP(c); !f.allows(addr) {
+			if logIPs {
+				logger.Debug("Rejecting request from blocked IP", zap.String("ip", addr.String()), zap.String("path", c.Path()))
+			} else {
+				logger.Debug("Rejecting request from blocked IP", zap.String("path", c.Path()))
+			}
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package stremio
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// killSwitches are the resources and types that are disabled at runtime.
+// Keys are either a resource like "catalog", or a resource and type like "catalog/movie".
+type killSwitches struct {
+	disabled map[string]struct{}
+	lock     *sync.RWMutex
+}
+
+func newKillSwitches() *killSwitches {
+	return &killSwitches{
+		disabled: map[string]struct{}{},
+		lock:     &sync.RWMutex{},
+	}
+}
+
+func (k *killSwitches) enabled(resource, mediaType string) bool {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	if len(k.disabled) == 0 {
+		return true
+	}
+	if _, ok := k.disabled[resource]; ok {
+		return false
+	}
+	_, ok := k.disabled[resource+"/"+mediaType]
+	return !ok
+}
+
+// DisableResource disables a resource ("catalog", "stream", "meta" or "subtitles") at runtime, for incident mitigation without redeploys.
+// Without types the whole resource is disabled, otherwise only the given types.
+// Requests for disabled catalogs, streams and subtitles get empty results, and requests for disabled meta get "404 Not Found".
+func (a *Addon) DisableResource(resource string, types ...string) {
+	a.killSwitches.lock.Lock()
+	defer a.killSwitches.lock.Unlock()
+	if len(types) == 0 {
+		a.killSwitches.disabled[resource] = struct{}{}
+	}
+	for _, t := range types {
+		a.killSwitches.disabled[resource+"/"+t] = struct{}{}
+	}
+	a.logger.Info("Disabled resource", zap.String("resource", resource), zap.Strings("types", types))
+}
+
+// EnableResource enables a resource that was disabled with DisableResource.
+// Without types the resource is enabled completely, including types that were disabled individually, otherwise only the given types.
+func (a *Addon) EnableResource(resource string, types ...string) {
+	a.killSwitches.lock.Lock()
+	defer a.killSwitches.lock.Unlock()
+	if len(types) == 0 {
+		for key := range a.killSwitches.disabled {
+			if key == resource || strings.HasPrefix(key, resource+"/") {
+				delete(a.killSwitches.disabled, key)
+			}
+		}
+	}
+	for _, t := range types {
+		delete(a.killSwitches.disabled, resource+"/"+t)
+	}
+	a.logger.Info("Enabled resource", zap.String("resource", resource), zap.Strings("types", types))
+}
+
+// ResourceEnabled reports whether the resource is enabled for the type, see DisableResource.
+func (a *Addon) ResourceEnabled(resource, mediaType string) bool {
+	return a.killSwitches.enabled(resource, mediaType)
+}
+
+// createKillSwitchMiddleware creates a middleware that responds with an empty result for disabled resources,
+// or "404 Not Found" when jsonArrayKey is empty (like for meta, which has no empty result).
+func createKillSwitchMiddleware(switches *killSwitches, resource, jsonArrayKey string, logger *zap.Logger) fiber.Handler {
+	emptyBody := []byte(`{"` + jsonArrayKey + `":[]}`)
+	return func(c fiber.Ctx) error {
+		if switches.enabled(resource, c.Params("type")) {
+			return c.Next()
+		}
+		logger.Debug("Rejecting request for disabled resource", zap.String("resource", resource), zap.String("type", c.Params("type")))
+		if jsonArrayKey == "" {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(emptyBody)
+	}
+}
@@ -0,0 +1,90 @@
+package stremio
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var (
+	styleTemplate   = template.Must(template.ParseFS(templatesFS, "templates/style.html"))
+	landingTemplate = template.Must(withStyleTemplate(template.Must(template.ParseFS(templatesFS, "templates/landing.html"))))
+)
+
+// withStyleTemplate returns a copy of the custom page template that can include the built-in "style" template,
+// unless it already defines its own.
+func withStyleTemplate(custom *template.Template) (*template.Template, error) {
+	tmpl, err := custom.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't clone template: %w", err)
+	}
+	if tmpl.Lookup("style") == nil {
+		if _, err = tmpl.AddParseTree("style", styleTemplate.Lookup("style").Tree); err != nil {
+			return nil, fmt.Errorf("couldn't add style template: %w", err)
+		}
+	}
+	return tmpl, nil
+}
+
+// LandingPageData is the data that the landing page template is executed with.
+type LandingPageData struct {
+	Manifest     types.Manifest
+	InstallLinks InstallLinks
+	// Same as InstallLinks.StremioURL, but marked as safe for "href" attributes,
+	// as html/template otherwise replaces URLs with the "stremio" scheme.
+	StremioURL template.URL
+	// URL of the configure page, empty if the addon doesn't have one.
+	ConfigureURL string
+	// QR code of the "stremio://" install URL as "data:" URL, empty unless the InstallQRCode option is set.
+	QRCode template.URL
+	// Custom CSS from the PageCSS option.
+	CSS template.CSS
+	// URL of the PageAssets, like "https://example.com/assets", empty if there are none.
+	AssetsURL string
+}
+
+func createLandingHandler(manifest types.Manifest, tmpl *template.Template, css string, hasAssets, hasConfigurePage, qrCode bool, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("landingHandler called")
+
+		baseURL := c.BaseURL()
+		links := InstallLinks{
+			ManifestURL: ManifestURL(baseURL, ""),
+			StremioURL:  StremioURL(baseURL, ""),
+			WebURL:      WebInstallURL(baseURL, ""),
+		}
+		data := LandingPageData{
+			Manifest:     manifest,
+			InstallLinks: links,
+			StremioURL:   template.URL(links.StremioURL),
+			CSS:          template.CSS(css),
+		}
+		if hasAssets {
+			data.AssetsURL = baseURL + "/assets"
+		}
+		if hasConfigurePage {
+			data.ConfigureURL = baseURL + "/configure"
+		}
+		if qrCode && !manifest.BehaviorHints.ConfigurationRequired {
+			var err error
+			if data.QRCode, err = qrCodeDataURL(links.StremioURL); err != nil {
+				logger.Error("Couldn't create QR code", zap.Error(err))
+			}
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			logger.Error("Couldn't execute landing page template", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(buf.Bytes())
+	}
+}
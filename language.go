@@ -0,0 +1,158 @@
+package stremio
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+type (
+	languageKey       struct{}
+	acceptLanguageKey struct{}
+)
+
+// wildcardLanguage is what the "*" in an "Accept-Language" header is parsed as.
+var wildcardLanguage = language.Make("mul")
+
+// unmarshalUserDataMap is for reading single config items from user data of any registered type.
+var unmarshalUserDataMap userDataUnmarshaler = unmarshalUserDataJSON[map[string]any]
+
+// languages are the languages that the addon supports, see Options.Languages.
+type languages struct {
+	// As given in the options, for putting them into the context unchanged
+	names     []string
+	matcher   language.Matcher
+	configKey string
+}
+
+func newLanguages(names []string, configKey string) (*languages, error) {
+	tags := make([]language.Tag, len(names))
+	for i, name := range names {
+		var err error
+		if tags[i], err = language.Parse(name); err != nil {
+			return nil, fmt.Errorf("invalid language %q: %w", name, err)
+		}
+	}
+	return &languages{
+		names:     names,
+		matcher:   language.NewMatcher(tags),
+		configKey: configKey,
+	}, nil
+}
+
+// match returns the supported language that matches the requested ones best, or false if none matches.
+func (l *languages) match(requested ...language.Tag) (string, bool) {
+	if len(requested) == 0 {
+		return "", false
+	}
+	_, index, confidence := l.matcher.Match(requested...)
+	if confidence == language.No {
+		return "", false
+	}
+	return l.names[index], true
+}
+
+// fromConfig returns the supported language that matches the value of the config item in the user data, or false if there's none.
+func (l *languages) fromConfig(userData string, userDataIsBase64 bool, logger *zap.Logger) (string, bool) {
+	if l.configKey == "" || userData == "" {
+		return "", false
+	}
+	// Invalid user data is rejected by the handler, so we don't need to care about it here
+	decoded, err := decodeUserData(userData, unmarshalUserDataMap, logger, userDataIsBase64)
+	if err != nil {
+		return "", false
+	}
+	value, _ := (*decoded.(*map[string]any))[l.configKey].(string)
+	tag, err := language.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	return l.match(tag)
+}
+
+// parseAcceptLanguage returns the languages of an "Accept-Language" header ordered by preference, without "*".
+// A malformed header leads to nil, like a missing one.
+func parseAcceptLanguage(header string) []language.Tag {
+	if header == "" {
+		return nil
+	}
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+	return slices.DeleteFunc(tags, func(tag language.Tag) bool {
+		return tag == wildcardLanguage || tag == language.Und
+	})
+}
+
+// createAcceptLanguageMiddleware puts the languages of the "Accept-Language" header into the context, see AcceptLanguagesFromContext.
+func createAcceptLanguageMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if tags := parseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)); len(tags) > 0 {
+			c.SetContext(WithAcceptLanguages(c.Context(), tags))
+		}
+		return c.Next()
+	}
+}
+
+// createLanguageMiddleware puts the user's language into the context, see Options.Languages for the precedence.
+func createLanguageMiddleware(l *languages, catalogs, userDataIsBase64 bool, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		lang := ""
+		if catalogs {
+			if _, catalogLang := types.SplitCatalogLanguage(c.Params("id")); catalogLang != "" {
+				for _, name := range l.names {
+					if name == catalogLang {
+						lang = name
+						break
+					}
+				}
+			}
+		}
+		if lang == "" {
+			lang, _ = l.fromConfig(userDataParam(c), userDataIsBase64, logger)
+		}
+		if lang == "" {
+			lang, _ = l.match(AcceptLanguagesFromContext(c.Context())...)
+		}
+		if lang == "" {
+			lang = l.names[0]
+		}
+		c.SetContext(WithLanguage(c.Context(), lang))
+		return c.Next()
+	}
+}
+
+// WithLanguage returns a copy of the context with the user's language, which can be read with LanguageFromContext.
+// go-stremio uses it when Languages are set in the options, but it's also useful for tests and custom middlewares.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFromContext returns the user's language as written in Options.Languages, like "de" or "pt-BR".
+// It's empty if no Languages are set in the options.
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// WithAcceptLanguages returns a copy of the context with the languages that the user accepts, which can be read with AcceptLanguagesFromContext.
+// It's useful for tests and custom middlewares.
+func WithAcceptLanguages(ctx context.Context, tags []language.Tag) context.Context {
+	return context.WithValue(ctx, acceptLanguageKey{}, tags)
+}
+
+// AcceptLanguagesFromContext returns the languages of the request's "Accept-Language" header, ordered by the user's preference,
+// so handlers can prioritize results in the user's language, like streams with matching audio or subtitles in the matching language.
+// Use the tags' Base method for comparing just the languages, so "de-AT" matches "de". Wildcards ("*") are left out.
+// It's nil for the manifest endpoint and custom endpoints, and if the header is missing or malformed.
+// Unlike LanguageFromContext it doesn't require Options.Languages, but it doesn't take config items or per-language catalogs into account.
+func AcceptLanguagesFromContext(ctx context.Context) []language.Tag {
+	tags, _ := ctx.Value(acceptLanguageKey{}).([]language.Tag)
+	return tags
+}
@@ -0,0 +1,217 @@
+package stremio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // For decoding GIF logos
+	_ "image/jpeg" // For decoding JPEG logos
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// manifestImageTTL is how long a fetched image is served before it's fetched again, so changed images show up eventually.
+	manifestImageTTL = time.Hour
+	// manifestImageMaxAge is the client-side cache duration of the images.
+	manifestImageMaxAge = 24 * time.Hour
+	// manifestImageMaxSize limits the size of fetched images, so a wrong URL can't fill the memory.
+	manifestImageMaxSize = 10 << 20
+	// faviconSize is the width and height of the favicon in pixels.
+	faviconSize = 32
+)
+
+type manifestImage struct {
+	url         string
+	contentType string
+	body        []byte
+	fetched     time.Time
+}
+
+// manifestImages fetches the images of the current manifest and keeps them in memory.
+type manifestImages struct {
+	manifest   *atomic.Pointer[manifestSnapshot]
+	httpClient *http.Client
+	// Deduplicates concurrent fetches of the same image
+	group  singleflight.Group
+	lock   sync.Mutex
+	images map[string]*manifestImage
+	logger *zap.Logger
+}
+
+func newManifestImages(manifest *atomic.Pointer[manifestSnapshot], logger *zap.Logger) *manifestImages {
+	return &manifestImages{
+		manifest:   manifest,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		images:     make(map[string]*manifestImage),
+		logger:     logger,
+	}
+}
+
+// get returns the "logo", "background" or "favicon" image of the current manifest, or nil if the manifest doesn't have it.
+// When fetching fails, a previously fetched image is returned as long as the URL didn't change.
+func (m *manifestImages) get(ctx context.Context, name string) (*manifestImage, error) {
+	manifest := m.manifest.Load().bodies.manifest
+	url := manifest.Logo
+	if name == "background" {
+		url = manifest.Background
+	}
+	if url == "" {
+		return nil, nil
+	}
+
+	m.lock.Lock()
+	cached := m.images[name]
+	m.lock.Unlock()
+	if cached != nil && cached.url == url && time.Since(cached.fetched) < manifestImageTTL {
+		return cached, nil
+	}
+
+	img, err, _ := m.group.Do(name, func() (any, error) {
+		var img *manifestImage
+		var err error
+		if name == "favicon" {
+			img, err = m.favicon(ctx)
+		} else {
+			img, err = m.fetch(ctx, url)
+		}
+		if err != nil {
+			return nil, err
+		}
+		img.url = url
+		m.lock.Lock()
+		m.images[name] = img
+		m.lock.Unlock()
+		return img, nil
+	})
+	if err != nil {
+		if cached != nil && cached.url == url {
+			m.logger.Warn("Couldn't fetch manifest image, serving the previous one", zap.String("image", name), zap.Error(err))
+			return cached, nil
+		}
+		return nil, err
+	}
+	return img.(*manifestImage), nil
+}
+
+func (m *manifestImages) fetch(ctx context.Context, url string) (*manifestImage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create request: %w", err)
+	}
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch image: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status code fetching image: %v", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, manifestImageMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read image: %w", err)
+	}
+	if len(body) > manifestImageMaxSize {
+		return nil, fmt.Errorf("image is larger than %v bytes", manifestImageMaxSize)
+	}
+	contentType := res.Header.Get(fiber.HeaderContentType)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(body)
+	}
+	return &manifestImage{contentType: contentType, body: body, fetched: time.Now()}, nil
+}
+
+// favicon returns the logo scaled down to the favicon size. Logos in formats that can't be decoded, like SVG, are used as they are.
+func (m *manifestImages) favicon(ctx context.Context) (*manifestImage, error) {
+	logo, err := m.get(ctx, "logo")
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(logo.body))
+	if err != nil {
+		m.logger.Debug("Couldn't decode logo, using it as favicon as it is", zap.Error(err))
+		return &manifestImage{contentType: logo.contentType, body: logo.body, fetched: logo.fetched}, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleToSquare(src, faviconSize)); err != nil {
+		return nil, fmt.Errorf("couldn't encode favicon: %w", err)
+	}
+	return &manifestImage{contentType: "image/png", body: buf.Bytes(), fetched: logo.fetched}, nil
+}
+
+// scaleToSquare scales the image to fit a transparent square with the given size, keeping its aspect ratio.
+// Each pixel is the average of the source pixels it covers, which is good enough for small icons.
+func scaleToSquare(src image.Image, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return dst
+	}
+	// Size of the scaled image within the square
+	dw, dh := size, size
+	if w > h {
+		dh = max(1, size*h/w)
+	} else {
+		dw = max(1, size*w/h)
+	}
+	offX, offY := (size-dw)/2, (size-dh)/2
+	for y := range dh {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := range dw {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					// Weighted by alpha, so transparent pixels don't darken the edges
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					b += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			dst.SetNRGBA(offX+x, offY+y, color.NRGBA{
+				R: uint8(r / a >> 8),
+				G: uint8(g / a >> 8),
+				B: uint8(b / a >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+func createManifestImageHandler(images *manifestImages, name string, logger *zap.Logger) fiber.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(manifestImageMaxAge.Seconds()))
+	return func(c fiber.Ctx) error {
+		logger.Debug("manifestImageHandler called", zap.String("image", name))
+		img, err := images.get(c.Context(), name)
+		switch {
+		case err != nil:
+			logger.Error("Couldn't get manifest image", zap.String("image", name), zap.Error(err))
+			return c.SendStatus(fiber.StatusBadGateway)
+		case img == nil:
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		c.Set(fiber.HeaderContentType, img.contentType)
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		return c.Send(img.body)
+	}
+}
@@ -0,0 +1,103 @@
+package stremio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xybydy/go-stremio/types"
+	"golang.org/x/text/language"
+)
+
+// ManifestTranslation is a translation of the manifest's texts, see AddManifestTranslation.
+// Empty fields keep the original text.
+type ManifestTranslation struct {
+	Name        string
+	Description string
+	// Names of the catalogs by catalog ID, or by type and ID like "movie/top" when catalogs of different types share an ID
+	Catalogs map[string]string
+}
+
+// apply returns a clone of the manifest with the translated texts.
+func (t ManifestTranslation) apply(manifest types.Manifest) types.Manifest {
+	translated := manifest.Clone()
+	if t.Name != "" {
+		translated.Name = t.Name
+	}
+	if t.Description != "" {
+		translated.Description = t.Description
+	}
+	for i, catalog := range translated.Catalogs {
+		if name, ok := t.Catalogs[catalog.Type+"/"+catalog.ID]; ok {
+			translated.Catalogs[i].Name = name
+		} else if name, ok := t.Catalogs[catalog.ID]; ok {
+			translated.Catalogs[i].Name = name
+		}
+	}
+	return translated
+}
+
+// manifestTranslations are the translations of a manifest by language. They're set up before the addon runs.
+type manifestTranslations struct {
+	// The first tag is the undetermined language of the original manifest, the others are the ones of the translations.
+	tags         []language.Tag
+	translations []ManifestTranslation
+	matcher      language.Matcher
+}
+
+// AddManifestTranslation adds a translation of the manifest's name, description and catalog names for a language like "de" or "pt-BR",
+// so international users see localized catalog rows in Stremio's board. The manifest endpoint serves the translation that matches
+// the "Accept-Language" header of the request best, so "de-AT" gets a "de" translation, and the original manifest otherwise.
+// The "Vary" header of the manifest endpoint then includes "Accept-Language" for shared caches.
+// Translations are applied to manifest variants (see AddManifestVariant) and updated manifests (see UpdateManifest) as well.
+// Like AddEndpoint, it must be called before running the addon.
+func (a *Addon) AddManifestTranslation(lang string, translation ManifestTranslation) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("invalid language %q: %w", lang, err)
+	}
+	t := a.manifestTranslations
+	if tag == language.Und {
+		return errors.New("the language of a manifest translation can't be undetermined")
+	}
+	for _, existing := range t.tags {
+		if existing == tag {
+			return fmt.Errorf("a manifest translation for %q already exists", lang)
+		}
+	}
+	if len(t.tags) == 0 {
+		t.tags = []language.Tag{language.Und}
+	}
+	t.tags = append(t.tags, tag)
+	t.translations = append(t.translations, translation)
+	t.matcher = language.NewMatcher(t.tags)
+	return nil
+}
+
+// translatedBodies pre-marshals the translations of the manifest by language tag.
+func (t *manifestTranslations) translatedBodies(manifest types.Manifest) (map[language.Tag]*manifestBodies, error) {
+	if len(t.translations) == 0 {
+		return nil, nil
+	}
+	bodies := make(map[language.Tag]*manifestBodies, len(t.translations))
+	for i, translation := range t.translations {
+		tag := t.tags[i+1]
+		var err error
+		if bodies[tag], err = newManifestBodies(translation.apply(manifest)); err != nil {
+			return nil, fmt.Errorf("manifest translation %q: %w", tag, err)
+		}
+	}
+	return bodies, nil
+}
+
+// choose returns the language tag of the translation that matches the requested languages best,
+// or false if the original manifest should be used.
+func (t *manifestTranslations) choose(requested []language.Tag) (language.Tag, bool) {
+	if len(t.translations) == 0 || len(requested) == 0 {
+		return language.Und, false
+	}
+	_, index, confidence := t.matcher.Match(requested...)
+	if index == 0 || confidence == language.No {
+		return language.Und, false
+	}
+	return t.tags[index], true
+}
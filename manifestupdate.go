@@ -0,0 +1,67 @@
+package stremio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// manifestSnapshot is a manifest with its pre-marshalled bodies and the ones of its variants, each with their translations.
+// It's swapped as a whole by UpdateManifest, so a request never sees a mix of an old and a new manifest.
+type manifestSnapshot struct {
+	bodies   *manifestBodies
+	variants map[string]*manifestBodies
+}
+
+func newManifestSnapshot(manifest types.Manifest, variants *manifestVariants, translations *manifestTranslations) (*manifestSnapshot, error) {
+	bodies, err := newManifestBodies(manifest)
+	if err != nil {
+		return nil, err
+	}
+	variantBodies, err := variants.variantBodies(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if bodies.translations, err = translations.translatedBodies(manifest); err != nil {
+		return nil, err
+	}
+	for name, variant := range variantBodies {
+		if variant.translations, err = translations.translatedBodies(variant.manifest); err != nil {
+			return nil, fmt.Errorf("manifest variant %q: %w", name, err)
+		}
+	}
+	return &manifestSnapshot{
+		bodies:   bodies,
+		variants: variantBodies,
+	}, nil
+}
+
+// UpdateManifest changes the manifest while the addon is running, for example for adding catalogs that are discovered at runtime,
+// like new genres or providers. The update function gets a clone of the current manifest, so it can change it freely.
+// The pre-marshalled manifest and its variants (see AddManifestVariant) and translations (see AddManifestTranslation) are then swapped atomically,
+// so requests get either the old or the new manifest. The ID can't be changed, as Stremio identifies installed addons by it.
+// Only the manifest endpoint serves the new manifest, generated pages like the LandingPage keep the one from when the addon started.
+// Stremio fetches the manifest when installing an addon and from time to time afterwards, so users see the change with some delay.
+// It's safe for concurrent use.
+func (a *Addon) UpdateManifest(update func(manifest *types.Manifest)) error {
+	a.manifestLock.Lock()
+	defer a.manifestLock.Unlock()
+
+	manifest := a.manifest.Clone()
+	update(&manifest)
+	a.applyCatalogExtras(&manifest)
+	switch {
+	case manifest.ID != a.manifest.ID:
+		return errors.New("the manifest ID can't be changed")
+	case manifest.Name == "" || manifest.Description == "" || manifest.Version == "":
+		return errors.New("the updated manifest is empty")
+	}
+	snapshot, err := newManifestSnapshot(manifest, a.manifestVariants, a.manifestTranslations)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare updated manifest: %w", err)
+	}
+	a.manifest = manifest
+	a.manifestSnapshot.Store(snapshot)
+	return nil
+}
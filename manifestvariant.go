@@ -0,0 +1,149 @@
+package stremio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+	"github.com/xybydy/go-stremio/types"
+	"golang.org/x/text/language"
+)
+
+// ManifestVariantSelector selects the manifest variant for a manifest request by its name, "" meaning the original manifest.
+// The user data is the one from the URL, empty for requests without user data.
+type ManifestVariantSelector func(ctx context.Context, userData string) string
+
+// manifestBodies are a manifest and its pre-marshalled JSON, for unconfigured and configured requests.
+type manifestBodies struct {
+	manifest types.Manifest
+	body     []byte
+	// When there's user data we want Stremio to show the "Install" button, which it only does when "configurationRequired" is false.
+	configuredBody []byte
+	// Translated bodies by language, see AddManifestTranslation, nil without translations
+	translations map[language.Tag]*manifestBodies
+}
+
+func newManifestBodies(manifest types.Manifest) (*manifestBodies, error) {
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal manifest: %w", err)
+	}
+	// To not change the boolean value of the manifest object on the fly and thus mess with a single object across concurrent goroutines, we copy it.
+	// Note that this manifest copy has some values shallowly copied, but `BehaviorHints.ConfigurationRequired` is a simple type and thus a real copy.
+	configuredManifest := manifest
+	configuredManifest.BehaviorHints.ConfigurationRequired = false
+	configuredBody, err := json.Marshal(configuredManifest)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal configured manifest: %w", err)
+	}
+	return &manifestBodies{
+		manifest:       manifest,
+		body:           body,
+		configuredBody: configuredBody,
+	}, nil
+}
+
+type manifestVariant struct {
+	name       string
+	percentage int
+	modify     func(manifest *types.Manifest)
+	// Counts the manifest requests that got the variant, for comparing the variants
+	counter *metrics.Counter
+}
+
+// manifestVariants are the variants of a manifest for A/B tests. They're set up before the addon runs.
+type manifestVariants struct {
+	variants []manifestVariant
+	selector ManifestVariantSelector
+}
+
+// AddManifestVariant adds a variant of the manifest for A/B tests, for rolling out new catalogs or names to a fraction of users.
+// The variant is a clone of the manifest that's changed by the modify function.
+// Unless a selector is set with SetManifestVariantSelector, users are assigned to variants by a hash of their user data
+// (or their IP address for requests without user data), and the variant gets the given percentage of users.
+// The remaining users get the original manifest. Like AddEndpoint, it must be called before running the addon.
+// With the Metrics option, "manifest_variant_requests_total" counts the manifest requests per variant.
+func (a *Addon) AddManifestVariant(name string, percentage int, modify func(manifest *types.Manifest)) error {
+	total := percentage
+	for _, v := range a.manifestVariants.variants {
+		if v.name == name {
+			return fmt.Errorf("a manifest variant with the name %q already exists", name)
+		}
+		total += v.percentage
+	}
+	switch {
+	case name == "":
+		return errors.New("the manifest variant name can't be empty")
+	case percentage < 0 || total > 100:
+		return errors.New("the percentages of the manifest variants must be between 0 and 100 in total")
+	}
+	a.manifestVariants.variants = append(a.manifestVariants.variants, manifestVariant{
+		name:       name,
+		percentage: percentage,
+		modify:     modify,
+		counter:    metrics.GetOrCreateCounter(`manifest_variant_requests_total{variant="` + name + `"}`),
+	})
+	return nil
+}
+
+// SetManifestVariantSelector sets a custom selection of the manifest variants instead of the percentages.
+// Selected names that don't belong to a variant lead to the original manifest.
+func (a *Addon) SetManifestVariantSelector(selector ManifestVariantSelector) {
+	a.manifestVariants.selector = selector
+}
+
+// ManifestVariant returns the name of the variant that users with the user data get by the percentages of AddManifestVariant,
+// "" meaning the original manifest. Handlers can use it for comparing the engagement of the variants.
+func (a *Addon) ManifestVariant(userData string) string {
+	return a.manifestVariants.byPercentage(userData)
+}
+
+func (v *manifestVariants) byPercentage(key string) string {
+	if len(v.variants) == 0 {
+		return ""
+	}
+	bucket := int(xxhash.Sum64String(key) % 100)
+	for _, variant := range v.variants {
+		if bucket < variant.percentage {
+			return variant.name
+		}
+		bucket -= variant.percentage
+	}
+	return ""
+}
+
+// variantBodies pre-marshals the manifests of the variants by name.
+func (v *manifestVariants) variantBodies(manifest types.Manifest) (map[string]*manifestBodies, error) {
+	bodies := make(map[string]*manifestBodies, len(v.variants))
+	for _, variant := range v.variants {
+		variantManifest := manifest.Clone()
+		variant.modify(&variantManifest)
+		var err error
+		if bodies[variant.name], err = newManifestBodies(variantManifest); err != nil {
+			return nil, fmt.Errorf("manifest variant %q: %w", variant.name, err)
+		}
+	}
+	return bodies, nil
+}
+
+// choose returns the name of the variant for the request. The fallback key is used for requests without user data.
+func (v *manifestVariants) choose(ctx context.Context, userData, fallbackKey string) string {
+	var name string
+	if v.selector != nil {
+		name = v.selector(ctx, userData)
+	} else if userData != "" {
+		name = v.byPercentage(userData)
+	} else {
+		name = v.byPercentage(fallbackKey)
+	}
+	for _, variant := range v.variants {
+		if variant.name == name {
+			variant.counter.Inc()
+			return name
+		}
+	}
+	return ""
+}
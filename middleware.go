@@ -1,6 +1,8 @@
 package stremio
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"net/url"
@@ -10,12 +12,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/xybydy/go-stremio/pkg/recording"
 	"github.com/xybydy/go-stremio/types"
 
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/basicauth"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 type customMiddleware struct {
@@ -48,14 +53,19 @@ func createLoggingMiddleware(logger *zap.Logger, logIPs, logUserAgent, logMediaN
 			}
 		}
 
-		// Then log
+		// Then log, unless info logging is off, in which case building the fields would be wasted work
+		ce := logger.Check(zap.InfoLevel, "Handled request")
+		if ce == nil {
+			return nil
+		}
 
-		isStream := c.Locals("isStream") != nil
+		// Set by the meta middleware, which only runs for stream and meta requests, and optionally catalog requests.
+		isMetaRequest := c.RequestCtx().UserValue("isMetaRequest") != nil
 
 		// Get meta from context - the meta middleware put it there.
 		// We ignore ErrNoMeta here, because actual issues are logged by the meta middleware already, and here we'd have to check for things like "is config required but not set", "is the ID bad and the ID matcher was used" which are all valid cases to not have meta in the context.
 		var mediaName string
-		if logMediaName && isStream {
+		if logMediaName && isMetaRequest {
 			if meta, err := GetMetaFromContext(c.Context()); err != nil && !errors.Is(err, ErrNoMeta) {
 				logger.Error("Couldn't get meta from context", zap.Error(err))
 			} else if !errors.Is(err, ErrNoMeta) {
@@ -65,7 +75,7 @@ func createLoggingMiddleware(logger *zap.Logger, logIPs, logUserAgent, logMediaN
 
 		var zapFields []zap.Field
 		// TODO: To increase performance, don't create a new slice for every request. Use sync.Pool.
-		if logMediaName && isStream {
+		if logMediaName && isMetaRequest {
 			zapFields = make([]zap.Field, zapFieldCount+1)
 		} else {
 			zapFields = make([]zap.Field, zapFieldCount)
@@ -89,7 +99,7 @@ func createLoggingMiddleware(logger *zap.Logger, logIPs, logUserAgent, logMediaN
 				zapFields[6] = zap.String("userAgent", c.Get(fiber.HeaderUserAgent))
 			}
 		}
-		if logMediaName && isStream {
+		if logMediaName && isMetaRequest {
 			if mediaName == "" {
 				mediaName = "?"
 			}
@@ -104,14 +114,14 @@ func createLoggingMiddleware(logger *zap.Logger, logIPs, logUserAgent, logMediaN
 			}
 		}
 
-		logger.Info("Handled request", zapFields...)
+		ce.Write(zapFields...)
 		return nil
 	}
 }
 
-func createMetricsMiddleware() fiber.Handler {
+func createMetricsMiddleware(healthPath string) fiber.Handler {
 	// Total number of errors from downstream handlers in the metrics middleware
-	errCounter := metrics.NewCounter("downstream_handlers_errors_total")
+	errCounter := metrics.GetOrCreateCounter("downstream_handlers_errors_total")
 
 	manifestRegex := regexp.MustCompile("^/.*/manifest.json$")
 	catalogRegex := regexp.MustCompile(`^/.*/catalog/.*/.*\.json`)
@@ -132,7 +142,7 @@ func createMetricsMiddleware() fiber.Handler {
 			endpoint = "manifest"
 		case "/configure":
 			endpoint = "configure"
-		case "/health":
+		case healthPath:
 			endpoint = "health"
 		case "/metrics":
 			endpoint = "metrics"
@@ -148,6 +158,12 @@ func createMetricsMiddleware() fiber.Handler {
 				endpoint = "configure-other"
 			case strings.HasPrefix(path, "/debug/pprof"):
 				endpoint = "pprof"
+			case strings.HasPrefix(path, "/resolve"):
+				endpoint = "resolve"
+			case strings.HasPrefix(path, "/vtt"):
+				endpoint = "vtt"
+			case strings.HasPrefix(path, "/proxy"):
+				endpoint = "proxy"
 			}
 		}
 
@@ -178,6 +194,14 @@ func createMetricsMiddleware() fiber.Handler {
 	}
 }
 
+func createVaryMiddleware(headers []string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// Set before calling the handler, so "304 Not Modified" and error responses have it as well
+		c.Vary(headers...)
+		return c.Next()
+	}
+}
+
 func corsMiddleware() fiber.Handler {
 	config := cors.Config{
 		// Headers as listed by the Stremio example addon.
@@ -206,6 +230,8 @@ func corsMiddleware() fiber.Handler {
 	return cors.New(config)
 }
 
+// Request info for later middlewares is stored as user values of the fasthttp request context, which is what c.Locals uses as well,
+// but without allocating its variadic argument on every request. Read them with UserValue, not c.Locals, to keep it consistent.
 func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDregexString string, logger *zap.Logger) {
 	streamIDregex := regexp.MustCompile(streamIDregexString)
 	if requiresUserData {
@@ -216,11 +242,11 @@ func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDre
 			return c.SendStatus(fiber.StatusBadRequest)
 		})
 		app.Use("/:userData/catalog/:type/:id.json", func(c fiber.Ctx) error {
-			if c.Params("type", "") == "" || c.Params("id", "") == "" {
+			if c.Params("type") == "" || c.Params("id") == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isConfigured", true)
+			c.RequestCtx().SetUserValue("isConfigured", true)
 			return c.Next()
 		})
 		// Stream
@@ -228,8 +254,8 @@ func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDre
 			return c.SendStatus(fiber.StatusBadRequest)
 		})
 		app.Use("/:userData/stream/:type/:id.json", func(c fiber.Ctx) error {
-			id := c.Params("id", "")
-			if c.Params("type", "") == "" || id == "" {
+			id := c.Params("id")
+			if c.Params("type") == "" || id == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
@@ -242,32 +268,32 @@ func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDre
 				logger.Debug("Rejecting bad request due to stream ID not matching the given regex")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isConfigured", true)
-			c.Locals("isStream", true)
+			c.RequestCtx().SetUserValue("isConfigured", true)
+			c.RequestCtx().SetUserValue("isStream", true)
 			return c.Next()
 		})
 	} else {
 		// Catalog
 		app.Use("/catalog/:type/:id.json", func(c fiber.Ctx) error {
-			if c.Params("type", "") == "" || c.Params("id", "") == "" {
+			if c.Params("type") == "" || c.Params("id") == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isConfigured", true)
+			c.RequestCtx().SetUserValue("isConfigured", true)
 			return c.Next()
 		})
 		app.Use("/:userData/catalog/:type/:id.json", func(c fiber.Ctx) error {
-			if c.Params("type", "") == "" || c.Params("id", "") == "" {
+			if c.Params("type") == "" || c.Params("id") == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isConfigured", true)
+			c.RequestCtx().SetUserValue("isConfigured", true)
 			return c.Next()
 		})
 		// Stream
 		app.Use("/stream/:type/:id.json", func(c fiber.Ctx) error {
-			id := c.Params("id", "")
-			if c.Params("type", "") == "" || id == "" {
+			id := c.Params("id")
+			if c.Params("type") == "" || id == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
@@ -280,12 +306,12 @@ func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDre
 				logger.Debug("Rejecting bad request due to stream ID not matching the given regex")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isStream", true)
+			c.RequestCtx().SetUserValue("isStream", true)
 			return c.Next()
 		})
 		app.Use("/:userData/stream/:type/:id.json", func(c fiber.Ctx) error {
-			id := c.Params("id", "")
-			if c.Params("type", "") == "" || id == "" {
+			id := c.Params("id")
+			if c.Params("type") == "" || id == "" {
 				logger.Debug("Rejecting bad request due to missing type or ID")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
@@ -298,78 +324,268 @@ func addRouteMatcherMiddleware(app *fiber.App, requiresUserData bool, streamIDre
 				logger.Debug("Rejecting bad request due to stream ID not matching the given regex")
 				return c.SendStatus(fiber.StatusBadRequest)
 			}
-			c.Locals("isConfigured", true)
-			c.Locals("isStream", true)
+			c.RequestCtx().SetUserValue("isConfigured", true)
+			c.RequestCtx().SetUserValue("isStream", true)
 			return c.Next()
 		})
 	}
 }
 
-func createMetaMiddleware(metaClient MetaFetcher, putMetaInHandlerContext, logMediaName bool, logger *zap.Logger) fiber.Handler {
+// createMetaMiddleware creates a middleware that gets the meta for the type and ID in the route parameters.
+// With imdbOnly it skips requests where the ID isn't an IMDb ID, which is required for catalog requests where the ID is usually a custom catalog ID.
+func createMetaMiddleware(metaClient *sharedMetaFetcher, putMetaInHandlerContext, logMediaName, imdbOnly bool, logger *zap.Logger) fiber.Handler {
 	return func(c fiber.Ctx) error {
+		// Nothing to do, so don't even read the route parameters
+		if !putMetaInHandlerContext && !logMediaName {
+			return c.Next()
+		}
+		if imdbOnly && !strings.HasPrefix(c.Params("id"), "tt") {
+			return c.Next()
+		}
+		c.RequestCtx().SetUserValue("isMetaRequest", true)
+		// type and id can never be empty, because that's been checked by a previous middleware.
+		// We read them here, because the Fiber context must not be used in another goroutine.
+		t := c.Params("type")
+		id := c.Params("id")
 		// If we should put the meta in the context for *handlers* we get the meta synchronously.
 		// Otherwise we only need it for logging and can get the meta asynchronously.
 		if putMetaInHandlerContext {
-			putMetaInContext(c, metaClient, logger)
+			if meta, ok := metaClient.fetch(c.Context(), t, id); ok {
+				c.SetContext(WithMeta(c.Context(), meta))
+			}
 			return c.Next()
-		} else if logMediaName {
-			var wg sync.WaitGroup
-			wg.Add(1)
-			go func() {
-				putMetaInContext(c, metaClient, logger)
-				wg.Done()
-			}()
-			err := c.Next()
-			// Wait so that the meta is in the context when returning to the logging middleware
-			wg.Wait()
-			return err
 		}
-		return c.Next()
+		var meta types.MetaItem
+		var ok bool
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func(ctx context.Context) {
+			meta, ok = metaClient.fetch(ctx, t, id)
+			wg.Done()
+		}(c.Context())
+		err := c.Next()
+		// Wait so that the meta is in the context when returning to the logging middleware
+		wg.Wait()
+		if ok {
+			c.SetContext(WithMeta(c.Context(), meta))
+		}
+		return err
 	}
 }
 
-func putMetaInContext(c fiber.Ctx, metaClient MetaFetcher, logger *zap.Logger) {
+// sharedMetaFetcher wraps a MetaFetcher for the meta middlewares.
+// Concurrent fetches of the same meta, like for a burst of stream requests for a new release, share a single MetaFetcher call,
+// and the number of concurrent MetaFetcher calls can be limited.
+type sharedMetaFetcher struct {
+	client MetaFetcher
+	group  *singleflight.Group
+	// Nil if there's no limit.
+	sem chan struct{}
+	// Max time for waiting for a free slot and fetching together. Only used when there's a limit.
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func newSharedMetaFetcher(client MetaFetcher, maxConcurrentFetches int, timeout time.Duration, logger *zap.Logger) *sharedMetaFetcher {
+	f := &sharedMetaFetcher{
+		client:  client,
+		group:   &singleflight.Group{},
+		timeout: timeout,
+		logger:  logger,
+	}
+	if maxConcurrentFetches > 0 {
+		f.sem = make(chan struct{}, maxConcurrentFetches)
+	}
+	return f
+}
+
+// fetch is like fetchMeta, but deduplicated and limited.
+func (f *sharedMetaFetcher) fetch(ctx context.Context, t, id string) (types.MetaItem, bool) {
+	type result struct {
+		meta types.MetaItem
+		ok   bool
+	}
+	resChan := f.group.DoChan(t+"/"+id, func() (any, error) {
+		// Detached from the first request's context, as other requests might still wait for the result.
+		fetchCtx := context.WithoutCancel(ctx)
+		if f.sem != nil {
+			// Waiting for a slot counts towards the timeout, so requests never wait longer than it for their meta.
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, f.timeout)
+			defer cancel()
+			select {
+			case f.sem <- struct{}{}:
+				defer func() { <-f.sem }()
+			case <-fetchCtx.Done():
+				// Rather go without meta than piling up waiting requests.
+				f.logger.Warn("Too many concurrent meta fetches, skipping meta", zap.String("type", t), zap.String("id", id))
+				return result{}, nil
+			}
+		}
+		meta, ok := fetchMeta(fetchCtx, f.client, t, id, f.logger)
+		return result{meta, ok}, nil
+	})
+	select {
+	case <-ctx.Done():
+		return types.MetaItem{}, false
+	case res := <-resChan:
+		r := res.Val.(result)
+		return r.meta, r.ok
+	}
+}
+
+func fetchMeta(ctx context.Context, metaClient MetaFetcher, t, id string, logger *zap.Logger) (types.MetaItem, bool) {
 	var meta types.MetaItem
-	var err error
-	// type and id can never be empty, because that's been checked by a previous middleware
-	t := c.Params("type", "")
-	id := c.Params("id", "")
-	id, err = url.PathUnescape(id)
+	id, err := url.PathUnescape(id)
 	if err != nil {
 		logger.Error("ID in URL parameters couldn't be unescaped", zap.String("id", id))
-		return
+		return types.MetaItem{}, false
+	}
+
+	// Other types and ID schemes are only supported by TypedMetaFetchers.
+	if typedClient, ok := metaClient.(TypedMetaFetcher); ok && ((t != "movie" && t != "series") || !strings.HasPrefix(id, "tt")) {
+		meta, err = typedClient.GetMeta(ctx, t, id)
+		if err != nil {
+			logger.Error("Couldn't get meta with MetaFetcher", zap.Error(err))
+			return types.MetaItem{}, false
+		}
+		logger.Debug("Got meta from MetaFetcher", zap.String("meta", fmt.Sprintf("%+v", meta)))
+		return meta, true
 	}
 
 	switch t {
 	case "movie":
-		meta, err = metaClient.GetMovie(c.Context(), id)
+		meta, err = metaClient.GetMovie(ctx, id)
 		if err != nil {
 			logger.Error("Couldn't get movie info with MetaFetcher", zap.Error(err))
-			return
+			return types.MetaItem{}, false
 		}
 	case "series":
 		splitID := strings.Split(id, ":")
+		// Meta and catalog requests only have the TV show ID, stream requests also have season and episode.
+		if len(splitID) == 1 {
+			meta, err = metaClient.GetSeries(ctx, id, 0, 0)
+			if err != nil {
+				logger.Error("Couldn't get TV show info with MetaFetcher", zap.Error(err))
+				return types.MetaItem{}, false
+			}
+			break
+		}
 		if len(splitID) != 3 {
 			logger.Warn("No 3 elements after splitting TV show ID by \":\"", zap.String("id", id))
-			return
+			return types.MetaItem{}, false
 		}
 		season, err := strconv.Atoi(splitID[1])
 		if err != nil {
 			logger.Warn("Can't parse season as int", zap.String("season", splitID[1]))
-			return
+			return types.MetaItem{}, false
 		}
 		episode, err := strconv.Atoi(splitID[2])
 		if err != nil {
 			logger.Warn("Can't parse episode as int", zap.String("episode", splitID[2]))
-			return
+			return types.MetaItem{}, false
 		}
-		meta, err = metaClient.GetSeries(c.Context(), splitID[0], season, episode)
+		meta, err = metaClient.GetSeries(ctx, splitID[0], season, episode)
 		if err != nil {
 			logger.Error("Couldn't get TV show info with MetaFetcher", zap.Error(err))
-			return
+			return types.MetaItem{}, false
 		}
+	default:
+		return types.MetaItem{}, false
 	}
 
-	logger.Debug("Got meta from cinemata client", zap.String("meta", fmt.Sprintf("%+v", meta)))
-	c.Locals("meta", meta)
+	logger.Debug("Got meta from MetaFetcher", zap.String("meta", fmt.Sprintf("%+v", meta)))
+	return meta, true
+}
+
+func createRecordingMiddleware(recorder *recording.Recorder, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		if err := c.Next(); err != nil {
+			// Let the error handler write the response, so we record what the client gets
+			if err = c.App().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		entry := recording.Entry{
+			Time:           start,
+			Duration:       time.Since(start),
+			Method:         c.Method(),
+			Path:           c.OriginalURL(),
+			Header:         c.GetReqHeaders(),
+			Status:         c.Response().StatusCode(),
+			ResponseHeader: c.GetRespHeaders(),
+		}
+		// Reading a streamed body (like from the stream proxy) would consume it
+		if c.Response().IsBodyStream() {
+			entry.Truncated = true
+		} else {
+			entry.ResponseBody = string(c.Response().Body())
+		}
+		if err := recorder.Record(entry); err != nil {
+			logger.Warn("Couldn't record request", zap.Error(err))
+		}
+		return nil
+	}
+}
+
+// createUserDataLengthMiddleware creates a middleware that rejects requests whose user data is longer than maxLength bytes.
+func createUserDataLengthMiddleware(maxLength int, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if length := len(c.Params("userData")); length > maxLength {
+			logger.Warn("Rejecting request with oversized user data", zap.Int("length", length))
+			return c.SendStatus(fiber.StatusRequestURITooLong)
+		}
+		return c.Next()
+	}
+}
+
+// createAPIKeyMiddleware creates a middleware that rejects requests that don't contain the API key,
+// in the "X-API-Key" header, as bearer token in the "Authorization" header or in the "api_key" query parameter.
+func createAPIKeyMiddleware(apiKey string, logIPs bool, logger *zap.Logger) fiber.Handler {
+	key := []byte(apiKey)
+	return func(c fiber.Ctx) error {
+		sent := c.Get("X-API-Key")
+		if sent == "" {
+			if auth := c.Get(fiber.HeaderAuthorization); len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+				sent = auth[len("Bearer "):]
+			}
+		}
+		if sent == "" {
+			sent = c.Query("api_key")
+		}
+		// Constant-time comparison, so the key can't be guessed byte by byte via response times
+		if subtle.ConstantTimeCompare([]byte(sent), key) != 1 {
+			zapFields := []zap.Field{zap.String("method", c.Method()), zap.String("path", c.Path()), zap.Bool("keySent", sent != "")}
+			if logIPs {
+				zapFields = append(zapFields, zap.String("ip", c.IP()))
+			}
+			logger.Warn("Rejecting request without valid API key", zapFields...)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.Next()
+	}
+}
+
+// createBasicAuthMiddleware creates a middleware that rejects requests without the credentials.
+func createBasicAuthMiddleware(credentials BasicAuth, realm string, logIPs bool, logger *zap.Logger) fiber.Handler {
+	return basicauth.New(basicauth.Config{
+		// Constant-time comparison of both values, so they can't be guessed byte by byte via response times
+		Authorizer: func(username, password string) bool {
+			usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(credentials.Username)) == 1
+			passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(credentials.Password)) == 1
+			return usernameOK && passwordOK
+		},
+		Unauthorized: func(c fiber.Ctx) error {
+			zapFields := []zap.Field{zap.String("method", c.Method()), zap.String("path", c.Path())}
+			if logIPs {
+				zapFields = append(zapFields, zap.String("ip", c.IP()))
+			}
+			logger.Warn("Rejecting request without valid basic auth credentials", zapFields...)
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="`+realm+`", charset="UTF-8"`)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		},
+		Realm: realm,
+	})
 }
@@ -0,0 +1,266 @@
+package stremio
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// OpenAPI returns an OpenAPI 3 document in JSON format that describes the addon's endpoints.
+// It's derived from the manifest and the registered handlers and endpoints, so call it after registering everything.
+// With the OpenAPI option the addon serves the same document at "/openapi.json".
+func (a *Addon) OpenAPI() ([]byte, error) {
+	schemas := map[string]any{}
+	ref := func(v any) map[string]any {
+		return openAPISchema(reflect.TypeOf(v), schemas)
+	}
+	manifestSchema := ref(types.Manifest{})
+	metaPreviewSchema := ref(types.MetaPreviewItem{})
+	metaSchema := ref(types.MetaItem{})
+	streamSchema := ref(types.StreamItem{})
+	subtitleSchema := ref(types.SubtitleItem{})
+
+	paths := map[string]any{}
+	// Same as in App(): The routes without user data aren't registered when user data is required.
+	// The routes with user data are only documented when the addon can be configured, to keep the document concise.
+	configRequired := a.manifest.BehaviorHints.ConfigurationRequired
+	withUserData := a.manifest.BehaviorHints.Configurable || a.unmarshalUserData != nil
+	addPath := func(path, summary string, params []map[string]any, response map[string]any, alwaysWithoutUserData bool) {
+		if !configRequired || alwaysWithoutUserData {
+			paths[path] = map[string]any{"get": openAPIOperation(summary, params, response)}
+		}
+		if withUserData {
+			params = append([]map[string]any{openAPIPathParam("userData", "User data of the configured addon.", nil, "")}, params...)
+			paths["/{userData}"+path] = map[string]any{"get": openAPIOperation(summary+" (configured)", params, response)}
+		}
+	}
+
+	addPath("/manifest.json", "Get the addon manifest", nil, manifestSchema, true)
+	if a.catalogHandlers != nil {
+		var catalogIDs []string
+		var extras []string
+		for _, catalog := range a.manifest.Catalogs {
+			if !slices.Contains(catalogIDs, catalog.ID) {
+				catalogIDs = append(catalogIDs, catalog.ID)
+			}
+			for _, extra := range catalog.Extra {
+				if !slices.Contains(extras, extra.Name) {
+					extras = append(extras, extra.Name)
+				}
+			}
+		}
+		params := []map[string]any{
+			openAPIPathParam("type", "", a.catalogHandlers.types(), ""),
+			openAPIPathParam("id", "Catalog ID.", catalogIDs, ""),
+		}
+		response := openAPIObject("metas", openAPIArray(metaPreviewSchema))
+		addPath("/catalog/{type}/{id}.json", "Get the items of a catalog", params, response, false)
+		if len(extras) > 0 {
+			extraParam := openAPIPathParam("extra", "Extra arguments like \"skip=100\", joined with \"&\". Supported: "+strings.Join(extras, ", ")+".", nil, "")
+			addPath("/catalog/{type}/{id}/{extra}.json", "Get the items of a catalog with extra arguments", append(params, extraParam), response, false)
+		}
+		if a.opts.CatalogFeeds {
+			addPath("/feed/{type}/{id}.rss", "Get the items of a catalog as RSS feed", params, nil, false)
+			addPath("/feed/{type}/{id}.json", "Get the items of a catalog as JSON Feed", params, nil, false)
+		}
+	}
+	idDescription := "Item ID."
+	if len(a.manifest.IDprefixes) > 0 {
+		idDescription = "Item ID, with one of the prefixes " + strings.Join(a.manifest.IDprefixes, ", ") + ". TV show episodes have the season and episode appended, like \"tt0944947:1:1\"."
+	}
+	if a.streamHandlers != nil {
+		params := []map[string]any{
+			openAPIPathParam("type", "", a.streamHandlers.types(), ""),
+			openAPIPathParam("id", idDescription, nil, a.opts.StreamIDregex),
+		}
+		addPath("/stream/{type}/{id}.json", "Get the streams of an item", params, openAPIObject("streams", openAPIArray(streamSchema)), false)
+	}
+	if a.metaHandlers != nil {
+		params := []map[string]any{
+			openAPIPathParam("type", "", a.metaHandlers.types(), ""),
+			openAPIPathParam("id", idDescription, nil, ""),
+		}
+		addPath("/meta/{type}/{id}.json", "Get the meta object of an item", params, openAPIObject("meta", metaSchema), false)
+	}
+	if a.subtitleHandlers != nil {
+		params := []map[string]any{
+			openAPIPathParam("type", "", a.subtitleHandlers.types(), ""),
+			openAPIPathParam("id", idDescription, nil, ""),
+		}
+		addPath("/subtitles/{type}/{id}.json", "Get the subtitles of an item", params, openAPIObject("subtitles", openAPIArray(subtitleSchema)), false)
+	}
+
+	if !a.opts.DisableHealthEndpoint {
+		paths[a.opts.HealthPath] = map[string]any{"get": openAPIOperation("Check the addon's health", nil, nil)}
+	}
+	if a.opts.VersionEndpoint {
+		paths["/version"] = map[string]any{"get": openAPIOperation("Get the version of the addon", nil, nil)}
+	}
+	if a.opts.ConfigureHTMLfs != nil {
+		paths["/configure"] = map[string]any{"get": openAPIOperation("Show the configure page", nil, nil)}
+	}
+	for _, endpoint := range a.customEndpoints {
+		path, params := openAPIPath(endpoint.path)
+		operations, _ := paths[path].(map[string]any)
+		if operations == nil {
+			operations = map[string]any{}
+			paths[path] = operations
+		}
+		for _, method := range endpoint.methods {
+			operations[strings.ToLower(method)] = openAPIOperation("Custom endpoint", params, nil)
+		}
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       a.manifest.Name,
+			"description": a.manifest.Description,
+			"version":     a.manifest.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+	return json.Marshal(doc)
+}
+
+func createOpenAPIHandler(doc []byte, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("openAPIHandler called")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(doc)
+	}
+}
+
+func openAPIOperation(summary string, params []map[string]any, response map[string]any) map[string]any {
+	ok := map[string]any{"description": "OK"}
+	if response != nil {
+		ok["content"] = map[string]any{
+			fiber.MIMEApplicationJSON: map[string]any{"schema": response},
+		}
+	}
+	operation := map[string]any{
+		"summary": summary,
+		"responses": map[string]any{
+			"200": ok,
+			"404": map[string]any{"description": http.StatusText(http.StatusNotFound)},
+		},
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	return operation
+}
+
+func openAPIPathParam(name, description string, enum []string, pattern string) map[string]any {
+	schema := map[string]any{"type": "string"}
+	if len(enum) > 0 {
+		schema["enum"] = enum
+	}
+	if pattern != "" {
+		schema["pattern"] = pattern
+	}
+	param := map[string]any{
+		"name":     name,
+		"in":       "path",
+		"required": true,
+		"schema":   schema,
+	}
+	if description != "" {
+		param["description"] = description
+	}
+	return param
+}
+
+var fiberParamRegex = regexp.MustCompile(`[:*+]([A-Za-z0-9_]*)\??`)
+
+// openAPIPath converts a Fiber route path like "/:userData/foo" to an OpenAPI path like "/{userData}/foo" and its parameters.
+func openAPIPath(route string) (string, []map[string]any) {
+	var params []map[string]any
+	path := fiberParamRegex.ReplaceAllStringFunc(route, func(match string) string {
+		name := fiberParamRegex.FindStringSubmatch(match)[1]
+		if name == "" {
+			// Wildcards like "*" and "+"
+			name = "wildcard" + strconv.Itoa(len(params)+1)
+		}
+		params = append(params, openAPIPathParam(name, "", nil, ""))
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+func openAPIObject(property string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{property: schema},
+		"required":   []string{property},
+	}
+}
+
+func openAPIArray(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// openAPISchema returns the schema of a type, based on its JSON encoding.
+// Structs are added to the schemas and referenced.
+func openAPISchema(t reflect.Type, schemas map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return openAPISchema(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return openAPIArray(openAPISchema(t.Elem(), schemas))
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Struct:
+	default:
+		return map[string]any{}
+	}
+
+	ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	if _, ok := schemas[t.Name()]; ok {
+		return ref
+	}
+	properties := map[string]any{}
+	schema := map[string]any{"type": "object", "properties": properties}
+	// Set before the fields, for recursive types
+	schemas[t.Name()] = schema
+	var required []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = openAPISchema(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return ref
+}
@@ -0,0 +1,362 @@
+// Package addoncheck checks the endpoints of a running Stremio addon like a linter,
+// for example the manifest's schema, CORS headers, whether the resource routes respond and whether the responses have the expected shape.
+// It works with any addon, not just go-stremio ones. For go-stremio addons, Addon.SelfCheck runs the checks in-process.
+package addoncheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Status is the result of a single check.
+type Status string
+
+const (
+	// Pass signals that the check passed.
+	Pass Status = "pass"
+	// Warn signals a problem that doesn't break the addon, like a 404 for a sample ID.
+	Warn Status = "warn"
+	// Fail signals a problem that breaks the addon in Stremio.
+	Fail Status = "fail"
+	// Skip signals that the check couldn't run, for example because there's no sample ID for a type.
+	Skip Status = "skip"
+)
+
+// Result is the result of a single check.
+type Result struct {
+	Name    string `json:"name"`
+	Path    string `json:"path,omitempty"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of all checks.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// OK returns true if no check failed.
+func (r Report) OK() bool {
+	return !slices.ContainsFunc(r.Results, func(res Result) bool {
+		return res.Status == Fail
+	})
+}
+
+// String returns a human-readable report with one line per check.
+func (r Report) String() string {
+	var sb strings.Builder
+	for _, res := range r.Results {
+		fmt.Fprintf(&sb, "%-4v  %v", strings.ToUpper(string(res.Status)), res.Name)
+		if res.Path != "" {
+			fmt.Fprintf(&sb, " (%v)", res.Path)
+		}
+		if res.Message != "" {
+			sb.WriteString(": " + res.Message)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Options are the options for the checks.
+type Options struct {
+	// Sample IDs per type for stream, meta and subtitle requests, like "tt1254207" for "movie".
+	// Meta requests prefer IDs from catalog responses.
+	// Default DefaultOptions.SampleIDs.
+	SampleIDs map[string]string
+	// User data for addons that require configuration, as it's used in the URL.
+	// Default empty.
+	UserData string
+	// HTTP client for the requests.
+	// Default a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	SampleIDs: map[string]string{
+		"movie":  "tt1254207",     // Big Buck Bunny
+		"series": "tt0944947:1:1", // Game of Thrones S01E01
+	},
+}
+
+// Check runs all checks against the addon at the base URL, which is the addon URL without "/manifest.json", like "https://example.com".
+// It doesn't stop at the first failure, so the report contains all problems.
+func Check(ctx context.Context, baseURL string, opts Options) Report {
+	if opts.SampleIDs == nil {
+		opts.SampleIDs = DefaultOptions.SampleIDs
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	c := &checker{
+		ctx:        ctx,
+		httpClient: opts.HTTPClient,
+		baseURL:    strings.TrimSuffix(strings.TrimSuffix(baseURL, "/manifest.json"), "/"),
+		prefix:     "",
+	}
+	if opts.UserData != "" {
+		c.prefix = "/" + opts.UserData
+	}
+
+	manifest, ok := c.checkManifest()
+	if !ok {
+		return c.report
+	}
+
+	// Catalogs first, because their items are good sample IDs for meta requests.
+	catalogIDs := map[string]string{}
+	for _, catalog := range manifest.Catalogs {
+		if id := c.checkCatalog(catalog); id != "" && catalogIDs[catalog.Type] == "" {
+			catalogIDs[catalog.Type] = id
+		}
+	}
+
+	for _, resource := range manifest.ResourceItems {
+		if resource.Name == "catalog" {
+			continue
+		}
+		resourceTypes := resource.Types
+		if len(resourceTypes) == 0 {
+			resourceTypes = manifest.Types
+		}
+		idPrefixes := resource.IDprefixes
+		if len(idPrefixes) == 0 {
+			idPrefixes = manifest.IDprefixes
+		}
+		for _, t := range resourceTypes {
+			id := opts.SampleIDs[t]
+			if resource.Name == "meta" {
+				if catalogID := catalogIDs[t]; catalogID != "" {
+					id = catalogID
+				} else if strings.HasPrefix(id, "tt") {
+					// Meta requests are for the TV show, not the episode.
+					id, _, _ = strings.Cut(id, ":")
+				}
+			}
+			name := resource.Name + " " + t
+			switch {
+			case id == "":
+				c.add(Result{Name: name, Status: Skip, Message: "no sample ID for type"})
+			case len(idPrefixes) > 0 && !slices.ContainsFunc(idPrefixes, func(p string) bool { return strings.HasPrefix(id, p) }):
+				c.add(Result{Name: name, Status: Skip, Message: fmt.Sprintf("sample ID %v doesn't match the ID prefixes", id)})
+			default:
+				c.checkResource(resource.Name, t, id)
+			}
+		}
+	}
+
+	return c.report
+}
+
+type checker struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+	// User data path segment, like "/eyJ0b2tlbiI6ImFiYyJ9", or empty.
+	prefix string
+	report Report
+}
+
+func (c *checker) add(res Result) {
+	c.report.Results = append(c.report.Results, res)
+}
+
+// get requests the path and returns the response with the body read.
+func (c *checker) get(path string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Stremio Web sends an Origin header, so the CORS headers are checked for such requests.
+	req.Header.Set("Origin", "https://web.stremio.com")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 10<<20))
+	return res, body, err
+}
+
+// manifestJSON accepts the resources in both forms, as objects and as plain names like "stream".
+type manifestJSON struct {
+	types.Manifest
+	Resources []json.RawMessage `json:"resources"`
+}
+
+func (c *checker) checkManifest() (types.Manifest, bool) {
+	path := c.prefix + "/manifest.json"
+	res, body, err := c.get(path)
+	if err != nil {
+		c.add(Result{Name: "manifest", Path: path, Status: Fail, Message: err.Error()})
+		return types.Manifest{}, false
+	}
+	if res.StatusCode != http.StatusOK {
+		c.add(Result{Name: "manifest", Path: path, Status: Fail, Message: fmt.Sprintf("status %v", res.StatusCode)})
+		return types.Manifest{}, false
+	}
+	c.checkHeaders("manifest", path, res)
+
+	var m manifestJSON
+	if err = json.Unmarshal(body, &m); err != nil {
+		c.add(Result{Name: "manifest", Path: path, Status: Fail, Message: "invalid JSON: " + err.Error()})
+		return types.Manifest{}, false
+	}
+	manifest := m.Manifest
+	manifest.ResourceItems = nil
+	for _, raw := range m.Resources {
+		var name string
+		if err = json.Unmarshal(raw, &name); err == nil {
+			manifest.ResourceItems = append(manifest.ResourceItems, types.ResourceItem{Name: name})
+			continue
+		}
+		var resource types.ResourceItem
+		if err = json.Unmarshal(raw, &resource); err != nil {
+			c.add(Result{Name: "manifest", Path: path, Status: Fail, Message: "invalid resource: " + string(raw)})
+			return types.Manifest{}, false
+		}
+		manifest.ResourceItems = append(manifest.ResourceItems, resource)
+	}
+	if err = manifest.Validate(); err != nil {
+		c.add(Result{Name: "manifest", Path: path, Status: Fail, Message: err.Error()})
+		return manifest, true
+	}
+	c.add(Result{Name: "manifest", Path: path, Status: Pass})
+	return manifest, true
+}
+
+// checkHeaders checks the CORS and content type headers, which Stremio requires.
+func (c *checker) checkHeaders(name, path string, res *http.Response) {
+	if origin := res.Header.Get("Access-Control-Allow-Origin"); origin != "*" && origin != "https://web.stremio.com" {
+		c.add(Result{Name: name + " CORS", Path: path, Status: Fail, Message: "missing Access-Control-Allow-Origin header, Stremio Web can't use the addon"})
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		c.add(Result{Name: name + " content type", Path: path, Status: Warn, Message: fmt.Sprintf("content type %q instead of application/json", ct)})
+	}
+}
+
+// checkCatalog checks the catalog and returns the ID of its first item, if there is one.
+func (c *checker) checkCatalog(catalog types.CatalogItem) string {
+	name := "catalog " + catalog.Type + "/" + catalog.ID
+	path := c.prefix + "/catalog/" + url.PathEscape(catalog.Type) + "/" + url.PathEscape(catalog.ID) + ".json"
+	for _, extra := range catalog.Extra {
+		if extra.IsRequired {
+			c.add(Result{Name: name, Path: path, Status: Skip, Message: fmt.Sprintf("requires the extra argument %q", extra.Name)})
+			return ""
+		}
+	}
+
+	var body struct {
+		Metas []types.MetaPreviewItem `json:"metas"`
+	}
+	if !c.getJSON(name, path, "metas", &body) {
+		return ""
+	}
+	if len(body.Metas) == 0 {
+		c.add(Result{Name: name, Path: path, Status: Warn, Message: "empty catalog"})
+		return ""
+	}
+	for i, meta := range body.Metas {
+		if meta.ID == "" || meta.Type == "" || meta.Name == "" {
+			c.add(Result{Name: name, Path: path, Status: Fail, Message: fmt.Sprintf("item %v lacks an ID, type or name", i)})
+			return ""
+		}
+	}
+	c.add(Result{Name: name, Path: path, Status: Pass, Message: fmt.Sprintf("%v items", len(body.Metas))})
+	return body.Metas[0].ID
+}
+
+func (c *checker) checkResource(resource, t, id string) {
+	name := resource + " " + t
+	path := c.prefix + "/" + resource + "/" + url.PathEscape(t) + "/" + url.PathEscape(id) + ".json"
+	var problem string
+	switch resource {
+	case "stream":
+		var body struct {
+			Streams []types.StreamItem `json:"streams"`
+		}
+		if !c.getJSON(name, path, "streams", &body) {
+			return
+		}
+		for i, s := range body.Streams {
+			if s.URL == "" && s.YoutubeID == "" && s.InfoHash == "" && s.ExternalURL == "" {
+				problem = fmt.Sprintf("stream %v has neither url, ytId, infoHash nor externalUrl", i)
+				break
+			}
+		}
+	case "meta":
+		var body struct {
+			Meta *types.MetaItem `json:"meta"`
+		}
+		if !c.getJSON(name, path, "meta", &body) {
+			return
+		}
+		if body.Meta == nil || body.Meta.ID == "" || body.Meta.Type == "" || body.Meta.Name == "" {
+			problem = "meta lacks an ID, type or name"
+		}
+	case "subtitles":
+		var body struct {
+			Subtitles []types.SubtitleItem `json:"subtitles"`
+		}
+		if !c.getJSON(name, path, "subtitles", &body) {
+			return
+		}
+		for i, s := range body.Subtitles {
+			if s.URL == "" || s.Lang == "" {
+				problem = fmt.Sprintf("subtitle %v lacks a URL or language", i)
+				break
+			}
+		}
+	default:
+		c.add(Result{Name: name, Path: path, Status: Skip, Message: "unknown resource"})
+		return
+	}
+	if problem != "" {
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: problem})
+		return
+	}
+	c.add(Result{Name: name, Path: path, Status: Pass})
+}
+
+// getJSON requests the path, checks the status and headers, and unmarshals the body, which must contain the key.
+// It adds a result and returns false for problems.
+func (c *checker) getJSON(name, path, key string, v any) bool {
+	res, body, err := c.get(path)
+	if err != nil {
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: err.Error()})
+		return false
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		c.add(Result{Name: name, Path: path, Status: Warn, Message: "status 404, the route might be missing or there's no content for the sample ID"})
+		return false
+	case res.StatusCode != http.StatusOK:
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: fmt.Sprintf("status %v", res.StatusCode)})
+		return false
+	}
+	c.checkHeaders(name, path, res)
+	var keys map[string]json.RawMessage
+	if err = json.Unmarshal(body, &keys); err != nil {
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: "invalid JSON: " + err.Error()})
+		return false
+	}
+	if _, ok := keys[key]; !ok {
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: fmt.Sprintf("response lacks %q", key)})
+		return false
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		c.add(Result{Name: name, Path: path, Status: Fail, Message: "unexpected JSON: " + err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,306 @@
+// Package aggregator fans catalog and stream requests out to a list of upstream addons and merges their results,
+// for building "meta addons" that combine other addons.
+// Its handlers can be passed to stremio.NewAddon directly, and its catalogs can be put into the aggregating addon's manifest.
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/client"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// Upstream is an addon whose results are aggregated.
+type Upstream struct {
+	// Name of the upstream, for logs and as stream name prefix.
+	Name string
+	// URL of the addon, like "https://example.com/manifest.json". See client.New for the accepted forms.
+	URL string
+	// Timeout for requests to this upstream. Slow upstreams are left out of the result instead of delaying it.
+	// Default Options.Timeout.
+	Timeout time.Duration
+}
+
+// Options are the options for the aggregator.
+type Options struct {
+	// Default timeout for requests to an upstream.
+	// Default 5 seconds.
+	Timeout time.Duration
+	// How long the upstreams' manifests are cached, which the aggregator uses to only send requests to upstreams that support them.
+	// Default 1 hour.
+	ManifestTTL time.Duration
+	// Flag for indicating whether to prefix the names of streams with the name of their upstream, like "Upstream A\n1080p".
+	// Default false.
+	PrefixStreamNames bool
+	// Options for the clients of the upstreams. Their Timeout is overwritten by the upstream's timeout.
+	// Default empty.
+	ClientOptions client.Options
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Timeout:     5 * time.Second,
+	ManifestTTL: time.Hour,
+}
+
+// Aggregator fans requests out to upstream addons. It's safe for concurrent use.
+type Aggregator struct {
+	upstreams         []*upstream
+	manifestTTL       time.Duration
+	prefixStreamNames bool
+	logger            *zap.Logger
+}
+
+type upstream struct {
+	name    string
+	client  *client.Client
+	timeout time.Duration
+
+	manifest        types.Manifest
+	manifestFetched time.Time
+	lock            *sync.Mutex
+}
+
+// New creates an aggregator for the upstreams, in the order of their priority.
+// Duplicate results are removed, keeping the one of the upstream that comes first.
+func New(upstreams []Upstream, opts Options, logger *zap.Logger) (*Aggregator, error) {
+	if len(upstreams) == 0 {
+		return nil, errors.New("at least one upstream is required")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.ManifestTTL == 0 {
+		opts.ManifestTTL = DefaultOptions.ManifestTTL
+	}
+
+	a := &Aggregator{
+		manifestTTL:       opts.ManifestTTL,
+		prefixStreamNames: opts.PrefixStreamNames,
+		logger:            logger,
+	}
+	for i, u := range upstreams {
+		if u.Name == "" {
+			u.Name = "upstream " + strconv.Itoa(i+1)
+		}
+		if u.Timeout == 0 {
+			u.Timeout = opts.Timeout
+		}
+		clientOpts := opts.ClientOptions
+		clientOpts.Timeout = u.Timeout
+		clientOpts.AttemptTimeout = 0
+		c, err := client.New(u.URL, clientOpts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create client for %v: %w", u.Name, err)
+		}
+		a.upstreams = append(a.upstreams, &upstream{
+			name:    u.Name,
+			client:  c,
+			timeout: u.Timeout,
+			lock:    &sync.Mutex{},
+		})
+	}
+	return a, nil
+}
+
+// getManifest returns the cached manifest of the upstream, or fetches it when it's expired.
+// When fetching fails, the previous manifest is used if there is one.
+func (u *upstream) getManifest(ctx context.Context, ttl time.Duration) (types.Manifest, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if !u.manifestFetched.IsZero() && time.Since(u.manifestFetched) < ttl {
+		return u.manifest, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	manifest, err := u.client.Manifest(ctx)
+	if err != nil {
+		if !u.manifestFetched.IsZero() {
+			return u.manifest, nil
+		}
+		return types.Manifest{}, err
+	}
+	u.manifest = manifest
+	u.manifestFetched = time.Now()
+	return manifest, nil
+}
+
+// Catalogs returns the catalogs of all upstreams, without duplicates, for the aggregating addon's manifest.
+// Upstreams whose manifest can't be fetched are left out.
+func (a *Aggregator) Catalogs(ctx context.Context) []types.CatalogItem {
+	var catalogs []types.CatalogItem
+	for _, u := range a.upstreams {
+		manifest, err := u.getManifest(ctx, a.manifestTTL)
+		if err != nil {
+			a.logger.Warn("Couldn't get upstream manifest", zap.Error(err), zap.String("upstream", u.name))
+			continue
+		}
+		for _, catalog := range manifest.Catalogs {
+			if !slices.ContainsFunc(catalogs, func(c types.CatalogItem) bool { return c.Type == catalog.Type && c.ID == catalog.ID }) {
+				catalogs = append(catalogs, catalog)
+			}
+		}
+	}
+	return catalogs
+}
+
+// fanOut calls fn for each upstream that supports the request concurrently and returns the results in the order of the upstreams.
+// The error is only non-nil if all upstreams that support the request failed. Not found responses don't count as failures.
+func fanOut[T any](ctx context.Context, a *Aggregator, resource, metaType, id string, fn func(ctx context.Context, c *client.Client) ([]T, error)) ([][]T, error) {
+	results := make([][]T, len(a.upstreams))
+	errs := make([]error, len(a.upstreams))
+	requested := make([]bool, len(a.upstreams))
+	var wg sync.WaitGroup
+	for i, u := range a.upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			manifest, err := u.getManifest(ctx, a.manifestTTL)
+			if err != nil {
+				errs[i] = fmt.Errorf("couldn't get manifest of %v: %w", u.name, err)
+				return
+			}
+			if !client.Supports(manifest, resource, metaType, id) {
+				return
+			}
+			requested[i] = true
+			ctx, cancel := context.WithTimeout(ctx, u.timeout)
+			defer cancel()
+			start := time.Now()
+			results[i], err = fn(ctx, u.client)
+			if err != nil && !errors.Is(err, client.ErrNotFound) {
+				errs[i] = fmt.Errorf("%v: %w", u.name, err)
+				a.logger.Warn("Upstream request failed", zap.Error(err), zap.String("upstream", u.name), zap.String("resource", resource), zap.String("id", id), zap.Duration("duration", time.Since(start)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed, total := 0, 0
+	for i := range a.upstreams {
+		if errs[i] != nil {
+			failed++
+		}
+		if errs[i] != nil || requested[i] {
+			total++
+		}
+	}
+	if failed > 0 && failed == total {
+		return nil, errors.Join(errs...)
+	}
+	return results, nil
+}
+
+// Streams requests the streams from all upstreams that support the type and ID concurrently,
+// and merges them in the order of the upstreams. Streams with the same source (like the URL or info hash and file index) are only included once.
+// The error is only non-nil when all upstreams failed.
+func (a *Aggregator) Streams(ctx context.Context, metaType, id string) ([]types.StreamItem, error) {
+	results, err := fanOut(ctx, a, "stream", metaType, id, func(ctx context.Context, c *client.Client) ([]types.StreamItem, error) {
+		return c.Streams(ctx, metaType, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var streams []types.StreamItem
+	for i, upstreamStreams := range results {
+		for _, stream := range upstreamStreams {
+			key := streamKey(stream)
+			if key != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			if a.prefixStreamNames {
+				if stream.Name == "" {
+					stream.Name = a.upstreams[i].name
+				} else {
+					stream.Name = a.upstreams[i].name + "\n" + stream.Name
+				}
+			}
+			streams = append(streams, stream)
+		}
+	}
+	return streams, nil
+}
+
+func streamKey(stream types.StreamItem) string {
+	switch {
+	case stream.URL != "":
+		return "url:" + stream.URL
+	case stream.InfoHash != "":
+		return "infoHash:" + stream.InfoHash + ":" + strconv.Itoa(int(stream.FileIndex))
+	case stream.YoutubeID != "":
+		return "yt:" + stream.YoutubeID
+	case stream.ExternalURL != "":
+		return "external:" + stream.ExternalURL
+	}
+	return ""
+}
+
+// Catalog requests the catalog from all upstreams that have a catalog with the type and ID concurrently,
+// and merges the items in the order of the upstreams. Items with the same ID are only included once.
+// The error is only non-nil when all upstreams failed.
+func (a *Aggregator) Catalog(ctx context.Context, metaType, id string, extra url.Values) ([]types.MetaPreviewItem, error) {
+	results, err := fanOut(ctx, a, "catalog", metaType, id, func(ctx context.Context, c *client.Client) ([]types.MetaPreviewItem, error) {
+		return c.Catalog(ctx, metaType, id, extra)
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var metas []types.MetaPreviewItem
+	for _, upstreamMetas := range results {
+		for _, meta := range upstreamMetas {
+			if seen[meta.ID] {
+				continue
+			}
+			seen[meta.ID] = true
+			metas = append(metas, meta)
+		}
+	}
+	return metas, nil
+}
+
+// StreamHandlers returns stream handlers for the types that respond with the aggregated streams, for stremio.NewAddon.
+// They respond with "404 Not Found" when there are no streams.
+func (a *Aggregator) StreamHandlers(metaTypes ...string) map[string]stremio.StreamHandler {
+	handlers := make(map[string]stremio.StreamHandler, len(metaTypes))
+	for _, metaType := range metaTypes {
+		handlers[metaType] = func(ctx context.Context, id string, _ any) ([]types.StreamItem, error) {
+			streams, err := a.Streams(ctx, metaType, id)
+			if err != nil {
+				return nil, err
+			} else if len(streams) == 0 {
+				return nil, stremio.ErrNotFound
+			}
+			return streams, nil
+		}
+	}
+	return handlers
+}
+
+// CatalogHandlers returns catalog handlers for the types that respond with the aggregated catalog items, for stremio.NewAddon.
+func (a *Aggregator) CatalogHandlers(metaTypes ...string) map[string]stremio.CatalogHandler {
+	handlers := make(map[string]stremio.CatalogHandler, len(metaTypes))
+	for _, metaType := range metaTypes {
+		handlers[metaType] = func(ctx context.Context, id string, extra url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			metas, err := a.Catalog(ctx, metaType, id, extra)
+			if err != nil {
+				return nil, err
+			} else if metas == nil {
+				return []types.MetaPreviewItem{}, nil
+			}
+			return metas, nil
+		}
+	}
+	return handlers
+}
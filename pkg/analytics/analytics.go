@@ -0,0 +1,252 @@
+// Package analytics aggregates usage statistics of an addon, like request counts per resource, type and ID and per user,
+// and keeps them in a store.Store. go-stremio collects them with the Analytics option.
+// Users are only identified by a hash of their user data, so the statistics don't contain their configuration.
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/store"
+	"go.uber.org/zap"
+)
+
+// Other is the key that requests and users are counted under once a day reached the MaxKeys limit.
+const Other = "other"
+
+// dateLayout is the layout of Day.Date. Days are in UTC.
+const dateLayout = time.DateOnly
+
+// keyPrefix namespaces the keys, so stores can be shared with other data.
+const keyPrefix = "analytics:"
+
+// Options are the options for a Collector.
+type Options struct {
+	// How often the aggregated counts are written to the store. Counts that weren't flushed yet are lost when the addon crashes.
+	// Default 1 minute.
+	FlushInterval time.Duration
+	// How long the statistics of a day are kept in the store.
+	// Default 30 days.
+	Retention time.Duration
+	// Max number of distinct request keys and users per day, so days with many distinct IDs don't grow without bound.
+	// Once reached, further requests and users are counted under Other.
+	// Default 10,000.
+	MaxKeys int
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	FlushInterval: time.Minute,
+	Retention:     30 * 24 * time.Hour,
+	MaxKeys:       10_000,
+}
+
+// Day are the statistics of a day.
+type Day struct {
+	// Date in the format "2006-01-02", in UTC.
+	Date string `json:"date"`
+	// Request counts by "resource/type/id", like "stream/movie/tt1254207".
+	Requests map[string]int64 `json:"requests"`
+	// Request counts by user hash. Requests without user data aren't counted.
+	Users map[string]int64 `json:"users"`
+}
+
+func newDay(date string) *Day {
+	return &Day{
+		Date:     date,
+		Requests: map[string]int64{},
+		Users:    map[string]int64{},
+	}
+}
+
+// add adds n to the count of the key, or to the count of Other when the map reached maxKeys.
+// Other itself doesn't count towards the limit.
+func add(counts map[string]int64, key string, n int64, maxKeys int) {
+	keys := len(counts)
+	if _, ok := counts[Other]; ok {
+		keys--
+	}
+	if _, ok := counts[key]; !ok && key != Other && keys >= maxKeys {
+		key = Other
+	}
+	counts[key] += n
+}
+
+// merge adds the counts of the other day.
+func (d *Day) merge(other *Day, maxKeys int) {
+	for key, n := range other.Requests {
+		add(d.Requests, key, n, maxKeys)
+	}
+	for user, n := range other.Users {
+		add(d.Users, user, n, maxKeys)
+	}
+}
+
+// Collector aggregates requests in memory and periodically adds them to the statistics in the store.
+// When multiple addon instances share a store, concurrent flushes of the same day can lose counts, so the statistics are estimates.
+// It's safe for concurrent use.
+type Collector struct {
+	store   store.Store
+	opts    Options
+	logger  *zap.Logger
+	pending map[string]*Day
+	lock    *sync.Mutex
+	// Serializes flushes, so the periodic flush and Close don't interfere
+	flushLock *sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewCollector creates a new Collector and starts flushing periodically. Close stops it.
+func NewCollector(s store.Store, opts Options, logger *zap.Logger) *Collector {
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultOptions.FlushInterval
+	}
+	if opts.Retention == 0 {
+		opts.Retention = DefaultOptions.Retention
+	}
+	if opts.MaxKeys == 0 {
+		opts.MaxKeys = DefaultOptions.MaxKeys
+	}
+	c := &Collector{
+		store:     s,
+		opts:      opts,
+		logger:    logger,
+		pending:   map[string]*Day{},
+		lock:      &sync.Mutex{},
+		flushLock: &sync.Mutex{},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go c.flushPeriodically()
+	return c
+}
+
+// HashUser returns the hash that users are identified by, the hex-encoded SHA-256 hash of their user data.
+func HashUser(userData string) string {
+	sum := sha256.Sum256([]byte(userData))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record counts a request. The user data can be empty for requests without it.
+func (c *Collector) Record(resource, mediaType, id, userData string) {
+	date := time.Now().UTC().Format(dateLayout)
+	var userHash string
+	if userData != "" {
+		userHash = HashUser(userData)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	day, ok := c.pending[date]
+	if !ok {
+		day = newDay(date)
+		c.pending[date] = day
+	}
+	add(day.Requests, resource+"/"+mediaType+"/"+id, 1, c.opts.MaxKeys)
+	if userHash != "" {
+		add(day.Users, userHash, 1, c.opts.MaxKeys)
+	}
+}
+
+// Flush adds the aggregated counts to the statistics in the store.
+// Counts that couldn't be written are kept for the next flush.
+func (c *Collector) Flush(ctx context.Context) error {
+	c.flushLock.Lock()
+	defer c.flushLock.Unlock()
+
+	c.lock.Lock()
+	pending := c.pending
+	c.pending = map[string]*Day{}
+	c.lock.Unlock()
+
+	var firstErr error
+	for date, day := range pending {
+		if err := c.flushDay(ctx, day); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			c.lock.Lock()
+			if newer, ok := c.pending[date]; ok {
+				day.merge(newer, c.opts.MaxKeys)
+			}
+			c.pending[date] = day
+			c.lock.Unlock()
+		}
+	}
+	return firstErr
+}
+
+func (c *Collector) flushDay(ctx context.Context, day *Day) error {
+	stored, err := c.getDay(ctx, day.Date)
+	if err != nil {
+		return err
+	}
+	stored.merge(day, c.opts.MaxKeys)
+	value, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal day: %w", err)
+	}
+	if err = c.store.Set(ctx, keyPrefix+day.Date, value, c.opts.Retention); err != nil {
+		return fmt.Errorf("couldn't store day: %w", err)
+	}
+	return nil
+}
+
+// getDay returns the stored statistics of the day, or empty ones if there are none.
+func (c *Collector) getDay(ctx context.Context, date string) (*Day, error) {
+	value, found, err := c.store.Get(ctx, keyPrefix+date)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get day: %w", err)
+	}
+	day := newDay(date)
+	if found {
+		if err = json.Unmarshal(value, day); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal day: %w", err)
+		}
+	}
+	return day, nil
+}
+
+// Export returns the flushed statistics of the days from "from" to "to", both inclusive, in chronological order.
+// Days without statistics are skipped.
+func (c *Collector) Export(ctx context.Context, from, to time.Time) ([]Day, error) {
+	var days []Day
+	for t := from.UTC().Truncate(24 * time.Hour); !t.After(to.UTC()); t = t.Add(24 * time.Hour) {
+		day, err := c.getDay(ctx, t.Format(dateLayout))
+		if err != nil {
+			return nil, err
+		}
+		if len(day.Requests) > 0 || len(day.Users) > 0 {
+			days = append(days, *day)
+		}
+	}
+	return days, nil
+}
+
+func (c *Collector) flushPeriodically() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				c.logger.Warn("Couldn't flush analytics, keeping the counts for the next flush", zap.Error(err))
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Close stops the periodic flushing and flushes the remaining counts.
+func (c *Collector) Close(ctx context.Context) error {
+	close(c.stop)
+	<-c.done
+	return c.Flush(ctx)
+}
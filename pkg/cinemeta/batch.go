@@ -0,0 +1,61 @@
+package cinemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// GetMovies returns the meta objects for multiple movies, for example for enriching a catalog page of 100 items.
+// Cached items are returned directly, the others are fetched from Cinemeta with at most ClientOptions.BatchConcurrency concurrent requests.
+// Each lookup has the client's timeout, so set a deadline on the context to limit the duration of the whole batch.
+// Duplicate IDs are only looked up once.
+//
+// The map contains all meta objects that could be fetched. If some lookups failed,
+// the error contains all of their errors, so the caller can decide to use the partial result.
+func (c *Client) GetMovies(ctx context.Context, imdbIDs []string) (map[string]types.MetaItem, error) {
+	res := make(map[string]types.MetaItem, len(imdbIDs))
+	var errs []error
+	lock := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, c.batchConcurrency)
+	seen := make(map[string]bool, len(imdbIDs))
+
+	for _, imdbID := range imdbIDs {
+		if seen[imdbID] {
+			continue
+		}
+		seen[imdbID] = true
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// Don't start further lookups, but still collect the results of the running ones.
+			lock.Lock()
+			errs = append(errs, fmt.Errorf("%v: %w", imdbID, ctx.Err()))
+			lock.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(imdbID string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			meta, err := c.GetMovie(ctx, imdbID)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", imdbID, err))
+				return
+			}
+			res[imdbID] = meta
+		}(imdbID)
+	}
+	wg.Wait()
+
+	return res, errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+// Package boltcache implements cinemeta.Cache with bbolt, an embedded key/value database,
+// so cached meta objects survive restarts of the addon.
+package boltcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/types"
+	"go.etcd.io/bbolt"
+)
+
+var _ cinemeta.Cache = (*Cache)(nil)
+
+// Cache is a cinemeta.Cache that stores meta objects in a bbolt DB.
+// It's safe for concurrent use.
+type Cache struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// New creates a new Cache that stores meta objects in the given bucket of the DB.
+// The bucket is created if it doesn't exist yet. Use different buckets for different clients, like the Cinemeta and TMDB clients.
+// Closing the DB is up to the caller.
+func New(db *bbolt.DB, bucket string) (*Cache, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create bucket: %w", err)
+	}
+	return &Cache{
+		db:     db,
+		bucket: []byte(bucket),
+	}, nil
+}
+
+// Set stores a meta object and the current time in the cache.
+func (c *Cache) Set(key string, meta types.MetaItem) error {
+	value, err := json.Marshal(cinemeta.CacheItem{
+		Meta:    meta,
+		Created: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't marshal cache item: %w", err)
+	}
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(c.bucket).Put([]byte(key), value)
+	})
+}
+
+// Get returns a meta object and the time it was cached from the cache.
+// The boolean return value signals if the value was found in the cache.
+func (c *Cache) Get(key string) (types.MetaItem, time.Time, bool, error) {
+	var item cinemeta.CacheItem
+	var found bool
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		// The value is only valid during the transaction, but unmarshalling copies it.
+		value := tx.Bucket(c.bucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &item)
+	})
+	if err != nil {
+		return types.MetaItem{}, time.Time{}, false, fmt.Errorf("couldn't get cache item: %w", err)
+	}
+	return item.Meta, item.Created, found, nil
+}
@@ -1,60 +1,124 @@
 package cinemeta
 
 import (
+	"container/list"
 	"sync"
 	"time"
+
+	"github.com/xybydy/go-stremio/types"
 )
 
 // CacheItem combines a meta object and a creation time in a single struct.
 // This can be useful for implementing the Cache interface, but is not necessarily required.
 // See the InMemoryCache example implementation of the Cache interface for its usage.
+// It can be marshalled to JSON, which caches that store bytes can use.
 type CacheItem struct {
-	Meta    any
-	Created time.Time
+	Meta    types.MetaItem `json:"meta"`
+	Created time.Time      `json:"created"`
 }
 
-// Cache is the interface that the cinemeta client uses for caching meta.
-// A package user must pass an implementation of this interface.
-// Usually you create a simple wrapper around an existing cache package.
-// An example implementation is the InMemoryCache in this package.
+// Cache is the interface that the Cinemeta client (and other MetaFetchers like the TMDB client) use for caching meta objects.
+// The package contains an in-memory implementation and the subpackages boltcache and rediscache contain persistent ones.
+// You can also create a simple wrapper around an existing cache package.
+//
+// Implementations must be safe for concurrent use.
+// They don't need to handle expiry, as the clients check the creation time themselves,
+// but they can evict items to limit their size.
 type Cache interface {
-	Set(key string, movie any) error
-	Get(key string) (any, time.Time, bool, error)
+	// Set stores the meta object with the current time as creation time.
+	Set(key string, meta types.MetaItem) error
+	// Get returns the meta object and the time it was stored.
+	// The boolean return value signals if the key was found. An error signals a problem with the cache itself.
+	Get(key string) (types.MetaItem, time.Time, bool, error)
 }
 
 var _ Cache = (*InMemoryCache)(nil)
 
 // InMemoryCache is an example implementation of the Cache interface.
 // It doesn't persist its data, so it's not suited for production use of the cinemeta package.
+// When it's created with NewLRUCache it's bounded and evicts the least recently used items,
+// otherwise it grows with every distinct key.
 type InMemoryCache struct {
-	cache map[string]CacheItem
-	lock  *sync.RWMutex
+	cache map[string]*list.Element
+	// Elements are of type *lruEntry, with the most recently used one at the front.
+	order      *list.List
+	maxEntries int
+	lock       *sync.Mutex
+}
+
+type lruEntry struct {
+	key  string
+	item CacheItem
 }
 
-// NewInMemoryCache creates a new InMemoryCache.
+// NewInMemoryCache creates a new unbounded InMemoryCache.
 func NewInMemoryCache() *InMemoryCache {
+	return NewLRUCache(0)
+}
+
+// NewLRUCache creates a new InMemoryCache that holds at most maxEntries items.
+// When it's full, the least recently used item is evicted. A value of 0 means no limit.
+func NewLRUCache(maxEntries int) *InMemoryCache {
 	return &InMemoryCache{
-		cache: map[string]CacheItem{},
-		lock:  &sync.RWMutex{},
+		cache:      map[string]*list.Element{},
+		order:      list.New(),
+		maxEntries: maxEntries,
+		lock:       &sync.Mutex{},
 	}
 }
 
 // Set stores a meta object and the current time in the cache.
-func (c *InMemoryCache) Set(key string, meta any) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.cache[key] = CacheItem{
+func (c *InMemoryCache) Set(key string, meta types.MetaItem) error {
+	c.setItem(key, CacheItem{
 		Meta:    meta,
 		Created: time.Now(),
-	}
+	})
 	return nil
 }
 
+func (c *InMemoryCache) setItem(key string, item CacheItem) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.cache[key]; ok {
+		elem.Value.(*lruEntry).item = item
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.cache[key] = c.order.PushFront(&lruEntry{key: key, item: item})
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.cache, oldest.Value.(*lruEntry).key)
+	}
+}
+
 // Get returns a meta object and the time it was cached from the cache.
 // The boolean return value signals if the value was found in the cache.
-func (c *InMemoryCache) Get(key string) (any, time.Time, bool, error) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
-	cacheItem, found := c.cache[key]
-	return cacheItem.Meta, cacheItem.Created, found, nil
+func (c *InMemoryCache) Get(key string) (types.MetaItem, time.Time, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, found := c.cache[key]
+	if !found {
+		return types.MetaItem{}, time.Time{}, false, nil
+	}
+	c.order.MoveToFront(elem)
+	item := elem.Value.(*lruEntry).item
+	return item.Meta, item.Created, true, nil
+}
+
+// Len returns the number of items in the cache.
+func (c *InMemoryCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// each calls fn for each item, from the least to the most recently used one.
+func (c *InMemoryCache) each(fn func(key string, item CacheItem)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*lruEntry)
+		fn(entry.key, entry.item)
+	}
 }
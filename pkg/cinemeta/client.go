@@ -2,15 +2,22 @@ package cinemeta
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/xybydy/go-stremio/types"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/sync/singleflight"
 )
 
 // ClientOptions are the options for the Cinemeta client.
@@ -18,31 +25,80 @@ type ClientOptions struct {
 	// The base URL for Cinemeta.
 	// Default "https://v3-cinemeta.strem.io".
 	BaseURL string
-	// Timeout for requests.
+	// Timeout for requests, including all retries.
 	// A more customizable cancellation can be achieved with the context,
 	// but it can never be *longer* than this timeout.
 	// Default 2 seconds.
 	Timeout time.Duration
+	// Number of retries after transient failures, which are network errors, timeouts of single attempts and 5xx and 429 responses.
+	// Default 0 (no retries).
+	Retries int
+	// Timeout for a single attempt, so a hanging request doesn't use up the whole Timeout and leaves time for a retry.
+	// Default the value of Timeout.
+	AttemptTimeout time.Duration
+	// Base delay before the first retry. It's doubled for each further retry, and the actual delay is a random value up to it (full jitter),
+	// so many addon instances don't retry in lockstep.
+	// Default 100 milliseconds.
+	RetryBackoff time.Duration
+	// Base URLs of Stremio addons that serve metas for IDs with the given prefix, like "kitsu" for IDs like "kitsu:1".
+	// IMDb IDs are always looked up in Cinemeta at BaseURL. Only used by GetMeta.
+	// TMDB IDs ("tmdb:550") aren't supported by Cinemeta, so either add the URL of a TMDB addon for the "tmdb" prefix,
+	// or use the tmdb package, which resolves them directly.
+	// Default the Kitsu addon for "kitsu" and Stremio's YouTube addon for "yt_id" (channels). Set the map to replace the defaults.
+	AddonURLs map[string]string
+	// Max number of concurrent requests to Cinemeta in batch lookups like GetMovies.
+	// Default 8.
+	BatchConcurrency int
+	// Proxy for all requests, like "http://proxy.example.com:3128" or "socks5://localhost:1080".
+	// Without it, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are honored.
+	// Ignored when Transport is set.
+	// Default nil.
+	Proxy *url.URL
+	// Transport for all requests, for full control over proxies, TLS and connection pooling.
+	// Default http.DefaultTransport.
+	Transport http.RoundTripper
 	// Max age of items in the cache.
 	// Default 30 days.
 	TTL time.Duration
+	// Max random extension of the TTL per item, so items that were cached at the same time,
+	// for example after a restart with an empty cache, don't all expire at once and cause a burst of requests to Cinemeta.
+	// The extension is derived from the item's key and creation time, so it's stable for the item.
+	// Default 0 (no jitter).
+	TTLJitter time.Duration
 }
 
+// ErrUnsupportedID signals that the client can't look up IDs with this prefix.
+var ErrUnsupportedID = errors.New("unsupported ID")
+
 // DefaultClientOpts is an options object with sensible defaults.
 var DefaultClientOpts = ClientOptions{
 	BaseURL: "https://v3-cinemeta.strem.io",
 	// HTTP client timeout
-	Timeout: 2 * time.Second,
-	TTL:     30 * 24 * time.Hour, // 30 days
+	Timeout:      2 * time.Second,
+	RetryBackoff: 100 * time.Millisecond,
+	AddonURLs: map[string]string{
+		"kitsu": "https://anime-kitsu.strem.fun",
+		"yt_id": "https://v3-channels.strem.io",
+	},
+	BatchConcurrency: 8,
+	TTL:              30 * 24 * time.Hour, // 30 days
 }
 
 // Client is the Cinemeta client.
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	cache      Cache
-	logger     *zap.Logger
-	ttl        time.Duration
+	baseURL          string
+	httpClient       *http.Client
+	cache            Cache
+	logger           *zap.Logger
+	ttl              time.Duration
+	ttlJitter        time.Duration
+	timeout          time.Duration
+	retries          int
+	attemptTimeout   time.Duration
+	retryBackoff     time.Duration
+	batchConcurrency int
+	addonURLs        map[string]string
+	group            *singleflight.Group
 }
 
 // NewClient creates a new Cinemeta client.
@@ -58,15 +114,42 @@ func NewClient(opts ClientOptions, cache Cache, logger *zap.Logger) *Client {
 	if opts.TTL == 0 {
 		opts.TTL = DefaultClientOpts.TTL
 	}
+	if opts.AttemptTimeout == 0 {
+		opts.AttemptTimeout = opts.Timeout
+	}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = DefaultClientOpts.RetryBackoff
+	}
+	if opts.AddonURLs == nil {
+		opts.AddonURLs = DefaultClientOpts.AddonURLs
+	}
+	if opts.BatchConcurrency == 0 {
+		opts.BatchConcurrency = DefaultClientOpts.BatchConcurrency
+	}
+
+	if opts.Transport == nil && opts.Proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(opts.Proxy)
+		opts.Transport = transport
+	}
 
 	return &Client{
 		baseURL: opts.BaseURL,
 		httpClient: &http.Client{
-			Timeout: opts.Timeout,
+			Transport: opts.Transport,
+			Timeout:   opts.AttemptTimeout,
 		},
-		cache:  cache,
-		logger: logger,
-		ttl:    opts.TTL,
+		cache:            cache,
+		logger:           logger,
+		ttl:              opts.TTL,
+		ttlJitter:        opts.TTLJitter,
+		timeout:          opts.Timeout,
+		retries:          opts.Retries,
+		attemptTimeout:   opts.AttemptTimeout,
+		retryBackoff:     opts.RetryBackoff,
+		batchConcurrency: opts.BatchConcurrency,
+		addonURLs:        opts.AddonURLs,
+		group:            &singleflight.Group{},
 	}
 }
 
@@ -76,7 +159,7 @@ func NewClient(opts ClientOptions, cache Cache, logger *zap.Logger) *Client {
 // than the HTTP client's configured timeout then it takes precedence.
 // If no timeout is set in the context, the HTTP client's timeout takes effect.
 func (c *Client) GetMovie(ctx context.Context, imdbID string) (types.MetaItem, error) {
-	return c.getMeta(ctx, movie, imdbID, 0, 0)
+	return c.getMeta(ctx, c.baseURL, "movie", imdbID, imdbID, zap.String("imdbID", imdbID))
 }
 
 // GetSeries returns the meta object either from the cache or from Cinemeta.
@@ -85,75 +168,142 @@ func (c *Client) GetMovie(ctx context.Context, imdbID string) (types.MetaItem, e
 // than the HTTP client's configured timeout then it takes precedence.
 // If no timeout is set in the context, the HTTP client's timeout takes effect.
 func (c *Client) GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error) {
-	return c.getMeta(ctx, tvShow, imdbID, season, episode)
+	return c.getMeta(ctx, c.baseURL, "series", imdbID, imdbID, zap.String("imdbID", fmt.Sprintf("%v:%v:%v", imdbID, season, episode)))
 }
 
-// GetMeta returns the meta object either from the cache or from Cinemeta.
-// It automatically fills the cache with new Cinemeta responses.
-// The context can control the lifetime of the request, and if for example the timeout is shorter
-// than the HTTP client's configured timeout then it takes precedence.
-// If no timeout is set in the context, the HTTP client's timeout takes effect.
-func (c *Client) getMeta(ctx context.Context, t mediaType, imdbID string, season int, episode int) (types.MetaItem, error) {
-	var zapFieldIMDbID zapcore.Field
-	switch t {
-	case movie:
-		zapFieldIMDbID = zap.String("imdbID", imdbID)
-	case tvShow:
-		zapFieldIMDbID = zap.String("imdbID", fmt.Sprintf("%v:%v:%v", imdbID, season, episode))
+// GetMeta returns the meta object of any type, like "movie", "series", "channel" or "tv", either from the cache or from a remote addon.
+// IMDb IDs are looked up in Cinemeta, IDs with a prefix like "kitsu:1" in the addon that's configured for the prefix in ClientOptions.AddonURLs.
+// The ID can contain a season and episode or an episode, like in stream requests ("tt0944947:1:2" or "kitsu:1:5"), which are ignored.
+// It returns ErrUnsupportedID if there's no addon for the ID's prefix.
+func (c *Client) GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error) {
+	if strings.HasPrefix(id, "tt") {
+		imdbID, _, _ := strings.Cut(id, ":")
+		// Same cache key as GetMovie and GetSeries.
+		return c.getMeta(ctx, c.baseURL, metaType, imdbID, imdbID, zap.String("imdbID", id))
 	}
+	prefix, rest, ok := strings.Cut(id, ":")
+	baseURL, supported := c.addonURLs[prefix]
+	if !ok || !supported {
+		return types.MetaItem{}, fmt.Errorf("%w: %v", ErrUnsupportedID, id)
+	}
+	// Remove the season and episode or the episode, like in "kitsu:1:5".
+	suffix, _, _ := strings.Cut(rest, ":")
+	metaID := prefix + ":" + suffix
+	return c.getMeta(ctx, baseURL, metaType, metaID, metaType+"/"+metaID, zap.String("id", id))
+}
 
+func (c *Client) getMeta(ctx context.Context, baseURL, metaType, metaID, cacheKey string, zapFieldID zapcore.Field) (types.MetaItem, error) {
 	// Check cache first
-	meta, created, found, err := c.cache.Get(imdbID)
+	meta, created, found, err := c.cache.Get(cacheKey)
 	if err != nil {
-		c.logger.Error("Couldn't decode meta", zap.Error(err), zapFieldIMDbID)
+		c.logger.Error("Couldn't decode meta", zap.Error(err), zapFieldID)
 	} else if !found {
-		c.logger.Debug("Meta not found in cache", zapFieldIMDbID)
-	} else if time.Since(created) > c.ttl {
-		expiredSince := time.Since(created.Add(c.ttl))
-		c.logger.Debug("Hit cache for meta, but item is expired", zap.Duration("expiredSince", expiredSince), zapFieldIMDbID)
+		c.logger.Debug("Meta not found in cache", zapFieldID)
+	} else if ttl := c.itemTTL(cacheKey, created); time.Since(created) > ttl {
+		expiredSince := time.Since(created.Add(ttl))
+		c.logger.Debug("Hit cache for meta, but item is expired", zap.Duration("expiredSince", expiredSince), zapFieldID)
 	} else {
 		c.logger.Debug("Hit cache for meta, returning result")
-		convMeta := meta.(types.MetaItem)
-		return convMeta, nil
+		return meta, nil
+	}
+
+	reqURL := baseURL + "/meta/" + url.PathEscape(metaType) + "/" + url.PathEscape(metaID) + ".json"
+
+	// Then check web service.
+	// Concurrent lookups of the same item, like for a burst of stream requests for a new release, share a single request.
+	// The request must not be canceled when the first caller goes away, as others might still wait for it,
+	// so it's detached from the caller's context. It still has the client's timeout.
+	resChan := c.group.DoChan(cacheKey, func() (any, error) {
+		res, err := c.fetchWithRetries(context.WithoutCancel(ctx), reqURL, metaType, zapFieldID)
+		if err != nil {
+			return types.MetaItem{}, err
+		}
+		// Fill cache
+		if err = c.cache.Set(cacheKey, res); err != nil {
+			c.logger.Error("Couldn't cache meta", zap.Error(err), zap.String("meta", fmt.Sprintf("%+v", res)), zapFieldID)
+		}
+		return res, nil
+	})
+	select {
+	case <-ctx.Done():
+		return types.MetaItem{}, ctx.Err()
+	case res := <-resChan:
+		if res.Err != nil {
+			return types.MetaItem{}, res.Err
+		}
+		if res.Shared {
+			c.logger.Debug("Shared meta request with concurrent lookups", zapFieldID)
+		}
+		return res.Val.(types.MetaItem), nil
 	}
+}
 
-	var reqURL string
-	switch t {
-	case movie:
-		reqURL = c.baseURL + "/meta/movie/" + imdbID + ".json"
-	case tvShow:
-		reqURL = c.baseURL + "/meta/series/" + imdbID + ".json"
+// fetchWithRetries fetches the meta object and retries transient failures.
+func (c *Client) fetchWithRetries(ctx context.Context, reqURL, metaType string, zapFieldID zapcore.Field) (types.MetaItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		res, retryable, err := c.fetch(ctx, reqURL, metaType)
+		if err == nil {
+			return res, nil
+		} else if !retryable || attempt >= c.retries || ctx.Err() != nil {
+			return types.MetaItem{}, err
+		}
+		// Full jitter: a random delay between 0 and the exponentially growing backoff.
+		backoff := time.Duration(rand.Int64N(int64(c.retryBackoff << min(attempt, 10))))
+		c.logger.Debug("Couldn't get meta, retrying", zap.Error(err), zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zapFieldID)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return types.MetaItem{}, fmt.Errorf("%w (gave up retrying: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
 	}
+}
 
-	// Then check web service
+// fetch makes a single request to Cinemeta or another addon.
+// The boolean return value signals if the error is transient, so the request can be retried.
+func (c *Client) fetch(ctx context.Context, reqURL string, metaType string) (types.MetaItem, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.attemptTimeout)
+	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
-		return types.MetaItem{}, fmt.Errorf("couldn't create request: %w", err)
+		return types.MetaItem{}, false, fmt.Errorf("couldn't create request: %w", err)
 	}
 	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return types.MetaItem{}, fmt.Errorf("couldn't GET %v: %w", reqURL, err)
+		return types.MetaItem{}, true, fmt.Errorf("couldn't GET %v: %w", reqURL, err)
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return types.MetaItem{}, fmt.Errorf("bad GET response: %v", res.StatusCode)
+		retryable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return types.MetaItem{}, retryable, fmt.Errorf("bad GET response: %v", res.StatusCode)
 	}
 	resBody, err := io.ReadAll(res.Body)
 	if err != nil {
-		return types.MetaItem{}, fmt.Errorf("couldn't read response body: %w", err)
+		return types.MetaItem{}, true, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	// Cinemeta is a Stremio addon, so the meta object is wrapped like in any meta response.
+	var cineRes struct {
+		Meta types.MetaItem `json:"meta"`
 	}
-	cineRes := types.MetaItem{}
 	if err := json.Unmarshal(resBody, &cineRes); err != nil {
-		return types.MetaItem{}, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		return types.MetaItem{}, false, fmt.Errorf("couldn't unmarshal response body: %w", err)
 	}
-	if cineRes.Name == "" {
-		return types.MetaItem{}, fmt.Errorf("couldn't find %v name in Cinemeta response", t)
+	if cineRes.Meta.Name == "" {
+		return types.MetaItem{}, false, fmt.Errorf("couldn't find %v name in response", metaType)
 	}
+	return cineRes.Meta, false, nil
+}
 
-	// Fill cache
-	if err = c.cache.Set(imdbID, types.MetaItem{}); err != nil {
-		c.logger.Error("Couldn't cache meta", zap.Error(err), zap.String("meta", fmt.Sprintf("%+v", cineRes)), zapFieldIMDbID)
+// itemTTL returns the TTL of a cache item, including its jitter.
+func (c *Client) itemTTL(key string, created time.Time) time.Duration {
+	if c.ttlJitter <= 0 {
+		return c.ttl
 	}
-
-	return cineRes, nil
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write(binary.LittleEndian.AppendUint64(nil, uint64(created.UnixNano())))
+	return c.ttl + time.Duration(h.Sum64()%uint64(c.ttlJitter))
 }
@@ -0,0 +1,118 @@
+package cinemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Fetcher returns metadata for movies and TV shows.
+// It's the same as go-stremio's MetaFetcher interface, so the Client, the MultiFetcher and go-stremio's MetaClient option are interchangeable.
+type Fetcher interface {
+	GetMovie(ctx context.Context, imdbID string) (types.MetaItem, error)
+	GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error)
+}
+
+var (
+	_ Fetcher = (*Client)(nil)
+	_ Fetcher = (*MultiFetcher)(nil)
+)
+
+// MultiFetcher tries multiple fetchers in order until one of them returns metadata,
+// so metadata lookups survive an outage of the primary source, like Cinemeta.
+// It's safe for concurrent use.
+type MultiFetcher struct {
+	fetchers []Fetcher
+	// Number of answers per fetcher, with the same indexes as fetchers.
+	answers []atomic.Int64
+}
+
+// NewMultiFetcher creates a new MultiFetcher that tries the primary fetcher first and then the fallbacks in the given order.
+func NewMultiFetcher(primary Fetcher, fallbacks ...Fetcher) *MultiFetcher {
+	fetchers := append([]Fetcher{primary}, fallbacks...)
+	return &MultiFetcher{
+		fetchers: fetchers,
+		answers:  make([]atomic.Int64, len(fetchers)),
+	}
+}
+
+// GetMovie returns the meta object from the first fetcher that has it.
+func (m *MultiFetcher) GetMovie(ctx context.Context, imdbID string) (types.MetaItem, error) {
+	meta, _, err := m.GetMovieWithSource(ctx, imdbID)
+	return meta, err
+}
+
+// GetSeries returns the meta object from the first fetcher that has it.
+func (m *MultiFetcher) GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error) {
+	meta, _, err := m.GetSeriesWithSource(ctx, imdbID, season, episode)
+	return meta, err
+}
+
+// GetMovieWithSource is like GetMovie, but also returns the index of the fetcher that answered,
+// with 0 being the primary fetcher and 1 the first fallback.
+func (m *MultiFetcher) GetMovieWithSource(ctx context.Context, imdbID string) (types.MetaItem, int, error) {
+	return m.get(ctx, func(f Fetcher) (types.MetaItem, error) {
+		return f.GetMovie(ctx, imdbID)
+	})
+}
+
+// GetSeriesWithSource is like GetSeries, but also returns the index of the fetcher that answered,
+// with 0 being the primary fetcher and 1 the first fallback.
+func (m *MultiFetcher) GetSeriesWithSource(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, int, error) {
+	return m.get(ctx, func(f Fetcher) (types.MetaItem, error) {
+		return f.GetSeries(ctx, imdbID, season, episode)
+	})
+}
+
+// GetMeta returns the meta object from the first fetcher that has it, for any type and ID scheme (see Client.GetMeta).
+// Fetchers that don't have a GetMeta method are only asked for IMDb movies and TV shows.
+func (m *MultiFetcher) GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error) {
+	meta, _, err := m.get(ctx, func(f Fetcher) (types.MetaItem, error) {
+		if tf, ok := f.(interface {
+			GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error)
+		}); ok {
+			return tf.GetMeta(ctx, metaType, id)
+		}
+		imdbID, _, _ := strings.Cut(id, ":")
+		switch {
+		case !strings.HasPrefix(id, "tt"):
+		case metaType == "movie":
+			return f.GetMovie(ctx, imdbID)
+		case metaType == "series":
+			return f.GetSeries(ctx, imdbID, 0, 0)
+		}
+		return types.MetaItem{}, fmt.Errorf("%w: %v", ErrUnsupportedID, id)
+	})
+	return meta, err
+}
+
+// Answers returns how many requests each fetcher answered, with the same indexes as for GetMovieWithSource.
+// It's useful for monitoring how often the primary fetcher fails.
+func (m *MultiFetcher) Answers() []int64 {
+	res := make([]int64, len(m.answers))
+	for i := range m.answers {
+		res[i] = m.answers[i].Load()
+	}
+	return res
+}
+
+func (m *MultiFetcher) get(ctx context.Context, fetch func(Fetcher) (types.MetaItem, error)) (types.MetaItem, int, error) {
+	var errs []error
+	for i, f := range m.fetchers {
+		meta, err := fetch(f)
+		if err == nil {
+			m.answers[i].Add(1)
+			return meta, i, nil
+		}
+		errs = append(errs, fmt.Errorf("fetcher %v: %w", i, err))
+		// Don't try the fallbacks when the caller isn't waiting for the result anymore.
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return types.MetaItem{}, -1, errors.Join(errs...)
+}
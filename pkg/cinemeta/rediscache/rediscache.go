@@ -0,0 +1,98 @@
+// Package rediscache implements cinemeta.Cache with Redis, so multiple instances of an addon can share their cached meta objects.
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Options are the options for the Redis cache.
+type Options struct {
+	// Prefix for all keys, so the cache can share a Redis DB with other data.
+	// Use different prefixes for different clients, like the Cinemeta and TMDB clients.
+	// Default "cinemeta:".
+	Prefix string
+	// Expiry of the keys in Redis, which limits the memory usage.
+	// It should be at least as long as the TTL of the client that uses the cache.
+	// Default 30 days.
+	Expiry time.Duration
+	// Timeout for each Redis command.
+	// Default 1 second.
+	Timeout time.Duration
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Prefix:  "cinemeta:",
+	Expiry:  30 * 24 * time.Hour, // 30 days
+	Timeout: time.Second,
+}
+
+var _ cinemeta.Cache = (*Cache)(nil)
+
+// Cache is a cinemeta.Cache that stores meta objects in Redis.
+// It's safe for concurrent use.
+type Cache struct {
+	client  redis.UniversalClient
+	prefix  string
+	expiry  time.Duration
+	timeout time.Duration
+}
+
+// New creates a new Cache. The client can be a single node, cluster or sentinel client.
+func New(client redis.UniversalClient, opts Options) *Cache {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultOptions.Prefix
+	}
+	if opts.Expiry == 0 {
+		opts.Expiry = DefaultOptions.Expiry
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	return &Cache{
+		client:  client,
+		prefix:  opts.Prefix,
+		expiry:  opts.Expiry,
+		timeout: opts.Timeout,
+	}
+}
+
+// Set stores a meta object and the current time in the cache.
+func (c *Cache) Set(key string, meta types.MetaItem) error {
+	value, err := json.Marshal(cinemeta.CacheItem{
+		Meta:    meta,
+		Created: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't marshal cache item: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.Set(ctx, c.prefix+key, value, c.expiry).Err()
+}
+
+// Get returns a meta object and the time it was cached from the cache.
+// The boolean return value signals if the value was found in the cache.
+func (c *Cache) Get(key string) (types.MetaItem, time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return types.MetaItem{}, time.Time{}, false, nil
+	} else if err != nil {
+		return types.MetaItem{}, time.Time{}, false, fmt.Errorf("couldn't get cache item: %w", err)
+	}
+	var item cinemeta.CacheItem
+	if err = json.Unmarshal(value, &item); err != nil {
+		return types.MetaItem{}, time.Time{}, false, fmt.Errorf("couldn't unmarshal cache item: %w", err)
+	}
+	return item.Meta, item.Created, true, nil
+}
@@ -0,0 +1,174 @@
+package cinemeta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// SnapshotCacheOptions are the options for the SnapshotCache.
+type SnapshotCacheOptions struct {
+	// Path of the snapshot file. Required.
+	// The directory must exist.
+	Path string
+	// Interval between snapshots. Snapshots are only written when the cache changed.
+	// Default 5 minutes.
+	Interval time.Duration
+	// Max number of items in the cache, see NewLRUCache.
+	// Default 0 (no limit).
+	MaxEntries int
+}
+
+// DefaultSnapshotCacheOpts is an options object with sensible defaults.
+// The Path must still be set.
+var DefaultSnapshotCacheOpts = SnapshotCacheOptions{
+	Interval: 5 * time.Minute,
+}
+
+var _ Cache = (*SnapshotCache)(nil)
+
+// SnapshotCache is an in-memory cache that periodically writes a snapshot of its items to a file
+// and loads it on creation, so a restart of the addon doesn't cause a burst of requests to Cinemeta.
+// It's simpler than the boltcache and rediscache packages, as all reads and writes are in memory,
+// but items that were set after the last snapshot are lost when the process crashes.
+type SnapshotCache struct {
+	*InMemoryCache
+	path   string
+	logger *zap.Logger
+	// Number of Set calls at the time of the last snapshot.
+	savedSets int64
+	// Atomic, so Set never waits for a snapshot that's being written.
+	sets atomic.Int64
+	// Guards savedSets and serializes snapshots.
+	lock      *sync.Mutex
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce *sync.Once
+}
+
+// NewSnapshotCache creates a new SnapshotCache, loads the existing snapshot if there is one and starts writing snapshots in the background.
+// Call Close when shutting down, to stop the background writes and write a final snapshot.
+func NewSnapshotCache(opts SnapshotCacheOptions, logger *zap.Logger) (*SnapshotCache, error) {
+	if opts.Path == "" {
+		return nil, errors.New("opts.Path must not be empty")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultSnapshotCacheOpts.Interval
+	}
+
+	c := &SnapshotCache{
+		InMemoryCache: NewLRUCache(opts.MaxEntries),
+		path:          opts.Path,
+		logger:        logger,
+		lock:          &sync.Mutex{},
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		closeOnce:     &sync.Once{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer close(c.stopped)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Save(); err != nil {
+					c.logger.Error("Couldn't write cache snapshot", zap.Error(err), zap.String("path", c.path))
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+// Set stores a meta object and the current time in the cache.
+func (c *SnapshotCache) Set(key string, meta types.MetaItem) error {
+	c.sets.Add(1)
+	return c.InMemoryCache.Set(key, meta)
+}
+
+// snapshotItem is an item in the snapshot file.
+type snapshotItem struct {
+	Key string `json:"key"`
+	CacheItem
+}
+
+// Save writes a snapshot of the cache to the file, if the cache changed since the last snapshot.
+// The file is replaced atomically, so a crash while writing doesn't corrupt the existing snapshot.
+// The cache is only locked while its items are copied, not while they're written.
+func (c *SnapshotCache) Save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Read before copying the items, so a concurrent Set leads to another snapshot next time, even if its item made it into this one
+	sets := c.sets.Load()
+	if sets == c.savedSets {
+		return nil
+	}
+
+	var items []snapshotItem
+	c.InMemoryCache.each(func(key string, item CacheItem) {
+		items = append(items, snapshotItem{Key: key, CacheItem: item})
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err = json.NewEncoder(tmp).Encode(items); err != nil {
+		tmp.Close()
+		return fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("couldn't replace snapshot: %w", err)
+	}
+	c.savedSets = sets
+	return nil
+}
+
+// Close stops the background snapshots and writes a final one.
+func (c *SnapshotCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	<-c.stopped
+	return c.Save()
+}
+
+func (c *SnapshotCache) load() error {
+	f, err := os.Open(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("couldn't open snapshot: %w", err)
+	}
+	defer f.Close()
+	var items []snapshotItem
+	if err = json.NewDecoder(f).Decode(&items); err != nil {
+		return fmt.Errorf("couldn't read snapshot: %w", err)
+	}
+	// The items are ordered from least to most recently used, so the LRU order is restored.
+	for _, item := range items {
+		c.InMemoryCache.setItem(item.Key, item.CacheItem)
+	}
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+
+	"github.com/xybydy/go-stremio/internal/metactx"
+	"github.com/xybydy/go-stremio/types"
 )
 
 var ErrNoMeta = errors.New("no meta in context")
@@ -11,13 +14,17 @@ var ErrNoMeta = errors.New("no meta in context")
 // GetMetaFromContext returns the Meta object that's stored in the context.
 // It returns an error if no meta was found in the context or the value found isn't of type Meta.
 // The former one is ErrNoMeta which acts as sentinel error so you can check for it.
-func GetMetaFromContext(ctx context.Context) (Meta, error) {
-	metaIface := ctx.Value("meta")
+//
+// Deprecated: Use stremio.GetMetaFromContext instead.
+func GetMetaFromContext(ctx context.Context) (types.MetaItem, error) {
+	metaIface := ctx.Value(metactx.Key)
+	if metaIface == nil {
+		metaIface = ctx.Value(metactx.LegacyKey)
+	}
 	if metaIface == nil {
-		return Meta{}, ErrNoMeta
-	} else if meta, ok := metaIface.(Meta); ok {
+		return types.MetaItem{}, ErrNoMeta
+	} else if meta, ok := metaIface.(types.MetaItem); ok {
 		return meta, nil
-	} else {
-		return Meta{}, fmt.Errorf("couldn't turn meta interface value to proper object: type is %T", metaIface)
 	}
+	return types.MetaItem{}, fmt.Errorf("couldn't turn meta interface value to proper object: type is %T", metaIface)
 }
@@ -0,0 +1,387 @@
+// Package client is a client for remote Stremio addons.
+// It fetches and validates an addon's manifest and queries its catalog, stream, meta and subtitle endpoints with typed results,
+// for building proxies, aggregators and monitoring tools. It works with any addon, not just go-stremio ones.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xybydy/go-stremio/types"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrNotFound signals that the addon responded with 404 Not Found.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidManifest signals that the addon's manifest is invalid.
+	ErrInvalidManifest = errors.New("invalid manifest")
+)
+
+// StatusError signals that the addon responded with an unexpected status code (other than 404).
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return "bad response status: " + strconv.Itoa(e.StatusCode)
+}
+
+// Options are the options for the client.
+type Options struct {
+	// Timeout for requests, including all retries.
+	// Default 10 seconds.
+	Timeout time.Duration
+	// Number of retries after transient failures, which are network errors, timeouts of single attempts and 5xx and 429 responses.
+	// Default 0 (no retries).
+	Retries int
+	// Timeout for a single attempt, so a hanging request doesn't use up the whole Timeout and leaves time for a retry.
+	// Default the value of Timeout.
+	AttemptTimeout time.Duration
+	// Base delay before the first retry. It's doubled for each further retry, and the actual delay is a random value up to it (full jitter).
+	// Default 100 milliseconds.
+	RetryBackoff time.Duration
+	// How long to cache responses. When 0, responses are cached as long as their "Cache-Control" header's max-age allows.
+	// A negative value disables caching. Error responses aren't cached.
+	// Default 0.
+	CacheTTL time.Duration
+	// Max number of cached responses.
+	// Default 1000.
+	MaxCacheEntries int
+	// Transport for all requests, for proxies, TLS and connection pooling.
+	// Default http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Timeout:         10 * time.Second,
+	RetryBackoff:    100 * time.Millisecond,
+	MaxCacheEntries: 1000,
+}
+
+// Client is a client for a single remote addon. It's safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	opts       Options
+	group      *singleflight.Group
+
+	cache map[string]cacheEntry
+	lock  *sync.Mutex
+}
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// New creates a client for the addon at the URL, which can be the URL of the manifest like "https://example.com/manifest.json",
+// the base URL without "/manifest.json" or an install URL with the "stremio://" scheme.
+// The URL can contain user data, like "https://example.com/abc/manifest.json".
+func New(addonURL string, opts Options) (*Client, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.AttemptTimeout == 0 {
+		opts.AttemptTimeout = opts.Timeout
+	}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = DefaultOptions.RetryBackoff
+	}
+	if opts.MaxCacheEntries == 0 {
+		opts.MaxCacheEntries = DefaultOptions.MaxCacheEntries
+	}
+
+	if rest, ok := strings.CutPrefix(addonURL, "stremio://"); ok {
+		addonURL = "https://" + rest
+	}
+	u, err := url.Parse(addonURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid addon URL: %w", err)
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid addon URL %q: must be an absolute HTTP(S) URL", addonURL)
+	}
+	u.RawQuery, u.Fragment = "", ""
+
+	return &Client{
+		baseURL: strings.TrimSuffix(strings.TrimSuffix(u.String(), "/manifest.json"), "/"),
+		httpClient: &http.Client{
+			Transport: opts.Transport,
+			Timeout:   opts.AttemptTimeout,
+		},
+		opts:  opts,
+		group: &singleflight.Group{},
+		cache: map[string]cacheEntry{},
+		lock:  &sync.Mutex{},
+	}, nil
+}
+
+// BaseURL returns the addon's URL without "/manifest.json".
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// manifestJSON accepts the resources in both forms, as objects and as plain names like "stream".
+type manifestJSON struct {
+	types.Manifest
+	Resources []json.RawMessage `json:"resources"`
+}
+
+// Manifest fetches the addon's manifest and validates it.
+// Resources in the short form (like "stream") get the manifest's types and ID prefixes.
+// It returns an error wrapping ErrInvalidManifest if the manifest is invalid.
+func (c *Client) Manifest(ctx context.Context) (types.Manifest, error) {
+	body, err := c.get(ctx, "/manifest.json")
+	if err != nil {
+		return types.Manifest{}, err
+	}
+	var m manifestJSON
+	if err = json.Unmarshal(body, &m); err != nil {
+		return types.Manifest{}, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+	manifest := m.Manifest
+	manifest.ResourceItems = nil
+	for _, raw := range m.Resources {
+		var name string
+		if json.Unmarshal(raw, &name) == nil {
+			manifest.ResourceItems = append(manifest.ResourceItems, types.ResourceItem{Name: name, Types: manifest.Types, IDprefixes: manifest.IDprefixes})
+			continue
+		}
+		var resource types.ResourceItem
+		if err = json.Unmarshal(raw, &resource); err != nil {
+			return types.Manifest{}, fmt.Errorf("%w: invalid resource %v", ErrInvalidManifest, string(raw))
+		}
+		manifest.ResourceItems = append(manifest.ResourceItems, resource)
+	}
+	if err = manifest.Validate(); err != nil {
+		return types.Manifest{}, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+	return manifest, nil
+}
+
+// Supports reports whether the manifest declares the resource (like "stream") for the type and ID.
+// For catalogs the ID is the catalog ID, otherwise the ID must have one of the resource's ID prefixes, if it declares any.
+func Supports(manifest types.Manifest, resource, metaType, id string) bool {
+	if resource == "catalog" {
+		return slices.ContainsFunc(manifest.Catalogs, func(catalog types.CatalogItem) bool {
+			return catalog.Type == metaType && catalog.ID == id
+		})
+	}
+	for _, item := range manifest.ResourceItems {
+		if item.Name != resource {
+			continue
+		}
+		itemTypes, idPrefixes := item.Types, item.IDprefixes
+		if len(itemTypes) == 0 {
+			itemTypes = manifest.Types
+		}
+		if len(idPrefixes) == 0 {
+			idPrefixes = manifest.IDprefixes
+		}
+		if !slices.Contains(itemTypes, metaType) {
+			continue
+		}
+		if len(idPrefixes) == 0 || slices.ContainsFunc(idPrefixes, func(prefix string) bool { return strings.HasPrefix(id, prefix) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// Catalog returns the items of a catalog. The extra arguments like "search" or "skip" can be nil.
+func (c *Client) Catalog(ctx context.Context, metaType, id string, extra url.Values) ([]types.MetaPreviewItem, error) {
+	var res struct {
+		Metas []types.MetaPreviewItem `json:"metas"`
+	}
+	err := c.getJSON(ctx, resourcePath("catalog", metaType, id, extra), &res)
+	return res.Metas, err
+}
+
+// Streams returns the streams of an item, like "tt1254207" for a movie or "tt0944947:1:1" for an episode.
+func (c *Client) Streams(ctx context.Context, metaType, id string) ([]types.StreamItem, error) {
+	var res struct {
+		Streams []types.StreamItem `json:"streams"`
+	}
+	err := c.getJSON(ctx, resourcePath("stream", metaType, id, nil), &res)
+	return res.Streams, err
+}
+
+// Meta returns the meta object of an item.
+func (c *Client) Meta(ctx context.Context, metaType, id string) (types.MetaItem, error) {
+	var res struct {
+		Meta types.MetaItem `json:"meta"`
+	}
+	err := c.getJSON(ctx, resourcePath("meta", metaType, id, nil), &res)
+	return res.Meta, err
+}
+
+// Subtitles returns the subtitles of an item. The extra arguments like "videoHash" can be nil.
+func (c *Client) Subtitles(ctx context.Context, metaType, id string, extra url.Values) ([]types.SubtitleItem, error) {
+	var res struct {
+		Subtitles []types.SubtitleItem `json:"subtitles"`
+	}
+	err := c.getJSON(ctx, resourcePath("subtitles", metaType, id, extra), &res)
+	return res.Subtitles, err
+}
+
+func resourcePath(resource, metaType, id string, extra url.Values) string {
+	path := "/" + resource + "/" + url.PathEscape(metaType) + "/" + url.PathEscape(id)
+	if len(extra) > 0 {
+		path += "/" + extra.Encode()
+	}
+	return path + ".json"
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+	return nil
+}
+
+// get returns the body of a response, from the cache or from the addon.
+// Concurrent requests for the same path share a single request.
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	if body, ok := c.cached(path); ok {
+		return body, nil
+	}
+	// Detached from the caller's context, as other callers might still wait for the request. It still has the client's timeout.
+	resChan := c.group.DoChan(path, func() (any, error) {
+		return c.fetchWithRetries(context.WithoutCancel(ctx), path)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-resChan:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	}
+}
+
+// fetchWithRetries fetches the path and retries transient failures.
+func (c *Client) fetchWithRetries(ctx context.Context, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		body, retryable, err := c.fetch(ctx, path)
+		if err == nil {
+			return body, nil
+		} else if !retryable || attempt >= c.opts.Retries || ctx.Err() != nil {
+			return nil, err
+		}
+		// Full jitter: a random delay between 0 and the exponentially growing backoff.
+		backoff := time.Duration(rand.Int64N(int64(c.opts.RetryBackoff << min(attempt, 10))))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (gave up retrying: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// fetch makes a single request and caches successful responses.
+// The boolean return value signals if the error is transient, so the request can be retried.
+func (c *Client) fetch(ctx context.Context, path string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.AttemptTimeout)
+	defer cancel()
+	reqURL := c.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't create request: %w", err)
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("couldn't GET %v: %w", reqURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, false, fmt.Errorf("%w: %v", ErrNotFound, reqURL)
+	} else if res.StatusCode != http.StatusOK {
+		retryable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return nil, retryable, &StatusError{StatusCode: res.StatusCode}
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	c.store(path, body, res.Header.Get("Cache-Control"))
+	return body, false, nil
+}
+
+func (c *Client) cached(path string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.cache[path]
+	if !ok {
+		return nil, false
+	} else if time.Now().After(entry.expires) {
+		delete(c.cache, path)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *Client) store(path string, body []byte, cacheControl string) {
+	ttl := c.opts.CacheTTL
+	if ttl == 0 {
+		ttl = maxAge(cacheControl)
+	}
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.cache) >= c.opts.MaxCacheEntries {
+		for key, entry := range c.cache {
+			if now.After(entry.expires) {
+				delete(c.cache, key)
+			}
+		}
+		// Still full, so evict a random entry (map iteration order is random)
+		for key := range c.cache {
+			if len(c.cache) < c.opts.MaxCacheEntries {
+				break
+			}
+			delete(c.cache, key)
+		}
+	}
+	c.cache[path] = cacheEntry{body: body, expires: now.Add(ttl)}
+}
+
+// maxAge returns the max-age of a "Cache-Control" header, or 0 if it's missing or caching isn't allowed.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0
+		case "max-age":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
+}
@@ -0,0 +1,127 @@
+// Package debrid converts torrent streams into direct HTTPS streams via debrid services like Real-Debrid,
+// so users with a debrid account don't need to use P2P.
+package debrid
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotCached signals that the debrid service doesn't have the torrent cached,
+	// so it can't be streamed right away.
+	ErrNotCached = errors.New("torrent not cached by debrid service")
+	// ErrBadAPIKey signals that the debrid service rejected the user's API key.
+	ErrBadAPIKey = errors.New("bad debrid API key")
+	// ErrRateLimited signals that the debrid service rejected the request because of too many requests.
+	ErrRateLimited = errors.New("rate limited by debrid service")
+)
+
+// Resolver resolves a torrent file to a direct HTTPS URL.
+type Resolver interface {
+	// Resolve returns a direct URL for the file with the given index in the torrent with the given info hash.
+	// It returns ErrNotCached if the service can't deliver the file right away.
+	Resolve(ctx context.Context, apiKey, infoHash string, fileIndex int) (string, error)
+}
+
+// WrapOptions are the options for WrapStreamHandler.
+type WrapOptions struct {
+	// Prefix for the stream name of converted streams, so users can see which streams are debrid streams.
+	// Default "[Debrid+]".
+	NamePrefix string
+	// Flag for indicating whether the original torrent streams should be returned as well.
+	// Torrent streams that couldn't be converted (e.g. not cached) are always returned.
+	// Default false.
+	KeepTorrents bool
+	// Max number of concurrent Resolve calls per request.
+	// Default 5.
+	MaxConcurrent int
+}
+
+// DefaultWrapOptions is a WrapOptions object with default values.
+var DefaultWrapOptions = WrapOptions{
+	NamePrefix:    "[Debrid+]",
+	MaxConcurrent: 5,
+}
+
+// WrapStreamHandler returns a StreamHandler that calls h and converts its torrent streams into HTTPS streams with r.
+// The apiKey func gets the user data that's passed to the handler and must return the user's debrid API key.
+// If it returns an empty string, the streams are returned unchanged.
+func WrapStreamHandler(h stremio.StreamHandler, r Resolver, apiKey func(userData any) string, opts WrapOptions, logger *zap.Logger) stremio.StreamHandler {
+	if opts.NamePrefix == "" {
+		opts.NamePrefix = DefaultWrapOptions.NamePrefix
+	}
+	if opts.MaxConcurrent == 0 {
+		opts.MaxConcurrent = DefaultWrapOptions.MaxConcurrent
+	}
+
+	return func(ctx context.Context, id string, userData any) ([]types.StreamItem, error) {
+		streams, err := h(ctx, id, userData)
+		if err != nil {
+			return nil, err
+		}
+		key := apiKey(userData)
+		if key == "" {
+			return streams, nil
+		}
+
+		converted := make([]*types.StreamItem, len(streams))
+		sem := make(chan struct{}, opts.MaxConcurrent)
+		var wg sync.WaitGroup
+		for i, s := range streams {
+			if s.InfoHash == "" {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				url, err := r.Resolve(ctx, key, s.InfoHash, int(s.FileIndex))
+				if err != nil {
+					if !errors.Is(err, ErrNotCached) {
+						logger.Warn("Couldn't resolve torrent with debrid service", zap.Error(err), zap.String("infoHash", s.InfoHash))
+					}
+					return
+				}
+				converted[i] = toDebridStream(s, url, opts.NamePrefix)
+			}()
+		}
+		wg.Wait()
+
+		// Debrid streams first, then the remaining torrents.
+		res := make([]types.StreamItem, 0, len(streams))
+		for _, s := range converted {
+			if s != nil {
+				res = append(res, *s)
+			}
+		}
+		for i, s := range streams {
+			if converted[i] == nil || opts.KeepTorrents {
+				res = append(res, s)
+			}
+		}
+		return res, nil
+	}
+}
+
+func toDebridStream(s types.StreamItem, url, namePrefix string) *types.StreamItem {
+	s.URL = url
+	s.InfoHash = ""
+	s.FileIndex = 0
+	s.Sources = nil
+	s.BehaviorHints.NotWebReady = false
+	if s.Name != "" {
+		s.Name = namePrefix + " " + s.Name
+	} else {
+		s.Name = namePrefix
+	}
+	return &s
+}
@@ -0,0 +1,173 @@
+package debrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/magnet"
+)
+
+// RealDebridOptions are the options for the Real-Debrid client.
+type RealDebridOptions struct {
+	// The base URL of the Real-Debrid API.
+	// Default "https://api.real-debrid.com/rest/1.0".
+	BaseURL string
+	// Timeout for each request to the API.
+	// Default 5 seconds.
+	Timeout time.Duration
+}
+
+// DefaultRealDebridOptions is an options object with sensible defaults.
+var DefaultRealDebridOptions = RealDebridOptions{
+	BaseURL: "https://api.real-debrid.com/rest/1.0",
+	Timeout: 5 * time.Second,
+}
+
+var _ Resolver = (*RealDebrid)(nil)
+
+// RealDebrid is a Resolver for Real-Debrid (https://real-debrid.com).
+type RealDebrid struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewRealDebrid creates a new Real-Debrid client.
+func NewRealDebrid(opts RealDebridOptions) *RealDebrid {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultRealDebridOptions.BaseURL
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultRealDebridOptions.Timeout
+	}
+	return &RealDebrid{
+		baseURL: strings.TrimSuffix(opts.BaseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+	}
+}
+
+type rdTorrentInfo struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Files  []struct {
+		ID       int    `json:"id"`
+		Path     string `json:"path"`
+		Bytes    int64  `json:"bytes"`
+		Selected int    `json:"selected"`
+	} `json:"files"`
+	Links []string `json:"links"`
+}
+
+// Resolve adds the torrent to the user's Real-Debrid account, selects the file and unrestricts its link.
+// Torrents that Real-Debrid doesn't have cached are removed from the account again and ErrNotCached is returned,
+// so listing streams doesn't fill up the user's download queue. The same goes for all other errors after adding the torrent.
+func (rd *RealDebrid) Resolve(ctx context.Context, apiKey, infoHash string, fileIndex int) (string, error) {
+	var added struct {
+		ID string `json:"id"`
+	}
+	form := url.Values{"magnet": {magnet.Magnet{InfoHash: infoHash}.String()}}
+	if err := rd.do(ctx, apiKey, http.MethodPost, "/torrents/addMagnet", form, &added); err != nil {
+		return "", fmt.Errorf("couldn't add magnet: %w", err)
+	}
+	resolved := false
+	defer func() {
+		if !resolved {
+			// Also when the request was canceled, as the torrent would stay in the account otherwise
+			rd.deleteTorrent(context.WithoutCancel(ctx), apiKey, added.ID)
+		}
+	}()
+
+	info, err := rd.torrentInfo(ctx, apiKey, added.ID)
+	if err != nil {
+		return "", err
+	}
+	if info.Status == "waiting_files_selection" {
+		// Real-Debrid's file IDs are 1-based and in the order of the torrent's files.
+		fileID := fileIndex + 1
+		found := false
+		for _, f := range info.Files {
+			if f.ID == fileID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("file index %v not in torrent", fileIndex)
+		}
+		form = url.Values{"files": {strconv.Itoa(fileID)}}
+		if err = rd.do(ctx, apiKey, http.MethodPost, "/torrents/selectFiles/"+added.ID, form, nil); err != nil {
+			return "", fmt.Errorf("couldn't select file: %w", err)
+		}
+		if info, err = rd.torrentInfo(ctx, apiKey, added.ID); err != nil {
+			return "", err
+		}
+	}
+	if info.Status != "downloaded" || len(info.Links) == 0 {
+		return "", ErrNotCached
+	}
+
+	var unrestricted struct {
+		Download string `json:"download"`
+	}
+	form = url.Values{"link": {info.Links[0]}}
+	if err = rd.do(ctx, apiKey, http.MethodPost, "/unrestrict/link", form, &unrestricted); err != nil {
+		return "", fmt.Errorf("couldn't unrestrict link: %w", err)
+	}
+	resolved = true
+	return unrestricted.Download, nil
+}
+
+func (rd *RealDebrid) torrentInfo(ctx context.Context, apiKey, id string) (rdTorrentInfo, error) {
+	var info rdTorrentInfo
+	if err := rd.do(ctx, apiKey, http.MethodGet, "/torrents/info/"+id, nil, &info); err != nil {
+		return rdTorrentInfo{}, fmt.Errorf("couldn't get torrent info: %w", err)
+	}
+	return info, nil
+}
+
+// deleteTorrent is best effort, as there's nothing the caller could do about an error.
+func (rd *RealDebrid) deleteTorrent(ctx context.Context, apiKey, id string) {
+	_ = rd.do(ctx, apiKey, http.MethodDelete, "/torrents/delete/"+id, nil, nil)
+}
+
+func (rd *RealDebrid) do(ctx context.Context, apiKey, method, path string, form url.Values, result any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rd.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	res, err := rd.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't %v %v: %w", method, path, err)
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return ErrBadAPIKey
+	case res.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case res.StatusCode >= 300:
+		return fmt.Errorf("bad %v response: %v", method, res.StatusCode)
+	case result == nil:
+		return nil
+	}
+	if err = json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+	return nil
+}
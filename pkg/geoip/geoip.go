@@ -0,0 +1,124 @@
+// Package geoip looks up the country of clients in a MaxMind DB (like GeoLite2-Country or GeoIP2-Country),
+// so stream handlers can hide geo-blocked streams from users who can't play them.
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+type countryKey struct{}
+
+// DB is a MaxMind country database.
+// It's safe for concurrent use.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// Open opens the MaxMind DB file at the given path, like "GeoLite2-Country.mmdb".
+// City databases work as well, as they contain the country too.
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open MaxMind DB: %w", err)
+	}
+	return &DB{reader: reader}, nil
+}
+
+// FromBytes creates a DB from the content of a MaxMind DB file, for example an embedded one.
+func FromBytes(data []byte) (*DB, error) {
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read MaxMind DB: %w", err)
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Close closes the DB. It must not be used afterwards.
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
+
+// Country returns the country of the IP as lowercase ISO 3166-1 alpha-3 code, like "deu",
+// which is the format of the CountryWhitelist in StreamBehaviorHints. It's empty if the IP isn't in the DB.
+func (db *DB) Country(ip net.IP) (string, error) {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := db.reader.Lookup(ip, &record); err != nil {
+		return "", fmt.Errorf("couldn't look up IP: %w", err)
+	}
+	return Alpha3(record.Country.ISOCode), nil
+}
+
+// Alpha3 converts an ISO 3166-1 alpha-2 country code (like "DE") to a lowercase alpha-3 code (like "deu").
+// It returns an empty string for unknown codes.
+func Alpha3(alpha2 string) string {
+	if alpha2 == "" {
+		return ""
+	}
+	region, err := language.ParseRegion(alpha2)
+	if err != nil || !region.IsCountry() {
+		return ""
+	}
+	return strings.ToLower(region.ISO3())
+}
+
+// Middleware returns a middleware that looks up the client's country and puts it into the context,
+// where stream handlers can get it with CountryFromContext.
+// The client IP is taken from Fiber, so if the addon runs behind a reverse proxy, set the ProxyHeader in the Fiber config.
+// Add it with `addon.AddMiddleware("/", geoip.Middleware(db, logger))`.
+func Middleware(db *DB, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ip := net.ParseIP(c.IP())
+		if ip == nil {
+			return c.Next()
+		}
+		country, err := db.Country(ip)
+		if err != nil {
+			logger.Warn("Couldn't look up country of client", zap.Error(err))
+			return c.Next()
+		}
+		if country != "" {
+			c.SetContext(WithCountry(c.Context(), country))
+		}
+		return c.Next()
+	}
+}
+
+// WithCountry returns a copy of the context with the country, which must be a lowercase ISO 3166-1 alpha-3 code.
+// It's useful for tests and custom lookups.
+func WithCountry(ctx context.Context, country string) context.Context {
+	return context.WithValue(ctx, countryKey{}, country)
+}
+
+// CountryFromContext returns the client's country that Middleware put into the context.
+// It's empty if the middleware isn't used or the country is unknown.
+func CountryFromContext(ctx context.Context) string {
+	country, _ := ctx.Value(countryKey{}).(string)
+	return country
+}
+
+// FilterStreams returns the streams that can be played in the country, which must be a lowercase ISO 3166-1 alpha-3 code.
+// Streams without a CountryWhitelist in their BehaviorHints are playable everywhere. If the country is unknown (empty), all streams are returned,
+// as it's better to show a stream that might not work than to hide it from a user who could play it.
+// The streams slice is filtered in place.
+func FilterStreams(streams []types.StreamItem, country string) []types.StreamItem {
+	if country == "" {
+		return streams
+	}
+	return slices.DeleteFunc(streams, func(s types.StreamItem) bool {
+		return len(s.BehaviorHints.CountryWhitelist) > 0 && !slices.Contains(s.BehaviorHints.CountryWhitelist, country)
+	})
+}
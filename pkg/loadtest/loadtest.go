@@ -0,0 +1,445 @@
+// Package loadtest generates realistic Stremio traffic against an addon and reports latency percentiles,
+// so performance changes can be compared across releases.
+//
+// Unlike generic load testing tools that request a single URL, it simulates what Stremio clients do:
+// They poll the manifest, page through catalogs and request the streams of several episodes at once.
+// It works with any addon, not just go-stremio ones. The "go-stremio loadtest" command runs it from the command line.
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Kind is the kind of a request.
+type Kind string
+
+const (
+	// Manifest requests, like Stremio sends when starting and for checking for addon updates.
+	Manifest Kind = "manifest"
+	// Catalog requests, including requests for further pages.
+	Catalog Kind = "catalog"
+	// Stream requests.
+	Stream Kind = "stream"
+)
+
+// Mix is the relative weight of the kinds of sessions that virtual users run.
+// For example with {Manifest: 1, Catalog: 2, Stream: 5} a stream session is five times as likely as a manifest session.
+type Mix struct {
+	Manifest int
+	Catalog  int
+	Stream   int
+}
+
+// Options are the options for a load test.
+type Options struct {
+	// Duration of the load test.
+	// Default 30 seconds.
+	Duration time.Duration
+	// Number of virtual users that run sessions concurrently.
+	// Default 50.
+	Concurrency int
+	// Max number of requests per second across all virtual users. 0 means no limit.
+	// Default 0.
+	Rate int
+	// Relative weight of the kinds of sessions.
+	// Default DefaultOptions.Mix.
+	Mix Mix
+	// Max number of pages that a catalog session requests.
+	// Further pages are only requested when the catalog supports the "skip" extra and the previous page wasn't empty.
+	// Default 3.
+	CatalogPages int
+	// Number of the "skip" extra for each page.
+	// Default 100.
+	PageSize int
+	// Number of concurrent stream requests in a stream session.
+	// For TV shows these are consecutive episodes, like when Stremio preloads the next episodes.
+	// Default 4.
+	StreamBurst int
+	// Sample IDs per type for stream requests, like "tt1254207" for "movie".
+	// For TV shows the IDs must not contain the season and episode, they're added for the stream bursts.
+	// Types of the manifest's stream resource without sample IDs aren't requested.
+	// Default DefaultOptions.SampleIDs.
+	SampleIDs map[string][]string
+	// User data for addons that require configuration, as it's used in the URL.
+	// Default empty.
+	UserData string
+	// HTTP client for the requests.
+	// Default a client with a 10 second timeout that keeps enough idle connections for the concurrency.
+	HTTPClient *http.Client
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Duration:     30 * time.Second,
+	Concurrency:  50,
+	Mix:          Mix{Manifest: 1, Catalog: 2, Stream: 5},
+	CatalogPages: 3,
+	PageSize:     100,
+	StreamBurst:  4,
+	SampleIDs: map[string][]string{
+		"movie":  {"tt1254207", "tt1727587"}, // Big Buck Bunny, Sintel
+		"series": {"tt0944947"},              // Game of Thrones
+	},
+}
+
+// Stats are the statistics of a set of requests.
+// Requests that failed or got a 5xx response count as errors. A 404 is a valid response for unknown IDs.
+type Stats struct {
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	Mean     time.Duration `json:"mean"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+}
+
+// Report is the result of a load test.
+type Report struct {
+	Duration time.Duration  `json:"duration"`
+	Total    Stats          `json:"total"`
+	Kinds    map[Kind]Stats `json:"kinds"`
+}
+
+// RPS returns the number of requests per second.
+func (r Report) RPS() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Total.Requests) / r.Duration.Seconds()
+}
+
+// String returns a human-readable report with one line per kind of request.
+func (r Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d requests in %v (%.1f/s), %d errors\n\n", r.Total.Requests, r.Duration.Round(time.Millisecond), r.RPS(), r.Total.Errors)
+	fmt.Fprintf(&sb, "%-8v  %8v  %6v  %9v  %9v  %9v  %9v  %9v\n", "kind", "requests", "errors", "mean", "p50", "p90", "p99", "max")
+	line := func(name string, s Stats) {
+		fmt.Fprintf(&sb, "%-8v  %8d  %6d  %9v  %9v  %9v  %9v  %9v\n", name, s.Requests, s.Errors,
+			s.Mean.Round(time.Microsecond), s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	for _, kind := range []Kind{Manifest, Catalog, Stream} {
+		if s, ok := r.Kinds[kind]; ok {
+			line(string(kind), s)
+		}
+	}
+	line("total", r.Total)
+	return sb.String()
+}
+
+// Run runs a load test against the addon at the base URL, which is the addon URL without "/manifest.json", like "https://example.com".
+// It first requests the manifest to find the catalogs and stream types. The error is only non-nil if that fails.
+// Cancelling the context ends the load test early.
+func Run(ctx context.Context, baseURL string, opts Options) (Report, error) {
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultOptions.Duration
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultOptions.Concurrency
+	}
+	if opts.Mix == (Mix{}) {
+		opts.Mix = DefaultOptions.Mix
+	}
+	if opts.CatalogPages <= 0 {
+		opts.CatalogPages = DefaultOptions.CatalogPages
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultOptions.PageSize
+	}
+	if opts.StreamBurst <= 0 {
+		opts.StreamBurst = DefaultOptions.StreamBurst
+	}
+	if opts.SampleIDs == nil {
+		opts.SampleIDs = DefaultOptions.SampleIDs
+	}
+	if opts.HTTPClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = opts.Concurrency
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	}
+
+	l := &loadTester{
+		opts:      opts,
+		baseURL:   strings.TrimSuffix(strings.TrimSuffix(baseURL, "/manifest.json"), "/"),
+		latencies: map[Kind][]time.Duration{},
+		errors:    map[Kind]int{},
+		lock:      &sync.Mutex{},
+	}
+	if opts.UserData != "" {
+		l.baseURL += "/" + opts.UserData
+	}
+	if err := l.loadManifest(ctx); err != nil {
+		return Report{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	if opts.Rate > 0 {
+		l.tokens = make(chan struct{})
+		go l.limit(ctx, opts.Rate)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				l.runSession(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+	return l.report(time.Since(start)), nil
+}
+
+type loadTester struct {
+	opts     Options
+	baseURL  string
+	catalogs []types.CatalogItem
+	// Stream types with sample IDs
+	streamTypes []string
+	// Fed by the rate limiter, nil without rate limit
+	tokens chan struct{}
+
+	latencies map[Kind][]time.Duration
+	errors    map[Kind]int
+	lock      *sync.Mutex
+}
+
+// manifestJSON accepts the resources in both forms, as objects and as plain names like "stream".
+type manifestJSON struct {
+	types.Manifest
+	Resources []json.RawMessage `json:"resources"`
+}
+
+func (l *loadTester) loadManifest(ctx context.Context) error {
+	status, body, err := l.get(ctx, "/manifest.json")
+	if err != nil {
+		return fmt.Errorf("couldn't get manifest: %w", err)
+	} else if status != http.StatusOK {
+		return fmt.Errorf("couldn't get manifest: status %v", status)
+	}
+	var m manifestJSON
+	if err = json.Unmarshal(body, &m); err != nil {
+		return fmt.Errorf("couldn't decode manifest: %w", err)
+	}
+	l.catalogs = m.Catalogs
+	for _, raw := range m.Resources {
+		var name string
+		if json.Unmarshal(raw, &name) == nil {
+			if name == "stream" {
+				l.addStreamTypes(m.Types)
+			}
+			continue
+		}
+		var resource types.ResourceItem
+		if json.Unmarshal(raw, &resource) == nil && resource.Name == "stream" {
+			if len(resource.Types) == 0 {
+				resource.Types = m.Types
+			}
+			l.addStreamTypes(resource.Types)
+		}
+	}
+	return nil
+}
+
+func (l *loadTester) addStreamTypes(streamTypes []string) {
+	for _, t := range streamTypes {
+		if len(l.opts.SampleIDs[t]) > 0 && !slices.Contains(l.streamTypes, t) {
+			l.streamTypes = append(l.streamTypes, t)
+		}
+	}
+}
+
+// limit hands out one token per request, at the rate.
+func (l *loadTester) limit(ctx context.Context, rate int) {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case l.tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (l *loadTester) runSession(ctx context.Context) {
+	mix := l.opts.Mix
+	if len(l.catalogs) == 0 {
+		mix.Catalog = 0
+	}
+	if len(l.streamTypes) == 0 {
+		mix.Stream = 0
+	}
+	total := mix.Manifest + mix.Catalog + mix.Stream
+	if total <= 0 {
+		mix, total = Mix{Manifest: 1}, 1
+	}
+	switch n := rand.IntN(total); {
+	case n < mix.Manifest:
+		l.request(ctx, Manifest, "/manifest.json")
+	case n < mix.Manifest+mix.Catalog:
+		l.catalogSession(ctx)
+	default:
+		l.streamSession(ctx)
+	}
+}
+
+// catalogSession requests the pages of a random catalog, like when scrolling through it.
+func (l *loadTester) catalogSession(ctx context.Context) {
+	catalog := l.catalogs[rand.IntN(len(l.catalogs))]
+	basePath := "/catalog/" + url.PathEscape(catalog.Type) + "/" + url.PathEscape(catalog.ID)
+	pages := 1
+	if slices.ContainsFunc(catalog.Extra, func(extra types.ExtraItem) bool { return extra.Name == "skip" }) {
+		pages = l.opts.CatalogPages
+	}
+	for page := range pages {
+		path := basePath + ".json"
+		if page > 0 {
+			path = basePath + "/skip=" + strconv.Itoa(page*l.opts.PageSize) + ".json"
+		}
+		body, ok := l.request(ctx, Catalog, path)
+		var res struct {
+			Metas []json.RawMessage `json:"metas"`
+		}
+		if !ok || json.Unmarshal(body, &res) != nil || len(res.Metas) == 0 {
+			return
+		}
+	}
+}
+
+// streamSession requests streams for a random sample ID concurrently, for TV shows for consecutive episodes.
+func (l *loadTester) streamSession(ctx context.Context) {
+	t := l.streamTypes[rand.IntN(len(l.streamTypes))]
+	ids := l.opts.SampleIDs[t]
+	id := ids[rand.IntN(len(ids))]
+	var wg sync.WaitGroup
+	for i := range l.opts.StreamBurst {
+		burstID := id
+		if t == "series" {
+			burstID += ":1:" + strconv.Itoa(i+1)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.request(ctx, Stream, "/stream/"+url.PathEscape(t)+"/"+url.PathEscape(burstID)+".json")
+		}()
+	}
+	wg.Wait()
+}
+
+// request requests the path and records its latency. The bool return value signals a 200 response.
+func (l *loadTester) request(ctx context.Context, kind Kind, path string) ([]byte, bool) {
+	if l.tokens != nil {
+		select {
+		case <-l.tokens:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	start := time.Now()
+	status, body, err := l.get(ctx, path)
+	latency := time.Since(start)
+	// Requests that were cancelled because the load test ended don't count
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.latencies[kind] = append(l.latencies[kind], latency)
+	if err != nil || status >= http.StatusInternalServerError {
+		l.errors[kind]++
+	}
+	return body, err == nil && status == http.StatusOK
+}
+
+func (l *loadTester) get(ctx context.Context, path string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+path, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := l.opts.HTTPClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return res.StatusCode, body, err
+}
+
+func (l *loadTester) report(duration time.Duration) Report {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	report := Report{
+		Duration: duration,
+		Kinds:    map[Kind]Stats{},
+	}
+	var all []time.Duration
+	for kind, latencies := range l.latencies {
+		report.Kinds[kind] = newStats(latencies, l.errors[kind])
+		all = append(all, latencies...)
+		report.Total.Errors += l.errors[kind]
+	}
+	report.Total = newStats(all, report.Total.Errors)
+	return report
+}
+
+func newStats(latencies []time.Duration, errs int) Stats {
+	stats := Stats{Requests: len(latencies), Errors: errs}
+	if len(latencies) == 0 {
+		return stats
+	}
+	slices.Sort(latencies)
+	var sum time.Duration
+	for _, latency := range latencies {
+		sum += latency
+	}
+	stats.Mean = sum / time.Duration(len(latencies))
+	stats.P50 = percentile(latencies, 50)
+	stats.P90 = percentile(latencies, 90)
+	stats.P99 = percentile(latencies, 99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+// ErrNoRequests signals that a report can't be compared, because it doesn't contain any requests.
+var ErrNoRequests = errors.New("no requests")
+
+// Compare returns the relative change of the p99 latency and the error rate from a baseline report to a report,
+// like 0.1 for a 10% higher p99 latency. It's meant for comparing releases, for example in CI.
+func Compare(baseline, report Report) (p99Change float64, errorRateChange float64, err error) {
+	if baseline.Total.Requests == 0 || report.Total.Requests == 0 || baseline.Total.P99 == 0 {
+		return 0, 0, ErrNoRequests
+	}
+	p99Change = float64(report.Total.P99-baseline.Total.P99) / float64(baseline.Total.P99)
+	errorRateChange = float64(report.Total.Errors)/float64(report.Total.Requests) - float64(baseline.Total.Errors)/float64(baseline.Total.Requests)
+	return p99Change, errorRateChange, nil
+}
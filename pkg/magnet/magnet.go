@@ -0,0 +1,158 @@
+// Package magnet converts between magnet links and the torrent fields of a StreamItem
+// (InfoHash and Sources), which torrent addons constantly need to do.
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+const (
+	btihPrefix    = "urn:btih:"
+	trackerPrefix = "tracker:"
+	dhtPrefix     = "dht:"
+)
+
+// ErrNoInfoHash signals that a magnet link or stream doesn't contain a BitTorrent info hash.
+var ErrNoInfoHash = errors.New("no BitTorrent info hash")
+
+// Magnet is a parsed magnet link.
+type Magnet struct {
+	// Lowercase hex encoded BitTorrent info hash, as Stremio expects it.
+	InfoHash string
+	// Display name ("dn" parameter).
+	Name string
+	// Tracker URLs ("tr" parameters).
+	Trackers []string
+	// Exact length in bytes ("xl" parameter), 0 if unknown.
+	// For torrents with multiple files it's the total size, not the size of the video.
+	Size int64
+}
+
+// Parse parses a magnet link.
+// Base32 encoded info hashes are converted to hex.
+func Parse(uri string) (Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Magnet{}, fmt.Errorf("couldn't parse magnet link: %w", err)
+	}
+	if u.Scheme != "magnet" {
+		return Magnet{}, fmt.Errorf("not a magnet link: scheme is %q", u.Scheme)
+	}
+	query := u.Query()
+
+	var m Magnet
+	for _, xt := range query["xt"] {
+		if !strings.HasPrefix(strings.ToLower(xt), btihPrefix) {
+			continue
+		}
+		if m.InfoHash, err = normalizeInfoHash(xt[len(btihPrefix):]); err != nil {
+			return Magnet{}, err
+		}
+		break
+	}
+	if m.InfoHash == "" {
+		return Magnet{}, ErrNoInfoHash
+	}
+	m.Name = query.Get("dn")
+	m.Trackers = query["tr"]
+	if xl := query.Get("xl"); xl != "" {
+		// The size is only informational, so a bad value isn't worth failing for.
+		m.Size, _ = strconv.ParseInt(xl, 10, 64)
+	}
+	return m, nil
+}
+
+func normalizeInfoHash(infoHash string) (string, error) {
+	switch len(infoHash) {
+	case 40:
+		if _, err := hex.DecodeString(infoHash); err != nil {
+			return "", fmt.Errorf("invalid hex info hash: %w", err)
+		}
+		return strings.ToLower(infoHash), nil
+	case 32:
+		b, err := base32.StdEncoding.DecodeString(strings.ToUpper(infoHash))
+		if err != nil {
+			return "", fmt.Errorf("invalid base32 info hash: %w", err)
+		}
+		return hex.EncodeToString(b), nil
+	}
+	return "", fmt.Errorf("invalid info hash length: %v", len(infoHash))
+}
+
+// String returns the magnet link.
+func (m Magnet) String() string {
+	var sb strings.Builder
+	sb.WriteString("magnet:?xt=urn:btih:")
+	sb.WriteString(m.InfoHash)
+	if m.Name != "" {
+		sb.WriteString("&dn=")
+		sb.WriteString(url.QueryEscape(m.Name))
+	}
+	if m.Size != 0 {
+		sb.WriteString("&xl=")
+		sb.WriteString(strconv.FormatInt(m.Size, 10))
+	}
+	for _, tr := range m.Trackers {
+		sb.WriteString("&tr=")
+		sb.WriteString(url.QueryEscape(tr))
+	}
+	return sb.String()
+}
+
+// Stream returns a torrent StreamItem with the info hash and the trackers as sources.
+// The DHT is added as source as well, so Stremio can find peers when all trackers are down.
+// Name, title etc. are left for the caller to set, for example with the StreamItem helpers.
+// The video size isn't set either, because the magnet's size is the size of the whole torrent, which only equals
+// the video size for single file torrents. Set BehaviorHints.VideoSize when you know the size of the video file.
+func (m Magnet) Stream() types.StreamItem {
+	sources := make([]string, 0, len(m.Trackers)+1)
+	for _, tr := range m.Trackers {
+		sources = append(sources, trackerPrefix+tr)
+	}
+	sources = append(sources, dhtPrefix+m.InfoHash)
+
+	return types.StreamItem{
+		InfoHash: m.InfoHash,
+		Sources:  sources,
+	}
+}
+
+// FromStream returns the magnet for a torrent StreamItem, with the trackers taken from its sources.
+// It returns ErrNoInfoHash if the stream isn't a torrent stream.
+func FromStream(s types.StreamItem) (Magnet, error) {
+	if s.InfoHash == "" {
+		return Magnet{}, ErrNoInfoHash
+	}
+	infoHash, err := normalizeInfoHash(s.InfoHash)
+	if err != nil {
+		return Magnet{}, err
+	}
+	m := Magnet{
+		InfoHash: infoHash,
+		Name:     s.BehaviorHints.Filename,
+	}
+	for _, source := range s.Sources {
+		if tr, ok := strings.CutPrefix(source, trackerPrefix); ok {
+			m.Trackers = append(m.Trackers, tr)
+		}
+	}
+	return m, nil
+}
+
+// Build returns a magnet link for the info hash and Stremio stream sources (like "tracker:udp://...").
+// Sources without the "tracker:" prefix (like "dht:...") are skipped.
+func Build(infoHash string, sources []string) (string, error) {
+	m, err := FromStream(types.StreamItem{InfoHash: infoHash, Sources: sources})
+	if err != nil {
+		return "", err
+	}
+	return m.String(), nil
+}
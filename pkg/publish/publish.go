@@ -0,0 +1,142 @@
+// Package publish submits an addon to Stremio's community addon collection,
+// like the publishToCentral function of the official Node.js SDK, so addons can be published programmatically on deploy.
+// Before submitting, it checks that the URL is public and that the addon works, because the collection doesn't reject broken addons.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/addoncheck"
+)
+
+var (
+	// ErrInvalidURL signals that the manifest URL can't be published, like an HTTP or localhost URL.
+	ErrInvalidURL = errors.New("invalid manifest URL")
+	// ErrCheckFailed signals that the addon didn't pass the checks of the addoncheck package.
+	ErrCheckFailed = errors.New("addon check failed")
+)
+
+// Options are the options for publishing.
+type Options struct {
+	// URL of Stremio's API.
+	// Default "https://api.strem.io/api/".
+	APIURL string
+	// Options for checking the addon before publishing, like sample IDs and user data.
+	// Default empty, meaning addoncheck's defaults.
+	CheckOptions addoncheck.Options
+	// Flag for indicating whether to skip checking the addon before publishing.
+	// Default false.
+	SkipCheck bool
+	// Flag for indicating whether to skip validating that the URL is a public HTTPS URL.
+	// Only meant for testing, as Stremio clients can't install addons from such URLs anyway.
+	// Default false.
+	SkipURLValidation bool
+	// HTTP client for the request to the API.
+	// Default a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	APIURL: "https://api.strem.io/api/",
+}
+
+// Publish validates the manifest URL, like "https://example.com/manifest.json", checks the addon and then submits it to Stremio's addon collection.
+// It returns an error wrapping ErrInvalidURL or ErrCheckFailed if the validation or the check failed,
+// and the check's report so the reason can be shown.
+func Publish(ctx context.Context, manifestURL string, opts Options) (addoncheck.Report, error) {
+	if opts.APIURL == "" {
+		opts.APIURL = DefaultOptions.APIURL
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if !opts.SkipURLValidation {
+		if err := ValidateURL(manifestURL); err != nil {
+			return addoncheck.Report{}, err
+		}
+	}
+	var report addoncheck.Report
+	if !opts.SkipCheck {
+		checkOpts := opts.CheckOptions
+		if checkOpts.HTTPClient == nil {
+			checkOpts.HTTPClient = opts.HTTPClient
+		}
+		report = addoncheck.Check(ctx, manifestURL, checkOpts)
+		if !report.OK() {
+			return report, ErrCheckFailed
+		}
+	}
+
+	reqBody, err := json.Marshal(map[string]string{
+		"transportUrl":  manifestURL,
+		"transportName": "http",
+	})
+	if err != nil {
+		return report, err
+	}
+	reqURL := strings.TrimSuffix(opts.APIURL, "/") + "/addonPublish"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return report, fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return report, fmt.Errorf("couldn't POST %v: %w", reqURL, err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return report, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	// The API responds with {"result": ...} or {"error": ...}, and not necessarily with an error status code.
+	var apiRes struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err = json.Unmarshal(resBody, &apiRes); err != nil {
+		return report, fmt.Errorf("couldn't unmarshal response body (status %v): %w", res.StatusCode, err)
+	}
+	if len(apiRes.Error) > 0 && string(apiRes.Error) != "null" {
+		return report, fmt.Errorf("API error: %s", apiRes.Error)
+	} else if res.StatusCode != http.StatusOK {
+		return report, fmt.Errorf("bad response status: %v", res.StatusCode)
+	}
+	return report, nil
+}
+
+// ValidateURL checks that the manifest URL can be installed by any Stremio client:
+// It must be an HTTPS URL of a public host, ending in "/manifest.json".
+func ValidateURL(manifestURL string) error {
+	u, err := url.Parse(manifestURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	switch {
+	case u.Scheme != "https":
+		return fmt.Errorf("%w: must use HTTPS", ErrInvalidURL)
+	case !strings.HasSuffix(u.Path, "/manifest.json"):
+		return fmt.Errorf("%w: must end with \"/manifest.json\"", ErrInvalidURL)
+	case u.RawQuery != "" || u.Fragment != "":
+		return fmt.Errorf("%w: must not contain a query or fragment", ErrInvalidURL)
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return fmt.Errorf("%w: host %v isn't public", ErrInvalidURL, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		return fmt.Errorf("%w: IP %v isn't public", ErrInvalidURL, ip)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package recording records requests to an addon and the addon's responses, and replays them against another addon instance.
+// go-stremio records with the RecordFile option, and the "go-stremio replay" command replays recordings.
+// This helps with debugging reports from Stremio users that can't be reproduced otherwise.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces sanitized values.
+const Redacted = "REDACTED"
+
+// MaxBodySize is the max size of recorded response bodies. Larger bodies are truncated.
+const MaxBodySize = 1 << 20
+
+// sensitiveHeaders are removed from recordings.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Real-Ip",
+}
+
+// resources are the path segments that follow the user data in Stremio's routes.
+var resources = []string{"manifest.json", "catalog", "stream", "meta", "subtitles", "configure"}
+
+// Entry is a recorded request and its response.
+type Entry struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Method   string        `json:"method"`
+	// Path including the query string.
+	Path           string      `json:"path"`
+	Header         http.Header `json:"header,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+	// Whether the response body was truncated to MaxBodySize or wasn't recorded at all, like for streamed bodies.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// RecorderOptions are the options of a Recorder.
+type RecorderOptions struct {
+	// Flag for indicating whether to keep the user data in the recorded paths.
+	// User data can contain credentials like API keys, so it's replaced by Redacted by default.
+	// But with redacted user data replaying requests of configured addons only leads to errors.
+	// Default false.
+	KeepUserData bool
+}
+
+// Recorder appends entries to a file in the JSON Lines format. It's safe for concurrent use.
+type Recorder struct {
+	file *os.File
+	enc  *json.Encoder
+	lock *sync.Mutex
+	opts RecorderOptions
+}
+
+// NewRecorder creates a Recorder that appends to the file, which is created if it doesn't exist.
+func NewRecorder(path string, opts RecorderOptions) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open recording file: %w", err)
+	}
+	return &Recorder{
+		file: f,
+		enc:  json.NewEncoder(f),
+		lock: &sync.Mutex{},
+		opts: opts,
+	}, nil
+}
+
+// Record sanitizes the entry and appends it to the file.
+func (r *Recorder) Record(entry Entry) error {
+	entry.Header = sanitizeHeader(entry.Header)
+	entry.ResponseHeader = sanitizeHeader(entry.ResponseHeader)
+	if !r.opts.KeepUserData {
+		entry.Path = RedactUserData(entry.Path)
+	}
+	if len(entry.ResponseBody) > MaxBodySize {
+		entry.ResponseBody = entry.ResponseBody[:MaxBodySize]
+		entry.Truncated = true
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.enc.Encode(entry)
+}
+
+// Close closes the file.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
+
+// RedactUserData replaces the user data in a path of one of Stremio's routes, like "/abc/stream/movie/tt1254207.json".
+func RedactUserData(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return path
+	}
+	for _, resource := range resources {
+		if parts[1] == resource || strings.HasPrefix(parts[1], resource+"?") {
+			parts[0] = Redacted
+			return "/" + strings.Join(parts, "/")
+		}
+	}
+	return path
+}
+
+func sanitizeHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	header = header.Clone()
+	for _, key := range sensitiveHeaders {
+		header.Del(key)
+	}
+	return header
+}
+
+// ReadFile reads all entries of a recording file.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads all entries from a reader in the JSON Lines format.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var entry Entry
+		if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+			return entries, nil
+		} else if err != nil {
+			return entries, fmt.Errorf("couldn't decode entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Result is the result of a replayed entry.
+type Result struct {
+	Entry  Entry  `json:"entry"`
+	Status int    `json:"status"`
+	Body   string `json:"body,omitempty"`
+	// Err is set when the request failed, for example when the addon isn't reachable.
+	Err error `json:"-"`
+	// Whether the status and body are the same as in the recording.
+	// JSON bodies are compared semantically, so the order of object keys doesn't matter.
+	// Truncated bodies are only compared by status.
+	Match bool `json:"match"`
+}
+
+// Replay re-issues the requests of the entries against the addon at baseURL, in order, and compares the responses.
+// Entries with redacted user data are replayed as they are, so they'll typically lead to a different response.
+// When the HTTP client is nil, http.DefaultClient is used.
+func Replay(ctx context.Context, baseURL string, entries []Entry, client *http.Client) []Result {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	results := make([]Result, 0, len(entries))
+	for _, entry := range entries {
+		result := Result{Entry: entry}
+		result.Status, result.Body, result.Err = replay(ctx, client, baseURL, entry)
+		if result.Err == nil {
+			result.Match = result.Status == entry.Status && (entry.Truncated || equalBodies(entry.ResponseBody, result.Body))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func replay(ctx context.Context, client *http.Client, baseURL string, entry Entry) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, entry.Method, baseURL+entry.Path, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	for key, values := range entry.Header {
+		// Hop-by-hop and transport headers are set by the client
+		if key == "Host" || key == "Content-Length" || key == "Connection" || key == "Accept-Encoding" {
+			continue
+		}
+		req.Header[key] = values
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxBodySize))
+	if err != nil {
+		return res.StatusCode, "", err
+	}
+	return res.StatusCode, string(body), nil
+}
+
+func equalBodies(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var aJSON, bJSON any
+	if json.Unmarshal([]byte(a), &aJSON) != nil || json.Unmarshal([]byte(b), &bJSON) != nil {
+		return bytes.Equal(bytes.TrimSpace([]byte(a)), bytes.TrimSpace([]byte(b)))
+	}
+	return reflect.DeepEqual(aJSON, bJSON)
+}
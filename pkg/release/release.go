@@ -0,0 +1,253 @@
+// Package release parses torrent and file names of scene/P2P releases,
+// like "Big.Buck.Bunny.2008.2160p.BluRay.HDR.x265-GROUP.mkv", into typed info.
+// The info can be used for sorting and filtering streams and for building stream titles.
+package release
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sources as returned in Info.Source.
+const (
+	SourceBluRay  = "BluRay"
+	SourceWEBDL   = "WEB-DL"
+	SourceWEBRip  = "WEBRip"
+	SourceHDTV    = "HDTV"
+	SourceDVD     = "DVD"
+	SourceTS      = "TS"
+	SourceCAM     = "CAM"
+	SourceUnknown = ""
+)
+
+// Codecs as returned in Info.Codec.
+const (
+	CodecH264 = "H.264"
+	CodecH265 = "H.265"
+	CodecAV1  = "AV1"
+	CodecVP9  = "VP9"
+	CodecXviD = "XviD"
+)
+
+// HDR formats as returned in Info.HDR.
+const (
+	HDRDolbyVision = "DV"
+	HDR10Plus      = "HDR10+"
+	HDR10          = "HDR10"
+	HDRGeneric     = "HDR"
+	HDRHLG         = "HLG"
+)
+
+// Info is the parsed info of a release name.
+// Fields are the zero value when they couldn't be found in the name.
+type Info struct {
+	Title      string
+	Year       int
+	Season     int
+	Episode    int
+	Resolution int    // Vertical resolution, like 1080
+	Source     string // One of the Source* constants
+	Remux      bool
+	Codec      string   // One of the Codec* constants
+	HDR        []string // HDR* constants, like ["DV", "HDR10"]
+	BitDepth   int      // Like 10 for "10bit"
+	Group      string
+}
+
+// Quality returns a short description of the quality, like "2160p BluRay DV HDR10 H.265".
+// It's meant for stream names and titles.
+func (i Info) Quality() string {
+	var parts []string
+	if i.Resolution != 0 {
+		parts = append(parts, strconv.Itoa(i.Resolution)+"p")
+	}
+	if i.Source != SourceUnknown {
+		source := i.Source
+		if i.Remux {
+			source += " Remux"
+		}
+		parts = append(parts, source)
+	}
+	parts = append(parts, i.HDR...)
+	if i.Codec != "" {
+		parts = append(parts, i.Codec)
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	extensionRegex   = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|mov|wmv|ts|webm|torrent)$`)
+	leadingTagRegex  = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+	yearRegex        = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+	episodeRegex     = regexp.MustCompile(`(?i)\bS(\d{1,2})[ .]?E(\d{1,4})\b|\b(\d{1,2})x(\d{2,3})\b`)
+	seasonRegex      = regexp.MustCompile(`(?i)\b(?:S|Season[ .]?)(\d{1,2})\b`)
+	resolutionRegex  = regexp.MustCompile(`(?i)\b(?:(4320|2160|1440|1080|720|576|480|360|240)[pi]|(8k|4k|uhd|2k|qhd|fhd|hd))\b`)
+	remuxRegex       = regexp.MustCompile(`(?i)\b(?:bd)?remux\b`)
+	bitDepthRegex    = regexp.MustCompile(`(?i)\b(8|10|12)[ .-]?bits?\b`)
+	groupRegex       = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+	separatorReplace = strings.NewReplacer(".", " ", "_", " ")
+)
+
+var resolutionNames = map[string]int{
+	"8k":  4320,
+	"4k":  2160,
+	"uhd": 2160,
+	"2k":  1440,
+	"qhd": 1440,
+	"fhd": 1080,
+	"hd":  720,
+}
+
+// The first match wins, so more specific patterns come first.
+var sourcePatterns = []struct {
+	regex  *regexp.Regexp
+	source string
+}{
+	{regexp.MustCompile(`(?i)\b(?:blu[ .-]?ray|bd[ .-]?rip|br[ .-]?rip|bdremux|bd25|bd50)\b`), SourceBluRay},
+	{regexp.MustCompile(`(?i)\bweb[ .-]?rip\b`), SourceWEBRip},
+	{regexp.MustCompile(`(?i)\b(?:web[ .-]?dl|web)\b`), SourceWEBDL},
+	{regexp.MustCompile(`(?i)\b(?:hdtv|pdtv|dsr|tv[ .-]?rip)\b`), SourceHDTV},
+	{regexp.MustCompile(`(?i)\b(?:dvd[ .-]?rip|dvd[ .-]?r|dvd5|dvd9|dvd)\b`), SourceDVD},
+	{regexp.MustCompile(`(?i)\b(?:hd[ .-]?ts|telesync|ts|pdvd)\b`), SourceTS},
+	{regexp.MustCompile(`(?i)\b(?:hd[ .-]?cam|cam[ .-]?rip|cam)\b`), SourceCAM},
+}
+
+var codecPatterns = []struct {
+	regex *regexp.Regexp
+	codec string
+}{
+	{regexp.MustCompile(`(?i)\b(?:x265|h[ .]?265|hevc)\b`), CodecH265},
+	{regexp.MustCompile(`(?i)\b(?:x264|h[ .]?264|avc)\b`), CodecH264},
+	{regexp.MustCompile(`(?i)\bav1\b`), CodecAV1},
+	{regexp.MustCompile(`(?i)\bvp9\b`), CodecVP9},
+	{regexp.MustCompile(`(?i)\b(?:xvid|divx)\b`), CodecXviD},
+}
+
+// Order matters for the result and for "HDR10+" not also counting as "HDR10".
+var hdrPatterns = []struct {
+	regex *regexp.Regexp
+	hdr   string
+}{
+	{regexp.MustCompile(`(?i)\b(?:dv|dovi|dolby[ .]?vision)\b`), HDRDolbyVision},
+	{regexp.MustCompile(`(?i)\bhdr10(?:\+|plus)`), HDR10Plus},
+	{regexp.MustCompile(`(?i)\bhdr10\b(?:[^+]|$)`), HDR10},
+	{regexp.MustCompile(`(?i)\bhlg\b`), HDRHLG},
+}
+
+var genericHDRRegex = regexp.MustCompile(`(?i)\bhdr\b`)
+
+// Parse parses a release or file name.
+// It never fails, but the less the name follows the usual naming scheme, the fewer fields are set.
+func Parse(name string) Info {
+	var info Info
+
+	name = strings.TrimSpace(extensionRegex.ReplaceAllString(strings.TrimSpace(name), ""))
+	// Anime releases usually start with the group in brackets
+	if match := leadingTagRegex.FindStringSubmatch(name); match != nil {
+		info.Group = match[1]
+		name = name[len(match[0]):]
+	}
+	if info.Group == "" {
+		if match := groupRegex.FindStringSubmatch(name); match != nil {
+			info.Group = match[1]
+			name = strings.TrimSuffix(name, match[0])
+		}
+	}
+	normalized := separatorReplace.Replace(name)
+
+	// The title ends where the first technical info starts.
+	titleEnd := len(normalized)
+	updateTitleEnd := func(loc []int) {
+		// A match at the very beginning is probably part of the title, like the year in "2001 A Space Odyssey".
+		if loc != nil && loc[0] > 0 && loc[0] < titleEnd {
+			titleEnd = loc[0]
+		}
+	}
+
+	if loc := episodeRegex.FindStringSubmatchIndex(normalized); loc != nil {
+		match := episodeRegex.FindStringSubmatch(normalized)
+		if match[1] != "" {
+			info.Season, _ = strconv.Atoi(match[1])
+			info.Episode, _ = strconv.Atoi(match[2])
+		} else {
+			info.Season, _ = strconv.Atoi(match[3])
+			info.Episode, _ = strconv.Atoi(match[4])
+		}
+		updateTitleEnd(loc)
+	} else if loc := seasonRegex.FindStringSubmatchIndex(normalized); loc != nil {
+		info.Season, _ = strconv.Atoi(normalized[loc[2]:loc[3]])
+		updateTitleEnd(loc)
+	}
+
+	for _, loc := range yearRegex.FindAllStringSubmatchIndex(normalized, -1) {
+		if loc[0] == 0 {
+			continue
+		}
+		info.Year, _ = strconv.Atoi(normalized[loc[2]:loc[3]])
+		updateTitleEnd(loc)
+		break
+	}
+
+	if loc := resolutionRegex.FindStringSubmatchIndex(normalized); loc != nil {
+		info.Resolution = ParseResolution(normalized[loc[0]:loc[1]])
+		updateTitleEnd(loc)
+	}
+
+	for _, p := range sourcePatterns {
+		if loc := p.regex.FindStringIndex(normalized); loc != nil {
+			info.Source = p.source
+			updateTitleEnd(loc)
+			break
+		}
+	}
+	if loc := remuxRegex.FindStringIndex(normalized); loc != nil {
+		info.Remux = true
+		if info.Source == SourceUnknown {
+			info.Source = SourceBluRay
+		}
+		updateTitleEnd(loc)
+	}
+
+	for _, p := range codecPatterns {
+		if loc := p.regex.FindStringIndex(normalized); loc != nil {
+			info.Codec = p.codec
+			updateTitleEnd(loc)
+			break
+		}
+	}
+
+	for _, p := range hdrPatterns {
+		if loc := p.regex.FindStringIndex(normalized); loc != nil {
+			info.HDR = append(info.HDR, p.hdr)
+			updateTitleEnd(loc)
+		}
+	}
+	if loc := genericHDRRegex.FindStringIndex(normalized); loc != nil {
+		if len(info.HDR) == 0 || (len(info.HDR) == 1 && info.HDR[0] == HDRDolbyVision) {
+			info.HDR = append(info.HDR, HDRGeneric)
+		}
+		updateTitleEnd(loc)
+	}
+
+	if match := bitDepthRegex.FindStringSubmatch(normalized); match != nil {
+		info.BitDepth, _ = strconv.Atoi(match[1])
+	}
+
+	info.Title = strings.Join(strings.Fields(strings.Trim(normalized[:titleEnd], " -([")), " ")
+	return info
+}
+
+// ParseResolution returns the vertical resolution (like 1080) for the first value like "1080p" or "4K" in the text, or 0 if there's none.
+func ParseResolution(text string) int {
+	match := resolutionRegex.FindStringSubmatch(text)
+	switch {
+	case match == nil:
+		return 0
+	case match[1] != "":
+		res, _ := strconv.Atoi(match[1])
+		return res
+	default:
+		return resolutionNames[strings.ToLower(match[2])]
+	}
+}
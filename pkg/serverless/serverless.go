@@ -0,0 +1,219 @@
+// Package serverless exposes go-stremio addons as serverless functions, like AWS Lambda behind API Gateway or a function URL,
+// and Google Cloud Functions. It bypasses Addon.Run, so there's no listener, signal handling or lifecycle hooks like OnStart,
+// which suits stateless addons. State that should outlive a single invocation must be kept in a store, see the store package.
+//
+// AWS Lambda example, with the aws-lambda-go package:
+//
+//	func main() {
+//		addon, _ := stremio.NewAddon(manifest, catalogHandlers, streamHandlers, nil, nil, stremio.Options{})
+//		lambda.Start(serverless.NewLambdaHandler(addon, nil))
+//	}
+//
+// Google Cloud Functions example, with the functions-framework-go package:
+//
+//	func init() {
+//		addon, _ := stremio.NewAddon(manifest, catalogHandlers, streamHandlers, nil, nil, stremio.Options{})
+//		functions.HTTP("Addon", serverless.NewHTTPHandler(addon, nil).ServeHTTP)
+//	}
+package serverless
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/valyala/fasthttp"
+	"github.com/xybydy/go-stremio"
+)
+
+// NewHTTPHandler returns a net/http handler for the addon, for platforms that take one, like Google Cloud Functions.
+// The Fiber config can be nil.
+func NewHTTPHandler(addon *stremio.Addon, fiberConf *fiber.Config) http.Handler {
+	return adaptor.FiberApp(addon.App(fiberConf))
+}
+
+// LambdaEvent is an AWS API Gateway proxy event. It covers the payload format 1.0 of REST APIs
+// as well as the format 2.0 of HTTP APIs and Lambda function URLs, so it's compatible with
+// events.APIGatewayProxyRequest and events.APIGatewayV2HTTPRequest of the aws-lambda-go package.
+type LambdaEvent struct {
+	// "2.0" for the payload format 2.0, empty for 1.0
+	Version string `json:"version"`
+
+	// Payload format 1.0
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+
+	// Payload format 2.0
+	RawPath        string   `json:"rawPath"`
+	RawQueryString string   `json:"rawQueryString"`
+	Cookies        []string `json:"cookies"`
+
+	// Both payload formats. With 1.0 the multi-value fields take precedence.
+	Headers               map[string]string  `json:"headers"`
+	QueryStringParameters map[string]string  `json:"queryStringParameters"`
+	RequestContext        LambdaEventContext `json:"requestContext"`
+	Body                  string             `json:"body"`
+	IsBase64Encoded       bool               `json:"isBase64Encoded"`
+}
+
+// LambdaEventContext is the request context of a LambdaEvent, with the fields that are relevant for the addon.
+type LambdaEventContext struct {
+	// Payload format 1.0
+	Identity struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"`
+	// Payload format 2.0
+	HTTP struct {
+		Method   string `json:"method"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"`
+}
+
+// LambdaResponse is the response to a LambdaEvent, in the payload format of the event.
+// It's compatible with events.APIGatewayProxyResponse and events.APIGatewayV2HTTPResponse of the aws-lambda-go package.
+type LambdaResponse struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// Payload format 1.0
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	// Payload format 2.0
+	Cookies         []string `json:"cookies,omitempty"`
+	Body            string   `json:"body"`
+	IsBase64Encoded bool     `json:"isBase64Encoded"`
+}
+
+// LambdaHandler handles the events of an AWS Lambda function. Pass it to lambda.Start of the aws-lambda-go package.
+type LambdaHandler func(ctx context.Context, event LambdaEvent) (LambdaResponse, error)
+
+// NewLambdaHandler returns an AWS Lambda handler for the addon, for API Gateway REST and HTTP APIs and Lambda function URLs.
+// The Fiber config can be nil. The app is created once, so warm invocations reuse it.
+func NewLambdaHandler(addon *stremio.Addon, fiberConf *fiber.Config) LambdaHandler {
+	handler := addon.App(fiberConf).Handler()
+	return func(_ context.Context, event LambdaEvent) (LambdaResponse, error) {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		if err := event.toRequest(req); err != nil {
+			return LambdaResponse{}, err
+		}
+		var remoteAddr net.TCPAddr
+		if ip := net.ParseIP(event.sourceIP()); ip != nil {
+			remoteAddr.IP = ip
+		}
+		var fctx fasthttp.RequestCtx
+		fctx.Init(req, &remoteAddr, nil)
+		handler(&fctx)
+		return event.toResponse(&fctx.Response), nil
+	}
+}
+
+func (e LambdaEvent) isV2() bool {
+	return e.Version == "2.0"
+}
+
+func (e LambdaEvent) sourceIP() string {
+	if e.isV2() {
+		return e.RequestContext.HTTP.SourceIP
+	}
+	return e.RequestContext.Identity.SourceIP
+}
+
+func (e LambdaEvent) toRequest(req *fasthttp.Request) error {
+	var method, uri, query string
+	if e.isV2() {
+		method, uri, query = e.RequestContext.HTTP.Method, e.RawPath, e.RawQueryString
+	} else {
+		method = e.HTTPMethod
+		// Unlike the raw path of the payload format 2.0 the path is unescaped,
+		// but the user data and IDs in it can contain characters like "?" when escaped.
+		uri = (&url.URL{Path: e.Path}).EscapedPath()
+		values := url.Values{}
+		for key, value := range e.QueryStringParameters {
+			values.Set(key, value)
+		}
+		for key, vals := range e.MultiValueQueryStringParameters {
+			values[key] = vals
+		}
+		query = values.Encode()
+	}
+	req.Header.SetMethod(method)
+	if query != "" {
+		uri += "?" + query
+	}
+	req.SetRequestURI(uri)
+
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, values := range e.MultiValueHeaders {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if len(e.Cookies) > 0 {
+		req.Header.Set(fiber.HeaderCookie, strings.Join(e.Cookies, "; "))
+	}
+	if host := req.Header.Peek(fiber.HeaderHost); len(host) > 0 {
+		req.SetHostBytes(host)
+	}
+
+	if e.IsBase64Encoded {
+		body, err := base64.StdEncoding.DecodeString(e.Body)
+		if err != nil {
+			return fmt.Errorf("couldn't decode Base64 body: %w", err)
+		}
+		req.SetBody(body)
+	} else {
+		req.SetBodyString(e.Body)
+	}
+	return nil
+}
+
+func (e LambdaEvent) toResponse(res *fasthttp.Response) LambdaResponse {
+	lambdaRes := LambdaResponse{StatusCode: res.StatusCode()}
+	if e.isV2() {
+		lambdaRes.Headers = map[string]string{}
+	} else {
+		lambdaRes.MultiValueHeaders = map[string][]string{}
+	}
+	res.Header.VisitAll(func(key, value []byte) {
+		k, v := string(key), string(value)
+		switch {
+		case e.isV2() && k == fiber.HeaderSetCookie:
+			lambdaRes.Cookies = append(lambdaRes.Cookies, v)
+		case e.isV2():
+			if existing, ok := lambdaRes.Headers[k]; ok {
+				v = existing + "," + v
+			}
+			lambdaRes.Headers[k] = v
+		default:
+			lambdaRes.MultiValueHeaders[k] = append(lambdaRes.MultiValueHeaders[k], v)
+		}
+	})
+
+	body := res.Body()
+	if isText(string(res.Header.ContentType())) {
+		lambdaRes.Body = string(body)
+	} else {
+		lambdaRes.Body = base64.StdEncoding.EncodeToString(body)
+		lambdaRes.IsBase64Encoded = true
+	}
+	return lambdaRes
+}
+
+// isText returns whether a response with the content type can be returned as is, while others have to be Base64-encoded.
+func isText(contentType string) bool {
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "javascript")
+}
@@ -0,0 +1,178 @@
+// Package signedurl creates and verifies tokens for go-stremio's "/resolve/:token", "/proxy/:token" and "/vtt/:token.vtt" endpoints.
+// A token contains an upstream URL and an expiry time. It's encrypted so users can't see the upstream URL
+// (which might contain credentials), and signed with HMAC-SHA256 so it can't be forged or extended.
+package signedurl
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ivSize  = aes.BlockSize
+	macSize = sha256.Size
+	// MinSecretLength is the minimum length of the secret in bytes.
+	MinSecretLength = 32
+)
+
+var (
+	// ErrInvalidToken signals that a token is malformed or its signature doesn't match.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpired signals that a token was valid, but is expired.
+	ErrExpired = errors.New("token expired")
+)
+
+var tokenEncoding = base64.RawURLEncoding
+
+// Target is the content of a token.
+type Target struct {
+	// The upstream URL.
+	URL string `json:"u"`
+	// Expiry time as Unix timestamp.
+	Expires int64 `json:"e"`
+	// Headers to send to the upstream, like "Referer" or "Authorization".
+	// Only used by the "/proxy/:token" endpoint, as a redirect can't carry headers.
+	Headers map[string]string `json:"h,omitempty"`
+	// Key that identifies the user, like a hash of their API key.
+	// The "/proxy/:token" endpoint uses it for per-user limits and falls back to the client IP when it's empty.
+	User string `json:"n,omitempty"`
+}
+
+// Signer creates and verifies tokens.
+// It's safe for concurrent use.
+type Signer struct {
+	block  cipher.Block
+	macKey []byte
+}
+
+// NewSigner creates a new Signer.
+// The secret must be at least MinSecretLength bytes long and must be the same for all instances of your addon,
+// otherwise tokens created by one instance can't be verified by another one.
+func NewSigner(secret []byte) (*Signer, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("secret must be at least %v bytes long", MinSecretLength)
+	}
+	// Separate keys for encryption and signing, derived from the secret.
+	block, err := aes.NewCipher(deriveKey(secret, "go-stremio signedurl encryption"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create cipher: %w", err)
+	}
+	return &Signer{
+		block:  block,
+		macKey: deriveKey(secret, "go-stremio signedurl signature"),
+	}, nil
+}
+
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Sign creates a token for the upstream URL that expires after the given duration.
+func (s *Signer) Sign(upstreamURL string, ttl time.Duration) (string, error) {
+	return s.sign(Target{URL: upstreamURL, Expires: time.Now().Add(ttl).Unix()})
+}
+
+// SignWithHeaders creates a token for the upstream URL and the headers to send to it, which expires after the given duration.
+// The headers are only sent when the token is used with the "/proxy/:token" endpoint.
+func (s *Signer) SignWithHeaders(upstreamURL string, headers map[string]string, ttl time.Duration) (string, error) {
+	return s.sign(Target{URL: upstreamURL, Expires: time.Now().Add(ttl).Unix(), Headers: headers})
+}
+
+// SignTarget creates a token for the target, which expires after the given duration.
+// The target's Expires field is overwritten.
+func (s *Signer) SignTarget(t Target, ttl time.Duration) (string, error) {
+	t.Expires = time.Now().Add(ttl).Unix()
+	return s.sign(t)
+}
+
+func (s *Signer) sign(t Target) (string, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal target: %w", err)
+	}
+
+	token := make([]byte, ivSize+len(payload), ivSize+len(payload)+macSize)
+	iv := token[:ivSize]
+	if _, err = rand.Read(iv); err != nil {
+		return "", fmt.Errorf("couldn't create IV: %w", err)
+	}
+	cipher.NewCTR(s.block, iv).XORKeyStream(token[ivSize:], payload)
+
+	mac := hmac.New(sha256.New, s.macKey)
+	mac.Write(token)
+	token = mac.Sum(token)
+	return tokenEncoding.EncodeToString(token), nil
+}
+
+// URL creates a token for the upstream URL and returns the URL of the addon's resolve endpoint for it.
+// The baseURL is the public URL of your addon, like "https://addon.example.com".
+func (s *Signer) URL(baseURL, upstreamURL string, ttl time.Duration) (string, error) {
+	token, err := s.Sign(upstreamURL, ttl)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/resolve/" + token, nil
+}
+
+// ProxyURL creates a token for the upstream URL and headers and returns the URL of the addon's proxy endpoint for it.
+// The baseURL is the public URL of your addon, like "https://addon.example.com".
+func (s *Signer) ProxyURL(baseURL, upstreamURL string, headers map[string]string, ttl time.Duration) (string, error) {
+	return s.ProxyURLForUser(baseURL, upstreamURL, headers, "", ttl)
+}
+
+// ProxyURLForUser is like ProxyURL, but also puts the user key into the token, so the proxy can apply per-user limits.
+func (s *Signer) ProxyURLForUser(baseURL, upstreamURL string, headers map[string]string, user string, ttl time.Duration) (string, error) {
+	token, err := s.SignTarget(Target{URL: upstreamURL, Headers: headers, User: user}, ttl)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/proxy/" + token, nil
+}
+
+// VTTURL creates a token for the upstream URL of an SRT subtitle and returns the URL of the addon's "/vtt/:token.vtt" endpoint for it,
+// which serves the subtitle converted to WebVTT.
+// The baseURL is the public URL of your addon, like "https://addon.example.com".
+func (s *Signer) VTTURL(baseURL, upstreamURL string, ttl time.Duration) (string, error) {
+	token, err := s.Sign(upstreamURL, ttl)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/vtt/" + token + ".vtt", nil
+}
+
+// Verify checks the token's signature and expiry and returns its content.
+// The errors are ErrInvalidToken and ErrExpired.
+func (s *Signer) Verify(token string) (Target, error) {
+	raw, err := tokenEncoding.DecodeString(token)
+	if err != nil || len(raw) < ivSize+macSize {
+		return Target{}, ErrInvalidToken
+	}
+	signed, sig := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+	mac := hmac.New(sha256.New, s.macKey)
+	mac.Write(signed)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return Target{}, ErrInvalidToken
+	}
+
+	payload := make([]byte, len(signed)-ivSize)
+	cipher.NewCTR(s.block, signed[:ivSize]).XORKeyStream(payload, signed[ivSize:])
+	var t Target
+	if err = json.Unmarshal(payload, &t); err != nil {
+		return Target{}, ErrInvalidToken
+	}
+	if time.Now().Unix() > t.Expires {
+		return Target{}, ErrExpired
+	}
+	return t, nil
+}
@@ -0,0 +1,111 @@
+// Package boltstore implements store.Store with bbolt, an embedded key/value database,
+// so values survive restarts of the addon.
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/store"
+	"go.etcd.io/bbolt"
+)
+
+var _ store.Store = (*Store)(nil)
+
+// The expiry is stored as Unix nanoseconds in front of each value, 0 meaning no expiry.
+const expirySize = 8
+
+// Store is a store.Store that stores values in a bbolt DB.
+// bbolt doesn't support expiry, so expired values are only removed when they're accessed.
+// It's safe for concurrent use.
+type Store struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// New creates a new Store that stores values in the given bucket of the DB.
+// The bucket is created if it doesn't exist yet. Closing the DB is up to the caller.
+func New(db *bbolt.DB, bucket string) (*Store, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create bucket: %w", err)
+	}
+	return &Store{
+		db:     db,
+		bucket: []byte(bucket),
+	}, nil
+}
+
+// get returns the value and expiry of the key. The returned value is only valid during the transaction.
+func get(b *bbolt.Bucket, key string) ([]byte, time.Time, bool) {
+	stored := b.Get([]byte(key))
+	if len(stored) < expirySize {
+		return nil, time.Time{}, false
+	}
+	var expires time.Time
+	if nanos := int64(binary.BigEndian.Uint64(stored)); nanos != 0 {
+		expires = time.Unix(0, nanos)
+		if !time.Now().Before(expires) {
+			return nil, time.Time{}, false
+		}
+	}
+	return stored[expirySize:], expires, true
+}
+
+// Get returns the value of the key.
+func (s *Store) Get(_ context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	var found bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var v []byte
+		v, _, found = get(tx.Bucket(s.bucket), key)
+		// The value is only valid during the transaction.
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't get value: %w", err)
+	}
+	return value, found, nil
+}
+
+// Set stores the value for the key.
+func (s *Store) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	stored := make([]byte, expirySize+len(value))
+	if ttl != 0 {
+		binary.BigEndian.PutUint64(stored, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	copy(stored[expirySize:], value)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(key), stored)
+	})
+}
+
+// Delete removes the key.
+func (s *Store) Delete(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(key))
+	})
+}
+
+// TTL returns the remaining time to live of the key.
+func (s *Store) TTL(_ context.Context, key string) (time.Duration, bool, error) {
+	var expires time.Time
+	var found bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		_, expires, found = get(tx.Bucket(s.bucket), key)
+		return nil
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("couldn't get value: %w", err)
+	}
+	if !found || expires.IsZero() {
+		return 0, found, nil
+	}
+	return time.Until(expires), true, nil
+}
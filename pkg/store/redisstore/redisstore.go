@@ -0,0 +1,98 @@
+// Package redisstore implements store.Store with Redis, so multiple instances of an addon can share their values.
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xybydy/go-stremio/pkg/store"
+)
+
+// Options are the options for the Redis store.
+type Options struct {
+	// Prefix for all keys, so the store can share a Redis DB with other data.
+	// Default "go-stremio:".
+	Prefix string
+	// Timeout for each Redis command, unless the context has an earlier deadline.
+	// Default 1 second.
+	Timeout time.Duration
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Prefix:  "go-stremio:",
+	Timeout: time.Second,
+}
+
+var _ store.Store = (*Store)(nil)
+
+// Store is a store.Store that stores values in Redis, which handles their expiry.
+// It's safe for concurrent use.
+type Store struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+// New creates a new Store. The client can be a single node, cluster or sentinel client.
+func New(client redis.UniversalClient, opts Options) *Store {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultOptions.Prefix
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	return &Store{
+		client:  client,
+		prefix:  opts.Prefix,
+		timeout: opts.Timeout,
+	}
+}
+
+// Get returns the value of the key.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("couldn't get value: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set stores the value for the key.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// Delete removes the key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// TTL returns the remaining time to live of the key.
+func (s *Store) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	ttl, err := s.client.TTL(ctx, s.prefix+key).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("couldn't get TTL: %w", err)
+	}
+	// Redis responds with -2 for missing keys and -1 for keys without expiry, which go-redis passes on as durations.
+	switch ttl {
+	case -2:
+		return 0, false, nil
+	case -1:
+		return 0, true, nil
+	}
+	return ttl, true, nil
+}
@@ -0,0 +1,149 @@
+// Package store is a simple key/value store abstraction for addons that need persistence,
+// like go-stremio's server-side configuration storage (see stremio.Options.ConfigStore).
+// The package contains an in-memory implementation, and the subpackages boltstore and redisstore contain persistent ones.
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is a key/value store with optional expiry.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value of the key. The boolean return value signals if the key was found.
+	// An error signals a problem with the store itself.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key. A TTL of 0 means the value doesn't expire.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key. Deleting a key that doesn't exist isn't an error.
+	Delete(ctx context.Context, key string) error
+	// TTL returns the remaining time to live of the key, 0 if it doesn't expire.
+	// The boolean return value signals if the key was found.
+	TTL(ctx context.Context, key string) (time.Duration, bool, error)
+}
+
+var _ Store = (*Memory)(nil)
+
+// Memory is a Store that keeps the values in memory, so they're lost when the addon restarts.
+// Expired values are removed when they're accessed and every now and then when setting values.
+type Memory struct {
+	items map[string]memoryItem
+	// Number of Set calls since the last removal of expired values.
+	sets int
+	// 0 means no limit.
+	maxEntries int
+	lock       *sync.Mutex
+}
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// Remove expired values every 1000 Set calls, so the memory usage doesn't grow with values that are never accessed again.
+const memoryCleanupInterval = 1000
+
+// NewMemory creates a new Memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		items: map[string]memoryItem{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+// NewMemoryWithLimit creates a new Memory store that keeps at most maxEntries values,
+// for keys that clients can make up, so they can't make the memory usage grow without limit.
+// When it's full, expired values are removed first, and otherwise a random value.
+func NewMemoryWithLimit(maxEntries int) *Memory {
+	m := NewMemory()
+	m.maxEntries = maxEntries
+	return m
+}
+
+// removeExpired removes the expired values. The lock must be held.
+func (m *Memory) removeExpired(now time.Time) {
+	for k, i := range m.items {
+		if i.expired(now) {
+			delete(m.items, k)
+		}
+	}
+}
+
+// get returns the item of the key, and removes it if it's expired.
+func (m *Memory) get(key string) (memoryItem, bool) {
+	item, ok := m.items[key]
+	if ok && item.expired(time.Now()) {
+		delete(m.items, key)
+		return memoryItem{}, false
+	}
+	return item, ok
+}
+
+// Get returns the value of the key.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	item, ok := m.get(key)
+	return item.value, ok, nil
+}
+
+// Set stores the value for the key.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	item := memoryItem{value: value}
+	if ttl != 0 {
+		item.expires = now.Add(ttl)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.items[key]; !ok && m.maxEntries > 0 && len(m.items) >= m.maxEntries {
+		m.sets = 0
+		m.removeExpired(now)
+		// Map iteration order is random
+		for k := range m.items {
+			if len(m.items) < m.maxEntries {
+				break
+			}
+			delete(m.items, k)
+		}
+	}
+	m.items[key] = item
+	m.sets++
+	if m.sets >= memoryCleanupInterval {
+		m.sets = 0
+		m.removeExpired(now)
+	}
+	return nil
+}
+
+// Delete removes the key.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// TTL returns the remaining time to live of the key.
+func (m *Memory) TTL(_ context.Context, key string) (time.Duration, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	item, ok := m.get(key)
+	if !ok || item.expires.IsZero() {
+		return 0, ok, nil
+	}
+	return time.Until(item.expires), true, nil
+}
+
+// Len returns the number of values in the store, including expired ones that weren't removed yet.
+func (m *Memory) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.items)
+}
@@ -0,0 +1,108 @@
+// Package streamutil contains helpers for post-processing the streams of a StreamHandler,
+// like removing duplicates from several sources and sorting them by quality.
+package streamutil
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xybydy/go-stremio/pkg/release"
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Deduplicate returns the streams without duplicates, keeping the first occurrence and the order.
+// Torrent streams are equal when their info hash (case-insensitive) and file index are equal,
+// other streams when their URL, YouTube ID or external URL are equal.
+func Deduplicate(streams []types.StreamItem) []types.StreamItem {
+	seen := make(map[string]struct{}, len(streams))
+	res := make([]types.StreamItem, 0, len(streams))
+	for _, s := range streams {
+		key := Key(s)
+		if key != "" {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// Key returns the value that identifies the stream's content, which is used by Deduplicate.
+// It's empty if the stream has neither an info hash nor any kind of URL.
+func Key(s types.StreamItem) string {
+	switch {
+	case s.InfoHash != "":
+		return "btih:" + strings.ToLower(s.InfoHash) + ":" + strconv.Itoa(int(s.FileIndex))
+	case s.URL != "":
+		return "url:" + s.URL
+	case s.YoutubeID != "":
+		return "yt:" + s.YoutubeID
+	case s.ExternalURL != "":
+		return "external:" + s.ExternalURL
+	}
+	return ""
+}
+
+// Sort sorts the streams in place by the given comparison functions.
+// When the first one considers two streams equal, the next one is used and so on.
+// The sort is stable, so streams that are equal for all functions keep their order.
+//
+//	streamutil.Sort(streams, streamutil.ByResolution, streamutil.BySize)
+func Sort(streams []types.StreamItem, cmps ...func(a, b types.StreamItem) int) {
+	slices.SortStableFunc(streams, func(a, b types.StreamItem) int {
+		for _, c := range cmps {
+			if res := c(a, b); res != 0 {
+				return res
+			}
+		}
+		return 0
+	})
+}
+
+// ByResolution sorts streams with a higher resolution first.
+// See Resolution for how it's determined.
+func ByResolution(a, b types.StreamItem) int {
+	return cmp.Compare(Resolution(b), Resolution(a))
+}
+
+// BySize sorts bigger streams first.
+// The size is taken from the stream details or the behavior hints.
+func BySize(a, b types.StreamItem) int {
+	return cmp.Compare(size(b), size(a))
+}
+
+// BySeeders sorts streams with more seeders first.
+func BySeeders(a, b types.StreamItem) int {
+	return cmp.Compare(b.Details.Seeders, a.Details.Seeders)
+}
+
+// ByPriority returns a comparison function that sorts streams with a higher priority first.
+// It's useful for addon specific preferences, like preferring cached debrid streams or a user's favorite source.
+func ByPriority(priority func(types.StreamItem) int) func(a, b types.StreamItem) int {
+	return func(a, b types.StreamItem) int {
+		return cmp.Compare(priority(b), priority(a))
+	}
+}
+
+func size(s types.StreamItem) int64 {
+	if s.Details.Size != 0 {
+		return s.Details.Size
+	}
+	return int64(s.BehaviorHints.VideoSize)
+}
+
+// Resolution returns the vertical resolution (like 1080) of the stream, or 0 if it's unknown.
+// It's determined from the quality in the stream details, or otherwise from the name, title, description and filename,
+// where it looks for values like "1080p" or "4K".
+func Resolution(s types.StreamItem) int {
+	for _, text := range []string{s.Details.Quality, s.Name, s.Title, s.Description, s.BehaviorHints.Filename} {
+		if res := release.ParseResolution(text); res != 0 {
+			return res
+		}
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package stremiotest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/xybydy/go-stremio"
+)
+
+// FuzzUserData fuzzes the user data and extra arguments of requests to one of the addon's endpoints,
+// like resource "stream", type "movie" and ID "tt1254207", and fails if a response has a 5xx status, which includes panics.
+// Malformed input must lead to a 4xx status, so handlers should return stremio.ErrBadRequest or stremio.ErrNotFound for invalid user data.
+// Use it in a fuzz test and run it with `go test -fuzz`:
+//
+//	func FuzzAddon(f *testing.F) {
+//		stremiotest.FuzzUserData(f, newAddon(), "catalog", "movie", "top", `{"token":"abc"}`)
+//	}
+//
+// The seeds are valid user data values as they would be in the URL, for example from Addon.EncodeUserData.
+func FuzzUserData(f *testing.F, addon *stremio.Addon, resource, metaType, id string, seeds ...string) {
+	srv := NewServer(f, addon)
+	f.Add("", "")
+	f.Add("%7B%7D", "skip=100")
+	f.Add("e30", "search=foo%20bar")
+	for _, seed := range seeds {
+		f.Add(seed, "")
+	}
+	f.Fuzz(func(t *testing.T, userData, extra string) {
+		path := "/"
+		if userData != "" {
+			path += url.PathEscape(userData) + "/"
+		}
+		path += resource + "/" + url.PathEscape(metaType) + "/" + url.PathEscape(id)
+		if extra != "" {
+			path += "/" + url.PathEscape(extra)
+		}
+		path += ".json"
+
+		res, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("couldn't send request: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode >= http.StatusInternalServerError {
+			t.Fatalf("got status %v for %v", res.StatusCode, path)
+		}
+	})
+}
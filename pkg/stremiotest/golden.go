@@ -0,0 +1,122 @@
+package stremiotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("stremiotest.update", false, "update the golden files of stremiotest instead of comparing against them")
+
+// Ignored is the value that ignored fields get in golden files.
+const Ignored = "<ignored>"
+
+// RequireGolden compares the response body with the golden file "testdata/{name}.golden.json" and fails the test if they differ.
+// Run the tests with the "-stremiotest.update" flag to create or update the golden files, then review and commit them.
+//
+// The JSON is normalized (indented, with sorted keys), so only actual changes show up in the diff.
+// Volatile fields like release info or URLs with tokens can be ignored with paths like "metas.*.releaseInfo" or "streams.*.url",
+// where "*" matches all array elements or object values. Their values are replaced by Ignored.
+func (r *Response) RequireGolden(tb testing.TB, name string, ignore ...string) {
+	tb.Helper()
+	r.RequireStatus(tb, 200)
+	RequireGoldenJSON(tb, filepath.Join("testdata", name+".golden.json"), r.Body, ignore...)
+}
+
+// RequireGoldenJSON is like Response.RequireGolden, but for any JSON and golden file path.
+func RequireGoldenJSON(tb testing.TB, path string, actual []byte, ignore ...string) {
+	tb.Helper()
+	normalized, err := normalizeJSON(actual, ignore)
+	if err != nil {
+		tb.Fatalf("couldn't normalize JSON: %v", err)
+	}
+
+	if *updateGolden {
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("couldn't create golden file directory: %v", err)
+		}
+		if err = os.WriteFile(path, normalized, 0o644); err != nil {
+			tb.Fatalf("couldn't write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		tb.Fatalf("golden file %v doesn't exist, run the test with -stremiotest.update to create it", path)
+	} else if err != nil {
+		tb.Fatalf("couldn't read golden file: %v", err)
+	}
+	if !bytes.Equal(expected, normalized) {
+		tb.Fatalf("response differs from golden file %v (run with -stremiotest.update to accept the changes):\n%v", path, diffLines(string(expected), string(normalized)))
+	}
+}
+
+func normalizeJSON(data []byte, ignore []string) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	for _, path := range ignore {
+		v = ignorePath(v, strings.Split(path, "."))
+	}
+	// Maps are marshalled with sorted keys. HTML escaping would make URLs and Ignored hard to read.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ignorePath replaces the value at the path with Ignored, if it exists.
+func ignorePath(v any, path []string) any {
+	if len(path) == 0 {
+		return Ignored
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if path[0] == "*" || path[0] == key {
+				v[key] = ignorePath(value, path[1:])
+			}
+		}
+	case []any:
+		if path[0] == "*" {
+			for i, value := range v {
+				v[i] = ignorePath(value, path[1:])
+			}
+		}
+	}
+	return v
+}
+
+// diffLines returns the lines around the first difference, as a simple and dependency-free diff.
+func diffLines(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	i := 0
+	for i < len(expectedLines) && i < len(actualLines) && expectedLines[i] == actualLines[i] {
+		i++
+	}
+	var sb strings.Builder
+	from := max(i-3, 0)
+	for j := from; j < i; j++ {
+		sb.WriteString("  " + expectedLines[j] + "\n")
+	}
+	for j := i; j < min(i+5, len(expectedLines)); j++ {
+		sb.WriteString("- " + expectedLines[j] + "\n")
+	}
+	for j := i; j < min(i+5, len(actualLines)); j++ {
+		sb.WriteString("+ " + actualLines[j] + "\n")
+	}
+	return sb.String()
+}
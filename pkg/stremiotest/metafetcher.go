@@ -0,0 +1,139 @@
+package stremiotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/types"
+)
+
+// ErrMetaNotFound is returned by the MetaFetcher for IDs without canned meta object or error.
+var ErrMetaNotFound = errors.New("meta not found")
+
+var _ stremio.TypedMetaFetcher = (*MetaFetcher)(nil)
+
+// MetaFetcher is a fake MetaFetcher for the addon's MetaClient option, so code that relies on PutMetaInContext or LogMediaName
+// can be tested without network access. It returns canned meta objects and errors and records all calls.
+// It's safe for concurrent use.
+type MetaFetcher struct {
+	metas map[string]types.MetaItem
+	errs  map[string]error
+	delay time.Duration
+	calls []MetaCall
+	lock  *sync.Mutex
+}
+
+// MetaCall is a recorded call of the MetaFetcher.
+type MetaCall struct {
+	// "GetMovie", "GetSeries" or "GetMeta".
+	Method string
+	// Only set for GetMeta.
+	Type    string
+	ID      string
+	Season  int
+	Episode int
+}
+
+// NewMetaFetcher creates a new MetaFetcher without any canned meta objects.
+func NewMetaFetcher() *MetaFetcher {
+	return &MetaFetcher{
+		metas: map[string]types.MetaItem{},
+		errs:  map[string]error{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+// WithMeta adds a canned meta object, which is returned for its ID.
+func (f *MetaFetcher) WithMeta(metas ...types.MetaItem) *MetaFetcher {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, meta := range metas {
+		f.metas[meta.ID] = meta
+	}
+	return f
+}
+
+// WithError makes the MetaFetcher return the error for the ID, for example to simulate an outage of Cinemeta.
+// Use an empty ID to return the error for all IDs.
+func (f *MetaFetcher) WithError(id string, err error) *MetaFetcher {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.errs[id] = err
+	return f
+}
+
+// WithDelay makes each call take the given time, for example to test timeouts.
+// Calls return early with the context's error when it's canceled.
+func (f *MetaFetcher) WithDelay(delay time.Duration) *MetaFetcher {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.delay = delay
+	return f
+}
+
+// GetMovie returns the canned meta object or error for the IMDb ID.
+func (f *MetaFetcher) GetMovie(ctx context.Context, imdbID string) (types.MetaItem, error) {
+	return f.get(ctx, MetaCall{Method: "GetMovie", ID: imdbID})
+}
+
+// GetSeries returns the canned meta object or error for the IMDb ID.
+func (f *MetaFetcher) GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error) {
+	return f.get(ctx, MetaCall{Method: "GetSeries", ID: imdbID, Season: season, Episode: episode})
+}
+
+// GetMeta returns the canned meta object or error for the ID. A season and episode in the ID are ignored.
+func (f *MetaFetcher) GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error) {
+	return f.get(ctx, MetaCall{Method: "GetMeta", Type: metaType, ID: id})
+}
+
+// Calls returns all recorded calls, in the order they were made.
+func (f *MetaFetcher) Calls() []MetaCall {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]MetaCall(nil), f.calls...)
+}
+
+func (f *MetaFetcher) get(ctx context.Context, call MetaCall) (types.MetaItem, error) {
+	f.lock.Lock()
+	f.calls = append(f.calls, call)
+	delay := f.delay
+	f.lock.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return types.MetaItem{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	// Look up the ID without season and episode, like "tt0944947" for "tt0944947:1:2" or "kitsu:1" for "kitsu:1:5".
+	id := call.ID
+	if !strings.HasPrefix(id, "tt") {
+		if prefix, rest, ok := strings.Cut(id, ":"); ok {
+			suffix, _, _ := strings.Cut(rest, ":")
+			id = prefix + ":" + suffix
+		}
+	} else {
+		id, _, _ = strings.Cut(id, ":")
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err, ok := f.errs[id]; ok {
+		return types.MetaItem{}, err
+	} else if err, ok = f.errs[""]; ok {
+		return types.MetaItem{}, err
+	}
+	if meta, ok := f.metas[id]; ok {
+		return meta, nil
+	}
+	return types.MetaItem{}, fmt.Errorf("%w: %v", ErrMetaNotFound, call.ID)
+}
@@ -0,0 +1,252 @@
+// Package stremiotest provides utilities for testing go-stremio addons in-process, similar to net/http/httptest.
+// It starts the addon with an httptest server, builds requests for the addon's endpoints including user data and extra arguments,
+// and decodes the responses into the types of the types package, so integration tests don't need to copy route strings.
+//
+// Example:
+//
+//	srv := stremiotest.NewServer(t, addon)
+//	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(config).Do(t).Streams(t)
+package stremiotest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/types"
+)
+
+// Server is an addon that's served by an httptest server.
+type Server struct {
+	*httptest.Server
+	addon *stremio.Addon
+}
+
+// NewServer starts the addon with an httptest server. The server is closed when the test finishes.
+func NewServer(tb testing.TB, addon *stremio.Addon) *Server {
+	tb.Helper()
+	srv := httptest.NewServer(adaptor.FiberApp(addon.App(nil)))
+	tb.Cleanup(srv.Close)
+	return &Server{
+		Server: srv,
+		addon:  addon,
+	}
+}
+
+// Manifest requests the manifest and returns it.
+func (s *Server) Manifest(tb testing.TB) types.Manifest {
+	tb.Helper()
+	return s.ManifestRequest().Do(tb).Manifest(tb)
+}
+
+// Catalog requests a catalog and returns its meta previews.
+func (s *Server) Catalog(tb testing.TB, metaType, id string) []types.MetaPreviewItem {
+	tb.Helper()
+	return s.CatalogRequest(metaType, id).Do(tb).Metas(tb)
+}
+
+// Streams requests the streams for the ID and returns them.
+func (s *Server) Streams(tb testing.TB, metaType, id string) []types.StreamItem {
+	tb.Helper()
+	return s.StreamRequest(metaType, id).Do(tb).Streams(tb)
+}
+
+// Meta requests the meta object for the ID and returns it.
+func (s *Server) Meta(tb testing.TB, metaType, id string) types.MetaItem {
+	tb.Helper()
+	return s.MetaRequest(metaType, id).Do(tb).Meta(tb)
+}
+
+// Subtitles requests the subtitles for the ID and returns them.
+func (s *Server) Subtitles(tb testing.TB, metaType, id string) []types.SubtitleItem {
+	tb.Helper()
+	return s.SubtitlesRequest(metaType, id).Do(tb).Subtitles(tb)
+}
+
+// ManifestRequest creates a request for "/manifest.json".
+func (s *Server) ManifestRequest() *Request {
+	return &Request{srv: s, resource: "manifest"}
+}
+
+// CatalogRequest creates a request for "/catalog/{type}/{id}.json".
+func (s *Server) CatalogRequest(metaType, id string) *Request {
+	return &Request{srv: s, resource: "catalog", metaType: metaType, id: id}
+}
+
+// StreamRequest creates a request for "/stream/{type}/{id}.json".
+func (s *Server) StreamRequest(metaType, id string) *Request {
+	return &Request{srv: s, resource: "stream", metaType: metaType, id: id}
+}
+
+// MetaRequest creates a request for "/meta/{type}/{id}.json".
+func (s *Server) MetaRequest(metaType, id string) *Request {
+	return &Request{srv: s, resource: "meta", metaType: metaType, id: id}
+}
+
+// SubtitlesRequest creates a request for "/subtitles/{type}/{id}.json".
+func (s *Server) SubtitlesRequest(metaType, id string) *Request {
+	return &Request{srv: s, resource: "subtitles", metaType: metaType, id: id}
+}
+
+// Request is a request to one of the addon's endpoints.
+// Create one with the Server's methods like StreamRequest, optionally add user data, extra arguments and headers, and send it with Do.
+type Request struct {
+	srv         *Server
+	resource    string
+	metaType    string
+	id          string
+	userData    any
+	hasUserData bool
+	extra       url.Values
+	header      http.Header
+}
+
+// WithUserData sets the user data, which is encoded like the addon expects it (see Addon.EncodeUserData).
+func (r *Request) WithUserData(userData any) *Request {
+	r.userData = userData
+	r.hasUserData = true
+	return r
+}
+
+// WithExtra adds an extra argument like "search" or "skip" for catalog requests.
+func (r *Request) WithExtra(key, value string) *Request {
+	if r.extra == nil {
+		r.extra = url.Values{}
+	}
+	r.extra.Add(key, value)
+	return r
+}
+
+// WithHeader sets a request header, like "If-None-Match".
+func (r *Request) WithHeader(key, value string) *Request {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set(key, value)
+	return r
+}
+
+// Path returns the URL path of the request, like "/stream/movie/tt1254207.json".
+func (r *Request) Path(tb testing.TB) string {
+	tb.Helper()
+	path := ""
+	if r.hasUserData {
+		userData, err := r.srv.addon.EncodeUserData(r.userData)
+		if err != nil {
+			tb.Fatalf("couldn't encode user data: %v", err)
+		}
+		path += "/" + userData
+	}
+	if r.resource == "manifest" {
+		return path + "/manifest.json"
+	}
+	path += "/" + r.resource + "/" + url.PathEscape(r.metaType) + "/" + url.PathEscape(r.id)
+	if len(r.extra) > 0 {
+		path += "/" + r.extra.Encode()
+	}
+	return path + ".json"
+}
+
+// Do sends the request and returns the response.
+func (r *Request) Do(tb testing.TB) *Response {
+	tb.Helper()
+	req, err := http.NewRequest(http.MethodGet, r.srv.URL+r.Path(tb), nil)
+	if err != nil {
+		tb.Fatalf("couldn't create request: %v", err)
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	res, err := r.srv.Client().Do(req)
+	if err != nil {
+		tb.Fatalf("couldn't send request: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		tb.Fatalf("couldn't read response body: %v", err)
+	}
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}
+}
+
+// Response is the response of the addon.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RequireStatus fails the test if the response doesn't have the status code.
+func (r *Response) RequireStatus(tb testing.TB, statusCode int) *Response {
+	tb.Helper()
+	if r.StatusCode != statusCode {
+		tb.Fatalf("expected status %v, got %v with body %q", statusCode, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// Decode requires a 200 response and unmarshals the body into v.
+func (r *Response) Decode(tb testing.TB, v any) {
+	tb.Helper()
+	r.RequireStatus(tb, http.StatusOK)
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		tb.Fatalf("couldn't unmarshal response body %q: %v", r.Body, err)
+	}
+}
+
+// Manifest requires a 200 response and returns the manifest.
+func (r *Response) Manifest(tb testing.TB) types.Manifest {
+	tb.Helper()
+	var manifest types.Manifest
+	r.Decode(tb, &manifest)
+	return manifest
+}
+
+// Metas requires a 200 response and returns the meta previews of a catalog response.
+func (r *Response) Metas(tb testing.TB) []types.MetaPreviewItem {
+	tb.Helper()
+	var res struct {
+		Metas []types.MetaPreviewItem `json:"metas"`
+	}
+	r.Decode(tb, &res)
+	return res.Metas
+}
+
+// Streams requires a 200 response and returns the streams of a stream response.
+func (r *Response) Streams(tb testing.TB) []types.StreamItem {
+	tb.Helper()
+	var res struct {
+		Streams []types.StreamItem `json:"streams"`
+	}
+	r.Decode(tb, &res)
+	return res.Streams
+}
+
+// Meta requires a 200 response and returns the meta object of a meta response.
+func (r *Response) Meta(tb testing.TB) types.MetaItem {
+	tb.Helper()
+	var res struct {
+		Meta types.MetaItem `json:"meta"`
+	}
+	r.Decode(tb, &res)
+	return res.Meta
+}
+
+// Subtitles requires a 200 response and returns the subtitles of a subtitle response.
+func (r *Response) Subtitles(tb testing.TB) []types.SubtitleItem {
+	tb.Helper()
+	var res struct {
+		Subtitles []types.SubtitleItem `json:"subtitles"`
+	}
+	r.Decode(tb, &res)
+	return res.Subtitles
+}
@@ -0,0 +1,40 @@
+package subtitles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxSubtitleSize is the max size of subtitle files that FetchVTT downloads.
+const MaxSubtitleSize = 5 << 20
+
+// FetchVTT downloads the SRT subtitle at the given URL and converts it to WebVTT.
+// Subtitles that are already WebVTT are returned as they are.
+func FetchVTT(ctx context.Context, httpClient *http.Client, srtURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srtURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create request: %w", err)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't GET %v: %w", srtURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad GET response: %v", res.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, MaxSubtitleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	if len(data) > MaxSubtitleSize {
+		return nil, fmt.Errorf("subtitle is bigger than %v bytes", MaxSubtitleSize)
+	}
+	if bytes.HasPrefix(bytes.TrimPrefix(data, utf8BOM), []byte("WEBVTT")) {
+		return data, nil
+	}
+	return SRTToVTT(data), nil
+}
@@ -0,0 +1,103 @@
+package subtitles
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The OpenSubtitles hash is computed from the first and last 64 KiB of a file.
+const hashChunkSize = 64 << 10
+
+// ErrFileTooSmall signals that a file is smaller than the 64 KiB that the OpenSubtitles hash requires.
+var ErrFileTooSmall = errors.New("file too small for OpenSubtitles hash")
+
+// Hash computes the OpenSubtitles hash ("moviehash") of a file with the given size,
+// as used for the videoHash extra parameter of subtitle requests.
+func Hash(r io.ReaderAt, size int64) (string, error) {
+	if size < hashChunkSize {
+		return "", ErrFileTooSmall
+	}
+	head := make([]byte, hashChunkSize)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return "", fmt.Errorf("couldn't read head of file: %w", err)
+	}
+	tail := make([]byte, hashChunkSize)
+	if _, err := r.ReadAt(tail, size-hashChunkSize); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("couldn't read tail of file: %w", err)
+	}
+	return hashChunks(size, head, tail), nil
+}
+
+// HashURL computes the OpenSubtitles hash of a remote file with two ranged HTTP requests,
+// so only 128 KiB are downloaded instead of the whole file.
+// The server must support range requests.
+func HashURL(ctx context.Context, httpClient *http.Client, fileURL string) (string, error) {
+	head, size, err := fetchRange(ctx, httpClient, fileURL, 0)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch head of file: %w", err)
+	}
+	if size < hashChunkSize {
+		return "", ErrFileTooSmall
+	}
+	tail, _, err := fetchRange(ctx, httpClient, fileURL, size-hashChunkSize)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch tail of file: %w", err)
+	}
+	return hashChunks(size, head, tail), nil
+}
+
+func hashChunks(size int64, head, tail []byte) string {
+	hash := uint64(size)
+	for _, chunk := range [][]byte{head, tail} {
+		for i := 0; i+8 <= len(chunk); i += 8 {
+			hash += binary.LittleEndian.Uint64(chunk[i:])
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// fetchRange fetches up to hashChunkSize bytes starting at the offset and returns them with the total size of the file.
+func fetchRange(ctx context.Context, httpClient *http.Client, fileURL string, offset int64) ([]byte, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+hashChunkSize-1))
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("couldn't GET %v: %w", fileURL, err)
+	}
+	defer res.Body.Close()
+
+	var size int64
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+		// Like "bytes 0-65535/1234567"
+		contentRange := res.Header.Get("Content-Range")
+		i := strings.LastIndexByte(contentRange, '/')
+		if i == -1 {
+			return nil, 0, fmt.Errorf("invalid Content-Range header: %q", contentRange)
+		}
+		if size, err = strconv.ParseInt(contentRange[i+1:], 10, 64); err != nil {
+			return nil, 0, fmt.Errorf("invalid Content-Range header: %q", contentRange)
+		}
+	case res.StatusCode == http.StatusOK && offset == 0 && res.ContentLength >= 0:
+		// The server ignored the range, but we only read the beginning of the body anyway.
+		size = res.ContentLength
+	default:
+		return nil, 0, fmt.Errorf("bad GET response: %v", res.StatusCode)
+	}
+
+	chunk := make([]byte, hashChunkSize)
+	n, err := io.ReadFull(res.Body, chunk)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, 0, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	return chunk[:n], size, nil
+}
@@ -0,0 +1,285 @@
+package subtitles
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// language is a language that can be detected.
+type language struct {
+	// ISO 639-2/B code, which is what OpenSubtitles and Stremio use, like "ger".
+	code string
+	// Other names and codes that are used in filenames, like "de", "deu", "german" and "deutsch".
+	aliases []string
+}
+
+var languages = []language{
+	{"eng", []string{"en", "english"}},
+	{"spa", []string{"es", "spanish", "espanol", "español", "castellano", "esp"}},
+	{"fre", []string{"fr", "fra", "french", "francais", "français"}},
+	{"ger", []string{"de", "deu", "german", "deutsch"}},
+	{"ita", []string{"it", "italian", "italiano"}},
+	{"por", []string{"pt", "portuguese", "portugues", "português"}},
+	{"pob", []string{"ptbr", "brazilian", "pb"}},
+	{"rus", []string{"ru", "russian"}},
+	{"pol", []string{"pl", "polish", "polski"}},
+	{"dut", []string{"nl", "nld", "dutch", "nederlands", "flemish"}},
+	{"swe", []string{"sv", "swedish", "svenska"}},
+	{"nor", []string{"no", "nb", "nob", "norwegian", "norsk"}},
+	{"dan", []string{"da", "danish", "dansk"}},
+	{"fin", []string{"fi", "finnish", "suomi"}},
+	{"tur", []string{"tr", "turkish", "turkce", "türkçe"}},
+	{"gre", []string{"el", "ell", "greek"}},
+	{"ara", []string{"ar", "arabic"}},
+	{"heb", []string{"he", "iw", "hebrew"}},
+	{"hin", []string{"hindi"}},
+	{"jpn", []string{"ja", "jp", "japanese"}},
+	{"kor", []string{"ko", "korean"}},
+	{"chi", []string{"zh", "zho", "chs", "cht", "chinese", "mandarin"}},
+	{"cze", []string{"cs", "ces", "czech", "cestina", "čeština"}},
+	{"hun", []string{"hu", "hungarian", "magyar"}},
+	{"rum", []string{"ro", "ron", "romanian", "romana", "română"}},
+	{"bul", []string{"bg", "bulgarian"}},
+	{"hrv", []string{"hr", "croatian", "hrvatski"}},
+	{"srp", []string{"sr", "serbian", "srpski"}},
+	{"slv", []string{"sl", "slovenian", "slovene", "slovenscina"}},
+	{"slo", []string{"sk", "slk", "slovak", "slovencina"}},
+	{"ukr", []string{"uk", "ukrainian"}},
+	{"vie", []string{"vi", "vietnamese"}},
+	{"tha", []string{"th", "thai"}},
+	{"ind", []string{"id", "indonesian", "indonesia"}},
+	{"may", []string{"ms", "msa", "malay", "melayu"}},
+	{"per", []string{"fa", "fas", "persian", "farsi"}},
+	{"est", []string{"et", "estonian", "eesti"}},
+	{"lav", []string{"lv", "latvian"}},
+	{"lit", []string{"lt", "lithuanian"}},
+	{"cat", []string{"ca", "catalan", "català"}},
+	{"baq", []string{"eu", "eus", "basque", "euskara"}},
+	{"glg", []string{"gl", "galician", "galego"}},
+	{"ice", []string{"is", "isl", "icelandic"}},
+	{"mac", []string{"mk", "mkd", "macedonian"}},
+	{"alb", []string{"sq", "sqi", "albanian", "shqip"}},
+	{"bos", []string{"bs", "bosnian", "bosanski"}},
+	{"geo", []string{"ka", "kat", "georgian"}},
+	{"arm", []string{"hy", "hye", "armenian"}},
+	{"ben", []string{"bn", "bengali", "bangla"}},
+	{"tam", []string{"ta", "tamil"}},
+	{"tel", []string{"te", "telugu"}},
+	{"urd", []string{"ur", "urdu"}},
+	{"mal", []string{"ml", "malayalam"}},
+	{"tgl", []string{"tl", "fil", "tagalog", "filipino"}},
+}
+
+// Lookup table from lowercase code, alias or name to ISO 639-2/B code.
+var languageAliases = func() map[string]string {
+	m := make(map[string]string)
+	for _, l := range languages {
+		m[l.code] = l.code
+		for _, alias := range l.aliases {
+			m[alias] = l.code
+		}
+	}
+	return m
+}()
+
+// Tokens in subtitle filenames that look like language codes, but aren't, like "hi" for "hearing impaired" (instead of Hindi).
+var nonLanguageTokens = map[string]bool{
+	"hi": true, "sdh": true, "cc": true, "forced": true, "full": true, "sub": true, "subs": true,
+}
+
+var filenameSeparatorRegex = regexp.MustCompile(`[\s._\-\[\]()]+`)
+
+// LanguageFromFilename guesses the language of a subtitle from its filename, like "Movie.2020.1080p.German.srt" or "movie_pt-BR.forced.srt".
+// It returns an ISO 639-2/B code like "ger", which is what SubtitleItem.Lang expects, or an empty string if the filename doesn't contain a language.
+// Only the last words of the filename are checked, as titles can contain words that are also language codes, like "It".
+func LanguageFromFilename(filename string) string {
+	name := strings.ToLower(path.Base(filename))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	for _, sep := range []string{"-", "_", "."} {
+		name = strings.ReplaceAll(name, "pt"+sep+"br", "ptbr")
+	}
+
+	tokens := filenameSeparatorRegex.Split(name, -1)
+	checked := 0
+	for i := len(tokens) - 1; i >= 0 && checked < 3; i-- {
+		token := tokens[i]
+		if token == "" || nonLanguageTokens[token] {
+			continue
+		}
+		if code, ok := languageAliases[token]; ok {
+			return code
+		}
+		checked++
+	}
+	return ""
+}
+
+// Scripts that are only used by a single detectable language.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Greek, "gre"},
+	{unicode.Hebrew, "heb"},
+	{unicode.Hangul, "kor"},
+	{unicode.Thai, "tha"},
+	{unicode.Devanagari, "hin"},
+	{unicode.Georgian, "geo"},
+	{unicode.Armenian, "arm"},
+	{unicode.Bengali, "ben"},
+	{unicode.Tamil, "tam"},
+	{unicode.Telugu, "tel"},
+	{unicode.Malayalam, "mal"},
+}
+
+// Frequent words per language, for languages that share a script.
+var stopwords = map[*unicode.RangeTable]map[string][]string{
+	unicode.Latin: {
+		"eng": strings.Fields("the and you to is of that it in what this i'm don't have with for not know your"),
+		"spa": strings.Fields("que de no el la es en lo los por una qué está para con me se las pero"),
+		"fre": strings.Fields("je de pas le la vous est et que les une ce tu il ne des pour c'est qu'il"),
+		"ger": strings.Fields("ich und die der das nicht du ist sie es ein zu wir was mit den ja auch"),
+		"ita": strings.Fields("che non di il è la un per sono ho mi ti lo ma cosa con della questo"),
+		"por": strings.Fields("que não de o a é um uma você eu se para com os isso está do da"),
+		"pol": strings.Fields("nie to się jest że na co ja w mi jak tak ci już czy ale"),
+		"dut": strings.Fields("de het een en ik je is niet van dat wat zijn op maar met hij we"),
+		"swe": strings.Fields("och det att jag är inte du en på som har vi för med vad den"),
+		"nor": strings.Fields("og det er jeg ikke du på en som har vi for med hva til meg deg"),
+		"dan": strings.Fields("og det er jeg ikke du på en der har vi for med hvad til mig dig"),
+		"fin": strings.Fields("ja on ei se että minä mitä sinä hän oli me kun mutta niin olen"),
+		"tur": strings.Fields("bir ve bu ne için de da ben sen çok mi değil var ama o"),
+		"cze": strings.Fields("je to se na že ne a v jsem co tak ale jak mi jsi"),
+		"hun": strings.Fields("a az nem hogy és egy is van ez meg mi de csak már"),
+		"rum": strings.Fields("și să nu de în că e este la pe o un ce cu am"),
+		"ind": strings.Fields("yang dan tidak ini itu aku kau apa ada saya kita untuk dengan"),
+		"vie": strings.Fields("không tôi là có của một anh em này được"),
+	},
+	unicode.Cyrillic: {
+		"rus": strings.Fields("и в не что я на ты это с он как мы а но да"),
+		"ukr": strings.Fields("і не що я на ти це в з та як ми але він так"),
+		"bul": strings.Fields("и на да не се е това за ще ли ме си от какво"),
+	},
+	unicode.Arabic: {
+		"ara": strings.Fields("في من على أن هذا لا ما هل إلى هذه"),
+		"per": strings.Fields("و این را به که از است من تو چه"),
+	},
+}
+
+var (
+	subtitleTimingRegex = regexp.MustCompile(`(?m)^.*-->.*$|^\d+$`)
+	subtitleTagRegex    = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+)
+
+// LanguageFromContent guesses the language of a subtitle (SRT, WebVTT or plain text) from its content.
+// It detects the script and, for scripts that are used by multiple languages like Latin, the most frequent words.
+// It returns an ISO 639-2/B code like "ger", or an empty string if the language couldn't be detected.
+// Only the first 64 KiB are sampled.
+func LanguageFromContent(content []byte) string {
+	if len(content) > 64<<10 {
+		// Don't cut a UTF-8 character in half, as the content would then be treated as Windows-1252.
+		cut := 64 << 10
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut]
+	}
+	content = bytes.TrimPrefix(content, utf8BOM)
+	if !utf8.Valid(content) {
+		if decoded, err := charmap.Windows1252.NewDecoder().Bytes(content); err == nil {
+			content = decoded
+		}
+	}
+	text := subtitleTimingRegex.ReplaceAllString(string(content), "")
+	text = subtitleTagRegex.ReplaceAllString(text, "")
+
+	// Count letters per script.
+	counts := make(map[*unicode.RangeTable]int)
+	kana := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			counts[unicode.Han]++
+		default:
+			for _, table := range []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Arabic} {
+				if unicode.Is(table, r) {
+					counts[table]++
+				}
+			}
+			for _, sl := range scriptLanguages {
+				if unicode.Is(sl.table, r) {
+					counts[sl.table]++
+				}
+			}
+		}
+	}
+	// Japanese mixes kana with Han characters, Chinese doesn't use kana.
+	if kana > 0 {
+		counts[unicode.Hiragana] = kana + counts[unicode.Han]
+	}
+
+	var script *unicode.RangeTable
+	for table, count := range counts {
+		if script == nil || count > counts[script] {
+			script = table
+		}
+	}
+	switch script {
+	case nil:
+		return ""
+	case unicode.Hiragana:
+		return "jpn"
+	case unicode.Han:
+		return "chi"
+	}
+	for _, sl := range scriptLanguages {
+		if sl.table == script {
+			return sl.code
+		}
+	}
+
+	// Count stopwords per language of the script.
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	best, bestHits := "", 0
+	for code, list := range stopwords[script] {
+		hits := 0
+		for _, w := range words {
+			for _, sw := range list {
+				if w == sw {
+					hits++
+					break
+				}
+			}
+		}
+		// Ties are broken by the code, so the result is deterministic despite the random map order.
+		if hits > bestHits || (hits == bestHits && hits > 0 && code < best) {
+			best, bestHits = code, hits
+		}
+	}
+	// A few hits could be a coincidence.
+	if bestHits < 3 {
+		return ""
+	}
+	return best
+}
+
+// DetectLanguage guesses the language of a subtitle from its filename and falls back to its content.
+// Either can be empty. It returns an ISO 639-2/B code like "ger", or an empty string if the language couldn't be detected.
+func DetectLanguage(filename string, content []byte) string {
+	if code := LanguageFromFilename(filename); code != "" {
+		return code
+	}
+	return LanguageFromContent(content)
+}
@@ -0,0 +1,67 @@
+// Package subtitles contains helpers for subtitle addons, like converting SRT to WebVTT.
+package subtitles
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// MIMETypeVTT is the content type of WebVTT files.
+const MIMETypeVTT = "text/vtt; charset=utf-8"
+
+var (
+	utf8BOM = []byte("\xef\xbb\xbf")
+	// SRT timestamps use a comma as decimal separator, WebVTT uses a dot.
+	// Some SRT files have single digit hours, but WebVTT requires at least two.
+	srtTimestampRegex = regexp.MustCompile(`\b(\d{1,2}):(\d{2}:\d{2}),(\d{3})`)
+	// <font> tags are common in SRT files, but not allowed in WebVTT.
+	srtFontTagRegex = regexp.MustCompile(`(?i)</?font[^>]*>`)
+	// ASS style overrides like "{\an8}" that some SRT files contain.
+	srtASSTagRegex = regexp.MustCompile(`\{\\[^}]*\}`)
+)
+
+// SRTToVTT converts an SRT subtitle to WebVTT, which is the only format Stremio Web can play.
+// SRT files that aren't valid UTF-8 are assumed to be Windows-1252 encoded, as that's the most common legacy encoding.
+// Formatting that WebVTT doesn't support, like <font> tags, is removed.
+func SRTToVTT(srt []byte) []byte {
+	srt = bytes.TrimPrefix(srt, utf8BOM)
+	if !utf8.Valid(srt) {
+		if decoded, err := charmap.Windows1252.NewDecoder().Bytes(srt); err == nil {
+			srt = decoded
+		}
+	}
+	srt = bytes.ReplaceAll(srt, []byte("\r\n"), []byte("\n"))
+	srt = bytes.ReplaceAll(srt, []byte("\r"), []byte("\n"))
+
+	var buf bytes.Buffer
+	buf.Grow(len(srt) + 16)
+	buf.WriteString("WEBVTT\n\n")
+	for _, line := range bytes.Split(bytes.TrimSpace(srt), []byte("\n")) {
+		if bytes.Contains(line, []byte("-->")) {
+			line = srtTimestampRegex.ReplaceAllFunc(line, convertSRTTimestamp)
+		} else {
+			line = srtFontTagRegex.ReplaceAll(line, nil)
+			line = srtASSTagRegex.ReplaceAll(line, nil)
+		}
+		buf.Write(bytes.TrimRight(line, " \t"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// convertSRTTimestamp converts an SRT timestamp like "0:01:02,000" to a WebVTT timestamp like "00:01:02.000".
+func convertSRTTimestamp(timestamp []byte) []byte {
+	m := srtTimestampRegex.FindSubmatch(timestamp)
+	res := make([]byte, 0, len("00:00:00.000"))
+	if len(m[1]) == 1 {
+		res = append(res, '0')
+	}
+	res = append(res, m[1]...)
+	res = append(res, ':')
+	res = append(res, m[2]...)
+	res = append(res, '.')
+	return append(res, m[3]...)
+}
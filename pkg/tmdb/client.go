@@ -0,0 +1,422 @@
+// Package tmdb is a client for The Movie Database (https://www.themoviedb.org).
+// It implements go-stremio's MetaFetcher interface, so it can be used for LogMediaName and PutMetaInContext
+// for content that Cinemeta doesn't know, and it can be the basis for meta handlers.
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound signals that TMDB doesn't know the requested ID.
+var ErrNotFound = errors.New("not found on TMDB")
+
+// ClientOptions are the options for the TMDB client.
+type ClientOptions struct {
+	// API key (v3) or API read access token (v4), which you can get in your TMDB account settings.
+	// Required.
+	APIKey string
+	// Language for titles and descriptions, as ISO 639-1 code with an optional ISO 3166-1 region, like "de" or "pt-BR".
+	// Default "en-US".
+	Language string
+	// The base URL for the TMDB API.
+	// Default "https://api.themoviedb.org/3".
+	BaseURL string
+	// The base URL for images. The size (like "w500") and file path are appended.
+	// Default "https://image.tmdb.org/t/p/".
+	ImageBaseURL string
+	// Timeout for requests.
+	// Default 2 seconds.
+	Timeout time.Duration
+	// Max age of items in the cache.
+	// Default 30 days.
+	TTL time.Duration
+}
+
+// DefaultClientOpts is an options object with sensible defaults.
+var DefaultClientOpts = ClientOptions{
+	Language:     "en-US",
+	BaseURL:      "https://api.themoviedb.org/3",
+	ImageBaseURL: "https://image.tmdb.org/t/p/",
+	Timeout:      2 * time.Second,
+	TTL:          30 * 24 * time.Hour, // 30 days
+}
+
+var _ stremio.TypedMetaFetcher = (*Client)(nil)
+
+// Client is the TMDB client.
+type Client struct {
+	apiKey       string
+	language     string
+	baseURL      string
+	imageBaseURL string
+	httpClient   *http.Client
+	cache        cinemeta.Cache
+	logger       *zap.Logger
+	ttl          time.Duration
+}
+
+// NewClient creates a new TMDB client.
+// The cache works the same as for the Cinemeta client, so you can use the caches of the cinemeta package.
+// Use a separate cache instance though, because the cache keys could collide.
+func NewClient(opts ClientOptions, cache cinemeta.Cache, logger *zap.Logger) (*Client, error) {
+	if opts.APIKey == "" {
+		return nil, errors.New("an API key is required")
+	}
+	if opts.Language == "" {
+		opts.Language = DefaultClientOpts.Language
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultClientOpts.BaseURL
+	}
+	if opts.ImageBaseURL == "" {
+		opts.ImageBaseURL = DefaultClientOpts.ImageBaseURL
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultClientOpts.Timeout
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultClientOpts.TTL
+	}
+
+	return &Client{
+		apiKey:       opts.APIKey,
+		language:     opts.Language,
+		baseURL:      strings.TrimSuffix(opts.BaseURL, "/"),
+		imageBaseURL: opts.ImageBaseURL,
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+		cache:  cache,
+		logger: logger,
+		ttl:    opts.TTL,
+	}, nil
+}
+
+// GetMovie returns the meta object of the movie with the IMDb ID, either from the cache or from TMDB.
+func (c *Client) GetMovie(ctx context.Context, imdbID string) (types.MetaItem, error) {
+	tmdbID, err := c.findByIMDbID(ctx, imdbID, "movie")
+	if err != nil {
+		return types.MetaItem{}, err
+	}
+	meta, err := c.GetMovieByTMDBID(ctx, tmdbID)
+	if err != nil {
+		return types.MetaItem{}, err
+	}
+	meta.ID = imdbID
+	return meta, nil
+}
+
+// GetSeries returns the meta object of the TV show with the IMDb ID, either from the cache or from TMDB.
+// The videos are the episodes of the given season. A season of 0 leads to no videos.
+func (c *Client) GetSeries(ctx context.Context, imdbID string, season int, episode int) (types.MetaItem, error) {
+	tmdbID, err := c.findByIMDbID(ctx, imdbID, "series")
+	if err != nil {
+		return types.MetaItem{}, err
+	}
+	meta, err := c.GetSeriesByTMDBID(ctx, tmdbID, season)
+	if err != nil {
+		return types.MetaItem{}, err
+	}
+	meta.ID = imdbID
+	// The videos slice is shared with the cache.
+	meta.Videos = slices.Clone(meta.Videos)
+	for i, v := range meta.Videos {
+		meta.Videos[i].ID = fmt.Sprintf("%v:%v:%v", imdbID, v.Season, v.Episode)
+	}
+	return meta, nil
+}
+
+// GetMeta returns the meta object of the movie or TV show ("series") with the IMDb or TMDB ID, like "tt0944947" or "tmdb:1399".
+// The ID can contain a season and episode like in stream requests ("tmdb:1399:1:2"), then the videos are the episodes of that season.
+// It returns cinemeta.ErrUnsupportedID for other types and ID schemes.
+func (c *Client) GetMeta(ctx context.Context, metaType string, id string) (types.MetaItem, error) {
+	if metaType != "movie" && metaType != "series" {
+		return types.MetaItem{}, fmt.Errorf("%w: type %v", cinemeta.ErrUnsupportedID, metaType)
+	}
+	parts := strings.Split(id, ":")
+	var tmdbID, season int
+	var err error
+	switch {
+	case strings.HasPrefix(id, "tt"):
+		if metaType == "movie" {
+			return c.GetMovie(ctx, parts[0])
+		}
+		if len(parts) == 3 {
+			season, _ = strconv.Atoi(parts[1])
+		}
+		return c.GetSeries(ctx, parts[0], season, 0)
+	case parts[0] == "tmdb" && len(parts) > 1:
+		if tmdbID, err = strconv.Atoi(parts[1]); err != nil {
+			return types.MetaItem{}, fmt.Errorf("%w: %v", cinemeta.ErrUnsupportedID, id)
+		}
+	default:
+		return types.MetaItem{}, fmt.Errorf("%w: %v", cinemeta.ErrUnsupportedID, id)
+	}
+	if metaType == "movie" {
+		return c.GetMovieByTMDBID(ctx, tmdbID)
+	}
+	if len(parts) == 4 {
+		season, _ = strconv.Atoi(parts[2])
+	}
+	return c.GetSeriesByTMDBID(ctx, tmdbID, season)
+}
+
+// FindByIMDbID returns the TMDB ID and the type ("movie" or "series") for the IMDb ID.
+func (c *Client) FindByIMDbID(ctx context.Context, imdbID string) (int, string, error) {
+	// The result is cached as meta object with just the TMDB ID and type.
+	cacheKey := "find:" + imdbID
+	if meta, ok := c.fromCache(cacheKey); ok {
+		tmdbID, err := strconv.Atoi(strings.TrimPrefix(meta.ID, "tmdb:"))
+		if err == nil {
+			return tmdbID, meta.Type, nil
+		}
+	}
+
+	var res findResponse
+	if err := c.get(ctx, "/find/"+url.PathEscape(imdbID), url.Values{"external_source": {"imdb_id"}}, &res); err != nil {
+		return 0, "", err
+	}
+	var tmdbID int
+	var mediaType string
+	switch {
+	case len(res.MovieResults) > 0:
+		tmdbID, mediaType = res.MovieResults[0].ID, "movie"
+	case len(res.TVResults) > 0:
+		tmdbID, mediaType = res.TVResults[0].ID, "series"
+	default:
+		return 0, "", ErrNotFound
+	}
+	c.toCache(cacheKey, types.MetaItem{ID: "tmdb:" + strconv.Itoa(tmdbID), Type: mediaType})
+	return tmdbID, mediaType, nil
+}
+
+// GetMovieByTMDBID returns the meta object of the movie with the TMDB ID, either from the cache or from TMDB.
+// The ID of the meta object is "tmdb:" followed by the TMDB ID.
+func (c *Client) GetMovieByTMDBID(ctx context.Context, tmdbID int) (types.MetaItem, error) {
+	cacheKey := fmt.Sprintf("movie:%v:%v", tmdbID, c.language)
+	if meta, ok := c.fromCache(cacheKey); ok {
+		return meta, nil
+	}
+
+	var res movieResponse
+	query := url.Values{"append_to_response": {"credits,videos"}}
+	if err := c.get(ctx, "/movie/"+strconv.Itoa(tmdbID), query, &res); err != nil {
+		return types.MetaItem{}, err
+	}
+	meta := types.MetaItem{
+		ID:          "tmdb:" + strconv.Itoa(tmdbID),
+		Type:        "movie",
+		Name:        res.Title,
+		Description: res.Overview,
+		Released:    toISO8601(res.ReleaseDate),
+		ReleaseInfo: year(res.ReleaseDate),
+		Language:    res.OriginalLanguage,
+	}
+	if res.Runtime > 0 {
+		meta.Runtime = strconv.Itoa(res.Runtime) + " min"
+	}
+	if res.IMDbID != "" {
+		meta.Website = "https://www.imdb.com/title/" + res.IMDbID
+	}
+	c.fillCommon(&meta, res.commonResponse)
+	c.toCache(cacheKey, meta)
+	return meta, nil
+}
+
+// GetSeriesByTMDBID returns the meta object of the TV show with the TMDB ID, either from the cache or from TMDB.
+// The videos are the episodes of the given season. A season of 0 leads to no videos.
+// The ID of the meta object is "tmdb:" followed by the TMDB ID, the video IDs are "tmdb:<id>:<season>:<episode>".
+func (c *Client) GetSeriesByTMDBID(ctx context.Context, tmdbID int, season int) (types.MetaItem, error) {
+	cacheKey := fmt.Sprintf("series:%v:%v:%v", tmdbID, season, c.language)
+	if meta, ok := c.fromCache(cacheKey); ok {
+		return meta, nil
+	}
+
+	var res seriesResponse
+	appendToResponse := "credits,videos"
+	if season > 0 {
+		appendToResponse += ",season/" + strconv.Itoa(season)
+	}
+	query := url.Values{"append_to_response": {appendToResponse}}
+	if err := c.get(ctx, "/tv/"+strconv.Itoa(tmdbID), query, &res); err != nil {
+		return types.MetaItem{}, err
+	}
+	id := "tmdb:" + strconv.Itoa(tmdbID)
+	meta := types.MetaItem{
+		ID:          id,
+		Type:        "series",
+		Name:        res.Name,
+		Description: res.Overview,
+		Released:    toISO8601(res.FirstAirDate),
+		Language:    res.OriginalLanguage,
+	}
+	if releaseInfo := year(res.FirstAirDate); releaseInfo != "" {
+		if res.InProduction {
+			releaseInfo += "-"
+		} else if lastYear := year(res.LastAirDate); lastYear != "" && lastYear != releaseInfo {
+			releaseInfo += "-" + lastYear
+		}
+		meta.ReleaseInfo = releaseInfo
+	}
+	if len(res.EpisodeRunTime) > 0 {
+		meta.Runtime = strconv.Itoa(res.EpisodeRunTime[0]) + " min"
+	}
+	for _, creator := range res.CreatedBy {
+		meta.Director = append(meta.Director, creator.Name)
+	}
+	if season > 0 {
+		if raw, ok := res.Extra["season/"+strconv.Itoa(season)]; ok {
+			var s seasonResponse
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return types.MetaItem{}, fmt.Errorf("couldn't unmarshal season: %w", err)
+			}
+			for _, e := range s.Episodes {
+				video := types.VideoItem{
+					ID:       fmt.Sprintf("%v:%v:%v", id, e.SeasonNumber, e.EpisodeNumber),
+					Title:    e.Name,
+					Released: toISO8601(e.AirDate),
+					Season:   e.SeasonNumber,
+					Episode:  e.EpisodeNumber,
+					Overview: e.Overview,
+				}
+				if e.StillPath != "" {
+					video.Thumbnail = c.imageBaseURL + "w300" + e.StillPath
+				}
+				meta.Videos = append(meta.Videos, video)
+			}
+		}
+	}
+	c.fillCommon(&meta, res.commonResponse)
+	c.toCache(cacheKey, meta)
+	return meta, nil
+}
+
+func (c *Client) fillCommon(meta *types.MetaItem, res commonResponse) {
+	for _, g := range res.Genres {
+		meta.Genres = append(meta.Genres, g.Name)
+	}
+	if res.PosterPath != "" {
+		meta.Poster = c.imageBaseURL + "w500" + res.PosterPath
+	}
+	if res.BackdropPath != "" {
+		meta.Background = c.imageBaseURL + "original" + res.BackdropPath
+	}
+	for _, crew := range res.Credits.Crew {
+		if crew.Job == "Director" {
+			meta.Director = append(meta.Director, crew.Name)
+		}
+	}
+	for i, cast := range res.Credits.Cast {
+		// Like Cinemeta, only the main cast.
+		if i == 5 {
+			break
+		}
+		meta.Cast = append(meta.Cast, cast.Name)
+	}
+	for _, v := range res.Videos.Results {
+		if v.Site == "YouTube" && v.Type == "Trailer" {
+			meta.Trailers = append(meta.Trailers, types.StreamItem{YoutubeID: v.Key})
+		}
+	}
+	if res.VoteAverage > 0 {
+		meta.IMDbRating = strconv.FormatFloat(res.VoteAverage, 'f', 1, 64)
+	}
+}
+
+func (c *Client) findByIMDbID(ctx context.Context, imdbID, wantedType string) (int, error) {
+	tmdbID, mediaType, err := c.FindByIMDbID(ctx, imdbID)
+	if err != nil {
+		return 0, err
+	}
+	if mediaType != wantedType {
+		return 0, ErrNotFound
+	}
+	return tmdbID, nil
+}
+
+func (c *Client) fromCache(key string) (types.MetaItem, bool) {
+	item, created, found, err := c.cache.Get(key)
+	switch {
+	case err != nil:
+		c.logger.Error("Couldn't decode meta", zap.Error(err), zap.String("key", key))
+		return types.MetaItem{}, false
+	case !found:
+		c.logger.Debug("Meta not found in cache", zap.String("key", key))
+		return types.MetaItem{}, false
+	case time.Since(created) > c.ttl:
+		c.logger.Debug("Hit cache for meta, but item is expired", zap.String("key", key))
+		return types.MetaItem{}, false
+	}
+	return item, true
+}
+
+func (c *Client) toCache(key string, meta types.MetaItem) {
+	if err := c.cache.Set(key, meta); err != nil {
+		c.logger.Error("Couldn't cache meta", zap.Error(err), zap.String("key", key))
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, result any) error {
+	query.Set("language", c.language)
+	// v4 read access tokens are JWTs and go into the header, v3 API keys into the query.
+	isToken := strings.HasPrefix(c.apiKey, "eyJ")
+	if !isToken {
+		query.Set("api_key", c.apiKey)
+	}
+	reqURL := c.baseURL + path + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't create request: %w", err)
+	}
+	if isToken {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		// Don't log the URL, it can contain the API key.
+		return fmt.Errorf("couldn't GET %v: %w", path, err)
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case res.StatusCode != http.StatusOK:
+		return fmt.Errorf("bad GET response: %v", res.StatusCode)
+	}
+	if err = json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+	return nil
+}
+
+// year returns the year of a "2006-01-02" date.
+func year(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	return date[:4]
+}
+
+// toISO8601 converts a "2006-01-02" date to the format that Stremio expects.
+func toISO8601(date string) string {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05.000Z")
+}
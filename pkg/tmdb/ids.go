@@ -0,0 +1,83 @@
+package tmdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xybydy/go-stremio/types"
+)
+
+// externalIDsResponse is the response of the external IDs endpoints for movies and TV shows.
+type externalIDsResponse struct {
+	IMDbID string `json:"imdb_id"`
+}
+
+// FindIMDbID returns the IMDb ID for the TMDB ID of a movie or TV show (metaType "movie" or "series").
+// The result is cached like meta objects.
+func (c *Client) FindIMDbID(ctx context.Context, metaType string, tmdbID int) (string, error) {
+	var path string
+	switch metaType {
+	case "movie":
+		path = "/movie/" + strconv.Itoa(tmdbID) + "/external_ids"
+	case "series":
+		path = "/tv/" + strconv.Itoa(tmdbID) + "/external_ids"
+	default:
+		return "", fmt.Errorf("unsupported type: %v", metaType)
+	}
+
+	// The result is cached as meta object with just the IMDb ID and type.
+	cacheKey := fmt.Sprintf("imdb:%v:%v", metaType, tmdbID)
+	if meta, ok := c.fromCache(cacheKey); ok {
+		return meta.ID, nil
+	}
+
+	var res externalIDsResponse
+	if err := c.get(ctx, path, url.Values{}, &res); err != nil {
+		return "", err
+	}
+	if res.IMDbID == "" {
+		return "", ErrNotFound
+	}
+	c.toCache(cacheKey, types.MetaItem{ID: res.IMDbID, Type: metaType})
+	return res.IMDbID, nil
+}
+
+// TranslateID converts an IMDb ID to a TMDB ID and vice versa, so addons whose upstream uses TMDB IDs can answer Stremio's IMDb-keyed requests.
+// "tt0944947" becomes "tmdb:1399" and the other way around. A season and episode like in stream requests are kept,
+// so "tt0944947:1:2" becomes "tmdb:1399:1:2". The type ("movie" or "series") is required for TMDB IDs, as movies and TV shows have separate ID spaces.
+// It returns ErrNotFound if TMDB doesn't know the ID or the ID is of a different type.
+func (c *Client) TranslateID(ctx context.Context, metaType string, id string) (string, error) {
+	switch {
+	case strings.HasPrefix(id, "tt"):
+		imdbID, suffix, _ := strings.Cut(id, ":")
+		tmdbID, err := c.findByIMDbID(ctx, imdbID, metaType)
+		if err != nil {
+			return "", err
+		}
+		return joinID("tmdb:"+strconv.Itoa(tmdbID), suffix), nil
+	case strings.HasPrefix(id, "tmdb:"):
+		tmdbIDString, suffix, _ := strings.Cut(strings.TrimPrefix(id, "tmdb:"), ":")
+		tmdbID, err := strconv.Atoi(tmdbIDString)
+		if err != nil {
+			return "", fmt.Errorf("invalid TMDB ID: %v", id)
+		}
+		imdbID, err := c.FindIMDbID(ctx, metaType, tmdbID)
+		if err != nil {
+			return "", err
+		}
+		return joinID(imdbID, suffix), nil
+	default:
+		return "", fmt.Errorf("neither an IMDb nor a TMDB ID: %v", id)
+	}
+}
+
+// joinID appends the suffix (like "1:2" for season and episode) to the ID, if there is one.
+func joinID(id, suffix string) string {
+	if suffix == "" {
+		return id
+	}
+	return id + ":" + suffix
+}
@@ -0,0 +1,83 @@
+package tmdb
+
+import "encoding/json"
+
+type findResponse struct {
+	MovieResults []struct {
+		ID int `json:"id"`
+	} `json:"movie_results"`
+	TVResults []struct {
+		ID int `json:"id"`
+	} `json:"tv_results"`
+}
+
+// commonResponse contains the fields that movie and TV show responses have in common.
+type commonResponse struct {
+	Overview         string `json:"overview"`
+	OriginalLanguage string `json:"original_language"`
+	PosterPath       string `json:"poster_path"`
+	BackdropPath     string `json:"backdrop_path"`
+	// TMDB's own rating, but it's the closest to IMDb's that TMDB offers.
+	VoteAverage float64 `json:"vote_average"`
+	Genres      []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	Credits struct {
+		Cast []struct {
+			Name string `json:"name"`
+		} `json:"cast"`
+		Crew []struct {
+			Name string `json:"name"`
+			Job  string `json:"job"`
+		} `json:"crew"`
+	} `json:"credits"`
+	Videos struct {
+		Results []struct {
+			Key  string `json:"key"`
+			Site string `json:"site"`
+			Type string `json:"type"`
+		} `json:"results"`
+	} `json:"videos"`
+}
+
+type movieResponse struct {
+	commonResponse
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date"`
+	Runtime     int    `json:"runtime"`
+	IMDbID      string `json:"imdb_id"`
+}
+
+type seriesResponse struct {
+	commonResponse
+	Name           string `json:"name"`
+	FirstAirDate   string `json:"first_air_date"`
+	LastAirDate    string `json:"last_air_date"`
+	InProduction   bool   `json:"in_production"`
+	EpisodeRunTime []int  `json:"episode_run_time"`
+	CreatedBy      []struct {
+		Name string `json:"name"`
+	} `json:"created_by"`
+	// All fields, for the appended "season/<n>" responses, whose keys depend on the request.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (r *seriesResponse) UnmarshalJSON(data []byte) error {
+	// Alias type without the UnmarshalJSON method, to prevent infinite recursion.
+	type plain seriesResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.Extra)
+}
+
+type seasonResponse struct {
+	Episodes []struct {
+		Name          string `json:"name"`
+		Overview      string `json:"overview"`
+		AirDate       string `json:"air_date"`
+		SeasonNumber  int    `json:"season_number"`
+		EpisodeNumber int    `json:"episode_number"`
+		StillPath     string `json:"still_path"`
+	} `json:"episodes"`
+}
@@ -0,0 +1,118 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Max nesting depth of bencoded lists and dicts, to not exhaust the stack with malicious input.
+const maxBencodeDepth = 64
+
+var errUnexpectedEnd = errors.New("unexpected end of bencoded data")
+
+// bdecoder decodes bencoded data into int64, string, []any and map[string]any values.
+// It also records the raw bytes of the top level "info" dict, which are required for computing the info hash.
+type bdecoder struct {
+	data    []byte
+	pos     int
+	rawInfo []byte
+}
+
+func (d *bdecoder) decode(depth int) (any, error) {
+	if depth > maxBencodeDepth {
+		return nil, errors.New("bencoded data is nested too deeply")
+	}
+	if d.pos >= len(d.data) {
+		return nil, errUnexpectedEnd
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.decodeInt()
+	case c == 'l':
+		d.pos++
+		var list []any
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errUnexpectedEnd
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return list, nil
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case c == 'd':
+		d.pos++
+		dict := map[string]any{}
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errUnexpectedEnd
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return dict, nil
+			}
+			key, err := d.decodeString()
+			if err != nil {
+				return nil, fmt.Errorf("couldn't decode dict key: %w", err)
+			}
+			start := d.pos
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if depth == 0 && key == "info" {
+				d.rawInfo = d.data[start:d.pos]
+			}
+			dict[key] = v
+		}
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("invalid bencoded value at position %v: %q", d.pos, c)
+	}
+}
+
+func (d *bdecoder) decodeInt() (int64, error) {
+	end := d.indexFrom('e', d.pos+1)
+	if end == -1 {
+		return 0, errUnexpectedEnd
+	}
+	i, err := strconv.ParseInt(string(d.data[d.pos+1:end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bencoded integer: %w", err)
+	}
+	d.pos = end + 1
+	return i, nil
+}
+
+func (d *bdecoder) decodeString() (string, error) {
+	colon := d.indexFrom(':', d.pos)
+	if colon == -1 {
+		return "", errUnexpectedEnd
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid bencoded string length at position %v", d.pos)
+	}
+	start := colon + 1
+	if length > len(d.data)-start {
+		return "", errUnexpectedEnd
+	}
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *bdecoder) indexFrom(c byte, from int) int {
+	for i := from; i < len(d.data); i++ {
+		if d.data[i] == c {
+			return i
+		}
+	}
+	return -1
+}
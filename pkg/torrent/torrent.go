@@ -0,0 +1,281 @@
+// Package torrent turns .torrent files into torrent StreamItems.
+// It computes the info hash and selects the right file index by filename, size or episode heuristics,
+// so stream handlers can work with torrent URLs from indexers.
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/xybydy/go-stremio/pkg/magnet"
+	"github.com/xybydy/go-stremio/pkg/release"
+	"github.com/xybydy/go-stremio/types"
+)
+
+// ErrNoMetadataFetcher signals that metadata for a magnet link was requested, but no MetadataFetcher is configured.
+var ErrNoMetadataFetcher = errors.New("no metadata fetcher configured for magnet links")
+
+// File is a file in a torrent.
+type File struct {
+	// Path within the torrent, with "/" as separator. For single file torrents it's the torrent name.
+	Path   string
+	Length int64
+}
+
+// Metadata is the metadata of a torrent that's relevant for streaming.
+type Metadata struct {
+	// Lowercase hex encoded info hash.
+	InfoHash string
+	Name     string
+	// Files in the order of the torrent, so the index in this slice is the file index that Stremio expects.
+	Files []File
+	// Tracker URLs from "announce" and "announce-list".
+	Trackers []string
+}
+
+// MetadataFetcher fetches torrent metadata by info hash, for example from the DHT or peers (BEP 9).
+// The package doesn't include an implementation, but you can plug one into Options, for example based on anacrolix/torrent.
+type MetadataFetcher interface {
+	FetchMetadata(ctx context.Context, infoHash string, trackers []string) (Metadata, error)
+}
+
+// Parse parses the content of a .torrent file.
+func Parse(data []byte) (Metadata, error) {
+	d := &bdecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("couldn't decode torrent: %w", err)
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return Metadata{}, errors.New("torrent isn't a bencoded dict")
+	}
+	info, ok := root["info"].(map[string]any)
+	if !ok || d.rawInfo == nil {
+		return Metadata{}, errors.New("torrent doesn't contain an info dict")
+	}
+
+	hash := sha1.Sum(d.rawInfo)
+	m := Metadata{
+		InfoHash: hex.EncodeToString(hash[:]),
+	}
+	m.Name, _ = info["name"].(string)
+	if utf8Name, ok := info["name.utf-8"].(string); ok {
+		m.Name = utf8Name
+	}
+
+	if files, ok := info["files"].([]any); ok {
+		for _, f := range files {
+			fileDict, ok := f.(map[string]any)
+			if !ok {
+				return Metadata{}, errors.New("invalid file entry in torrent")
+			}
+			length, _ := fileDict["length"].(int64)
+			pathList, _ := fileDict["path"].([]any)
+			if utf8Path, ok := fileDict["path.utf-8"].([]any); ok {
+				pathList = utf8Path
+			}
+			elems := make([]string, 0, len(pathList))
+			for _, elem := range pathList {
+				if s, ok := elem.(string); ok {
+					elems = append(elems, s)
+				}
+			}
+			m.Files = append(m.Files, File{Path: strings.Join(elems, "/"), Length: length})
+		}
+	} else {
+		length, _ := info["length"].(int64)
+		m.Files = []File{{Path: m.Name, Length: length}}
+	}
+
+	addTracker := func(tr string) {
+		for _, existing := range m.Trackers {
+			if existing == tr {
+				return
+			}
+		}
+		m.Trackers = append(m.Trackers, tr)
+	}
+	if announce, ok := root["announce"].(string); ok && announce != "" {
+		addTracker(announce)
+	}
+	if tiers, ok := root["announce-list"].([]any); ok {
+		for _, tier := range tiers {
+			trackers, _ := tier.([]any)
+			for _, tr := range trackers {
+				if s, ok := tr.(string); ok && s != "" {
+					addTracker(s)
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// FileHint contains what's known about the wanted file, for selecting it among a torrent's files.
+// All fields are optional.
+type FileHint struct {
+	// Exact filename (without directories) or path within the torrent.
+	Filename string
+	// Exact file size in bytes.
+	Size int64
+	// Season and episode for series, like from the "tt0944947:1:2" stream ID.
+	Season  int
+	Episode int
+}
+
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".mov": true,
+	".wmv": true, ".ts": true, ".webm": true, ".mpg": true, ".mpeg": true, ".flv": true,
+}
+
+// SelectFile returns the index of the file that matches the hint best, or -1 if there's no video file.
+// It checks in this order: exact filename, exact size, season and episode in the filename, and otherwise
+// falls back to the biggest video file (which skips samples and extras).
+func (m Metadata) SelectFile(hint FileHint) int {
+	if hint.Filename != "" {
+		for i, f := range m.Files {
+			if f.Path == hint.Filename || path.Base(f.Path) == hint.Filename {
+				return i
+			}
+		}
+	}
+	if hint.Size != 0 {
+		for i, f := range m.Files {
+			if f.Length == hint.Size {
+				return i
+			}
+		}
+	}
+
+	best := -1
+	for i, f := range m.Files {
+		if !videoExtensions[strings.ToLower(path.Ext(f.Path))] {
+			continue
+		}
+		if hint.Episode != 0 {
+			info := release.Parse(path.Base(f.Path))
+			if info.Episode != hint.Episode || (info.Season != 0 && hint.Season != 0 && info.Season != hint.Season) {
+				continue
+			}
+		}
+		if best == -1 || f.Length > m.Files[best].Length {
+			best = i
+		}
+	}
+	return best
+}
+
+// Stream returns a torrent StreamItem for the file matching the hint, with the trackers and DHT as sources.
+// Stremio's file index is limited to 255, so torrents where the selected file has a higher index lead to an error.
+func (m Metadata) Stream(hint FileHint) (types.StreamItem, error) {
+	i := m.SelectFile(hint)
+	switch {
+	case i == -1:
+		return types.StreamItem{}, errors.New("no matching video file in torrent")
+	case i > 255:
+		return types.StreamItem{}, fmt.Errorf("file index %v is too big for a stream", i)
+	}
+	s := magnet.Magnet{InfoHash: m.InfoHash, Trackers: m.Trackers}.Stream()
+	s.FileIndex = uint8(i)
+	s.BehaviorHints.Filename = path.Base(m.Files[i].Path)
+	s.BehaviorHints.VideoSize = int(m.Files[i].Length)
+	return s, nil
+}
+
+// Options are the options for the Resolver.
+type Options struct {
+	// Timeout for downloading a .torrent file.
+	// Default 5 seconds.
+	Timeout time.Duration
+	// Max size of a .torrent file in bytes.
+	// Default 10 MB.
+	MaxSize int64
+	// Optional fetcher for resolving magnet links, which don't contain the file list.
+	// Default nil.
+	MetadataFetcher MetadataFetcher
+}
+
+// DefaultOptions is an options object with sensible defaults.
+var DefaultOptions = Options{
+	Timeout: 5 * time.Second,
+	MaxSize: 10 << 20,
+}
+
+// Resolver resolves torrent URLs and magnet links to metadata.
+type Resolver struct {
+	httpClient      *http.Client
+	maxSize         int64
+	metadataFetcher MetadataFetcher
+}
+
+// NewResolver creates a new Resolver.
+func NewResolver(opts Options) *Resolver {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultOptions.MaxSize
+	}
+	return &Resolver{
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+		maxSize:         opts.MaxSize,
+		metadataFetcher: opts.MetadataFetcher,
+	}
+}
+
+// Resolve downloads and parses the .torrent file at the given HTTP(S) URL,
+// or uses the configured MetadataFetcher for magnet links.
+func (r *Resolver) Resolve(ctx context.Context, torrentURL string) (Metadata, error) {
+	if strings.HasPrefix(torrentURL, "magnet:") {
+		m, err := magnet.Parse(torrentURL)
+		if err != nil {
+			return Metadata{}, err
+		}
+		if r.metadataFetcher == nil {
+			return Metadata{}, ErrNoMetadataFetcher
+		}
+		return r.metadataFetcher.FetchMetadata(ctx, m.InfoHash, m.Trackers)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, torrentURL, nil)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("couldn't create request: %w", err)
+	}
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("couldn't GET %v: %w", torrentURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Metadata{}, fmt.Errorf("bad GET response: %v", res.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, r.maxSize+1))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	if int64(len(data)) > r.maxSize {
+		return Metadata{}, fmt.Errorf("torrent is bigger than %v bytes", r.maxSize)
+	}
+	return Parse(data)
+}
+
+// Stream resolves the torrent URL or magnet link and returns a torrent StreamItem for the file matching the hint.
+func (r *Resolver) Stream(ctx context.Context, torrentURL string, hint FileHint) (types.StreamItem, error) {
+	m, err := r.Resolve(ctx, torrentURL)
+	if err != nil {
+		return types.StreamItem{}, err
+	}
+	return m.Stream(hint)
+}
@@ -0,0 +1,45 @@
+package stremio
+
+import (
+	"time"
+)
+
+// ProductionOptions returns Options with good defaults for running an addon in production, which can be changed before passing them to NewAddon.
+// The addon listens on all interfaces, as is common in containers, and logs JSON for centralized log solutions.
+// Responses are cached by Stremio and proxies for some time, and can be revalidated with ETags:
+// catalogs for 6 hours, streams for 1 hour and meta objects for 1 day, with stale-while-revalidate and stale-if-error for longer.
+// The caching is private, so responses for configured users aren't shared. Set CachePublicCatalogs etc. for addons without user data.
+// The shutdown timeout fits `docker stop`. Metrics aren't enabled, as they'd be public without an APIKey or MetricsAuth.
+func ProductionOptions() Options {
+	return Options{
+		BindAddr:                "0.0.0.0",
+		LogEncoding:             "json",
+		CacheAgeCatalogs:        6 * time.Hour,
+		StaleRevalidateCatalogs: 24 * time.Hour,
+		StaleErrorCatalogs:      7 * 24 * time.Hour,
+		HandleEtagCatalogs:      true,
+		CacheAgeStreams:         time.Hour,
+		StaleRevalidateStreams:  4 * time.Hour,
+		StaleErrorStreams:       24 * time.Hour,
+		HandleEtagStreams:       true,
+		CacheAgeMeta:            24 * time.Hour,
+		StaleRevalidateMeta:     7 * 24 * time.Hour,
+		StaleErrorMeta:          7 * 24 * time.Hour,
+		HandleEtagMeta:          true,
+		ShutdownTimeout:         8 * time.Second,
+	}
+}
+
+// DevelopmentOptions returns Options with good defaults for developing an addon, which can be changed before passing them to NewAddon.
+// The addon only listens on localhost and logs on the debug level including the user agent, for seeing what Stremio requests.
+// Nothing is cached, so changes show up immediately. Profiling and the OpenAPI document are enabled for inspecting the addon.
+func DevelopmentOptions() Options {
+	return Options{
+		BindAddr:     "localhost",
+		LoggingLevel: "debug",
+		LogEncoding:  "console",
+		LogUserAgent: true,
+		Profiling:    true,
+		OpenAPI:      true,
+	}
+}
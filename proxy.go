@@ -0,0 +1,235 @@
+package stremio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Request headers that are forwarded from the client to the upstream, so seeking in the player works.
+var proxyRequestHeaders = []string{
+	fiber.HeaderRange,
+	fiber.HeaderIfRange,
+}
+
+// Response headers that are forwarded from the upstream to the client.
+var proxyResponseHeaders = []string{
+	fiber.HeaderContentType,
+	fiber.HeaderContentRange,
+	fiber.HeaderAcceptRanges,
+	fiber.HeaderContentDisposition,
+	fiber.HeaderLastModified,
+	fiber.HeaderETag,
+}
+
+type streamProxy struct {
+	signer     *signedurl.Signer
+	httpClient *http.Client
+	// Semaphore for limiting the number of concurrent proxied streams. Nil if there's no limit.
+	conns chan struct{}
+	// Per-user limits. 0 means no limit.
+	maxUserConns  int
+	userBandwidth int
+	// Users with active streams, by user key.
+	usersLock sync.Mutex
+	users     map[string]*proxyUser
+	logger    *zap.Logger
+}
+
+type proxyUser struct {
+	conns int
+	// Shared by all streams of the user. Nil if there's no bandwidth limit.
+	limiter *rate.Limiter
+}
+
+func createProxyHandler(signer *signedurl.Signer, maxConns, maxUserConns, userBandwidth int, logger *zap.Logger) fiber.Handler {
+	p := &streamProxy{
+		signer: signer,
+		httpClient: &http.Client{
+			// No overall timeout, as streams can take hours. Only wait a limited time for the upstream to respond.
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 10 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+				// The body must be passed through as is, otherwise Content-Length and Content-Range would be wrong.
+				DisableCompression: true,
+			},
+		},
+		maxUserConns:  maxUserConns,
+		userBandwidth: userBandwidth,
+		users:         make(map[string]*proxyUser),
+		logger:        logger,
+	}
+	if maxConns > 0 {
+		p.conns = make(chan struct{}, maxConns)
+	}
+	return p.handle
+}
+
+func (p *streamProxy) handle(c fiber.Ctx) error {
+	p.logger.Debug("proxyHandler called")
+
+	target, err := p.signer.Verify(c.Params("token"))
+	switch {
+	case errors.Is(err, signedurl.ErrExpired):
+		p.logger.Debug("Got request with expired token; returning 410")
+		return c.SendStatus(fiber.StatusGone)
+	case err != nil:
+		p.logger.Warn("Got request with invalid token; returning 403", zap.Error(err))
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if p.conns != nil {
+		select {
+		case p.conns <- struct{}{}:
+		default:
+			p.logger.Warn("Max number of proxy connections reached; returning 503")
+			c.Set(fiber.HeaderRetryAfter, "5")
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		}
+	}
+	userKey := target.User
+	if userKey == "" {
+		userKey = c.IP()
+	}
+	limiter, ok := p.acquireUser(userKey)
+	if !ok {
+		if p.conns != nil {
+			<-p.conns
+		}
+		p.logger.Warn("Max number of proxy connections per user reached; returning 429")
+		c.Set(fiber.HeaderRetryAfter, "5")
+		return c.SendStatus(fiber.StatusTooManyRequests)
+	}
+	release := sync.OnceFunc(func() {
+		p.releaseUser(userKey)
+		if p.conns != nil {
+			<-p.conns
+		}
+	})
+
+	// The request context isn't canceled when the client disconnects, but closing the upstream body is enough to abort the request.
+	req, err := http.NewRequestWithContext(context.Background(), c.Method(), target.URL, nil)
+	if err != nil {
+		release()
+		p.logger.Error("Couldn't create upstream request", zap.Error(err))
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	for _, h := range proxyRequestHeaders {
+		if v := c.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		release()
+		p.logger.Warn("Couldn't reach upstream; returning 502", zap.Error(err))
+		return c.SendStatus(fiber.StatusBadGateway)
+	}
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+	default:
+		res.Body.Close()
+		release()
+		p.logger.Warn("Got bad upstream response; returning 502", zap.Int("status", res.StatusCode))
+		return c.SendStatus(fiber.StatusBadGateway)
+	}
+
+	for _, h := range proxyResponseHeaders {
+		if v := res.Header.Get(h); v != "" {
+			c.Set(h, v)
+		}
+	}
+	// Tokens expire, so clients must not cache the response.
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Status(res.StatusCode)
+	// Fasthttp closes the body stream when the response is written or the client disconnects,
+	// which releases the upstream connection and our connection slot.
+	body := &proxyBody{Reader: res.Body, closer: res.Body, release: release}
+	if limiter != nil {
+		body.Reader = &throttledReader{r: res.Body, limiter: limiter}
+	}
+	return c.SendStream(body, int(res.ContentLength))
+}
+
+// acquireUser registers a stream for the user and returns the user's bandwidth limiter, which is nil if there's no limit.
+// It returns false if the user already has the max number of streams.
+func (p *streamProxy) acquireUser(key string) (*rate.Limiter, bool) {
+	if p.maxUserConns == 0 && p.userBandwidth == 0 {
+		return nil, true
+	}
+	p.usersLock.Lock()
+	defer p.usersLock.Unlock()
+	u, ok := p.users[key]
+	if !ok {
+		u = &proxyUser{}
+		if p.userBandwidth > 0 {
+			// Burst of one second, so reads aren't split into tiny chunks.
+			u.limiter = rate.NewLimiter(rate.Limit(p.userBandwidth), p.userBandwidth)
+		}
+		p.users[key] = u
+	}
+	if p.maxUserConns > 0 && u.conns >= p.maxUserConns {
+		return nil, false
+	}
+	u.conns++
+	return u.limiter, true
+}
+
+func (p *streamProxy) releaseUser(key string) {
+	if p.maxUserConns == 0 && p.userBandwidth == 0 {
+		return
+	}
+	p.usersLock.Lock()
+	defer p.usersLock.Unlock()
+	u, ok := p.users[key]
+	if !ok {
+		return
+	}
+	u.conns--
+	if u.conns <= 0 {
+		delete(p.users, key)
+	}
+}
+
+type proxyBody struct {
+	io.Reader
+	closer  io.Closer
+	release func()
+}
+
+func (b *proxyBody) Close() error {
+	defer b.release()
+	return b.closer.Close()
+}
+
+// throttledReader limits the read rate with a limiter that can be shared by multiple readers.
+type throttledReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.WaitN(context.Background(), n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
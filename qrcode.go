@@ -0,0 +1,51 @@
+package stremio
+
+import (
+	"encoding/base64"
+	"html/template"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+	"rsc.io/qr"
+)
+
+// QRCodePNG returns a QR code of the text as PNG image, for example of the "stremio://" install URL (see StremioURL),
+// so users can install the addon on devices where typing URLs is cumbersome, like Android TV.
+func QRCodePNG(text string) ([]byte, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	code.Scale = 6
+	return code.PNG(), nil
+}
+
+// qrCodeDataURL returns a QR code of the text as "data:" URL for "img" elements.
+func qrCodeDataURL(text string) (template.URL, error) {
+	png, err := QRCodePNG(text)
+	if err != nil {
+		return "", err
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)), nil
+}
+
+// createQRCodeHandler creates a handler that responds with a QR code of the "stremio://" install URL,
+// including the user data if the route has it. Configure pages can show it with an "img" element.
+func createQRCodeHandler(manifest types.Manifest, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("qrCodeHandler called")
+
+		userData := c.Params("userData")
+		if userData == "" && manifest.BehaviorHints.ConfigurationRequired {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		png, err := QRCodePNG(StremioURL(c.BaseURL(), userData))
+		if err != nil {
+			logger.Error("Couldn't create QR code", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, "image/png")
+		return c.Send(png)
+	}
+}
@@ -0,0 +1,19 @@
+package stremio
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/xybydy/go-stremio/pkg/addoncheck"
+)
+
+// SelfCheck runs the addon in-process and checks its endpoints like a Stremio addon linter:
+// the manifest's schema, CORS headers, whether the resource routes respond and whether the responses have the expected shape.
+// It's useful in tests and before deployments. Addons that require configuration need opts.UserData.
+// See the addoncheck package for details, and the go-stremio CLI for checking deployed addons.
+func (a *Addon) SelfCheck(ctx context.Context, opts addoncheck.Options) addoncheck.Report {
+	srv := httptest.NewServer(adaptor.FiberApp(a.App(nil)))
+	defer srv.Close()
+	return addoncheck.Check(ctx, srv.URL, opts)
+}
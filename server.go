@@ -0,0 +1,298 @@
+package stremio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp/reuseport"
+	"go.uber.org/zap"
+)
+
+// controlRequest is a request of Stop or Restart to a running Run.
+type controlRequest struct {
+	restart bool
+	// Gets the result when the server was shut down, or started again for a restart
+	done chan error
+}
+
+// runControl is the state of a running Run, for Stop and Restart.
+type runControl struct {
+	requests chan controlRequest
+	// Closed when Run returns
+	stopped chan struct{}
+}
+
+// Run starts the remote addon. It sets up an HTTP server that handles requests to "/manifest.json" etc. and gracefully handles shutdowns.
+// The call is *blocking*, so use the stoppingChan param if you want to be notified when the addon is about to shut down
+// because of a system signal like Ctrl+C or `docker stop`, or because of Stop. It should be a buffered channel with a capacity of 1.
+// Run returns after the server shut down, so it can be called again, for example in tests.
+func (a *Addon) Run(stoppingChan chan bool, fiberConf *fiber.Config) {
+	logger := a.logger
+
+	defer func() {
+		err := logger.Sync()
+		if err != nil {
+			logger.Error("Failed to sync logger", zap.Error(err))
+		}
+	}()
+
+	// Make sure the passed channel is buffered, so we can send a message before shutting down and not be blocked by the channel.
+	if stoppingChan != nil && cap(stoppingChan) < 1 {
+		logger.Fatal("The passed stopping channel isn't buffered")
+	}
+
+	control := &runControl{
+		requests: make(chan controlRequest),
+		stopped:  make(chan struct{}),
+	}
+	if !a.running.CompareAndSwap(nil, control) {
+		logger.Fatal("The addon is already running")
+	}
+	defer func() {
+		a.running.Store(nil)
+		close(control.stopped)
+	}()
+
+	addr := a.opts.BindAddr + ":" + strconv.Itoa(a.opts.Port)
+	var restart *controlRequest
+	for {
+		req, err := serve(a.App(fiberConf), addr, stoppingChan, logger, []*Addon{a}, control.requests, restart)
+		if err != nil {
+			if restart == nil {
+				logger.Fatal("Couldn't start server", zap.Error(err))
+			}
+			// The caller of Restart gets the error, so there's no reason to exit the process.
+			logger.Error("Couldn't restart server", zap.Error(err))
+			return
+		}
+		if req == nil || !req.restart {
+			logger.Info("Finished shutting down server")
+			if req != nil {
+				req.done <- nil
+			}
+			return
+		}
+		restart = req
+	}
+}
+
+// Stop gracefully shuts down the server of a running Run, like on SIGINT or SIGTERM, including the lifecycle hooks
+// like OnShutdown in the options. It returns when the server shut down and Run is about to return.
+// It returns ErrNotRunning when the addon isn't running.
+func (a *Addon) Stop() error {
+	return a.sendControlRequest(false)
+}
+
+// Restart gracefully shuts down the server of a running Run and starts it again, without Run returning.
+// The app is created anew, so custom middlewares and endpoints that were added in the meantime are used,
+// and the lifecycle hooks like OnShutdown and OnStart in the options are called again.
+// It returns when the server is running again, or the error why it couldn't be started, in which case Run returns.
+// It returns ErrNotRunning when the addon isn't running.
+func (a *Addon) Restart() error {
+	return a.sendControlRequest(true)
+}
+
+func (a *Addon) sendControlRequest(restart bool) error {
+	control := a.running.Load()
+	if control == nil {
+		return ErrNotRunning
+	}
+	req := controlRequest{restart: restart, done: make(chan error, 1)}
+	select {
+	case control.requests <- req:
+		return <-req.done
+	case <-control.stopped:
+		return ErrNotRunning
+	}
+}
+
+// serve starts the server and shuts it down gracefully on SIGINT or SIGTERM, or on a request of Stop or Restart, which it returns.
+// The lifecycle hooks of the addons are called around it, and their resources are released afterwards.
+// When restarting, the restart request gets the result of the start.
+func serve(app *fiber.App, addr string, stoppingChan chan bool, logger *zap.Logger, addons []*Addon, requests <-chan controlRequest, restart *controlRequest) (*controlRequest, error) {
+	// Accept SIGINT (Ctrl+C) and SIGTERM (`docker stop`)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	reusePort := slices.ContainsFunc(addons, func(a *Addon) bool { return a.opts.ReusePort })
+	logger.Info("Starting server", zap.String("address", addr), zap.Bool("reusePort", reusePort))
+	started := make(chan struct{})
+	listenErr := make(chan error, 1)
+	go func() {
+		listenConf := fiber.ListenConfig{
+			DisableStartupMessage: true,
+			// Called after the listener is up and before serving
+			BeforeServeFunc: func(*fiber.App) error {
+				for i, a := range addons {
+					if err := a.start(); err != nil {
+						// Undo the addons that already started, like their OnStart hooks and version checks
+						for _, started := range addons[:i] {
+							started.close()
+						}
+						return err
+					}
+				}
+				close(started)
+				return nil
+			},
+		}
+		if !reusePort {
+			listenErr <- app.Listen(addr, listenConf)
+			return
+		}
+		ln, err := reuseport.Listen(reusePortNetwork(addr), addr)
+		if err != nil {
+			listenErr <- fmt.Errorf("couldn't listen with SO_REUSEPORT: %w", err)
+			return
+		}
+		listenErr <- app.Listener(ln, listenConf)
+	}()
+	select {
+	case <-started:
+	case err := <-listenErr:
+		// The addons that started were already closed, but the others' resources like the recorder must be released as well
+		for _, a := range addons {
+			a.release()
+		}
+		if restart != nil {
+			restart.done <- err
+		}
+		return nil, err
+	}
+	if restart != nil {
+		restart.done <- nil
+	}
+
+	// Graceful shutdown
+
+	var req *controlRequest
+	select {
+	case sig := <-c:
+		logger.Info("Received signal, shutting down server...", zap.Stringer("signal", sig))
+	case r := <-requests:
+		req = &r
+		if r.restart {
+			logger.Info("Restarting server...")
+		} else {
+			logger.Info("Stopping server...")
+		}
+	case err := <-listenErr:
+		if err == nil {
+			err = errors.New("server stopped unexpectedly")
+		}
+		return nil, err
+	}
+	if stoppingChan != nil && (req == nil || !req.restart) {
+		stoppingChan <- true
+	}
+	for _, a := range addons {
+		a.beforeShutdown()
+	}
+	// Graceful shutdown, waiting for all current requests to finish without accepting new ones.
+	var err error
+	timeout := shutdownTimeout(addons)
+	if timeout > 0 {
+		err = app.ShutdownWithTimeout(timeout)
+	} else {
+		err = app.Shutdown()
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("Shutdown timeout exceeded, abandoning the remaining requests", zap.Duration("timeout", timeout))
+	case err != nil:
+		logger.Error("Error shutting down server", zap.Error(err))
+	}
+	if err := <-listenErr; err != nil {
+		logger.Error("Error in app.Listen() during server shutdown", zap.Error(err))
+	}
+	for _, a := range addons {
+		a.close()
+	}
+	return req, nil
+}
+
+// reusePortNetwork returns the network for listening on the address with SO_REUSEPORT, which only supports "tcp4" and "tcp6".
+func reusePortNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp4"
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.Is6() && !ip.Is4In6() {
+		return "tcp6"
+	}
+	return "tcp4"
+}
+
+// shutdownTimeout returns the longest ShutdownTimeout of the addons, 0 meaning no limit.
+func shutdownTimeout(addons []*Addon) time.Duration {
+	var timeout time.Duration
+	for _, a := range addons {
+		if a.opts.ShutdownTimeout == 0 {
+			return 0
+		}
+		timeout = max(timeout, a.opts.ShutdownTimeout)
+	}
+	return timeout
+}
+
+// start calls the OnStart hook and starts checking the latest version.
+func (a *Addon) start() error {
+	if a.opts.OnStart != nil {
+		if err := a.opts.OnStart(context.Background()); err != nil {
+			return fmt.Errorf("OnStart hook failed: %w", err)
+		}
+	}
+	if a.versionChecker != nil {
+		var ctx context.Context
+		ctx, a.stopVersionCheck = context.WithCancel(context.Background())
+		go a.versionChecker.run(ctx)
+	}
+	return nil
+}
+
+// beforeShutdown calls the OnShutdown hook.
+func (a *Addon) beforeShutdown() {
+	if a.opts.OnShutdown == nil {
+		return
+	}
+	if err := a.opts.OnShutdown(context.Background()); err != nil {
+		a.logger.Error("OnShutdown hook failed", zap.Error(err))
+	}
+}
+
+// close releases the resources of the addon after the server shut down and calls the AfterShutdown hook.
+func (a *Addon) close() {
+	a.release()
+	if a.opts.AfterShutdown != nil {
+		if err := a.opts.AfterShutdown(context.Background()); err != nil {
+			a.logger.Error("AfterShutdown hook failed", zap.Error(err))
+		}
+	}
+}
+
+// release releases the resources of the addon, which are also created when the server couldn't start.
+// Calling it again does nothing.
+func (a *Addon) release() {
+	if a.recorder != nil {
+		if err := a.recorder.Close(); err != nil {
+			a.logger.Error("Couldn't close recording file", zap.Error(err))
+		}
+		// A restart opens the file again
+		a.recorder = nil
+	}
+	if a.stopVersionCheck != nil {
+		a.stopVersionCheck()
+		a.stopVersionCheck = nil
+	}
+}
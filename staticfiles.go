@@ -0,0 +1,91 @@
+package stremio
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// StaticFilesOptions are the options for AddStaticFiles.
+type StaticFilesOptions struct {
+	// Duration for which browsers and proxies may cache the files without revalidating them.
+	// Default 0 (meaning they revalidate the files on every use, which is cheap thanks to the ETag).
+	MaxAge time.Duration
+	// Flag for indicating that a file never changes under its name, for example because the name contains a hash of the content,
+	// like "app.3f2a1b.js". Browsers then don't revalidate it even when reloading the page. Only makes sense with a MaxAge.
+	// Default false.
+	Immutable bool
+	// Name of the file that's served for directories.
+	// Default "index.html".
+	Index string
+}
+
+// AddStaticFiles serves the files of the file system, like an embed.FS or os.DirFS, below the path, like "/static".
+// Responses have a "Cache-Control" header according to the options and an "ETag", so clients can revalidate their cached files
+// with "If-None-Match" and get "304 Not Modified" if they didn't change. HEAD requests are answered as well.
+// Like AddEndpoint, it must be called before running the addon.
+func (a *Addon) AddStaticFiles(path string, fsys fs.FS, opts StaticFilesOptions) {
+	handler := createStaticFilesHandler(fsys, opts, a.logger)
+	route := strings.TrimSuffix(path, "/") + "/*"
+	a.AddEndpoint(fiber.MethodGet+","+fiber.MethodHead, route, handler)
+}
+
+func createStaticFilesHandler(fsys fs.FS, opts StaticFilesOptions, logger *zap.Logger) fiber.Handler {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+	cacheControl := "no-cache"
+	if opts.MaxAge != 0 {
+		cacheControl = "public, max-age=" + strconv.FormatInt(int64(opts.MaxAge.Seconds()), 10)
+		if opts.Immutable {
+			cacheControl += ", immutable"
+		}
+	}
+	return func(c fiber.Ctx) error {
+		logger.Debug("staticFilesHandler called")
+
+		name := strings.TrimPrefix(path.Clean("/"+c.Params("*")), "/")
+		if name == "" {
+			name = "."
+		}
+		info, err := fs.Stat(fsys, name)
+		if err == nil && info.IsDir() {
+			name = path.Join(name, opts.Index)
+			info, err = fs.Stat(fsys, name)
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()):
+			return c.SendStatus(fiber.StatusNotFound)
+		case err != nil:
+			logger.Error("Couldn't stat static file", zap.String("file", name), zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			logger.Error("Couldn't read static file", zap.String("file", name), zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		eTag := `"` + strconv.FormatUint(xxhash.Sum64(body), 16) + `"`
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		c.Set(fiber.HeaderETag, eTag)
+		if c.Get(fiber.HeaderIfNoneMatch) == eTag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(body)
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.Send(body)
+	}
+}
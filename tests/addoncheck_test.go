@@ -0,0 +1,19 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/addoncheck"
+)
+
+func TestSelfCheck(t *testing.T) {
+	report := newTestAddon(t).SelfCheck(context.Background(), addoncheck.Options{})
+	require.True(t, report.OK(), report.String())
+	// Manifest, catalog and stream for the movie type.
+	require.Len(t, report.Results, 3)
+	for _, res := range report.Results {
+		require.Equal(t, addoncheck.Pass, res.Status, res.Name)
+	}
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestAdminDashboard(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true, UserDataCacheSize: 10, AdminDashboard: true, APIKey: "key"})
+	srv := stremiotest.NewServer(t, addon)
+
+	srv.Streams(t, "movie", "tt1254207")
+	srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).RequireStatus(t, http.StatusOK)
+	srv.StreamRequest("movie", "tt0000001").Do(t).RequireStatus(t, http.StatusNotFound)
+
+	res, err := http.Get(srv.URL + "/admin")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/admin?api_key=key")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "<td>stream/movie/tt1254207</td><td class=\"number\">2</td>")
+	require.Contains(t, string(body), "<tr><th>Client errors (4xx)</th><td class=\"number\">2</td></tr>")
+	require.Contains(t, string(body), "0.0% (0 hits, 1 misses)")
+
+	_, err = stremio.NewAddon(newConfigurableManifest(), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{AdminDashboard: true})
+	require.Error(t, err)
+}
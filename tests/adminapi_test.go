@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/store"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestAdminAPI(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), ConfigStore: store.NewMemory(), AdminAPI: true, APIKey: "key"})
+	srv := stremiotest.NewServer(t, addon)
+
+	token, err := addon.EncodeUserData(testUserData{Quality: "1080p"})
+	require.NoError(t, err)
+	res, err := http.Get(srv.URL + "/" + token + "/stream/movie/tt1254207.json")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	do := func(method, path string, v any) int {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", "key")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	var inspection stremio.UserDataInspection
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/api/userdata?data="+url.QueryEscape(token), &inspection))
+	require.True(t, inspection.Valid)
+	require.Equal(t, map[string]any{"quality": "1080p"}, inspection.UserData)
+
+	var configs []stremio.RecentConfig
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/api/configs", &configs))
+	require.Len(t, configs, 1)
+	require.Equal(t, token, configs[0].Token)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/api/configs/"+token, nil))
+	inspection = stremio.UserDataInspection{}
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/api/userdata?data="+url.QueryEscape(token), &inspection))
+	require.False(t, inspection.Valid)
+	require.Equal(t, stremio.ErrUnknownConfigToken.Error(), inspection.Error)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/api/configs", &configs))
+	require.Empty(t, configs)
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/aggregator"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestAggregator(t *testing.T) {
+	upstreamA := stremiotest.NewServer(t, newTestAddon(t))
+	// Same streams as A, so they're deduplicated
+	upstreamB := stremiotest.NewServer(t, newTestAddon(t))
+	manifest := types.NewManifest("com.example.other", "Other", "0.1.0").WithDescription("Other addon").WithStreamResource("movie", "series")
+	streamHandler := func(_ context.Context, id string, _ any) ([]types.StreamItem, error) {
+		return []types.StreamItem{{InfoHash: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c", Title: id}}, nil
+	}
+	other, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": streamHandler, "series": streamHandler}, nil, nil, stremio.Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	upstreamC := stremiotest.NewServer(t, other)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.json" {
+			_, _ = w.Write([]byte(`{"id":"com.example.slow","name":"Slow","description":"Slow addon","version":"0.1.0","resources":["stream"],"types":["movie"],"catalogs":[]}`))
+			return
+		}
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	agg, err := aggregator.New([]aggregator.Upstream{
+		{Name: "A", URL: upstreamA.URL},
+		{Name: "B", URL: upstreamB.URL + "/manifest.json"},
+		{Name: "C", URL: upstreamC.URL},
+		{Name: "Slow", URL: slow.URL, Timeout: 50 * time.Millisecond},
+	}, aggregator.Options{PrefixStreamNames: true}, zap.NewNop())
+	require.NoError(t, err)
+
+	streams, err := agg.Streams(context.Background(), "movie", "tt1254207")
+	require.NoError(t, err)
+	require.Len(t, streams, 2)
+	require.Equal(t, "https://example.com/any.mp4", streams[0].URL)
+	require.Equal(t, "A", streams[0].Name)
+	require.Equal(t, "C", streams[1].Name)
+
+	// Only C supports series
+	streams, err = agg.Streams(context.Background(), "series", "tt0944947:1:1")
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+
+	catalogs := agg.Catalogs(context.Background())
+	require.Len(t, catalogs, 1)
+
+	// The aggregator as addon
+	aggManifest := types.NewManifest("com.example.aggregator", "Aggregator", "0.1.0").
+		WithDescription("Aggregating addon").
+		WithStreamResource("movie").
+		WithCatalog(catalogs...)
+	addon, err := stremio.NewAddon(aggManifest, agg.CatalogHandlers("movie"), agg.StreamHandlers("movie"), nil, nil, stremio.Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+	require.Len(t, srv.Streams(t, "movie", "tt1254207"), 2)
+	require.Len(t, srv.Catalog(t, "movie", "top"), 1)
+
+	// All upstreams that support the request fail
+	agg, err = aggregator.New([]aggregator.Upstream{{URL: slow.URL, Timeout: 50 * time.Millisecond}}, aggregator.Options{}, zap.NewNop())
+	require.NoError(t, err)
+	_, err = agg.Streams(context.Background(), "movie", "tt1254207")
+	require.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/analytics"
+	"github.com/xybydy/go-stremio/pkg/store"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestAnalytics(t *testing.T) {
+	collector := analytics.NewCollector(store.NewMemory(), analytics.Options{FlushInterval: time.Hour, MaxKeys: 1}, zap.NewNop())
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true, Analytics: collector, APIKey: "key"})
+	srv := stremiotest.NewServer(t, addon)
+
+	srv.Streams(t, "movie", "tt1254207")
+	srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).RequireStatus(t, http.StatusOK)
+	// Beyond MaxKeys
+	srv.Catalog(t, "movie", "top")
+	// Failed requests aren't counted
+	srv.StreamRequest("movie", "tt0000001").Do(t).RequireStatus(t, http.StatusNotFound)
+	require.NoError(t, collector.Close(context.Background()))
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/analytics.json", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "key")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var days []analytics.Day
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&days))
+
+	require.Len(t, days, 1)
+	require.Equal(t, time.Now().UTC().Format(time.DateOnly), days[0].Date)
+	require.Equal(t, map[string]int64{"stream/movie/tt1254207": 2, analytics.Other: 1}, days[0].Requests)
+	require.Len(t, days[0].Users, 1)
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestAPIKey(t *testing.T) {
+	apiKey := "secret"
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), Metrics: true, APIKey: apiKey})
+	addon.AddProtectedEndpoint("GET", "/stats", func(c fiber.Ctx) error { return c.SendString("stats") })
+	addon.AddEndpoint("GET", "/ping", func(c fiber.Ctx) error { return c.SendString("pong") })
+	srv := stremiotest.NewServer(t, addon)
+
+	get := func(path string, header http.Header) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	for _, path := range []string{"/metrics", "/stats"} {
+		require.Equal(t, http.StatusUnauthorized, get(path, nil), path)
+		require.Equal(t, http.StatusUnauthorized, get(path, http.Header{"X-Api-Key": {"wrong"}}), path)
+		require.Equal(t, http.StatusUnauthorized, get(path+"?api_key=secre", nil), path)
+		require.Equal(t, http.StatusOK, get(path, http.Header{"X-Api-Key": {apiKey}}), path)
+		require.Equal(t, http.StatusOK, get(path, http.Header{"Authorization": {"Bearer " + apiKey}}), path)
+		require.Equal(t, http.StatusOK, get(path+"?api_key="+apiKey, nil), path)
+	}
+
+	// Stremio and unprotected endpoints don't require the key
+	require.Equal(t, http.StatusOK, get("/manifest.json", nil))
+	require.Equal(t, http.StatusOK, get("/health", nil))
+	require.Equal(t, http.StatusOK, get("/ping", nil))
+}
+
+func TestMetricsBasicAuth(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:      zap.NewNop(),
+		Metrics:     true,
+		MetricsAuth: &stremio.BasicAuth{Username: "prometheus", Password: "secret"},
+		APIKey:      "key",
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	get := func(username, password string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/metrics", nil)
+		require.NoError(t, err)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := get("", "")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Contains(t, res.Header.Get("WWW-Authenticate"), `Basic realm="Metrics"`)
+	require.Equal(t, http.StatusUnauthorized, get("prometheus", "wrong").StatusCode)
+	require.Equal(t, http.StatusOK, get("prometheus", "secret").StatusCode)
+
+	_, err := stremio.NewAddon(newConfigurableManifest(), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{ProfilingAuth: &stremio.BasicAuth{Username: "a", Password: "b"}})
+	require.Error(t, err)
+}
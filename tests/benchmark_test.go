@@ -0,0 +1,84 @@
+//go:build !race
+
+// Allocations are different with the race detector, so the benchmarks and the allocation test only run without it.
+
+package tests
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"github.com/xybydy/go-stremio"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The handlers are called in-process without network, so the allocations are the ones of go-stremio and Fiber.
+// Compare them across changes with `go test -run XXX -bench . -benchmem ./tests/`.
+
+func newRequestHandler(t testing.TB, opts stremio.Options, path string) func() {
+	addon := newTestAddonWithOptions(t, opts)
+	handler := addon.App(nil).Handler()
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI(path)
+	handler(&ctx)
+	require.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), string(ctx.Response.Body()))
+	return func() {
+		ctx.Response.Reset()
+		handler(&ctx)
+	}
+}
+
+func benchmarkRequest(b *testing.B, opts stremio.Options, path string) {
+	handle := newRequestHandler(b, opts, path)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		handle()
+	}
+}
+
+// infoLogger logs like in production, but discards the output.
+func infoLogger() *zap.Logger {
+	return zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zap.InfoLevel))
+}
+
+func BenchmarkStream(b *testing.B) {
+	benchmarkRequest(b, stremio.Options{Logger: zap.NewNop(), DisableRequestLogging: true}, "/stream/movie/tt1254207.json")
+}
+
+func BenchmarkStreamWithRequestLogging(b *testing.B) {
+	benchmarkRequest(b, stremio.Options{Logger: infoLogger()}, "/stream/movie/tt1254207.json")
+}
+
+func BenchmarkStreamWithUserData(b *testing.B) {
+	benchmarkRequest(b, stremio.Options{Logger: zap.NewNop(), DisableRequestLogging: true}, "/%7B%22quality%22%3A%221080p%22%7D/stream/movie/tt1254207.json")
+}
+
+func BenchmarkCatalogWithETag(b *testing.B) {
+	opts := stremio.Options{Logger: zap.NewNop(), DisableRequestLogging: true, CacheAgeCatalogs: time.Hour, HandleEtagCatalogs: true}
+	benchmarkRequest(b, opts, "/catalog/movie/top/search=big%20buck.json")
+}
+
+// TestStreamAllocations fails when the stream hot path gets more allocations, which the benchmarks alone wouldn't catch.
+// Most of the remaining ones are from the test handler and from putting its result into an interface.
+func TestStreamAllocations(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		opts      stremio.Options
+		path      string
+		maxAllocs float64
+	}{
+		{"without logging", stremio.Options{Logger: infoLogger(), DisableRequestLogging: true}, "/stream/movie/tt1254207.json", 5},
+		{"with debug logging off", stremio.Options{Logger: infoLogger()}, "/stream/movie/tt1254207.json", 8},
+		{"with user data", stremio.Options{Logger: infoLogger(), DisableRequestLogging: true}, "/%7B%22quality%22%3A%221080p%22%7D/stream/movie/tt1254207.json", 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handle := newRequestHandler(t, tc.opts, tc.path)
+			require.LessOrEqual(t, testing.AllocsPerRun(100, handle), tc.maxAllocs)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestNewManifest(t *testing.T) {
+	// A manifest without resources or description isn't valid yet.
+	m := types.NewManifest("com.example.some-addon", "Some addon", "0.1.0")
+	require.Error(t, m.Validate())
+	require.NotNil(t, m.Types)
+	require.NotNil(t, m.Catalogs)
+
+	m = m.WithDescription("Some addon").
+		WithStreamResource("movie", "series").
+		WithCatalog(types.CatalogItem{Type: "movie", ID: "top", Name: "Top"}).
+		WithIDPrefixes("tt")
+	require.NoError(t, m.Validate())
+
+	require.Equal(t, []string{"movie", "series"}, m.Types)
+	require.Equal(t, []types.ResourceItem{
+		{Name: "stream", Types: []string{"movie", "series"}},
+		{Name: "catalog", Types: []string{"movie"}},
+	}, m.ResourceItems)
+	require.Equal(t, []string{"tt"}, m.IDprefixes)
+
+	// Adding a resource that already exists merges the types.
+	m2 := m.WithStreamResource("channel")
+	require.Equal(t, []string{"movie", "series", "channel"}, m2.ResourceItems[0].Types)
+	require.Equal(t, []string{"movie", "series", "channel"}, m2.Types)
+	// The base manifest is unchanged.
+	require.Equal(t, []string{"movie", "series"}, m.ResourceItems[0].Types)
+	require.Equal(t, []string{"movie", "series"}, m.Types)
+}
+
+func TestManifestCloneConfig(t *testing.T) {
+	m := types.NewManifest("com.example.some-addon", "Some addon", "0.1.0").
+		WithConfig(types.ConfigItem{ConfKey: "quality", ConfType: "select", ConfOptions: []string{"720p", "1080p"}})
+	m2 := m.Clone()
+	require.Equal(t, m, m2)
+
+	m2.Config[0].ConfOptions[0] = "changed"
+	require.NotEqual(t, m, m2)
+}
+
+func TestNewCatalog(t *testing.T) {
+	c := types.NewCatalog("movie", "top", "Top").WithSearch().WithGenres("Action", "Drama").WithSkip()
+	require.Equal(t, types.CatalogItem{
+		Type: "movie",
+		ID:   "top",
+		Name: "Top",
+		Extra: []types.ExtraItem{
+			{Name: types.ExtraSearch},
+			{Name: types.ExtraGenre, Options: []string{"Action", "Drama"}, OptionsLimit: 1},
+			{Name: types.ExtraSkip},
+		},
+	}, c)
+
+	// Setting an extra again replaces it instead of adding a duplicate.
+	c2 := c.WithRequiredSearch()
+	require.Len(t, c2.Extra, 3)
+	require.True(t, c2.Extra[0].IsRequired)
+	require.False(t, c.Extra[0].IsRequired)
+}
+
+func TestStreamItemFormat(t *testing.T) {
+	s := types.StreamItem{InfoHash: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c"}.
+		WithAddonName("Example").
+		WithQuality("1080p").
+		WithFilename("Big.Buck.Bunny.2008.1080p.mkv").
+		WithSize(1503238553).
+		WithSeeders(42)
+	require.Equal(t, "Example\n1080p", s.Name)
+	require.Equal(t, "Big.Buck.Bunny.2008.1080p.mkv\n👤 42 💾 1.4 GB", s.Title)
+	require.Equal(t, 1503238553, s.BehaviorHints.VideoSize)
+
+	// Custom formats can be applied at the end.
+	f, err := types.NewStreamFormat("", "", "{{.Quality}} - {{size .Size}}")
+	require.NoError(t, err)
+	s = s.Format(f)
+	require.Equal(t, "Example\n1080p", s.Name)
+	require.Equal(t, "1080p - 1.4 GB", s.Description)
+
+	// A Name and Title that were set directly survive the helpers
+	s = types.StreamItem{Name: "Example", Title: "Movie.2024.mkv"}.WithQuality("1080p")
+	require.Equal(t, "Example\n1080p", s.Name)
+	require.Equal(t, "Movie.2024.mkv", s.Title)
+	s = types.StreamItem{Title: "Movie.2024.mkv"}.WithQuality("1080p").WithSeeders(42)
+	require.Equal(t, "1080p", s.Name)
+	require.Equal(t, "Movie.2024.mkv\n👤 42", s.Title)
+	s = types.StreamItem{BehaviorHints: types.StreamBehaviorHints{Filename: "Movie.2024.mkv"}}.WithSize(1024)
+	require.Equal(t, "Movie.2024.mkv\n💾 1.0 KB", s.Title)
+	// Templates that render to nothing keep the previous value
+	s = types.StreamItem{Title: "Movie.2024.mkv"}.Format(types.MustStreamFormat("", "{{.Quality}}", ""))
+	require.Equal(t, "Movie.2024.mkv", s.Title)
+
+	// Accessing unknown fields is detected when parsing.
+	_, err = types.NewStreamFormat("{{.Foo}}", "", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+// The stale directives must be part of the same "Cache-Control" header as the max-age, also for "304 Not Modified" responses.
+func TestCacheControlStaleDirectives(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{
+		Logger:                  zap.NewNop(),
+		CacheAgeCatalogs:        time.Hour,
+		StaleRevalidateCatalogs: 2 * time.Hour,
+		StaleErrorCatalogs:      24 * time.Hour,
+		HandleEtagCatalogs:      true,
+		CacheAgeStreams:         time.Hour,
+		StaleErrorStreams:       24 * time.Hour,
+	}))
+	expected := "max-age=3600, private, stale-while-revalidate=7200, stale-if-error=86400"
+
+	res := srv.CatalogRequest("movie", "top").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, []string{expected}, res.Header.Values("Cache-Control"))
+	res = srv.CatalogRequest("movie", "top").WithHeader("If-None-Match", res.Header.Get("ETag")).Do(t).RequireStatus(t, http.StatusNotModified)
+	require.Equal(t, []string{expected}, res.Header.Values("Cache-Control"))
+
+	// Each directive is independent of the other one
+	res = srv.StreamRequest("movie", "tt1254207").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, []string{"max-age=3600, private, stale-if-error=86400"}, res.Header.Values("Cache-Control"))
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestCanaryHandler(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop()})
+	canary := func(_ context.Context, _ string, _ any) ([]types.StreamItem, error) {
+		return []types.StreamItem{{URL: "https://example.com/canary.mp4"}}, nil
+	}
+	require.Error(t, addon.AddCanaryStreamHandler("series", 100, canary))
+	require.Error(t, addon.AddCanaryStreamHandler("movie", 101, canary))
+	require.NoError(t, addon.AddCanaryStreamHandler("movie", 100, canary))
+	srv := stremiotest.NewServer(t, addon)
+
+	require.Equal(t, "https://example.com/canary.mp4", srv.Streams(t, "movie", "tt1254207")[0].URL)
+
+	// Without traffic for the canary the primary handler gets all requests
+	require.NoError(t, addon.AddCanaryStreamHandler("movie", 0, canary))
+	srv = stremiotest.NewServer(t, addon)
+	require.Equal(t, "https://example.com/any.mp4", srv.Streams(t, "movie", "tt1254207")[0].URL)
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestCatalogExport(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithCatalog(types.NewCatalog("movie", "top", "Top").WithGenres("Action").WithSkip())
+	catalogHandlers := map[string]stremio.CatalogHandler{
+		"movie": func(_ context.Context, _ string, extra url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			skip, _ := strconv.Atoi(extra.Get("skip"))
+			var metas []types.MetaPreviewItem
+			for i := skip; i < min(skip+100, 250); i++ {
+				metas = append(metas, types.MetaPreviewItem{ID: "tt" + strconv.Itoa(i), Type: "movie", Name: extra.Get("genre")})
+			}
+			return metas, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, catalogHandlers, nil, nil, nil, stremio.Options{Logger: zap.NewNop(), APIKey: "secret", AdminAPI: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/admin/api/catalogs/movie/top?genre=Action", nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(res.Body)
+	var ids []string
+	for scanner.Scan() {
+		var meta types.MetaPreviewItem
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &meta))
+		require.Equal(t, "Action", meta.Name)
+		ids = append(ids, meta.ID)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, ids, 250)
+	require.Equal(t, "tt249", ids[249])
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestCatalogExtras(t *testing.T) {
+	addon := newTestAddon(t)
+	require.NoError(t, addon.SetCatalogExtras("movie", "top", stremio.CatalogExtras{Search: true, Genres: []string{"Action", "Drama"}, Skip: true}))
+	require.Error(t, addon.SetCatalogExtras("movie", "unknown", stremio.CatalogExtras{Skip: true}))
+	require.Error(t, addon.SetCatalogExtras("movie", "top", stremio.CatalogExtras{GenreRequired: true}))
+	srv := stremiotest.NewServer(t, addon)
+
+	expected := []types.ExtraItem{
+		{Name: "search"},
+		{Name: "genre", Options: []string{"Action", "Drama"}, OptionsLimit: 1},
+		{Name: "skip"},
+	}
+	require.Equal(t, expected, srv.Manifest(t).Catalogs[0].Extra)
+
+	// Updates can't make the manifest disagree with the handler
+	require.NoError(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.Catalogs[0].Extra = nil
+		manifest.Catalogs[0].Name = "Popular"
+	}))
+	catalog := srv.Manifest(t).Catalogs[0]
+	require.Equal(t, "Popular", catalog.Name)
+	require.Equal(t, expected, catalog.Extra)
+}
+
+func TestUpdateCatalogGenres(t *testing.T) {
+	metas := []types.MetaPreviewItem{
+		{ID: "tt1", Genres: []string{"Drama", "Action"}},
+		{ID: "tt2", Genres: []string{"Action "}, Links: []types.MetaLinkItem{{Name: "Comedy", Category: "Genres"}, {Name: "Jane Doe", Category: "Cast"}}},
+	}
+	genres := types.Genres(metas)
+	require.Equal(t, []string{"Action", "Comedy", "Drama"}, genres)
+
+	addon := newTestAddon(t)
+	require.NoError(t, addon.SetCatalogExtras("movie", "top", stremio.CatalogExtras{Skip: true}))
+	srv := stremiotest.NewServer(t, addon)
+
+	require.NoError(t, addon.UpdateCatalogGenres("movie", "top", genres))
+	require.Error(t, addon.UpdateCatalogGenres("movie", "unknown", genres))
+	require.Equal(t, []types.ExtraItem{
+		{Name: "genre", Options: genres, OptionsLimit: 1},
+		{Name: "skip"},
+	}, srv.Manifest(t).Catalogs[0].Extra)
+}
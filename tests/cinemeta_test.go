@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/pkg/cinemeta/boltcache"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+type fakeFetcher struct {
+	meta types.MetaItem
+	err  error
+}
+
+func (f fakeFetcher) GetMovie(_ context.Context, _ string) (types.MetaItem, error) {
+	return f.meta, f.err
+}
+
+func (f fakeFetcher) GetSeries(_ context.Context, _ string, _ int, _ int) (types.MetaItem, error) {
+	return f.meta, f.err
+}
+
+func TestMultiFetcher(t *testing.T) {
+	outage := errors.New("outage")
+	m := cinemeta.NewMultiFetcher(fakeFetcher{err: outage}, fakeFetcher{meta: types.MetaItem{Name: "fallback"}})
+
+	meta, source, err := m.GetMovieWithSource(context.Background(), "tt1254207")
+	require.NoError(t, err)
+	require.Equal(t, "fallback", meta.Name)
+	require.Equal(t, 1, source)
+	require.Equal(t, []int64{0, 1}, m.Answers())
+
+	m = cinemeta.NewMultiFetcher(fakeFetcher{err: outage}, fakeFetcher{err: outage})
+	_, err = m.GetSeries(context.Background(), "tt0944947", 1, 1)
+	require.ErrorIs(t, err, outage)
+}
+
+func TestBoltCache(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "cache.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	cache, err := boltcache.New(db, "cinemeta")
+	require.NoError(t, err)
+
+	_, _, found, err := cache.Get("tt1254207")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, cache.Set("tt1254207", types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny"}))
+	meta, created, found, err := cache.Get("tt1254207")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "Big Buck Bunny", meta.Name)
+	require.WithinDuration(t, time.Now(), created, time.Minute)
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := cinemeta.NewLRUCache(2)
+	require.NoError(t, cache.Set("tt1", types.MetaItem{Name: "1"}))
+	require.NoError(t, cache.Set("tt2", types.MetaItem{Name: "2"}))
+	// Using tt1 makes tt2 the least recently used item.
+	_, _, found, _ := cache.Get("tt1")
+	require.True(t, found)
+	require.NoError(t, cache.Set("tt3", types.MetaItem{Name: "3"}))
+
+	require.Equal(t, 2, cache.Len())
+	_, _, found, _ = cache.Get("tt2")
+	require.False(t, found)
+	meta, _, found, _ := cache.Get("tt1")
+	require.True(t, found)
+	require.Equal(t, "1", meta.Name)
+}
+
+func TestSnapshotCache(t *testing.T) {
+	opts := cinemeta.SnapshotCacheOptions{Path: filepath.Join(t.TempDir(), "cinemeta.json")}
+	cache, err := cinemeta.NewSnapshotCache(opts, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, cache.Set("tt1254207", types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny"}))
+	require.NoError(t, cache.Close())
+
+	// A new cache loads the snapshot, including the creation time.
+	cache, err = cinemeta.NewSnapshotCache(opts, zap.NewNop())
+	require.NoError(t, err)
+	defer cache.Close()
+	meta, created, found, err := cache.Get("tt1254207")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "Big Buck Bunny", meta.Name)
+	require.WithinDuration(t, time.Now(), created, time.Minute)
+}
+
+// Items that are set while a snapshot is written end up in a later snapshot.
+func TestSnapshotCacheConcurrentSets(t *testing.T) {
+	opts := cinemeta.SnapshotCacheOptions{Path: filepath.Join(t.TempDir(), "cinemeta.json")}
+	cache, err := cinemeta.NewSnapshotCache(opts, zap.NewNop())
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 1000 {
+			id := "tt" + strconv.Itoa(i)
+			require.NoError(t, cache.Set(id, types.MetaItem{ID: id}))
+		}
+	}()
+	for range 10 {
+		require.NoError(t, cache.Save())
+	}
+	wg.Wait()
+	require.NoError(t, cache.Close())
+
+	cache, err = cinemeta.NewSnapshotCache(opts, zap.NewNop())
+	require.NoError(t, err)
+	defer cache.Close()
+	for i := range 1000 {
+		_, _, found, err := cache.Get("tt" + strconv.Itoa(i))
+		require.NoError(t, err)
+		require.True(t, found, i)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "/meta/movie/tt1254207.json", r.URL.Path)
+		_, _ = w.Write([]byte(`{"meta":{"id":"tt1254207","type":"movie","name":"Big Buck Bunny"}}`))
+	}))
+	defer srv.Close()
+
+	opts := cinemeta.ClientOptions{BaseURL: srv.URL, Retries: 1, RetryBackoff: time.Millisecond}
+	client := cinemeta.NewClient(opts, cinemeta.NewInMemoryCache(), zap.NewNop())
+	meta, err := client.GetMovie(context.Background(), "tt1254207")
+	require.NoError(t, err)
+	require.Equal(t, "Big Buck Bunny", meta.Name)
+	require.EqualValues(t, 2, requests.Load())
+}
+
+func TestClientGetMovies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/meta/movie/tt0000000.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"meta":{"name":"` + r.URL.Path + `"}}`))
+	}))
+	defer srv.Close()
+
+	client := cinemeta.NewClient(cinemeta.ClientOptions{BaseURL: srv.URL, BatchConcurrency: 2}, cinemeta.NewInMemoryCache(), zap.NewNop())
+	metas, err := client.GetMovies(context.Background(), []string{"tt1", "tt2", "tt0000000", "tt3", "tt1"})
+	require.ErrorContains(t, err, "tt0000000")
+	require.Len(t, metas, 3)
+	require.Equal(t, "/meta/movie/tt2.json", metas["tt2"].Name)
+}
+
+func TestClientGetMeta(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"meta":{"name":"` + r.URL.Path + `"}}`))
+	}))
+	defer srv.Close()
+
+	opts := cinemeta.ClientOptions{BaseURL: srv.URL, AddonURLs: map[string]string{"kitsu": srv.URL + "/kitsu"}}
+	client := cinemeta.NewClient(opts, cinemeta.NewInMemoryCache(), zap.NewNop())
+
+	meta, err := client.GetMeta(context.Background(), "series", "kitsu:1:5")
+	require.NoError(t, err)
+	require.Equal(t, "/kitsu/meta/series/kitsu:1.json", meta.Name)
+	meta, err = client.GetMeta(context.Background(), "series", "tt0944947:1:2")
+	require.NoError(t, err)
+	require.Equal(t, "/meta/series/tt0944947.json", meta.Name)
+	_, err = client.GetMeta(context.Background(), "movie", "tmdb:550")
+	require.ErrorIs(t, err, cinemeta.ErrUnsupportedID)
+}
+
+func TestClientProxy(t *testing.T) {
+	// The proxy gets the request with the absolute URL of the target.
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte(`{"meta":{"name":"Big Buck Bunny"}}`))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	opts := cinemeta.ClientOptions{BaseURL: "http://cinemeta.invalid", Proxy: proxyURL}
+	client := cinemeta.NewClient(opts, cinemeta.NewInMemoryCache(), zap.NewNop())
+	_, err = client.GetMovie(context.Background(), "tt1254207")
+	require.NoError(t, err)
+	require.Equal(t, "http://cinemeta.invalid/meta/movie/tt1254207.json", proxied)
+}
+
+func TestClientDeduplicatesConcurrentFetches(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"meta":{"name":"Big Buck Bunny"}}`))
+	}))
+	defer srv.Close()
+
+	client := cinemeta.NewClient(cinemeta.ClientOptions{BaseURL: srv.URL}, cinemeta.NewInMemoryCache(), zap.NewNop())
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, err := client.GetMovie(context.Background(), "tt1254207")
+			require.NoError(t, err)
+			require.Equal(t, "Big Buck Bunny", meta.Name)
+		}()
+	}
+	wg.Wait()
+	require.EqualValues(t, 1, requests.Load())
+}
+
+// The addon's default Cinemeta client uses the CinemetaOptions.
+func TestCinemetaOptions(t *testing.T) {
+	var requests atomic.Int32
+	cinemetaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/meta/movie/tt0000000.json" {
+			time.Sleep(time.Second)
+		}
+		_, _ = w.Write([]byte(`{"meta":{"id":"tt1254207","type":"movie","name":"Big Buck Bunny"}}`))
+	}))
+	defer cinemetaSrv.Close()
+
+	srv := stremiotest.NewServer(t, newMetaTestAddon(t, stremio.Options{
+		Logger:           zap.NewNop(),
+		PutMetaInContext: true,
+		CinemetaOptions: cinemeta.ClientOptions{
+			BaseURL:      cinemetaSrv.URL,
+			Timeout:      500 * time.Millisecond,
+			Retries:      1,
+			RetryBackoff: time.Millisecond,
+		},
+	}))
+
+	// The self-hosted Cinemeta is used, with a retry after the first failure
+	require.Equal(t, "Big Buck Bunny", srv.Streams(t, "movie", "tt1254207")[0].Title)
+	require.EqualValues(t, 2, requests.Load())
+	// The request to the slow Cinemeta is canceled after the timeout
+	require.Equal(t, "none", srv.Streams(t, "movie", "tt0000000")[0].Title)
+
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	_, err := stremio.NewAddon(manifest, nil, nil, nil, nil, stremio.Options{
+		PutMetaInContext: true,
+		MetaClient:       stremiotest.NewMetaFetcher(),
+		CinemetaOptions:  cinemeta.ClientOptions{BaseURL: cinemetaSrv.URL},
+	})
+	require.ErrorContains(t, err, "Cinemeta options")
+	_, err = stremio.NewAddon(manifest, nil, nil, nil, nil, stremio.Options{
+		PutMetaInContext: true,
+		MetaTimeout:      time.Second,
+		CinemetaOptions:  cinemeta.ClientOptions{Timeout: time.Second},
+	})
+	require.ErrorContains(t, err, "ambiguous")
+}
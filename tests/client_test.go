@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/client"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestClient(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+	ctx := context.Background()
+
+	c, err := client.New(srv.URL+"/manifest.json", client.Options{})
+	require.NoError(t, err)
+	require.Equal(t, srv.URL, c.BaseURL())
+
+	manifest, err := c.Manifest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "com.example.test", manifest.ID)
+	require.True(t, client.Supports(manifest, "stream", "movie", "tt1254207"))
+	require.False(t, client.Supports(manifest, "stream", "series", "tt0944947:1:1"))
+	require.True(t, client.Supports(manifest, "catalog", "movie", "top"))
+
+	metas, err := c.Catalog(ctx, "movie", "top", url.Values{"search": {"big buck"}})
+	require.NoError(t, err)
+	require.Equal(t, "top big buck", metas[0].Name)
+
+	streams, err := c.Streams(ctx, "movie", "tt1254207")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/any.mp4", streams[0].URL)
+
+	_, err = c.Streams(ctx, "movie", "tt0000000")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = client.New("ftp://example.com", client.Options{})
+	require.Error(t, err)
+}
+
+func TestClientRetriesAndCaching(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(`{"streams":[{"url":"https://example.com/stream.mp4"}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.Options{Retries: 1, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	for range 3 {
+		streams, err := c.Streams(context.Background(), "movie", "tt1254207")
+		require.NoError(t, err)
+		require.Len(t, streams, 1)
+	}
+	// One failed attempt, one successful retry, then cached
+	require.EqualValues(t, 2, calls.Load())
+
+	c, err = client.New(srv.URL, client.Options{CacheTTL: -1})
+	require.NoError(t, err)
+	_, err = c.Streams(context.Background(), "movie", "tt1254207")
+	require.NoError(t, err)
+	_, err = c.Streams(context.Background(), "movie", "tt1254207")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, calls.Load())
+
+	var statusErr *client.StatusError
+	calls.Store(0)
+	c, err = client.New(srv.URL, client.Options{})
+	require.NoError(t, err)
+	_, err = c.Streams(context.Background(), "movie", "tt1254207")
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+)
+
+type testConfig struct {
+	TMDB struct {
+		APIKey string `yaml:"api_key" toml:"api_key"`
+	} `yaml:"tmdb" toml:"tmdb"`
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestOptionsFromFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+port: 7000
+cache_age_streams: 1h
+meta_timeout: 5
+metrics: true
+metrics_auth:
+  username: admin
+  password: secret
+allowed_ips: [10.0.0.0/8]
+tmdb:
+  api_key: abc
+`,
+		"config.toml": `
+port = 7000
+cache_age_streams = "1h"
+meta_timeout = 5
+metrics = true
+allowed_ips = ["10.0.0.0/8"]
+
+[metrics_auth]
+username = "admin"
+password = "secret"
+
+[tmdb]
+api_key = "abc"
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			var custom testConfig
+			opts, err := stremio.OptionsFromFile(writeConfigFile(t, name, content), &custom)
+			require.NoError(t, err)
+			require.Equal(t, 7000, opts.Port)
+			require.Equal(t, time.Hour, opts.CacheAgeStreams)
+			require.Equal(t, 5*time.Second, opts.MetaTimeout)
+			require.True(t, opts.Metrics)
+			require.Equal(t, &stremio.BasicAuth{Username: "admin", Password: "secret"}, opts.MetricsAuth)
+			require.Equal(t, []string{"10.0.0.0/8"}, opts.AllowedIPs)
+			require.Equal(t, "abc", custom.TMDB.APIKey)
+		})
+	}
+}
+
+func TestOptionsFromFileErrors(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+port: http
+cache_age_streams: 1x
+tmdb:
+  api_key: abc
+`)
+	_, err := stremio.OptionsFromFile(path, nil)
+	require.ErrorContains(t, err, "port: ")
+	require.ErrorContains(t, err, "cache_age_streams: ")
+	require.ErrorContains(t, err, "tmdb: unknown option")
+
+	// Unknown keys in custom sections
+	path = writeConfigFile(t, "config.yaml", "tmdb:\n  key: abc\n")
+	_, err = stremio.OptionsFromFile(path, &testConfig{})
+	require.ErrorContains(t, err, "invalid custom section")
+
+	_, err = stremio.OptionsFromFile(writeConfigFile(t, "config.json", "{}"), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestConfigSchema(t *testing.T) {
+	maxSeeders := 100.0
+	manifest := newConfigurableManifest()
+	manifest.Config[2].ConfMax = &maxSeeders
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), ConfigSchema: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/config.schema.json")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/schema+json", res.Header.Get("Content-Type"))
+	require.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Test configuration",
+		"type": "object",
+		"properties": {
+			"apiKey": {"title": "API key", "type": "string", "format": "password", "writeOnly": true},
+			"quality": {"type": "string", "enum": ["720p", "1080p"], "default": "1080p"},
+			"minSeeders": {"type": "number", "maximum": 100, "default": 5},
+			"hdr": {"title": "Include HDR", "type": "boolean", "default": true}
+		},
+		"required": ["apiKey"]
+	}`, string(body))
+
+	schema, err := addon.ConfigSchema()
+	require.NoError(t, err)
+	require.True(t, json.Valid(schema))
+	require.JSONEq(t, string(body), string(schema))
+}
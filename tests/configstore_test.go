@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/store"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestConfigStore(t *testing.T) {
+	manifest := newConfigurableManifest()
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(_ context.Context, _ string, userData any) ([]types.StreamItem, error) {
+			return []types.StreamItem{{URL: "https://example.com/" + userData.(*testUserData).Quality + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), ConfigurePage: true, ConfigStore: store.NewMemory()})
+	require.NoError(t, err)
+	addon.RegisterUserData(testUserData{})
+	srv := stremiotest.NewServer(t, addon)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	// Configuring stores the config and redirects to the install link with a token
+	res, err := client.PostForm(srv.URL+"/configure", url.Values{"apiKey": {"secret"}, "quality": {"720p"}})
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	prefix := stremio.StremioURL(srv.URL, "")
+	prefix = strings.TrimSuffix(prefix, "manifest.json")
+	location := res.Header.Get("Location")
+	require.True(t, strings.HasPrefix(location, prefix), location)
+	token := strings.TrimSuffix(strings.TrimPrefix(location, prefix), "/manifest.json")
+	require.NotContains(t, token, "secret")
+
+	streamURL := srv.URL + "/" + token + "/stream/movie/tt1254207.json"
+	res, err = http.Get(streamURL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), "https://example.com/720p.mp4")
+
+	// Reconfiguring prefills the form and updates the config for the same token
+	res, err = http.Get(srv.URL + "/" + token + "/configure")
+	require.NoError(t, err)
+	body, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), `<input type="hidden" name="configToken" value="`+token+`">`)
+	require.Contains(t, string(body), "<option selected>720p</option>")
+	res, err = client.PostForm(srv.URL+"/configure", url.Values{"configToken": {token}, "apiKey": {"secret"}, "quality": {"1080p"}})
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, location, res.Header.Get("Location"))
+	res, err = http.Get(streamURL)
+	require.NoError(t, err)
+	body, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), "https://example.com/1080p.mp4")
+
+	// Unknown tokens are rejected
+	res, err = http.Get(srv.URL + "/unknown/stream/movie/tt1254207.json")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func newConfigurableManifest() types.Manifest {
+	return types.NewManifest("com.example.test", "Test", "1.2.3").
+		WithDescription("Test addon").
+		WithStreamResource("movie").
+		WithBehaviorHints(types.ManifestBehaviorHints{Configurable: true, ConfigurationRequired: true}).
+		WithConfig(
+			types.ConfigItem{ConfKey: "apiKey", ConfType: "password", ConfTitle: "API key", ConfRequired: true},
+			types.ConfigItem{ConfKey: "quality", ConfType: "select", ConfOptions: []string{"720p", "1080p"}, ConfDefault: "1080p"},
+			types.ConfigItem{ConfKey: "minSeeders", ConfType: "number", ConfDefault: "5"},
+			types.ConfigItem{ConfKey: "hdr", ConfType: "checkbox", ConfTitle: "Include HDR", ConfDefault: "checked"},
+		)
+}
+
+func TestConfigurePage(t *testing.T) {
+	manifest := newConfigurableManifest()
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), ConfigurePage: true, LandingPage: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/configure")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	html := string(body)
+	require.Contains(t, html, `<input type="password" name="apiKey" value="" required>`)
+	require.Contains(t, html, "<option selected>1080p</option>")
+	require.Contains(t, html, `<input type="number" name="minSeeders" value="5" step="any">`)
+	require.Contains(t, html, `<input type="checkbox" name="hdr" checked> Include HDR`)
+
+	// The landing page links to the configure page
+	res, err = http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	body, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), `href="`+srv.URL+`/configure"`)
+
+	_, err = stremio.NewAddon(manifest.WithBehaviorHints(types.ManifestBehaviorHints{}), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{ConfigurePage: true})
+	require.Error(t, err)
+}
+
+func TestConfigureSubmit(t *testing.T) {
+	minSeeders := 1.0
+	manifest := newConfigurableManifest()
+	manifest.Config[2].ConfMin = &minSeeders
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), ConfigurePage: true, UserDataIsBase64: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	res, err := client.PostForm(srv.URL+"/configure", url.Values{"apiKey": {"secret"}, "quality": {"720p"}, "minSeeders": {"10"}})
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	encoded, err := addon.EncodeUserData(map[string]any{"apiKey": "secret", "quality": "720p", "minSeeders": 10, "hdr": false})
+	require.NoError(t, err)
+	require.Equal(t, stremio.StremioURL(srv.URL, encoded), res.Header.Get("Location"))
+
+	res, err = client.PostForm(srv.URL+"/configure", url.Values{"quality": {"4K"}, "minSeeders": {"0"}})
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	html := string(body)
+	require.Contains(t, html, "API key is required")
+	require.Contains(t, html, "quality must be one of 720p, 1080p")
+	require.Contains(t, html, "minSeeders must be at least 1")
+	require.Contains(t, html, `value="0"`)
+}
+
+func TestConfigurePrefill(t *testing.T) {
+	addon, err := stremio.NewAddon(newConfigurableManifest(), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), ConfigurePage: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	encoded, err := addon.EncodeUserData(map[string]any{"apiKey": "secret", "quality": "720p", "hdr": false})
+	require.NoError(t, err)
+	res, err := http.Get(srv.URL + "/" + encoded + "/configure")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	html := string(body)
+	require.Contains(t, html, `<input type="password" name="apiKey" value="secret" required>`)
+	require.Contains(t, html, "<option selected>720p</option>")
+	// Missing values get their defaults
+	require.Contains(t, html, `<input type="number" name="minSeeders" value="5" step="any">`)
+	require.Contains(t, html, `<input type="checkbox" name="hdr"> Include HDR`)
+}
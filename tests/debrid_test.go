@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/debrid"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+const (
+	cachedInfoHash    = "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c"
+	notCachedInfoHash = "08ada5a7a6183aae1e09d831df6748d566095a10"
+)
+
+// newFakeRealDebrid returns a Real-Debrid API that only has the cachedInfoHash cached,
+// and records the IDs of deleted torrents. Selecting files fails for the API key "flaky-select",
+// and unrestricting links fails for "flaky-unrestrict".
+func newFakeRealDebrid(t *testing.T) (*debrid.RealDebrid, *[]string) {
+	var lock sync.Mutex
+	selected := map[string]bool{}
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /torrents/addMagnet", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		case "Bearer valid", "Bearer flaky-select", "Bearer flaky-unrestrict":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		id := "other"
+		if strings.Contains(r.FormValue("magnet"), cachedInfoHash) {
+			id = "cached"
+		}
+		_, _ = w.Write([]byte(`{"id":"` + id + `"}`))
+	})
+	mux.HandleFunc("GET /torrents/info/{id}", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.PathValue("id") != "cached":
+			_, _ = w.Write([]byte(`{"id":"other","status":"magnet_conversion"}`))
+		case selected[r.Header.Get("Authorization")]:
+			_, _ = w.Write([]byte(`{"id":"cached","status":"downloaded","links":["https://real-debrid.com/d/abc"]}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"cached","status":"waiting_files_selection","files":[{"id":1,"path":"/movie.mkv","bytes":100}]}`))
+		}
+	})
+	mux.HandleFunc("POST /torrents/selectFiles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, "1", r.FormValue("files"))
+		if r.Header.Get("Authorization") == "Bearer flaky-select" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		selected[r.Header.Get("Authorization")] = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /torrents/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /unrestrict/link", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "https://real-debrid.com/d/abc", r.FormValue("link"))
+		if r.Header.Get("Authorization") == "Bearer flaky-unrestrict" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"download":"https://download.real-debrid.com/movie.mkv"}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return debrid.NewRealDebrid(debrid.RealDebridOptions{BaseURL: srv.URL}), &deleted
+}
+
+func TestRealDebrid(t *testing.T) {
+	rd, deleted := newFakeRealDebrid(t)
+	ctx := context.Background()
+
+	url, err := rd.Resolve(ctx, "valid", cachedInfoHash, 0)
+	require.NoError(t, err)
+	require.Equal(t, "https://download.real-debrid.com/movie.mkv", url)
+	require.Empty(t, *deleted)
+
+	// Uncached torrents are removed from the account again
+	_, err = rd.Resolve(ctx, "valid", notCachedInfoHash, 0)
+	require.ErrorIs(t, err, debrid.ErrNotCached)
+	require.Equal(t, []string{"other"}, *deleted)
+
+	// Torrents are also removed when a later step fails
+	_, err = rd.Resolve(ctx, "flaky-select", cachedInfoHash, 0)
+	require.Error(t, err)
+	require.Equal(t, []string{"other", "cached"}, *deleted)
+	_, err = rd.Resolve(ctx, "flaky-unrestrict", cachedInfoHash, 0)
+	require.Error(t, err)
+	require.Equal(t, []string{"other", "cached", "cached"}, *deleted)
+
+	_, err = rd.Resolve(ctx, "invalid", cachedInfoHash, 0)
+	require.ErrorIs(t, err, debrid.ErrBadAPIKey)
+	_, err = rd.Resolve(ctx, "limited", cachedInfoHash, 0)
+	require.ErrorIs(t, err, debrid.ErrRateLimited)
+}
+
+type fakeResolver map[string]string
+
+func (r fakeResolver) Resolve(_ context.Context, apiKey, infoHash string, _ int) (string, error) {
+	if apiKey != "valid" {
+		return "", debrid.ErrBadAPIKey
+	}
+	if url, ok := r[infoHash]; ok {
+		return url, nil
+	}
+	if infoHash == "broken" {
+		return "", errors.New("broken")
+	}
+	return "", debrid.ErrNotCached
+}
+
+func TestWrapStreamHandler(t *testing.T) {
+	streams := []types.StreamItem{
+		{Name: "Cached", InfoHash: cachedInfoHash, FileIndex: 1},
+		{Name: "Not cached", InfoHash: notCachedInfoHash},
+		{Name: "Broken", InfoHash: "broken"},
+		{Name: "HTTP", URL: "https://example.com/movie.mp4"},
+	}
+	h := func(context.Context, string, any) ([]types.StreamItem, error) {
+		return streams, nil
+	}
+	resolver := fakeResolver{cachedInfoHash: "https://debrid.example/movie.mkv"}
+	apiKey := func(userData any) string {
+		key, _ := userData.(string)
+		return key
+	}
+	ctx := context.Background()
+
+	// Converted streams come first, the others are kept as fallback
+	wrapped := debrid.WrapStreamHandler(h, resolver, apiKey, debrid.WrapOptions{}, zap.NewNop())
+	res, err := wrapped(ctx, "tt1254207", "valid")
+	require.NoError(t, err)
+	require.Equal(t, []types.StreamItem{
+		{Name: "[Debrid+] Cached", URL: "https://debrid.example/movie.mkv"},
+		streams[1], streams[2], streams[3],
+	}, res)
+
+	wrapped = debrid.WrapStreamHandler(h, resolver, apiKey, debrid.WrapOptions{NamePrefix: "[RD]", KeepTorrents: true}, zap.NewNop())
+	res, err = wrapped(ctx, "tt1254207", "valid")
+	require.NoError(t, err)
+	require.Len(t, res, 5)
+	require.Equal(t, "[RD] Cached", res[0].Name)
+	require.Equal(t, streams, res[1:])
+
+	// Without or with a bad API key, the streams are unchanged
+	for _, key := range []string{"", "invalid"} {
+		res, err = wrapped(ctx, "tt1254207", key)
+		require.NoError(t, err)
+		require.Equal(t, streams, res)
+	}
+
+	failing := debrid.WrapStreamHandler(func(context.Context, string, any) ([]types.StreamItem, error) {
+		return nil, errors.New("handler failed")
+	}, resolver, apiKey, debrid.WrapOptions{}, zap.NewNop())
+	_, err = failing(ctx, "tt1254207", "valid")
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("PORT", "7000")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("METRICS", "true")
+	t.Setenv("METRICS_USERNAME", "admin")
+	t.Setenv("METRICS_PASSWORD", "secret")
+	t.Setenv("CACHE_AGE_STREAMS", "1h")
+	t.Setenv("META_TIMEOUT", "5")
+	t.Setenv("ALLOWED_IPS", "10.0.0.0/8, 192.168.0.1")
+
+	opts, err := stremio.OptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 7000, opts.Port)
+	require.Equal(t, "debug", opts.LoggingLevel)
+	require.True(t, opts.Metrics)
+	require.Equal(t, &stremio.BasicAuth{Username: "admin", Password: "secret"}, opts.MetricsAuth)
+	require.Nil(t, opts.ProfilingAuth)
+	require.Equal(t, time.Hour, opts.CacheAgeStreams)
+	require.Equal(t, 5*time.Second, opts.MetaTimeout)
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.0.1"}, opts.AllowedIPs)
+	require.Empty(t, opts.BindAddr)
+
+	// All invalid values are reported
+	t.Setenv("PORT", "http")
+	t.Setenv("METRICS", "maybe")
+	_, err = stremio.OptionsFromEnv()
+	require.ErrorContains(t, err, "PORT")
+	require.ErrorContains(t, err, "METRICS")
+}
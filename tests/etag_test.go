@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestSetETag(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	built := 0
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(ctx context.Context, _ string, _ any) ([]types.StreamItem, error) {
+			if stremio.SetETag(ctx, "v1") {
+				return nil, nil
+			}
+			built++
+			return []types.StreamItem{{URL: "https://example.com/1080p.mp4"}, {URL: "https://example.com/720p.mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{
+		Logger:             zap.NewNop(),
+		CacheAgeStreams:    time.Hour,
+		HandleEtagStreams:  true,
+		StreamingThreshold: 2,
+	})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	// The supplied ETag is used instead of a hash, and the result can still be streamed
+	res := srv.StreamRequest("movie", "tt1254207").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, "v1", res.Header.Get("ETag"))
+	require.Len(t, res.Streams(t), 2)
+	require.Equal(t, 1, built)
+
+	res = srv.StreamRequest("movie", "tt1254207").WithHeader("If-None-Match", "v1").Do(t).RequireStatus(t, http.StatusNotModified)
+	require.Equal(t, "v1", res.Header.Get("ETag"))
+	require.Equal(t, 1, built)
+
+	srv.StreamRequest("movie", "tt1254207").WithHeader("If-None-Match", "v0").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, 2, built)
+
+	// Without ETag handling there's nothing to compare with
+	require.False(t, stremio.SetETag(context.Background(), "v1"))
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestCatalogFeeds(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), CatalogFeeds: true})
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/feed/movie/top.rss")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/rss+xml; charset=utf-8", res.Header.Get("Content-Type"))
+	var rss struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title string `xml:"title"`
+				Link  string `xml:"link"`
+				GUID  string `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.NewDecoder(res.Body).Decode(&rss))
+	require.Equal(t, "Test - Top", rss.Channel.Title)
+	require.Len(t, rss.Channel.Items, 1)
+	require.Equal(t, "top ", rss.Channel.Items[0].Title)
+	require.Equal(t, "https://web.stremio.com/#/detail/movie/tt1254207", rss.Channel.Items[0].Link)
+	require.Equal(t, "tt1254207", rss.Channel.Items[0].GUID)
+
+	res, err = http.Get(srv.URL + "/feed/movie/top.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var feed struct {
+		Version string `json:"version"`
+		FeedURL string `json:"feed_url"`
+		Items   []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&feed))
+	require.Equal(t, "https://jsonfeed.org/version/1.1", feed.Version)
+	require.Equal(t, srv.URL+"/feed/movie/top.json", feed.FeedURL)
+	require.Len(t, feed.Items, 1)
+	require.Equal(t, "tt1254207", feed.Items[0].ID)
+
+	// Only the manifest's catalogs have feeds
+	res, err = http.Get(srv.URL + "/feed/movie/unknown.rss")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+// Feeds with user data are verified like the other endpoints.
+func TestCatalogFeedsSignedUserData(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), stremio.MinUserDataSigningKeyLength)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:             zap.NewNop(),
+		CatalogFeeds:       true,
+		UserDataIsBase64:   true,
+		UserDataSigningKey: key,
+		MaxUserDataLength:  100,
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	signed, err := addon.EncodeUserData(testUserData{Quality: "1080p"})
+	require.NoError(t, err)
+	unsigned := signed[:strings.LastIndex(signed, ".")]
+	for userData, status := range map[string]int{
+		signed:                   http.StatusOK,
+		unsigned:                 http.StatusBadRequest,
+		strings.Repeat("a", 101): http.StatusRequestURITooLong,
+	} {
+		for _, format := range []string{"rss", "json"} {
+			res, err := http.Get(srv.URL + "/" + userData + "/feed/movie/top." + format)
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, status, res.StatusCode, userData)
+		}
+	}
+}
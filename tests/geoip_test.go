@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/geoip"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestAlpha3(t *testing.T) {
+	require.Equal(t, "deu", geoip.Alpha3("DE"))
+	require.Equal(t, "usa", geoip.Alpha3("us"))
+	require.Equal(t, "", geoip.Alpha3(""))
+	require.Equal(t, "", geoip.Alpha3("XX"))
+}
+
+func TestFilterStreams(t *testing.T) {
+	streams := []types.StreamItem{
+		{URL: "everywhere"},
+		{URL: "germany", BehaviorHints: types.StreamBehaviorHints{CountryWhitelist: []string{"deu", "aut"}}},
+		{URL: "usa", BehaviorHints: types.StreamBehaviorHints{CountryWhitelist: []string{"usa"}}},
+	}
+
+	require.Len(t, geoip.FilterStreams(append([]types.StreamItem(nil), streams...), ""), 3)
+
+	filtered := geoip.FilterStreams(append([]types.StreamItem(nil), streams...), "aut")
+	require.Len(t, filtered, 2)
+	require.Equal(t, "everywhere", filtered[0].URL)
+	require.Equal(t, "germany", filtered[1].URL)
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestGroup(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), APIKey: "secret"})
+	tag := func(name string) fiber.Handler {
+		return func(c fiber.Ctx) error {
+			c.Append("X-Middlewares", name)
+			return c.Next()
+		}
+	}
+	api := addon.Group("/api", tag("api"))
+	api.AddEndpoint("GET,POST", "/items", func(c fiber.Ctx) error {
+		return c.SendString(c.Method() + " items")
+	})
+	admin := api.Group("/admin")
+	admin.AddProtectedEndpoint("DELETE", "/cache", func(c fiber.Ctx) error {
+		return c.SendString("cleared")
+	})
+	// Middlewares that are added later apply to the existing endpoints as well
+	admin.Use(tag("admin"))
+	srv := stremiotest.NewServer(t, addon)
+
+	do := func(method, path string, header http.Header) (*http.Response, string) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(""))
+		require.NoError(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		res, body := do(method, "/api/items", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, method+" items", body)
+		require.Equal(t, "api", res.Header.Get("X-Middlewares"))
+	}
+
+	res, _ := do(http.MethodDelete, "/api/admin/cache", nil)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res, body := do(http.MethodDelete, "/api/admin/cache", http.Header{"X-Api-Key": {"secret"}})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "cleared", body)
+	require.Equal(t, "api, admin", res.Header.Get("X-Middlewares"))
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestSetHandler(t *testing.T) {
+	addon := newTestAddon(t)
+	srv := stremiotest.NewServer(t, addon)
+	require.Equal(t, "https://example.com/any.mp4", srv.Streams(t, "movie", "tt1254207")[0].URL)
+
+	// Handlers can be swapped and added while running
+	require.NoError(t, addon.SetStreamHandler("movie", func(_ context.Context, _ string, _ any) ([]types.StreamItem, error) {
+		return []types.StreamItem{{URL: "https://example.com/swapped.mp4"}}, nil
+	}))
+	require.NoError(t, addon.SetStreamHandler("series", func(_ context.Context, _ string, _ any) ([]types.StreamItem, error) {
+		return []types.StreamItem{{URL: "https://example.com/episode.mp4"}}, nil
+	}))
+	require.Equal(t, "https://example.com/swapped.mp4", srv.Streams(t, "movie", "tt1254207")[0].URL)
+	require.Equal(t, "https://example.com/episode.mp4", srv.Streams(t, "series", "tt0944947:1:1")[0].URL)
+
+	// And removed
+	require.NoError(t, addon.SetStreamHandler("series", nil))
+	srv.StreamRequest("series", "tt0944947:1:1").Do(t).RequireStatus(t, http.StatusNotFound)
+
+	// The addon has no meta routes
+	require.Error(t, addon.SetMetaHandler("movie", func(_ context.Context, _ string, _ any) (types.MetaItem, error) {
+		return types.MetaItem{}, nil
+	}))
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+// requireHEADLikeGET requires the HEAD response to have the headers of the GET response, including its Content-Length, but no body.
+func requireHEADLikeGET(t *testing.T, url string) {
+	get, err := http.Get(url)
+	require.NoError(t, err)
+	body, err := io.ReadAll(get.Body)
+	get.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, get.StatusCode, url)
+
+	head, err := http.Head(url)
+	require.NoError(t, err)
+	headBody, err := io.ReadAll(head.Body)
+	head.Body.Close()
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, head.StatusCode, url)
+	require.Empty(t, headBody, url)
+	require.Equal(t, strconv.Itoa(len(body)), head.Header.Get("Content-Length"), url)
+	for _, header := range []string{"Content-Type", "Cache-Control", "ETag"} {
+		require.Equal(t, get.Header.Get(header), head.Header.Get(header), url+" "+header)
+	}
+}
+
+func TestHEADRequests(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{
+		Logger:            zap.NewNop(),
+		CacheAgeStreams:   time.Hour,
+		HandleEtagStreams: true,
+	}))
+
+	for _, path := range []string{"/manifest.json", "/catalog/movie/top.json", "/stream/movie/tt1254207.json"} {
+		requireHEADLikeGET(t, srv.URL+path)
+	}
+}
+
+// Streamed responses don't have a Content-Length for GET requests, but HEAD requests get the length of the body they'd have.
+func TestHEADRequestsStreamed(t *testing.T) {
+	port := freePort(t)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:             zap.NewNop(),
+		Port:               port,
+		CacheAgeStreams:    time.Hour,
+		StreamingThreshold: 1,
+	})
+	go addon.Run(nil, nil)
+	t.Cleanup(func() { _ = addon.Stop() })
+	baseURL := "http://localhost:" + strconv.Itoa(port)
+	require.Eventually(t, func() bool {
+		res, err := http.Get(baseURL + "/manifest.json")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// With a real server, where the GET response is actually streamed, and with the test server
+	requireHEADLikeGET(t, baseURL+"/stream/movie/tt1254207.json")
+	srv := stremiotest.NewServer(t, addon)
+	requireHEADLikeGET(t, srv.URL+"/stream/movie/tt1254207.json")
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestRegisterHealthCheck(t *testing.T) {
+	addon := newTestAddon(t)
+	addon.RegisterHealthCheck("disk", func(context.Context) error { return nil })
+	srv := stremiotest.NewServer(t, addon)
+
+	get := func() (int, string) {
+		res, err := http.Get(srv.URL + "/health")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	status, body := get()
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "OK", body)
+
+	// Checks can be registered while the addon is running
+	addon.RegisterHealthCheck("scraper", func(context.Context) error { return errors.New("session expired") })
+	status, body = get()
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "scraper: session expired\n", body)
+
+	// Registering a check with the same name replaces it
+	addon.RegisterHealthCheck("scraper", func(context.Context) error { return nil })
+	status, _ = get()
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestHealthPath(t *testing.T) {
+	status := func(srv *stremiotest.Server, path string) int {
+		res, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), HealthPath: "/healthz"}))
+	require.Equal(t, http.StatusOK, status(srv, "/healthz"))
+	require.Equal(t, http.StatusNotFound, status(srv, "/health"))
+
+	srv = stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), DisableHealthEndpoint: true}))
+	require.Equal(t, http.StatusNotFound, status(srv, "/health"))
+}
@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestAddHTTPEndpoint(t *testing.T) {
+	addon := newTestAddon(t)
+	addon.AddHTTPEndpoint("POST", "/:userData/webhook", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, r.PathValue("userData")+": "+string(body)+" "+r.URL.Query().Get("source"))
+	}))
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Post(srv.URL+"/abc/webhook?source=test", "text/plain", strings.NewReader("event"))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.Equal(t, "text/plain", res.Header.Get("Content-Type"))
+	require.Equal(t, "abc: event test", string(body))
+}
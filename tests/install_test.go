@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestInstallCallback(t *testing.T) {
+	type install struct {
+		userHash string
+		userData any
+	}
+	installs := make(chan install, 10)
+	events := make(chan stremio.InstallEvent, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event stremio.InstallEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	t.Cleanup(webhook.Close)
+
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:           zap.NewNop(),
+		UserDataIsBase64: true,
+		InstallCallback: func(_ context.Context, userHash string, userData any) {
+			installs <- install{userHash: userHash, userData: userData}
+		},
+		InstallWebhookURL: webhook.URL,
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	receive := func() install {
+		select {
+		case i := <-installs:
+			return i
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "callback wasn't called")
+		}
+		return install{}
+	}
+
+	srv.ManifestRequest().WithUserData(testUserData{Quality: "1080p"}).Do(t).RequireStatus(t, http.StatusOK)
+	first := receive()
+	require.Equal(t, &testUserData{Quality: "1080p"}, first.userData)
+	require.Len(t, first.userHash, 64)
+	select {
+	case event := <-events:
+		require.Equal(t, "com.example.test", event.AddonID)
+		require.Equal(t, first.userHash, event.UserHash)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "webhook wasn't called")
+	}
+
+	// Known users and requests without user data aren't reported
+	srv.ManifestRequest().WithUserData(testUserData{Quality: "1080p"}).Do(t).RequireStatus(t, http.StatusOK)
+	srv.ManifestRequest().Do(t).RequireStatus(t, http.StatusOK)
+	srv.ManifestRequest().WithUserData(testUserData{Quality: "720p"}).Do(t).RequireStatus(t, http.StatusOK)
+	second := receive()
+	require.Equal(t, &testUserData{Quality: "720p"}, second.userData)
+	require.NotEqual(t, first.userHash, second.userHash)
+	require.Empty(t, installs)
+}
+
+func TestInstallNotificationLimit(t *testing.T) {
+	installs := make(chan string, 10)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:           zap.NewNop(),
+		UserDataIsBase64: true,
+		InstallCallback: func(_ context.Context, userHash string, _ any) {
+			installs <- userHash
+		},
+		InstallNotificationsPerMinute: 2,
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	for _, quality := range []string{"2160p", "1080p", "720p", "480p"} {
+		srv.ManifestRequest().WithUserData(testUserData{Quality: quality}).Do(t).RequireStatus(t, http.StatusOK)
+	}
+	require.Eventually(t, func() bool { return len(installs) == 2 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, installs, 2)
+}
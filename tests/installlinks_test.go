@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+)
+
+func TestInstallLinks(t *testing.T) {
+	require.Equal(t, "https://example.com/manifest.json", stremio.ManifestURL("https://example.com/", ""))
+	require.Equal(t, "stremio://example.com/abc/manifest.json", stremio.StremioURL("https://example.com/manifest.json", "abc"))
+	require.Equal(t, "https://web.strem.io/#/addons?addon=https%3A%2F%2Fexample.com%2Fabc%2Fmanifest.json", stremio.WebInstallURL("https://example.com", "abc"))
+
+	links, err := newTestAddon(t).InstallLinks("https://example.com", testUserData{Quality: "1080p"})
+	require.NoError(t, err)
+	// Base64 of {"quality":"1080p"}
+	require.Equal(t, "https://example.com/eyJxdWFsaXR5IjoiMTA4MHAifQ/manifest.json", links.ManifestURL)
+	require.Equal(t, "stremio://example.com/eyJxdWFsaXR5IjoiMTA4MHAifQ/manifest.json", links.StremioURL)
+}
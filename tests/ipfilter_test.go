@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestIPFilter(t *testing.T) {
+	get := func(t *testing.T, srv *stremiotest.Server, forwardedFor string) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/manifest.json", nil)
+		require.NoError(t, err)
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("allowlist", func(t *testing.T) {
+		srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), AllowedIPs: []string{"10.0.0.0/8"}}))
+		require.Equal(t, http.StatusForbidden, get(t, srv, ""))
+		// The header is ignored when the request doesn't come from a trusted proxy
+		require.Equal(t, http.StatusForbidden, get(t, srv, "10.1.2.3"))
+	})
+	t.Run("trusted proxy", func(t *testing.T) {
+		srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), AllowedIPs: []string{"10.0.0.0/8"}, TrustedProxies: []string{"127.0.0.1", "::1"}}))
+		require.Equal(t, http.StatusOK, get(t, srv, "10.1.2.3"))
+		// The rightmost untrusted entry counts, not a spoofed one on the left
+		require.Equal(t, http.StatusForbidden, get(t, srv, "10.1.2.3, 192.0.2.1"))
+		require.Equal(t, http.StatusOK, get(t, srv, "192.0.2.1, 10.1.2.3, 127.0.0.1"))
+	})
+	t.Run("denylist", func(t *testing.T) {
+		srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), DeniedIPs: []string{"127.0.0.0/8", "::1"}}))
+		require.Equal(t, http.StatusForbidden, get(t, srv, ""))
+	})
+
+	_, err := stremio.NewAddon(newConfigurableManifest(), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{AllowedIPs: []string{"10.0.0.0/33"}})
+	require.Error(t, err)
+}
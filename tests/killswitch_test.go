@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestKillSwitches(t *testing.T) {
+	addon := newTestAddon(t)
+	srv := stremiotest.NewServer(t, addon)
+
+	addon.DisableResource("catalog")
+	require.False(t, addon.ResourceEnabled("catalog", "movie"))
+	require.Empty(t, srv.Catalog(t, "movie", "top"))
+	// Other resources keep working
+	require.Len(t, srv.Streams(t, "movie", "tt1254207"), 1)
+
+	addon.EnableResource("catalog")
+	require.Len(t, srv.Catalog(t, "movie", "top"), 1)
+
+	addon.DisableResource("stream", "series")
+	require.Len(t, srv.Streams(t, "movie", "tt1254207"), 1)
+	addon.DisableResource("stream", "movie")
+	require.Empty(t, srv.Streams(t, "movie", "tt1254207"))
+	addon.EnableResource("stream")
+	require.True(t, addon.ResourceEnabled("stream", "series"))
+	require.Len(t, srv.Streams(t, "movie", "tt1254207"), 1)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"html/template"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestLandingPage(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test <addon>", "1.2.3").
+		WithDescription("Test addon").
+		WithLogo("https://example.com/logo.png").
+		WithStreamResource("movie")
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), LandingPage: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Contains(t, res.Header.Get("Content-Type"), "text/html")
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	html := string(body)
+	require.Contains(t, html, "<h1>Test &lt;addon&gt;</h1>")
+	require.Contains(t, html, "Version 1.2.3")
+	require.Contains(t, html, `src="https://example.com/logo.png"`)
+	require.Contains(t, html, `href="`+stremio.StremioURL(srv.URL, "")+`"`)
+	require.NotContains(t, html, "Configure")
+
+	_, err = stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{LandingPage: true, RedirectURL: "https://example.com"})
+	require.Error(t, err)
+}
+
+func TestInstallQRCode(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "1.2.3").WithDescription("Test addon").WithStreamResource("movie")
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{Logger: zap.NewNop(), LandingPage: true, InstallQRCode: true})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), `src="data:image/png;base64,`)
+
+	for _, path := range []string{"/install-qr.png", "/abc/install-qr.png"} {
+		res, err = http.Get(srv.URL + path)
+		require.NoError(t, err)
+		body, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode, path)
+		require.Equal(t, "image/png", res.Header.Get("Content-Type"))
+		_, err = png.Decode(bytes.NewReader(body))
+		require.NoError(t, err)
+	}
+}
+
+func TestLandingPageTheming(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "1.2.3").WithDescription("Test addon").WithStreamResource("movie")
+	tmpl := template.Must(template.New("landing").Parse(`<html><head>{{template "style" .}}<style>{{.CSS}}</style></head><body><img src="{{.AssetsURL}}/brand.svg"><h1 class="brand">{{.Manifest.Name}}</h1></body></html>`))
+	assets := fstest.MapFS{"brand.svg": {Data: []byte("<svg></svg>")}}
+	addon, err := stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{
+		Logger:              zap.NewNop(),
+		LandingPage:         true,
+		LandingPageTemplate: tmpl,
+		PageCSS:             "h1.brand { color: red; }",
+		PageAssets:          assets,
+	})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	html := string(body)
+	require.Contains(t, html, `<h1 class="brand">Test</h1>`)
+	require.Contains(t, html, "h1.brand { color: red; }")
+	require.Contains(t, html, "font-family")
+	require.Contains(t, html, `src="`+srv.URL+`/assets/brand.svg"`)
+
+	res, err = http.Get(srv.URL + "/assets/brand.svg")
+	require.NoError(t, err)
+	body, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "<svg></svg>", string(body))
+
+	_, err = stremio.NewAddon(manifest, nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{LandingPageTemplate: tmpl})
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestLanguages(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithStreamResource("movie").
+		WithCatalog(types.NewCatalog("movie", "top", "Top").ForLanguages("en", "de")...).
+		WithConfig(types.ConfigItem{ConfKey: "language", ConfType: "select", ConfOptions: []string{"en", "de", "pt-BR"}})
+	catalogHandlers := map[string]stremio.CatalogHandler{
+		"movie": func(ctx context.Context, id string, _ url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			catalogID, _ := types.SplitCatalogLanguage(id)
+			return []types.MetaPreviewItem{{ID: "tt1254207", Type: "movie", Name: catalogID + " " + stremio.LanguageFromContext(ctx)}}, nil
+		},
+	}
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(ctx context.Context, _ string, _ any) ([]types.StreamItem, error) {
+			return []types.StreamItem{{URL: "https://example.com/" + stremio.LanguageFromContext(ctx) + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, catalogHandlers, streamHandlers, nil, nil, stremio.Options{
+		Logger:            zap.NewNop(),
+		UserDataIsBase64:  true,
+		Languages:         []string{"en", "de", "pt-BR"},
+		LanguageConfigKey: "language",
+	})
+	require.NoError(t, err)
+	addon.RegisterUserData(map[string]string{})
+	srv := stremiotest.NewServer(t, addon)
+
+	// The language of a per-language catalog takes precedence
+	res := srv.CatalogRequest("movie", "top.de").WithHeader("Accept-Language", "pt-BR").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, "Accept-Language", res.Header.Get("Vary"))
+	require.Equal(t, "top de", res.Metas(t)[0].Name)
+
+	for _, tc := range []struct {
+		name, acceptLanguage, configured, expected string
+	}{
+		{"fallback", "", "", "en"},
+		{"Accept-Language", "fr-FR,pt;q=0.8", "", "pt-BR"},
+		{"unsupported Accept-Language", "fr-FR,fr;q=0.9", "", "en"},
+		{"config item", "pt-BR", "de-AT", "de"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := srv.StreamRequest("movie", "tt1254207").WithHeader("Accept-Language", tc.acceptLanguage)
+			if tc.configured != "" {
+				req = req.WithUserData(map[string]string{"language": tc.configured})
+			}
+			require.Equal(t, "https://example.com/"+tc.expected+".mp4", req.Do(t).Streams(t)[0].URL)
+		})
+	}
+
+	_, err = stremio.NewAddon(manifest, catalogHandlers, nil, nil, nil, stremio.Options{Languages: []string{"en", "not a language"}})
+	require.ErrorContains(t, err, "not a language")
+	_, err = stremio.NewAddon(manifest, catalogHandlers, nil, nil, nil, stremio.Options{LanguageConfigKey: "language"})
+	require.Error(t, err)
+}
+
+func TestAcceptLanguages(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithStreamResource("movie")
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(ctx context.Context, _ string, _ any) ([]types.StreamItem, error) {
+			var streams []types.StreamItem
+			for _, tag := range stremio.AcceptLanguagesFromContext(ctx) {
+				streams = append(streams, types.StreamItem{URL: "https://example.com/" + tag.String() + ".mp4"})
+			}
+			return streams, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	streams := srv.StreamRequest("movie", "tt1254207").WithHeader("Accept-Language", "en;q=0.5, de-AT, *;q=0.1, fr;q=0.8").Do(t).Streams(t)
+	require.Len(t, streams, 3)
+	for i, expected := range []string{"de-AT", "fr", "en"} {
+		require.Equal(t, "https://example.com/"+expected+".mp4", streams[i].URL)
+	}
+
+	require.Empty(t, srv.Streams(t, "movie", "tt1254207"))
+}
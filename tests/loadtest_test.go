@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/loadtest"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestLoadTest(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+
+	// Only correctness is checked, as the throughput depends on the machine that runs the tests
+	report, err := loadtest.Run(context.Background(), srv.URL, loadtest.Options{
+		Duration:    300 * time.Millisecond,
+		Concurrency: 4,
+		Rate:        400,
+	})
+	require.NoError(t, err)
+	require.Positive(t, report.Total.Requests)
+	require.Zero(t, report.Total.Errors)
+	requests := 0
+	for _, stats := range report.Kinds {
+		requests += stats.Requests
+	}
+	require.Equal(t, report.Total.Requests, requests)
+	require.LessOrEqual(t, report.Total.P50, report.Total.P99)
+	require.LessOrEqual(t, report.Total.P99, report.Total.Max)
+
+	// Sessions are picked randomly, so run one kind at a time to make sure every kind of request works
+	for kind, mix := range map[loadtest.Kind]loadtest.Mix{
+		loadtest.Manifest: {Manifest: 1},
+		loadtest.Catalog:  {Catalog: 1},
+		loadtest.Stream:   {Stream: 1},
+	} {
+		report, err := loadtest.Run(context.Background(), srv.URL, loadtest.Options{Duration: 50 * time.Millisecond, Concurrency: 2, Mix: mix})
+		require.NoError(t, err)
+		require.Positive(t, report.Kinds[kind].Requests, kind)
+		require.Zero(t, report.Total.Errors, kind)
+		require.Equal(t, report.Total.Requests, report.Kinds[kind].Requests, kind)
+	}
+
+	p99Change, _, err := loadtest.Compare(report, report)
+	require.NoError(t, err)
+	require.Zero(t, p99Change)
+
+	_, err = loadtest.Run(context.Background(), srv.URL+"/invalid", loadtest.Options{Duration: time.Millisecond})
+	require.Error(t, err)
+}
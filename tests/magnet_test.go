@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/magnet"
+)
+
+func TestMagnet(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:DD8255ECDC7CA55FB0BBF81323D87062DB1F6D1C&dn=Big+Buck+Bunny&xl=276445467&tr=udp%3A%2F%2Fexplodie.org%3A6969&tr=wss%3A%2F%2Ftracker.btorrent.xyz"
+	m, err := magnet.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c", m.InfoHash)
+	require.Equal(t, "Big Buck Bunny", m.Name)
+	require.Equal(t, int64(276445467), m.Size)
+
+	s := m.Stream()
+	require.Equal(t, m.InfoHash, s.InfoHash)
+	// The size is the size of the whole torrent, which might contain more than the video
+	require.Zero(t, s.BehaviorHints.VideoSize)
+	require.Equal(t, []string{
+		"tracker:udp://explodie.org:6969",
+		"tracker:wss://tracker.btorrent.xyz",
+		"dht:dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c",
+	}, s.Sources)
+
+	// And back
+	built, err := magnet.Build(s.InfoHash, s.Sources)
+	require.NoError(t, err)
+	m2, err := magnet.Parse(built)
+	require.NoError(t, err)
+	require.Equal(t, m.Trackers, m2.Trackers)
+	require.Equal(t, m.InfoHash, m2.InfoHash)
+
+	// Base32 info hashes are converted to hex.
+	m, err = magnet.Parse("magnet:?xt=urn:btih:3WBFL3G4PSSV7MF37AJSHWDQMLNR63I4")
+	require.NoError(t, err)
+	require.Equal(t, "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c", m.InfoHash)
+
+	_, err = magnet.Parse("magnet:?dn=foo")
+	require.ErrorIs(t, err, magnet.ErrNoInfoHash)
+}
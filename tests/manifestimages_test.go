@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestManifestImages(t *testing.T) {
+	logo := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for x := range 64 {
+		for y := range 32 {
+			logo.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var logoPNG bytes.Buffer
+	require.NoError(t, png.Encode(&logoPNG, logo))
+	var logoFetches atomic.Int32
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		logoFetches.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(logoPNG.Bytes())
+	}))
+	t.Cleanup(images.Close)
+
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), ManifestImages: true})
+	srv := stremiotest.NewServer(t, addon)
+	get := func(path string) (*http.Response, []byte) {
+		res, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	// The manifest doesn't have images yet
+	res, _ := get("/favicon.ico")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	require.NoError(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.Logo = images.URL + "/logo.png"
+	}))
+	res, body := get("/logo")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "image/png", res.Header.Get("Content-Type"))
+	require.Equal(t, "public, max-age=86400", res.Header.Get("Cache-Control"))
+	require.Equal(t, logoPNG.Bytes(), body)
+
+	res, body = get("/favicon.ico")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	favicon, err := png.Decode(bytes.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 32, 32), favicon.Bounds())
+	// The wide logo is centered vertically on a transparent background
+	require.Equal(t, color.NRGBA{R: 255, A: 255}, color.NRGBAModel.Convert(favicon.At(16, 16)))
+	require.Equal(t, color.NRGBA{}, color.NRGBAModel.Convert(favicon.At(16, 0)))
+	// The logo is kept in memory
+	require.Equal(t, int32(1), logoFetches.Load())
+
+	res, _ = get("/background")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
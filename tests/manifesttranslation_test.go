@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestManifestTranslations(t *testing.T) {
+	addon := newTestAddon(t)
+	require.NoError(t, addon.AddManifestTranslation("de", stremio.ManifestTranslation{
+		Name:     "Test DE",
+		Catalogs: map[string]string{"movie/top": "Beliebt"},
+	}))
+	require.NoError(t, addon.AddManifestTranslation("pt-BR", stremio.ManifestTranslation{Description: "Addon de teste"}))
+	require.Error(t, addon.AddManifestTranslation("de", stremio.ManifestTranslation{}))
+	require.Error(t, addon.AddManifestTranslation("not a language", stremio.ManifestTranslation{}))
+	srv := stremiotest.NewServer(t, addon)
+
+	res := srv.ManifestRequest().WithHeader("Accept-Language", "de-AT,de;q=0.9,en;q=0.8").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, "Accept-Language", res.Header.Get("Vary"))
+	manifest := res.Manifest(t)
+	require.Equal(t, "Test DE", manifest.Name)
+	require.Equal(t, "Test addon", manifest.Description)
+	require.Equal(t, "Beliebt", manifest.Catalogs[0].Name)
+
+	manifest = srv.ManifestRequest().WithHeader("Accept-Language", "pt-BR").Do(t).Manifest(t)
+	require.Equal(t, "Test", manifest.Name)
+	require.Equal(t, "Addon de teste", manifest.Description)
+
+	// Other languages get the original manifest
+	for _, acceptLanguage := range []string{"", "fr-FR,fr;q=0.9", "*"} {
+		manifest = srv.ManifestRequest().WithHeader("Accept-Language", acceptLanguage).Do(t).Manifest(t)
+		require.Equal(t, "Test", manifest.Name, acceptLanguage)
+		require.Equal(t, "Top", manifest.Catalogs[0].Name, acceptLanguage)
+	}
+
+	// Translations apply to updated manifests as well
+	require.NoError(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.Version = "0.2.0"
+	}))
+	manifest = srv.ManifestRequest().WithHeader("Accept-Language", "de").Do(t).Manifest(t)
+	require.Equal(t, "Test DE", manifest.Name)
+	require.Equal(t, "0.2.0", manifest.Version)
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestUpdateManifest(t *testing.T) {
+	addon := newTestAddon(t)
+	require.NoError(t, addon.AddManifestVariant("renamed", 0, func(manifest *types.Manifest) {
+		manifest.Name = "Renamed"
+	}))
+	srv := stremiotest.NewServer(t, addon)
+	require.Len(t, srv.Manifest(t).Catalogs, 1)
+
+	require.NoError(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.Catalogs = append(manifest.Catalogs, types.CatalogItem{Type: "movie", ID: "new", Name: "New"})
+	}))
+	require.Len(t, srv.Manifest(t).Catalogs, 2)
+	require.Len(t, srv.ManifestRequest().WithUserData(testUserData{Quality: "1080p"}).Do(t).Manifest(t).Catalogs, 2)
+
+	// Invalid updates keep the current manifest
+	require.Error(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.ID = "com.example.other"
+	}))
+	require.Error(t, addon.UpdateManifest(func(manifest *types.Manifest) {
+		manifest.Name = ""
+	}))
+	require.Equal(t, "Test", srv.Manifest(t).Name)
+}
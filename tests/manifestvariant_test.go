@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestManifestVariants(t *testing.T) {
+	addon := newTestAddon(t)
+	require.NoError(t, addon.AddManifestVariant("renamed", 50, func(manifest *types.Manifest) {
+		manifest.Name = "Renamed"
+	}))
+	require.Error(t, addon.AddManifestVariant("renamed", 10, func(*types.Manifest) {}))
+	require.Error(t, addon.AddManifestVariant("other", 60, func(*types.Manifest) {}))
+	srv := stremiotest.NewServer(t, addon)
+
+	// Users are assigned to variants by their user data
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		userData := testUserData{Quality: fmt.Sprintf("q%d", i)}
+		manifest := srv.ManifestRequest().WithUserData(userData).Do(t).Manifest(t)
+		encoded, err := addon.EncodeUserData(userData)
+		require.NoError(t, err)
+		if addon.ManifestVariant(encoded) == "renamed" {
+			require.Equal(t, "Renamed", manifest.Name)
+		} else {
+			require.Equal(t, "Test", manifest.Name)
+		}
+		counts[manifest.Name]++
+	}
+	require.InDelta(t, 100, counts["Renamed"], 30)
+}
+
+func TestManifestVariantSelector(t *testing.T) {
+	addon := newTestAddon(t)
+	require.NoError(t, addon.AddManifestVariant("beta", 0, func(manifest *types.Manifest) {
+		manifest.Catalogs = append(manifest.Catalogs, types.CatalogItem{Type: "movie", ID: "new", Name: "New"})
+	}))
+	addon.SetManifestVariantSelector(func(_ context.Context, userData string) string {
+		if userData != "" {
+			return "beta"
+		}
+		return ""
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	require.Len(t, srv.Manifest(t).Catalogs, 1)
+	require.Len(t, srv.ManifestRequest().WithUserData(testUserData{Quality: "1080p"}).Do(t).Manifest(t).Catalogs, 2)
+}
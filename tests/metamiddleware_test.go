@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// metaName returns the name of the meta in the context, or "none".
+func metaName(ctx context.Context) string {
+	meta, err := stremio.GetMetaFromContext(ctx)
+	if err != nil {
+		return "none"
+	}
+	return meta.Name
+}
+
+// newMetaTestAddon creates an addon whose handlers respond with the name of the meta in their context.
+func newMetaTestAddon(t *testing.T, opts stremio.Options) *stremio.Addon {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithStreamResource("movie").
+		WithMetaResource("movie").
+		WithCatalog(types.CatalogItem{Type: "movie", ID: "top", Name: "Top"})
+	catalogHandlers := map[string]stremio.CatalogHandler{
+		"movie": func(ctx context.Context, _ string, _ url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			return []types.MetaPreviewItem{{ID: "tt1254207", Type: "movie", Name: metaName(ctx)}}, nil
+		},
+	}
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(ctx context.Context, _ string, _ any) ([]types.StreamItem, error) {
+			return []types.StreamItem{{URL: "https://example.com/stream.mp4", Title: metaName(ctx)}}, nil
+		},
+	}
+	metaHandlers := map[string]stremio.MetaHandler{
+		"movie": func(ctx context.Context, id string, _ any) (types.MetaItem, error) {
+			return types.MetaItem{ID: id, Type: "movie", Name: metaName(ctx)}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, catalogHandlers, streamHandlers, metaHandlers, nil, opts)
+	require.NoError(t, err)
+	stremio.RegisterUserDataType[testUserData](addon)
+	return addon
+}
+
+func TestMetaMiddlewareRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		metaForCatalogs bool
+		userData        bool
+	}{
+		{"default", false, false},
+		{"with user data", false, true},
+		{"meta for catalogs", true, false},
+		{"meta for catalogs with user data", true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metaFetcher := stremiotest.NewMetaFetcher().WithMeta(types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny"})
+			srv := stremiotest.NewServer(t, newMetaTestAddon(t, stremio.Options{
+				Logger:           zap.NewNop(),
+				PutMetaInContext: true,
+				MetaForCatalogs:  tc.metaForCatalogs,
+				MetaClient:       metaFetcher,
+			}))
+			withUserData := func(r *stremiotest.Request) *stremiotest.Request {
+				if tc.userData {
+					return r.WithUserData(testUserData{Quality: "1080p"})
+				}
+				return r
+			}
+
+			streams := withUserData(srv.StreamRequest("movie", "tt1254207")).Do(t).Streams(t)
+			require.Equal(t, "Big Buck Bunny", streams[0].Title)
+
+			var metaRes struct {
+				Meta types.MetaItem `json:"meta"`
+			}
+			withUserData(srv.MetaRequest("movie", "tt1254207")).Do(t).Decode(t, &metaRes)
+			require.Equal(t, "Big Buck Bunny", metaRes.Meta.Name)
+
+			// Catalogs are only enriched when enabled, and only for IMDb IDs, with and without extras
+			expected := "none"
+			if tc.metaForCatalogs {
+				expected = "Big Buck Bunny"
+			}
+			metas := withUserData(srv.CatalogRequest("movie", "tt1254207")).Do(t).Metas(t)
+			require.Equal(t, expected, metas[0].Name)
+			metas = withUserData(srv.CatalogRequest("movie", "tt1254207").WithExtra("skip", "100")).Do(t).Metas(t)
+			require.Equal(t, expected, metas[0].Name)
+			metas = withUserData(srv.CatalogRequest("movie", "top")).Do(t).Metas(t)
+			require.Equal(t, "none", metas[0].Name)
+
+			calls := 2
+			if tc.metaForCatalogs {
+				calls = 4
+			}
+			require.Len(t, metaFetcher.Calls(), calls)
+		})
+	}
+}
+
+func TestMetaMiddlewareLogMediaName(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	metaFetcher := stremiotest.NewMetaFetcher().WithMeta(types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny", ReleaseInfo: "2008"})
+	srv := stremiotest.NewServer(t, newMetaTestAddon(t, stremio.Options{
+		Logger:          zap.New(core),
+		LogMediaName:    true,
+		MetaForCatalogs: true,
+		MetaClient:      metaFetcher,
+	}))
+
+	for _, req := range []*stremiotest.Request{
+		srv.StreamRequest("movie", "tt1254207"),
+		srv.MetaRequest("movie", "tt1254207"),
+		srv.CatalogRequest("movie", "tt1254207"),
+	} {
+		req.Do(t).RequireStatus(t, http.StatusOK)
+	}
+	srv.CatalogRequest("movie", "top").Do(t).RequireStatus(t, http.StatusOK)
+
+	entries := logs.FilterMessage("Handled request").AllUntimed()
+	require.Len(t, entries, 4)
+	for _, entry := range entries[:3] {
+		require.Equal(t, "Big Buck Bunny (2008)", entry.ContextMap()["mediaName"], entry.ContextMap()["url"])
+	}
+	// Only requests that the meta middleware handled have the field
+	require.NotContains(t, entries[3].ContextMap(), "mediaName")
+}
+
+// Meta objects stored with WithMeta and with the legacy string key can be read by both GetMetaFromContext functions.
+func TestGetMetaFromContext(t *testing.T) {
+	meta := types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny"}
+	legacyMeta := types.MetaItem{ID: "tt1254207", Name: "Legacy Big Buck Bunny"}
+
+	for _, get := range []func(context.Context) (types.MetaItem, error){stremio.GetMetaFromContext, cinemeta.GetMetaFromContext} {
+		res, err := get(stremio.WithMeta(context.Background(), meta))
+		require.NoError(t, err)
+		require.Equal(t, meta, res)
+
+		// Like users stored them before WithMeta existed
+		legacyCtx := context.WithValue(context.Background(), "meta", legacyMeta)
+		res, err = get(legacyCtx)
+		require.NoError(t, err)
+		require.Equal(t, legacyMeta, res)
+
+		// The typed key takes precedence
+		res, err = get(stremio.WithMeta(legacyCtx, meta))
+		require.NoError(t, err)
+		require.Equal(t, meta, res)
+
+		_, err = get(context.Background())
+		require.Error(t, err)
+		_, err = get(context.WithValue(context.Background(), "meta", "Big Buck Bunny"))
+		require.Error(t, err)
+	}
+
+	_, err := stremio.GetMetaFromContext(context.Background())
+	require.ErrorIs(t, err, stremio.ErrNoMeta)
+	_, err = cinemeta.GetMetaFromContext(context.Background())
+	require.ErrorIs(t, err, cinemeta.ErrNoMeta)
+}
+
+// concurrencyMetaFetcher records the max number of concurrent calls. Each call takes 50ms.
+type concurrencyMetaFetcher struct {
+	lock   sync.Mutex
+	active int
+	max    int
+}
+
+func (f *concurrencyMetaFetcher) GetMovie(_ context.Context, imdbID string) (types.MetaItem, error) {
+	f.lock.Lock()
+	f.active++
+	f.max = max(f.max, f.active)
+	f.lock.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	f.lock.Lock()
+	f.active--
+	f.lock.Unlock()
+	return types.MetaItem{ID: imdbID, Name: "Movie " + imdbID}, nil
+}
+
+func (f *concurrencyMetaFetcher) GetSeries(ctx context.Context, imdbID string, _, _ int) (types.MetaItem, error) {
+	return f.GetMovie(ctx, imdbID)
+}
+
+func TestMaxConcurrentMetaFetches(t *testing.T) {
+	for _, limit := range []int{0, 1, 2} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			metaFetcher := &concurrencyMetaFetcher{}
+			srv := stremiotest.NewServer(t, newMetaTestAddon(t, stremio.Options{
+				Logger:                   zap.NewNop(),
+				PutMetaInContext:         true,
+				MetaClient:               metaFetcher,
+				MaxConcurrentMetaFetches: limit,
+			}))
+
+			var wg sync.WaitGroup
+			for i := range 6 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// Different IDs, because concurrent fetches of the same meta are shared anyway
+					id := "tt" + strconv.Itoa(i)
+					streams := srv.StreamRequest("movie", id).Do(t).Streams(t)
+					// Requests wait for a free slot (up to MetaTimeout), so they all get their meta
+					require.Equal(t, "Movie "+id, streams[0].Title)
+				}()
+			}
+			wg.Wait()
+
+			if limit == 0 {
+				require.Greater(t, metaFetcher.max, 1)
+			} else {
+				require.Equal(t, limit, metaFetcher.max)
+			}
+		})
+	}
+}
+
+// The time spent waiting for a slot counts towards MetaTimeout, so a request never waits longer than it for its meta.
+func TestMaxConcurrentMetaFetchesTimeout(t *testing.T) {
+	cinemetaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(250 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"meta":{"id":"tt1","type":"movie","name":"Movie"}}`))
+	}))
+	defer cinemetaSrv.Close()
+	srv := stremiotest.NewServer(t, newMetaTestAddon(t, stremio.Options{
+		Logger:                   zap.NewNop(),
+		PutMetaInContext:         true,
+		MetaTimeout:              400 * time.Millisecond,
+		CinemetaOptions:          cinemeta.ClientOptions{BaseURL: cinemetaSrv.URL},
+		MaxConcurrentMetaFetches: 1,
+	}))
+
+	// The second fetch only gets the slot after 250ms, and 150ms aren't enough for it
+	titles := make(chan string, 2)
+	for _, id := range []string{"tt1", "tt2"} {
+		go func() {
+			titles <- srv.StreamRequest("movie", id).Do(t).Streams(t)[0].Title
+		}()
+	}
+	require.ElementsMatch(t, []string{"Movie", "none"}, []string{<-titles, <-titles})
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestOpenAPI(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithStreamResource("movie", "series").
+		WithIDPrefixes("tt")
+	streamHandler := func(_ context.Context, _ string, _ any) ([]types.StreamItem, error) {
+		return nil, stremio.ErrNotFound
+	}
+	streamHandlers := map[string]stremio.StreamHandler{"movie": streamHandler, "series": streamHandler}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), OpenAPI: true})
+	require.NoError(t, err)
+	addon.AddEndpoint("GET", "/:userData/foo", func(c fiber.Ctx) error { return nil })
+
+	srv := stremiotest.NewServer(t, addon)
+	res, err := http.Get(srv.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var doc struct {
+		Info struct {
+			Title string `json:"title"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name   string `json:"name"`
+				Schema struct {
+					Enum []string `json:"enum"`
+				} `json:"schema"`
+			} `json:"parameters"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(body, &doc))
+	require.Equal(t, "Test", doc.Info.Title)
+	require.Contains(t, doc.Paths, "/manifest.json")
+	require.Contains(t, doc.Paths, "/{userData}/foo")
+	require.NotContains(t, doc.Paths, "/catalog/{type}/{id}.json")
+	// Without user data and configurability only the routes without user data are documented
+	require.NotContains(t, doc.Paths, "/{userData}/stream/{type}/{id}.json")
+	stream := doc.Paths["/stream/{type}/{id}.json"]["get"]
+	require.Equal(t, "type", stream.Parameters[0].Name)
+	require.Equal(t, []string{"movie", "series"}, stream.Parameters[0].Schema.Enum)
+	require.Contains(t, doc.Components.Schemas, "StreamItem")
+	require.Contains(t, doc.Components.Schemas, "SubtitleItem")
+}
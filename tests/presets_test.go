@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestOptionsPresets(t *testing.T) {
+	for name, opts := range map[string]stremio.Options{
+		"production":  stremio.ProductionOptions(),
+		"development": stremio.DevelopmentOptions(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The development preset's logging level is replaced by the logger
+			if name == "development" {
+				opts.LoggingLevel = ""
+			}
+			opts.Logger = zap.NewNop()
+			srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, opts))
+
+			res := srv.StreamRequest("movie", "tt1254207").Do(t).RequireStatus(t, http.StatusOK)
+			if name == "production" {
+				require.Equal(t, "max-age=3600, private, stale-while-revalidate=14400, stale-if-error=86400", res.Header.Get("Cache-Control"))
+				require.NotEmpty(t, res.Header.Get("ETag"))
+			} else {
+				require.Empty(t, res.Header.Get("Cache-Control"))
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+// proxyTest is an addon with the stream proxy and an upstream with the following paths:
+// "/video" responds right away if the Referer header was forwarded, "/slow" responds once release is closed, "/big" responds with 4000 bytes
+// and "/missing" responds with 404.
+type proxyTest struct {
+	srv      *stremiotest.Server
+	signer   *signedurl.Signer
+	upstream *httptest.Server
+	// Receives a value when a request to "/slow" arrived at the upstream.
+	started chan struct{}
+	release chan struct{}
+}
+
+func newProxyTest(t *testing.T, opts stremio.Options) *proxyTest {
+	pt := &proxyTest{
+		signer:  newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength)),
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/video", func(w http.ResponseWriter, r *http.Request) {
+		// The token's headers are sent to the upstream
+		if r.Header.Get("Referer") != "https://addon.example" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		_, _ = w.Write([]byte("video"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		pt.started <- struct{}{}
+		<-pt.release
+		_, _ = w.Write([]byte("slow"))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 4000)))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	pt.upstream = httptest.NewServer(mux)
+	t.Cleanup(pt.upstream.Close)
+	// Unblock the slow requests before the upstream is closed
+	t.Cleanup(func() {
+		select {
+		case <-pt.release:
+		default:
+			close(pt.release)
+		}
+	})
+
+	opts.Logger = zap.NewNop()
+	opts.URLSigner = pt.signer
+	opts.StreamProxy = true
+	pt.srv = stremiotest.NewServer(t, newTestAddonWithOptions(t, opts))
+	return pt
+}
+
+// get requests the upstream path via the proxy, as the given user.
+func (pt *proxyTest) get(t *testing.T, path, user string) (int, string) {
+	u, err := pt.signer.ProxyURLForUser(pt.srv.URL, pt.upstream.URL+path, map[string]string{"Referer": "https://addon.example"}, user, time.Hour)
+	require.NoError(t, err)
+	res, err := pt.srv.Client().Get(u)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(body)
+}
+
+// getSlow starts a request to "/slow" and waits until it arrived at the upstream.
+// The returned channel receives the status code once the request is done.
+func (pt *proxyTest) getSlow(t *testing.T, user string) <-chan int {
+	done := make(chan int, 1)
+	go func() {
+		status, _ := pt.get(t, "/slow", user)
+		done <- status
+	}()
+	select {
+	case <-pt.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow request didn't arrive at the upstream")
+	}
+	return done
+}
+
+func TestProxy(t *testing.T) {
+	pt := newProxyTest(t, stremio.Options{})
+
+	status, body := pt.get(t, "/video", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "video", body)
+
+	// Upstream errors and unreachable upstreams are rejected
+	status, _ = pt.get(t, "/missing", "")
+	require.Equal(t, http.StatusBadGateway, status)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	u, err := pt.signer.ProxyURL(pt.srv.URL, closed.URL+"/video", nil, time.Hour)
+	require.NoError(t, err)
+	res, err := pt.srv.Client().Get(u)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadGateway, res.StatusCode)
+
+	// Expired and invalid tokens are rejected
+	u, err = pt.signer.ProxyURL(pt.srv.URL, pt.upstream.URL+"/video", nil, -time.Minute)
+	require.NoError(t, err)
+	res, err = pt.srv.Client().Get(u)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusGone, res.StatusCode)
+	res, err = pt.srv.Client().Get(pt.srv.URL + "/proxy/abc")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestProxyMaxConnections(t *testing.T) {
+	pt := newProxyTest(t, stremio.Options{MaxProxyConnections: 1})
+
+	done := pt.getSlow(t, "a")
+	status, _ := pt.get(t, "/video", "b")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+
+	// The connection is released when the stream is done
+	close(pt.release)
+	require.Equal(t, http.StatusOK, <-done)
+	status, _ = pt.get(t, "/video", "b")
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestProxyMaxConnectionsPerUser(t *testing.T) {
+	pt := newProxyTest(t, stremio.Options{MaxProxyConnectionsPerUser: 1})
+
+	done := pt.getSlow(t, "a")
+	status, _ := pt.get(t, "/video", "a")
+	require.Equal(t, http.StatusTooManyRequests, status)
+	// Other users aren't affected
+	status, _ = pt.get(t, "/video", "b")
+	require.Equal(t, http.StatusOK, status)
+
+	close(pt.release)
+	require.Equal(t, http.StatusOK, <-done)
+	status, _ = pt.get(t, "/video", "a")
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestProxyMaxBandwidthPerUser(t *testing.T) {
+	pt := newProxyTest(t, stremio.Options{MaxProxyBandwidthPerUser: 2000})
+
+	// The first 2000 bytes are the burst, the other 2000 bytes take a second
+	start := time.Now()
+	status, body := pt.get(t, "/big", "a")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, body, 4000)
+	require.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	// Small responses within the burst aren't delayed
+	start = time.Now()
+	status, _ = pt.get(t, "/video", "b")
+	require.Equal(t, http.StatusOK, status)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
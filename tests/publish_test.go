@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/publish"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestPublish(t *testing.T) {
+	var published map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/addonPublish", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&published))
+		_, _ = w.Write([]byte(`{"result":{"success":true}}`))
+	}))
+	defer api.Close()
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+
+	// Local URLs can't be published
+	_, err := publish.Publish(context.Background(), srv.URL+"/manifest.json", publish.Options{APIURL: api.URL + "/api/"})
+	require.ErrorIs(t, err, publish.ErrInvalidURL)
+
+	report, err := publish.Publish(context.Background(), srv.URL+"/manifest.json", publish.Options{APIURL: api.URL + "/api/", SkipURLValidation: true})
+	require.NoError(t, err)
+	require.True(t, report.OK())
+	require.Equal(t, map[string]string{"transportUrl": srv.URL + "/manifest.json", "transportName": "http"}, published)
+
+	// Broken addons aren't published
+	published = nil
+	_, err = publish.Publish(context.Background(), srv.URL+"/invalid/manifest.json", publish.Options{APIURL: api.URL + "/api/", SkipURLValidation: true})
+	require.ErrorIs(t, err, publish.ErrCheckFailed)
+	require.Nil(t, published)
+}
+
+func TestPublishValidateURL(t *testing.T) {
+	require.NoError(t, publish.ValidateURL("https://example.com/abc/manifest.json"))
+	require.Error(t, publish.ValidateURL("http://example.com/manifest.json"))
+	require.Error(t, publish.ValidateURL("https://example.com/"))
+	require.Error(t, publish.ValidateURL("https://192.168.1.2/manifest.json"))
+	require.Error(t, publish.ValidateURL("https://localhost/manifest.json"))
+}
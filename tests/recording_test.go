@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/recording"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestRecording(t *testing.T) {
+	recordFile := filepath.Join(t.TempDir(), "recording.jsonl")
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(_ context.Context, id string, userData any) ([]types.StreamItem, error) {
+			if userData != nil {
+				return []types.StreamItem{{URL: "https://example.com/" + userData.(*testUserData).Quality + ".mp4"}}, nil
+			}
+			return []types.StreamItem{{URL: "https://example.com/" + id + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true, RecordFile: recordFile})
+	require.NoError(t, err)
+	addon.RegisterUserData(testUserData{})
+	srv := stremiotest.NewServer(t, addon)
+
+	srv.StreamRequest("movie", "tt1254207").WithHeader("Authorization", "secret").Do(t).Streams(t)
+	srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+
+	entries, err := recording.ReadFile(recordFile)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "/stream/movie/tt1254207.json", entries[0].Path)
+	require.Equal(t, 200, entries[0].Status)
+	require.Empty(t, entries[0].Header.Get("Authorization"))
+	require.Contains(t, entries[0].ResponseBody, "tt1254207.mp4")
+	require.Equal(t, "/"+recording.Redacted+"/stream/movie/tt1254207.json", entries[1].Path)
+
+	results := recording.Replay(context.Background(), srv.URL, entries, nil)
+	require.True(t, results[0].Match)
+	// The user data was redacted
+	require.False(t, results[1].Match)
+}
+
+func TestRedactUserData(t *testing.T) {
+	require.Equal(t, "/REDACTED/catalog/movie/top/skip=100.json", recording.RedactUserData("/abc/catalog/movie/top/skip=100.json"))
+	require.Equal(t, "/REDACTED/manifest.json", recording.RedactUserData("/abc/manifest.json"))
+	require.Equal(t, "/manifest.json", recording.RedactUserData("/manifest.json"))
+	require.Equal(t, "/stream/movie/tt1254207.json", recording.RedactUserData("/stream/movie/tt1254207.json"))
+	require.Equal(t, "/resolve/abc", recording.RedactUserData("/resolve/abc"))
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/release"
+)
+
+func TestReleaseParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected release.Info
+	}{
+		{
+			name: "Big.Buck.Bunny.2008.2160p.BluRay.REMUX.HDR.HEVC.10bit-GROUP.mkv",
+			expected: release.Info{
+				Title:      "Big Buck Bunny",
+				Year:       2008,
+				Resolution: 2160,
+				Source:     release.SourceBluRay,
+				Remux:      true,
+				Codec:      release.CodecH265,
+				HDR:        []string{release.HDRGeneric},
+				BitDepth:   10,
+				Group:      "GROUP",
+			},
+		},
+		{
+			name: "Some Show S02E05 1080p WEB-DL DV HDR10+ x265-ABC",
+			expected: release.Info{
+				Title:      "Some Show",
+				Season:     2,
+				Episode:    5,
+				Resolution: 1080,
+				Source:     release.SourceWEBDL,
+				Codec:      release.CodecH265,
+				HDR:        []string{release.HDRDolbyVision, release.HDR10Plus},
+				Group:      "ABC",
+			},
+		},
+		{
+			name: "[SubsPlease] Some Anime - 1x03 (720p).mkv",
+			expected: release.Info{
+				Title:      "Some Anime",
+				Season:     1,
+				Episode:    3,
+				Resolution: 720,
+				Group:      "SubsPlease",
+			},
+		},
+		{
+			name: "2001.A.Space.Odyssey.1968.720p.WEBRip.x264",
+			expected: release.Info{
+				Title:      "2001 A Space Odyssey",
+				Year:       1968,
+				Resolution: 720,
+				Source:     release.SourceWEBRip,
+				Codec:      release.CodecH264,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, release.Parse(test.name))
+		})
+	}
+
+	require.Equal(t, "2160p BluRay Remux HDR H.265", release.Parse(tests[0].name).Quality())
+}
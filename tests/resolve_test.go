@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	signer := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{
+		Logger:    zap.NewNop(),
+		URLSigner: signer,
+	}))
+	client := srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	get := func(path string) *http.Response {
+		res, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	u, err := signer.URL(srv.URL, upstreamURL, time.Hour)
+	require.NoError(t, err)
+	res := get(strings.TrimPrefix(u, srv.URL))
+	require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	require.Equal(t, upstreamURL, res.Header.Get("Location"))
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+
+	token, err := signer.Sign(upstreamURL, -time.Minute)
+	require.NoError(t, err)
+	res = get("/resolve/" + token)
+	require.Equal(t, http.StatusGone, res.StatusCode)
+	require.Empty(t, res.Header.Get("Location"))
+
+	// Tokens of another signer, tampered and malformed tokens are rejected
+	token, err = newTestSigner(t, strings.Repeat("b", signedurl.MinSecretLength)).Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	valid, err := signer.Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	tampered := []byte(valid)
+	tampered[len(tampered)/2] ^= 1
+	for _, token := range []string{token, string(tampered), "abc"} {
+		res = get("/resolve/" + token)
+		require.Equal(t, http.StatusForbidden, res.StatusCode, token)
+		require.Empty(t, res.Header.Get("Location"))
+	}
+}
+
+// Without a URLSigner, there's no resolve endpoint.
+func TestResolveEndpointDisabled(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop()}))
+	res, err := srv.Client().Get(srv.URL + "/resolve/abc")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+// Responses are marshalled into pooled buffers, so concurrent requests must never get each other's bodies.
+func TestConcurrentResponses(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quality := strconv.Itoa(i) + "p"
+			for range 10 {
+				streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: quality}).Do(t).Streams(t)
+				require.Equal(t, "https://example.com/"+quality+".mp4", streams[0].URL)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Middlewares run after the handler returned, while other requests already reuse the pooled buffers,
+// so the response body must not point into a pooled buffer.
+func TestConcurrentResponsesWithPostProcessing(t *testing.T) {
+	addon := newTestAddon(t)
+	addon.AddMiddleware("/", func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quality := strconv.Itoa(i) + "p"
+			expected := `{"streams":[{"url":"https://example.com/` + quality + `.mp4","behaviorHints":{}}]}`
+			for range 10 {
+				res := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: quality}).Do(t)
+				require.Equal(t, expected, string(res.Body))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xybydy/go-stremio/internal/scaffold"
+)
+
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	files, err := scaffold.Generate(dir, scaffold.Options{
+		Module:       "example.com/my-addon",
+		ID:           "com.example.my-addon",
+		Name:         "My addon",
+		Resources:    []string{"catalog", "stream"},
+		Types:        []string{"movie"},
+		Configurable: true,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Dockerfile", "README.md", "go.mod", "main.go", "main_test.go", "web/index.html"}, files)
+
+	for _, file := range []string{"main.go", "main_test.go"} {
+		_, err = parser.ParseFile(token.NewFileSet(), filepath.Join(dir, file), nil, 0)
+		require.NoError(t, err, file)
+	}
+	mainGo, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(mainGo), `types.NewCatalog("movie", "top", "Top")`)
+	require.NotContains(t, string(mainGo), "SubtitleHandler")
+
+	// The directory isn't empty anymore
+	_, err = scaffold.Generate(dir, scaffold.Options{Module: "m", ID: "i", Name: "n", Resources: []string{"stream"}, Types: []string{"movie"}})
+	require.Error(t, err)
+	_, err = scaffold.Generate(t.TempDir(), scaffold.Options{Module: "m", ID: "i", Name: "n", Resources: []string{"addon_catalog"}, Types: []string{"movie"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"go.uber.org/zap"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestStopAndRestart(t *testing.T) {
+	var starts, shutdowns, afterShutdowns atomic.Int32
+	port := freePort(t)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:          zap.NewNop(),
+		Port:            port,
+		ShutdownTimeout: time.Second,
+		OnStart: func(context.Context) error {
+			starts.Add(1)
+			return nil
+		},
+		OnShutdown: func(context.Context) error {
+			shutdowns.Add(1)
+			return nil
+		},
+		AfterShutdown: func(context.Context) error {
+			afterShutdowns.Add(1)
+			return nil
+		},
+	})
+	require.ErrorIs(t, addon.Stop(), stremio.ErrNotRunning)
+
+	stoppingChan := make(chan bool, 1)
+	returned := make(chan struct{})
+	go func() {
+		addon.Run(stoppingChan, nil)
+		close(returned)
+	}()
+	manifestURL := "http://localhost:" + strconv.Itoa(port) + "/manifest.json"
+	require.Eventually(t, func() bool {
+		res, err := http.Get(manifestURL)
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 1, starts.Load())
+
+	require.NoError(t, addon.Restart())
+	require.EqualValues(t, 2, starts.Load())
+	require.EqualValues(t, 1, shutdowns.Load())
+	require.EqualValues(t, 1, afterShutdowns.Load())
+	require.Empty(t, stoppingChan)
+	res, err := http.Get(manifestURL)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.NoError(t, addon.Stop())
+	<-returned
+	require.EqualValues(t, 2, shutdowns.Load())
+	require.EqualValues(t, 2, afterShutdowns.Load())
+	require.Len(t, stoppingChan, 1)
+	require.ErrorIs(t, addon.Stop(), stremio.ErrNotRunning)
+}
+
+func TestReusePort(t *testing.T) {
+	port := freePort(t)
+	var addons []*stremio.Addon
+	for i := 0; i < 2; i++ {
+		started := make(chan struct{})
+		addon := newTestAddonWithOptions(t, stremio.Options{
+			Logger:    zap.NewNop(),
+			Port:      port,
+			ReusePort: true,
+			OnStart: func(context.Context) error {
+				close(started)
+				return nil
+			},
+		})
+		go addon.Run(nil, nil)
+		<-started
+		addons = append(addons, addon)
+	}
+
+	// The old version can be stopped while the new one keeps serving
+	require.NoError(t, addons[0].Stop())
+	res, err := http.Get("http://localhost:" + strconv.Itoa(port) + "/manifest.json")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, addons[1].Stop())
+}
+
+// The app is created anew when restarting, which must not register the metrics a second time.
+func TestRestartWithMetrics(t *testing.T) {
+	port := freePort(t)
+	started := make(chan struct{}, 2)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:  zap.NewNop(),
+		Port:    port,
+		Metrics: true,
+		OnStart: func(context.Context) error {
+			started <- struct{}{}
+			return nil
+		},
+	})
+	returned := make(chan struct{})
+	go func() {
+		addon.Run(nil, nil)
+		close(returned)
+	}()
+	<-started
+
+	require.NoError(t, addon.Restart())
+	res, err := http.Get("http://localhost:" + strconv.Itoa(port) + "/metrics")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.NoError(t, addon.Stop())
+	<-returned
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/serverless"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestLambdaHandler(t *testing.T) {
+	handler := serverless.NewLambdaHandler(newTestAddon(t), nil)
+
+	// Payload format 1.0 of REST APIs
+	res, err := handler(context.Background(), serverless.LambdaEvent{
+		HTTPMethod: http.MethodGet,
+		Path:       "/stream/movie/tt1254207.json",
+		Headers:    map[string]string{"Host": "example.com"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, []string{"application/json"}, res.MultiValueHeaders["Content-Type"])
+	require.False(t, res.IsBase64Encoded)
+	var streams struct {
+		Streams []types.StreamItem `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &streams))
+	require.Equal(t, "https://example.com/any.mp4", streams.Streams[0].URL)
+
+	// Payload format 2.0 of HTTP APIs and function URLs
+	event := serverless.LambdaEvent{Version: "2.0", RawPath: "/manifest.json"}
+	event.RequestContext.HTTP.Method = http.MethodGet
+	res, err = handler(context.Background(), event)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Headers["Content-Type"])
+	var manifest types.Manifest
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &manifest))
+	require.Equal(t, "com.example.test", manifest.ID)
+
+	event.RawPath = "/stream/movie/tt0000000.json"
+	res, err = handler(context.Background(), event)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	srv := httptest.NewServer(serverless.NewHTTPHandler(newTestAddon(t), nil))
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/manifest.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/signedurl"
+)
+
+const upstreamURL = "https://upstream.example/movie.mp4?token=secret"
+
+func newTestSigner(t *testing.T, secret string) *signedurl.Signer {
+	s, err := signedurl.NewSigner([]byte(secret))
+	require.NoError(t, err)
+	return s
+}
+
+func TestSignedURLSignAndVerify(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+
+	token, err := s.Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	// The upstream URL is encrypted
+	require.NotContains(t, token, "upstream")
+	target, err := s.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, upstreamURL, target.URL)
+	require.InDelta(t, time.Now().Add(time.Hour).Unix(), target.Expires, 2)
+
+	// Tokens for the same URL differ because of the random IV
+	other, err := s.Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+
+	headers := map[string]string{"Referer": "https://upstream.example"}
+	token, err = s.SignWithHeaders(upstreamURL, headers, time.Hour)
+	require.NoError(t, err)
+	target, err = s.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, headers, target.Headers)
+
+	token, err = s.SignTarget(signedurl.Target{URL: upstreamURL, User: "user", Expires: 1}, time.Hour)
+	require.NoError(t, err)
+	target, err = s.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, "user", target.User)
+	require.Greater(t, target.Expires, time.Now().Unix())
+
+	// Another instance with the same secret can verify the token
+	_, err = newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength)).Verify(token)
+	require.NoError(t, err)
+}
+
+func TestSignedURLHelpers(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	for _, tc := range []struct {
+		name   string
+		create func() (string, error)
+		prefix string
+		suffix string
+	}{
+		{"resolve", func() (string, error) { return s.URL("https://addon.example/", upstreamURL, time.Hour) }, "https://addon.example/resolve/", ""},
+		{"proxy", func() (string, error) { return s.ProxyURL("https://addon.example", upstreamURL, nil, time.Hour) }, "https://addon.example/proxy/", ""},
+		{"vtt", func() (string, error) { return s.VTTURL("https://addon.example", upstreamURL, time.Hour) }, "https://addon.example/vtt/", ".vtt"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := tc.create()
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(u, tc.prefix), u)
+			require.True(t, strings.HasSuffix(u, tc.suffix), u)
+			target, err := s.Verify(strings.TrimSuffix(strings.TrimPrefix(u, tc.prefix), tc.suffix))
+			require.NoError(t, err)
+			require.Equal(t, upstreamURL, target.URL)
+		})
+	}
+
+	u, err := s.ProxyURLForUser("https://addon.example", upstreamURL, nil, "user", time.Hour)
+	require.NoError(t, err)
+	target, err := s.Verify(strings.TrimPrefix(u, "https://addon.example/proxy/"))
+	require.NoError(t, err)
+	require.Equal(t, "user", target.User)
+}
+
+func TestSignedURLExpired(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	token, err := s.Sign(upstreamURL, -time.Minute)
+	require.NoError(t, err)
+	_, err = s.Verify(token)
+	require.ErrorIs(t, err, signedurl.ErrExpired)
+}
+
+func TestSignedURLTampered(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	token, err := s.Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	require.NoError(t, err)
+
+	// Flipping a bit anywhere, in the IV, the encrypted payload (for example to extend the expiry) or the signature,
+	// invalidates the token. The signature is compared with hmac.Equal, which takes the same time no matter where
+	// the first difference is, so it can't be guessed byte by byte.
+	for i := range raw {
+		tampered := bytes.Clone(raw)
+		tampered[i] ^= 1
+		_, err = s.Verify(base64.RawURLEncoding.EncodeToString(tampered))
+		require.ErrorIs(t, err, signedurl.ErrInvalidToken, "byte %d", i)
+	}
+
+	// Cut off or extended tokens
+	_, err = s.Verify(base64.RawURLEncoding.EncodeToString(raw[:len(raw)-1]))
+	require.ErrorIs(t, err, signedurl.ErrInvalidToken)
+	_, err = s.Verify(base64.RawURLEncoding.EncodeToString(append(bytes.Clone(raw), 0)))
+	require.ErrorIs(t, err, signedurl.ErrInvalidToken)
+}
+
+func TestSignedURLWrongKey(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	token, err := s.Sign(upstreamURL, time.Hour)
+	require.NoError(t, err)
+	_, err = newTestSigner(t, strings.Repeat("b", signedurl.MinSecretLength)).Verify(token)
+	require.ErrorIs(t, err, signedurl.ErrInvalidToken)
+}
+
+func TestSignedURLMalformed(t *testing.T) {
+	s := newTestSigner(t, strings.Repeat("a", signedurl.MinSecretLength))
+	for _, token := range []string{"", "abc", "not base64!", strings.Repeat("A", 64)} {
+		_, err := s.Verify(token)
+		require.ErrorIs(t, err, signedurl.ErrInvalidToken, token)
+	}
+}
+
+func TestSignedURLShortSecret(t *testing.T) {
+	_, err := signedurl.NewSigner([]byte(strings.Repeat("a", signedurl.MinSecretLength-1)))
+	require.Error(t, err)
+	_, err = signedurl.NewSigner(nil)
+	require.Error(t, err)
+}
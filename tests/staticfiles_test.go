@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+)
+
+func TestAddStaticFiles(t *testing.T) {
+	addon := newTestAddon(t)
+	addon.AddStaticFiles("/static", fstest.MapFS{
+		"index.html":     {Data: []byte("<h1>Hi</h1>")},
+		"js/app.3f2a.js": {Data: []byte("console.log(1)")},
+	}, stremio.StaticFilesOptions{MaxAge: 365 * 24 * time.Hour, Immutable: true})
+	srv := stremiotest.NewServer(t, addon)
+
+	get := func(path, ifNoneMatch string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	res, body := get("/static/js/app.3f2a.js", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "console.log(1)", body)
+	require.Contains(t, res.Header.Get("Content-Type"), "javascript")
+	require.Equal(t, "public, max-age=31536000, immutable", res.Header.Get("Cache-Control"))
+	eTag := res.Header.Get("ETag")
+	require.NotEmpty(t, eTag)
+
+	res, body = get("/static/js/app.3f2a.js", eTag)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Empty(t, body)
+
+	res, body = get("/static/", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "<h1>Hi</h1>", body)
+
+	res, _ = get("/static/missing.css", "")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, _ = get("/static/js", "")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
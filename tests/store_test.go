@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/store"
+	"github.com/xybydy/go-stremio/pkg/store/boltstore"
+	"go.etcd.io/bbolt"
+)
+
+func testStore(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	_, found, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, s.Set(ctx, "b", []byte("2"), time.Hour))
+	require.NoError(t, s.Set(ctx, "c", []byte("3"), time.Millisecond))
+	value, found, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []byte("1"), value)
+
+	ttl, found, err := s.TTL(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Zero(t, ttl)
+	ttl, found, err = s.TTL(ctx, "b")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.InDelta(t, time.Hour, ttl, float64(time.Minute))
+
+	time.Sleep(5 * time.Millisecond)
+	_, found, err = s.Get(ctx, "c")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, s.Delete(ctx, "a"))
+	require.NoError(t, s.Delete(ctx, "a"))
+	_, found, err = s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, store.NewMemory())
+}
+
+func TestMemoryStoreWithLimit(t *testing.T) {
+	ctx := context.Background()
+	testStore(t, store.NewMemoryWithLimit(10))
+
+	s := store.NewMemoryWithLimit(2)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, s.Set(ctx, key, []byte(key), 0))
+		require.LessOrEqual(t, s.Len(), 2)
+	}
+	// The newest value is always kept, and overwriting doesn't evict anything
+	value, found, err := s.Get(ctx, "d")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []byte("d"), value)
+	require.NoError(t, s.Set(ctx, "d", []byte("e"), 0))
+	require.Equal(t, 2, s.Len())
+}
+
+func TestBoltStore(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "store.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	s, err := boltstore.New(db, "store")
+	require.NoError(t, err)
+	testStore(t, s)
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestStreamingThreshold(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithCatalog(types.NewCatalog("movie", "top", "Top"), types.NewCatalog("movie", "small", "Small"))
+	catalogHandlers := map[string]stremio.CatalogHandler{
+		"movie": func(_ context.Context, id string, _ url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			count := 1000
+			if id == "small" {
+				count = 10
+			}
+			metas := make([]types.MetaPreviewItem, count)
+			for i := range metas {
+				metas[i] = types.MetaPreviewItem{ID: "tt" + strconv.Itoa(i), Type: "movie", Name: "Movie " + strconv.Itoa(i)}
+			}
+			return metas, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, catalogHandlers, nil, nil, nil, stremio.Options{Logger: zap.NewNop(), StreamingThreshold: 100})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	res := srv.CatalogRequest("movie", "top").Do(t).RequireStatus(t, http.StatusOK)
+	require.Empty(t, res.Header.Get("Content-Length"))
+	metas := res.Metas(t)
+	require.Len(t, metas, 1000)
+	require.Equal(t, "Movie 999", metas[999].Name)
+
+	// Small responses are marshalled as a whole
+	res = srv.CatalogRequest("movie", "small").Do(t).RequireStatus(t, http.StatusOK)
+	require.NotEmpty(t, res.Header.Get("Content-Length"))
+	require.Len(t, res.Metas(t), 10)
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/streamutil"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestDeduplicate(t *testing.T) {
+	streams := []types.StreamItem{
+		{InfoHash: "DD8255ECDC7CA55FB0BBF81323D87062DB1F6D1C", Title: "first"},
+		{URL: "https://example.com/a.mp4"},
+		{InfoHash: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c", Title: "duplicate"},
+		{InfoHash: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c", FileIndex: 1, Title: "other file"},
+		{URL: "https://example.com/a.mp4"},
+	}
+	res := streamutil.Deduplicate(streams)
+	require.Len(t, res, 3)
+	require.Equal(t, "first", res[0].Title)
+	require.Equal(t, "other file", res[2].Title)
+}
+
+func TestSort(t *testing.T) {
+	streams := []types.StreamItem{
+		{Title: "Movie.720p.mkv", BehaviorHints: types.StreamBehaviorHints{VideoSize: 100}},
+		{Title: "Movie.1080p.small.mkv", BehaviorHints: types.StreamBehaviorHints{VideoSize: 100}},
+		{Title: "unknown"},
+		{Name: "Addon\n4K"},
+		{Title: "Movie.1080p.big.mkv", BehaviorHints: types.StreamBehaviorHints{VideoSize: 200}},
+	}
+	streamutil.Sort(streams, streamutil.ByResolution, streamutil.BySize)
+	require.Equal(t, []string{"", "Movie.1080p.big.mkv", "Movie.1080p.small.mkv", "Movie.720p.mkv", "unknown"}, titles(streams))
+
+	// Priority takes precedence when used first.
+	streamutil.Sort(streams, streamutil.ByPriority(func(s types.StreamItem) int {
+		if s.Title == "unknown" {
+			return 1
+		}
+		return 0
+	}))
+	require.Equal(t, "unknown", streams[0].Title)
+	require.Equal(t, 2160, streamutil.Resolution(streams[1]))
+}
+
+func titles(streams []types.StreamItem) []string {
+	res := make([]string, len(streams))
+	for i, s := range streams {
+		res[i] = s.Title
+	}
+	return res
+}
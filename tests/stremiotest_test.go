@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+type testUserData struct {
+	Quality string `json:"quality"`
+}
+
+func newTestAddon(t testing.TB) *stremio.Addon {
+	return newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true})
+}
+
+func newTestAddonWithOptions(t testing.TB, opts stremio.Options) *stremio.Addon {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").
+		WithDescription("Test addon").
+		WithStreamResource("movie").
+		WithCatalog(types.CatalogItem{Type: "movie", ID: "top", Name: "Top"})
+	catalogHandlers := map[string]stremio.CatalogHandler{
+		"movie": func(_ context.Context, id string, extra url.Values, _ any) ([]types.MetaPreviewItem, error) {
+			return []types.MetaPreviewItem{{ID: "tt1254207", Type: "movie", Name: id + " " + extra.Get("search")}}, nil
+		},
+	}
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(_ context.Context, id string, userData any) ([]types.StreamItem, error) {
+			if id != "tt1254207" {
+				return nil, stremio.ErrNotFound
+			}
+			quality := "any"
+			if ud, ok := userData.(*testUserData); ok {
+				quality = ud.Quality
+			}
+			return []types.StreamItem{{URL: "https://example.com/" + quality + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, catalogHandlers, streamHandlers, nil, nil, opts)
+	require.NoError(t, err)
+	stremio.RegisterUserDataType[testUserData](addon)
+	return addon
+}
+
+func TestStremiotest(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+
+	require.Equal(t, "com.example.test", srv.Manifest(t).ID)
+
+	metas := srv.CatalogRequest("movie", "top").WithExtra("search", "big buck").Do(t).Metas(t)
+	require.Equal(t, "top big buck", metas[0].Name)
+
+	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+	require.Equal(t, "https://example.com/1080p.mp4", streams[0].URL)
+
+	srv.StreamRequest("movie", "tt0000000").Do(t).RequireStatus(t, http.StatusNotFound)
+}
+
+func TestStremiotestGolden(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+	srv.CatalogRequest("movie", "top").Do(t).RequireGolden(t, "catalog", "metas.*.poster")
+}
+
+func TestStremiotestMetaFetcher(t *testing.T) {
+	metaFetcher := stremiotest.NewMetaFetcher().WithMeta(types.MetaItem{ID: "tt1254207", Name: "Big Buck Bunny"})
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(ctx context.Context, _ string, _ any) ([]types.StreamItem, error) {
+			meta, err := stremio.GetMetaFromContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return []types.StreamItem{{URL: "https://example.com/stream.mp4", Title: meta.Name}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), PutMetaInContext: true, MetaClient: metaFetcher})
+	require.NoError(t, err)
+	srv := stremiotest.NewServer(t, addon)
+
+	require.Equal(t, "Big Buck Bunny", srv.Streams(t, "movie", "tt1254207")[0].Title)
+	require.Equal(t, []stremiotest.MetaCall{{Method: "GetMovie", ID: "tt1254207"}}, metaFetcher.Calls())
+}
+
+func FuzzStremiotestAddon(f *testing.F) {
+	stremiotest.FuzzUserData(f, newTestAddon(f), "catalog", "movie", "top", "eyJxdWFsaXR5IjoiMTA4MHAifQ")
+}
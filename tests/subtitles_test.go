@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/subtitles"
+)
+
+func TestSRTToVTT(t *testing.T) {
+	srt := "\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:02,500\r\n<font color=\"#ffff00\">Hello</font> <i>world</i>\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n{\\an8}Caf\xe9\r\n"
+	expected := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.500\nHello <i>world</i>\n\n2\n00:00:03.000 --> 00:00:04.000\nCafé\n"
+	require.Equal(t, expected, string(subtitles.SRTToVTT([]byte(srt))))
+
+	// Single digit hours are padded
+	srt = "1\n0:01:02,000 --> 1:00:00,500\nHello\n"
+	expected = "WEBVTT\n\n1\n00:01:02.000 --> 01:00:00.500\nHello\n"
+	require.Equal(t, expected, string(subtitles.SRTToVTT([]byte(srt))))
+}
+
+func TestHash(t *testing.T) {
+	// 128 KiB of 0x01 bytes: each 8 byte word is 0x0101010101010101, 16384 words in total.
+	data := bytes.Repeat([]byte{1}, 128<<10)
+	word := uint64(0x0101010101010101)
+	expected := fmt.Sprintf("%016x", uint64(len(data))+16384*word)
+
+	hash, err := subtitles.Hash(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Equal(t, expected, hash)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mkv", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+	hash, err = subtitles.HashURL(context.Background(), srv.Client(), srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, expected, hash)
+
+	_, err = subtitles.Hash(bytes.NewReader(data[:100]), 100)
+	require.ErrorIs(t, err, subtitles.ErrFileTooSmall)
+}
+
+func TestLanguageFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"Movie.2020.1080p.German.srt":       "ger",
+		"movie_pt-BR.forced.srt":            "pob",
+		"Show.S01E02.en.hi.srt":             "eng",
+		"It.2017.1080p.BluRay.x264-GRP.srt": "",
+		"subs/Movie [fre].srt":              "fre",
+		"Movie.2020.srt":                    "",
+	}
+	for filename, expected := range tests {
+		require.Equal(t, expected, subtitles.LanguageFromFilename(filename), filename)
+	}
+}
+
+func TestLanguageFromContent(t *testing.T) {
+	tests := map[string]string{
+		"1\n00:00:01,000 --> 00:00:02,000\nI don't know what you want.\n\n2\n00:00:03,000 --> 00:00:04,000\nThe thing is in the car and it is not mine.\n":             "eng",
+		"1\n00:00:01,000 --> 00:00:02,000\nIch weiß nicht, was du willst.\n\n2\n00:00:03,000 --> 00:00:04,000\nDas ist nicht mein Auto und es ist auch nicht deins.\n": "ger",
+		"1\n00:00:01,000 --> 00:00:02,000\nЯ не знаю, что ты хочешь.\n\n2\n00:00:03,000 --> 00:00:04,000\nЭто не моя машина, и он как мы.\n":                           "rus",
+		"1\n00:00:01,000 --> 00:00:02,000\n何をしているのですか？\n":                                                                                                              "jpn",
+		"1\n00:00:01,000 --> 00:00:02,000\n你在做什么？\n":                                                                                                                   "chi",
+		"1\n00:00:01,000 --> 00:00:02,000\n123\n": "",
+	}
+	for content, expected := range tests {
+		require.Equal(t, expected, subtitles.LanguageFromContent([]byte(content)), content)
+	}
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/cinemeta"
+	"github.com/xybydy/go-stremio/pkg/tmdb"
+	"go.uber.org/zap"
+)
+
+func TestTMDBClient(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "key", r.URL.Query().Get("api_key"))
+		require.Equal(t, "de-DE", r.URL.Query().Get("language"))
+		switch r.URL.Path {
+		case "/find/tt0944947":
+			_, _ = w.Write([]byte(`{"movie_results":[],"tv_results":[{"id":1399}]}`))
+		case "/tv/1399":
+			require.Equal(t, "credits,videos,season/1", r.URL.Query().Get("append_to_response"))
+			_, _ = w.Write([]byte(`{"name":"Game of Thrones","first_air_date":"2011-04-17","last_air_date":"2019-05-19","in_production":false,
+				"genres":[{"name":"Drama"}],"poster_path":"/p.jpg","credits":{"cast":[{"name":"Emilia Clarke"}]},
+				"season/1":{"episodes":[{"name":"Der Winter naht","air_date":"2011-04-17","season_number":1,"episode_number":1}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := tmdb.NewClient(tmdb.ClientOptions{APIKey: "key", Language: "de-DE", BaseURL: srv.URL}, cinemeta.NewInMemoryCache(), zap.NewNop())
+	require.NoError(t, err)
+
+	meta, err := client.GetSeries(context.Background(), "tt0944947", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, "tt0944947", meta.ID)
+	require.Equal(t, "series", meta.Type)
+	require.Equal(t, "Game of Thrones", meta.Name)
+	require.Equal(t, "2011-2019", meta.ReleaseInfo)
+	require.Equal(t, []string{"Drama"}, meta.Genres)
+	require.Equal(t, "https://image.tmdb.org/t/p/w500/p.jpg", meta.Poster)
+	require.Equal(t, []string{"Emilia Clarke"}, meta.Cast)
+	require.Len(t, meta.Videos, 1)
+	require.Equal(t, "tt0944947:1:1", meta.Videos[0].ID)
+	require.Equal(t, "Der Winter naht", meta.Videos[0].Title)
+
+	// Second request is served from the cache.
+	_, err = client.GetSeries(context.Background(), "tt0944947", 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	_, err = client.GetMovie(context.Background(), "tt0944947")
+	require.ErrorIs(t, err, tmdb.ErrNotFound)
+}
+
+func TestTMDBTranslateID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/find/tt0944947":
+			_, _ = w.Write([]byte(`{"movie_results":[],"tv_results":[{"id":1399}]}`))
+		case "/tv/1399/external_ids":
+			_, _ = w.Write([]byte(`{"imdb_id":"tt0944947"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := tmdb.NewClient(tmdb.ClientOptions{APIKey: "key", BaseURL: srv.URL}, cinemeta.NewInMemoryCache(), zap.NewNop())
+	require.NoError(t, err)
+
+	id, err := client.TranslateID(context.Background(), "series", "tt0944947:1:2")
+	require.NoError(t, err)
+	require.Equal(t, "tmdb:1399:1:2", id)
+	id, err = client.TranslateID(context.Background(), "series", "tmdb:1399")
+	require.NoError(t, err)
+	require.Equal(t, "tt0944947", id)
+	_, err = client.TranslateID(context.Background(), "movie", "tmdb:1399")
+	require.ErrorIs(t, err, tmdb.ErrNotFound)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio/pkg/torrent"
+)
+
+// bstr bencodes a string.
+func bstr(s string) string {
+	return strconv.Itoa(len(s)) + ":" + s
+}
+
+// bfile bencodes a file entry of a multi file torrent.
+func bfile(length int, path ...string) string {
+	elems := ""
+	for _, elem := range path {
+		elems += bstr(elem)
+	}
+	return "d" + bstr("length") + "i" + strconv.Itoa(length) + "e" + bstr("path") + "l" + elems + "ee"
+}
+
+var (
+	testTorrentInfo = "d" + bstr("files") + "l" +
+		bfile(1000, "Sample", "sample.mkv") +
+		bfile(700000000, "Show.S01E02.1080p.mkv") +
+		bfile(900000000, "Show.S01E03.1080p.mkv") +
+		bfile(50, "readme.txt") +
+		"e" + bstr("name") + bstr("Show Season 1") + "e"
+	testTorrent = "d" + bstr("announce") + bstr("udp://tracker.example:1337") +
+		bstr("announce-list") + "ll" + bstr("udp://tracker.example:1337") + bstr("wss://tracker.example") + "ee" +
+		bstr("info") + testTorrentInfo + "e"
+)
+
+func TestTorrentParse(t *testing.T) {
+	m, err := torrent.Parse([]byte(testTorrent))
+	require.NoError(t, err)
+	hash := sha1.Sum([]byte(testTorrentInfo))
+	require.Equal(t, hex.EncodeToString(hash[:]), m.InfoHash)
+	require.Equal(t, "Show Season 1", m.Name)
+	require.Equal(t, []torrent.File{
+		{Path: "Sample/sample.mkv", Length: 1000},
+		{Path: "Show.S01E02.1080p.mkv", Length: 700000000},
+		{Path: "Show.S01E03.1080p.mkv", Length: 900000000},
+		{Path: "readme.txt", Length: 50},
+	}, m.Files)
+	require.Equal(t, []string{"udp://tracker.example:1337", "wss://tracker.example"}, m.Trackers)
+
+	// Single file torrents
+	m, err = torrent.Parse([]byte("d4:infod6:lengthi42e4:name9:movie.mp4ee"))
+	require.NoError(t, err)
+	require.Equal(t, []torrent.File{{Path: "movie.mp4", Length: 42}}, m.Files)
+	require.Empty(t, m.Trackers)
+}
+
+func TestTorrentParseMalformed(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"d",
+		"d4:info",
+		"d4:infod",
+		"d4:infod4:name",
+		"d4:infod4:name5:abce",
+		"d4:infod6:lengthi42",
+		"d4:infod6:lengthi4x2ee",
+		"d4:infod6:lengthiee",
+		"d4:infod4:name-1:ee",
+		"d4:infod4:name99999999999999999999999:aee",
+		"d4:infoi1ee",
+		"d5:filesle",
+		"di1e4:infoe",
+		"l4:infoe",
+		"i42e",
+		"x",
+		"d4:infod5:filesl3:fooeee",
+		strings.Repeat("l", 100) + strings.Repeat("e", 100),
+		// The valid torrent cut off at every position
+		testTorrent[:len(testTorrent)/2],
+		testTorrent[:len(testTorrent)-1],
+	} {
+		require.NotPanics(t, func() {
+			_, err := torrent.Parse([]byte(data))
+			require.Error(t, err, data)
+		}, data)
+	}
+	for i := range len(testTorrent) {
+		require.NotPanics(t, func() {
+			_, err := torrent.Parse([]byte(testTorrent[:i]))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTorrentSelectFile(t *testing.T) {
+	m, err := torrent.Parse([]byte(testTorrent))
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		name     string
+		hint     torrent.FileHint
+		expected int
+	}{
+		{"biggest video", torrent.FileHint{}, 2},
+		{"filename", torrent.FileHint{Filename: "sample.mkv"}, 0},
+		{"path", torrent.FileHint{Filename: "Sample/sample.mkv"}, 0},
+		{"size", torrent.FileHint{Size: 50}, 3},
+		{"episode", torrent.FileHint{Season: 1, Episode: 2}, 1},
+		{"other season", torrent.FileHint{Season: 2, Episode: 2}, -1},
+		{"missing episode", torrent.FileHint{Season: 1, Episode: 5}, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, m.SelectFile(tc.hint))
+		})
+	}
+
+	s, err := m.Stream(torrent.FileHint{Season: 1, Episode: 2})
+	require.NoError(t, err)
+	require.Equal(t, m.InfoHash, s.InfoHash)
+	require.EqualValues(t, 1, s.FileIndex)
+	require.Equal(t, "Show.S01E02.1080p.mkv", s.BehaviorHints.Filename)
+
+	_, err = torrent.Metadata{Files: []torrent.File{{Path: "readme.txt"}}}.Stream(torrent.FileHint{})
+	require.Error(t, err)
+}
+
+func FuzzTorrentParse(f *testing.F) {
+	f.Add([]byte(testTorrent))
+	f.Add([]byte("d4:infod6:lengthi42e4:name9:movie.mp4ee"))
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		// Must not panic
+		_, _ = torrent.Parse(data)
+	})
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestUserDataCache(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	var received []*testUserData
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(_ context.Context, _ string, userData any) ([]types.StreamItem, error) {
+			ud := userData.(*testUserData)
+			received = append(received, ud)
+			return []types.StreamItem{{URL: "https://example.com/" + ud.Quality + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), UserDataCacheSize: 1})
+	require.NoError(t, err)
+	addon.RegisterUserData(testUserData{})
+	srv := stremiotest.NewServer(t, addon)
+
+	for _, quality := range []string{"720p", "720p", "1080p", "720p"} {
+		streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: quality}).Do(t).Streams(t)
+		require.Equal(t, "https://example.com/"+quality+".mp4", streams[0].URL)
+	}
+	// The second request got the cached user data, but the last one was evicted by the third one.
+	require.Same(t, received[0], received[1])
+	require.NotSame(t, received[0], received[3])
+}
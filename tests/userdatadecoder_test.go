@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestRegisterUserDataDecoder(t *testing.T) {
+	manifest := types.NewManifest("com.example.test", "Test", "0.1.0").WithDescription("Test addon").WithStreamResource("movie")
+	streamHandlers := map[string]stremio.StreamHandler{
+		"movie": func(_ context.Context, _ string, userData any) ([]types.StreamItem, error) {
+			return []types.StreamItem{{URL: "https://example.com/" + userData.(string) + ".mp4"}}, nil
+		},
+	}
+	addon, err := stremio.NewAddon(manifest, nil, streamHandlers, nil, nil, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true})
+	require.NoError(t, err)
+	// A decoder that only reads the quality, like generated unmarshalling code would
+	addon.RegisterUserDataDecoder(func(userDataJSON []byte) (any, error) {
+		quality, ok := bytes.CutPrefix(userDataJSON, []byte(`{"quality":"`))
+		if !ok {
+			return nil, errors.New("unexpected user data")
+		}
+		quality, ok = bytes.CutSuffix(quality, []byte(`"}`))
+		if !ok {
+			return nil, errors.New("unexpected user data")
+		}
+		return string(quality), nil
+	})
+	srv := stremiotest.NewServer(t, addon)
+
+	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+	require.Equal(t, "https://example.com/1080p.mp4", streams[0].URL)
+
+	srv.StreamRequest("movie", "tt1254207").WithUserData(map[string]int{"limit": 1}).Do(t).RequireStatus(t, http.StatusBadRequest)
+}
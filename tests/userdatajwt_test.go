@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestUserDataJWT(t *testing.T) {
+	oldKey := bytes.Repeat([]byte("o"), stremio.MinUserDataSigningKeyLength)
+	newKey := bytes.Repeat([]byte("n"), stremio.MinUserDataSigningKeyLength)
+	oldAddon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataJWT: &stremio.JWTOptions{Keys: [][]byte{oldKey}, Issuer: "test"}})
+	oldToken, err := oldAddon.EncodeUserData(testUserData{Quality: "720p"})
+	require.NoError(t, err)
+	require.Len(t, strings.Split(oldToken, "."), 3)
+
+	// Rotated key, old tokens are still accepted
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataJWT: &stremio.JWTOptions{Keys: [][]byte{newKey, oldKey}, Issuer: "test", TTL: time.Hour}})
+	srv := stremiotest.NewServer(t, addon)
+	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+	require.Equal(t, "https://example.com/1080p.mp4", streams[0].URL)
+	res, err := http.Get(srv.URL + "/" + oldToken + "/stream/movie/tt1254207.json")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Other issuer
+	otherAddon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataJWT: &stremio.JWTOptions{Keys: [][]byte{newKey}, Issuer: "other"}})
+	otherToken, err := otherAddon.EncodeUserData(testUserData{Quality: "720p"})
+	require.NoError(t, err)
+	// Invalidated by IssuedAfter
+	invalidatingAddon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataJWT: &stremio.JWTOptions{Keys: [][]byte{newKey, oldKey}, Issuer: "test", IssuedAfter: time.Now().Add(time.Minute)}})
+	invalidatingSrv := stremiotest.NewServer(t, invalidatingAddon)
+	for _, url := range []string{
+		srv.URL + "/" + otherToken + "/stream/movie/tt1254207.json",
+		srv.URL + "/" + oldToken[:len(oldToken)-2] + "/stream/movie/tt1254207.json",
+		invalidatingSrv.URL + "/" + oldToken + "/stream/movie/tt1254207.json",
+	} {
+		res, err = http.Get(url)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, url)
+	}
+}
@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestMaxUserDataLength(t *testing.T) {
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true, MaxUserDataLength: 64})
+	srv := stremiotest.NewServer(t, addon)
+
+	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+	require.Equal(t, "https://example.com/1080p.mp4", streams[0].URL)
+
+	srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: strings.Repeat("a", 100)}).Do(t).RequireStatus(t, http.StatusRequestURITooLong)
+	res, err := http.Get(srv.URL + "/" + strings.Repeat("a", 65) + "/manifest.json")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusRequestURITooLong, res.StatusCode)
+}
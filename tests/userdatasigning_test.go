@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+)
+
+func TestUserDataSigning(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), stremio.MinUserDataSigningKeyLength)
+	addon := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), UserDataIsBase64: true, UserDataSigningKey: key})
+	srv := stremiotest.NewServer(t, addon)
+
+	// Signed by EncodeUserData
+	streams := srv.StreamRequest("movie", "tt1254207").WithUserData(testUserData{Quality: "1080p"}).Do(t).Streams(t)
+	require.Equal(t, "https://example.com/1080p.mp4", streams[0].URL)
+
+	signed, err := addon.EncodeUserData(testUserData{Quality: "1080p"})
+	require.NoError(t, err)
+	forged, err := addon.EncodeUserData(testUserData{Quality: "4K"})
+	require.NoError(t, err)
+	unsigned := forged[:strings.LastIndex(forged, ".")]
+	signature := signed[strings.LastIndex(signed, "."):]
+	for _, userData := range []string{unsigned, unsigned + signature} {
+		res, err := http.Get(srv.URL + "/" + userData + "/stream/movie/tt1254207.json")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res, err = http.Get(srv.URL + "/" + userData + "/manifest.json")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	}
+
+	_, err = stremio.NewAddon(types.NewManifest("com.example.test", "Test", "0.1.0").WithStreamResource("movie"), nil, map[string]stremio.StreamHandler{"movie": nil}, nil, nil, stremio.Options{UserDataSigningKey: []byte("short")})
+	require.Error(t, err)
+}
@@ -0,0 +1,23 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/types"
+)
+
+func TestNewAddonReportsAllViolations(t *testing.T) {
+	_, err := stremio.NewAddon(types.Manifest{}, nil, nil, nil, nil, stremio.Options{
+		ShutdownTimeout: -1,
+		AdminAPI:        true,
+		AllowedIPs:      []string{"not an IP"},
+	})
+	require.Error(t, err)
+	require.ErrorContains(t, err, "an empty manifest was passed")
+	require.ErrorContains(t, err, "no handler was passed")
+	require.ErrorContains(t, err, "the ShutdownTimeout can't be negative")
+	require.ErrorContains(t, err, "the AdminAPI requires an APIKey")
+	require.ErrorContains(t, err, "not an IP")
+}
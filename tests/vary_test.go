@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestVaryHeaders(t *testing.T) {
+	srv := stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{
+		Logger:            zap.NewNop(),
+		CacheAgeStreams:   time.Hour,
+		HandleEtagStreams: true,
+		VaryHeaders:       []string{"Accept-Language", "X-Region"},
+	}))
+
+	res := srv.ManifestRequest().Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, "Accept-Language, X-Region", res.Header.Get("Vary"))
+
+	res = srv.StreamRequest("movie", "tt1254207").Do(t).RequireStatus(t, http.StatusOK)
+	require.Equal(t, "Accept-Language, X-Region", res.Header.Get("Vary"))
+
+	// Caches need it for revalidated responses as well
+	res = srv.StreamRequest("movie", "tt1254207").WithHeader("If-None-Match", res.Header.Get("ETag")).Do(t).RequireStatus(t, http.StatusNotModified)
+	require.Equal(t, "Accept-Language, X-Region", res.Header.Get("Vary"))
+}
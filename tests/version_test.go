@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"go.uber.org/zap"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	latest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name": "v0.2.0"}`))
+	}))
+	t.Cleanup(latest.Close)
+
+	port := freePort(t)
+	addon := newTestAddonWithOptions(t, stremio.Options{
+		Logger:           zap.NewNop(),
+		Port:             port,
+		VersionEndpoint:  true,
+		LatestVersionURL: latest.URL,
+	})
+	returned := make(chan struct{})
+	go func() {
+		addon.Run(nil, nil)
+		close(returned)
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, addon.Stop())
+		<-returned
+	})
+
+	// The latest version is checked in the background after starting
+	var info stremio.VersionInfo
+	require.Eventually(t, func() bool {
+		res, err := http.Get("http://localhost:" + strconv.Itoa(port) + "/version")
+		if err != nil {
+			return false
+		}
+		defer res.Body.Close()
+		info = stremio.VersionInfo{}
+		return res.StatusCode == http.StatusOK && json.NewDecoder(res.Body).Decode(&info) == nil && info.LatestVersion != ""
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "0.1.0", info.Version)
+	require.Equal(t, runtime.Version(), info.GoVersion)
+	require.Equal(t, "v0.2.0", info.LatestVersion)
+	require.True(t, info.Outdated)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestVirtualHosts(t *testing.T) {
+	movies := newTestAddon(t)
+	series, err := stremio.NewAddon(types.NewManifest("com.example.series", "Series", "0.1.0").WithDescription("Series addon").WithStreamResource("series"),
+		nil, map[string]stremio.StreamHandler{"series": nil}, nil, nil, stremio.Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	vhosts, err := stremio.NewVirtualHosts(map[string]*stremio.Addon{"Movies.example.com": movies}, series, zap.NewNop())
+	require.NoError(t, err)
+	srv := httptest.NewServer(adaptor.FiberApp(vhosts.App(nil)))
+	t.Cleanup(srv.Close)
+
+	manifestName := func(host string) string {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/manifest.json", nil)
+		require.NoError(t, err)
+		req.Host = host
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var manifest types.Manifest
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&manifest))
+		return manifest.Name
+	}
+	require.Equal(t, "Test", manifestName("movies.example.com:8080"))
+	require.Equal(t, "Series", manifestName("series.example.com"))
+
+	_, err = stremio.NewVirtualHosts(nil, series, zap.NewNop())
+	require.Error(t, err)
+}
+
+// Every hosted addon creates its own app, which must not register the same metrics twice.
+func TestVirtualHostsWithMetrics(t *testing.T) {
+	movies := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), Metrics: true})
+	series := newTestAddonWithOptions(t, stremio.Options{Logger: zap.NewNop(), Metrics: true})
+	vhosts, err := stremio.NewVirtualHosts(map[string]*stremio.Addon{"movies.example.com": movies}, series, zap.NewNop())
+	require.NoError(t, err)
+	srv := httptest.NewServer(adaptor.FiberApp(vhosts.App(nil)))
+	t.Cleanup(srv.Close)
+
+	for _, host := range []string{"movies.example.com", "series.example.com"} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/metrics", nil)
+		require.NoError(t, err)
+		req.Host = host
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode, host)
+	}
+}
+
+// When an addon fails to start, the addons that already started are shut down again.
+func TestVirtualHostsStartFailure(t *testing.T) {
+	var starts, afterShutdowns atomic.Int32
+	movies := newTestAddonWithOptions(t, stremio.Options{
+		Logger: zap.NewNop(),
+		OnStart: func(context.Context) error {
+			starts.Add(1)
+			return nil
+		},
+		AfterShutdown: func(context.Context) error {
+			afterShutdowns.Add(1)
+			return nil
+		},
+	})
+	// The fallback is started last
+	series := newTestAddonWithOptions(t, stremio.Options{
+		Logger: zap.NewNop(),
+		OnStart: func(context.Context) error {
+			return errors.New("database unavailable")
+		},
+	})
+	// Panic instead of exiting on the fatal log, so the test can continue
+	logger := zap.NewNop().WithOptions(zap.WithFatalHook(zapcore.WriteThenPanic))
+	vhosts, err := stremio.NewVirtualHosts(map[string]*stremio.Addon{"movies.example.com": movies}, series, logger)
+	require.NoError(t, err)
+
+	require.Panics(t, func() {
+		vhosts.Run("localhost:"+strconv.Itoa(freePort(t)), nil, nil)
+	})
+	require.EqualValues(t, 1, starts.Load())
+	require.EqualValues(t, 1, afterShutdowns.Load())
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xybydy/go-stremio"
+	"github.com/xybydy/go-stremio/pkg/stremiotest"
+	"go.uber.org/zap"
+)
+
+func TestRobotsAndSecurityTxt(t *testing.T) {
+	get := func(srv *stremiotest.Server, path string) (int, string) {
+		res, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	srv := stremiotest.NewServer(t, newTestAddon(t))
+	status, _ := get(srv, "/robots.txt")
+	require.Equal(t, http.StatusNotFound, status)
+
+	srv = stremiotest.NewServer(t, newTestAddonWithOptions(t, stremio.Options{
+		Logger:    zap.NewNop(),
+		RobotsTxt: stremio.DefaultRobotsTxt,
+		SecurityTxt: &stremio.SecurityTxt{
+			Contact:            []string{"mailto:security@example.com"},
+			Expires:            time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			PreferredLanguages: []string{"en", "de"},
+		},
+	}))
+	status, body := get(srv, "/robots.txt")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "User-agent: *\nDisallow: /\n", body)
+	status, body = get(srv, "/.well-known/security.txt")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "Contact: mailto:security@example.com\nExpires: 2030-01-01T00:00:00Z\nPreferred-Languages: en, de\n", body)
+}
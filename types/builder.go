@@ -0,0 +1,279 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NewManifest creates a new Manifest with the required fields set and all slices that Stremio expects initialized.
+// Use the With* methods to add resources, catalogs and optional fields, for example:
+//
+//	manifest := types.NewManifest("com.example.addon", "Example", "0.1.0").
+//		WithDescription("Example addon").
+//		WithStreamResource("movie", "series").
+//		WithCatalog(types.NewCatalog("movie", "top", "Top").WithSkip())
+//
+// Each method returns a modified copy, so a partially built manifest can be reused as base for others.
+func NewManifest(id, name, version string) Manifest {
+	return Manifest{
+		ID:      id,
+		Name:    name,
+		Version: version,
+
+		ResourceItems: []ResourceItem{},
+
+		// An empty slice is required for serializing to a JSON that Stremio expects
+		Types:    []string{},
+		Catalogs: []CatalogItem{},
+	}
+}
+
+// WithDescription returns a copy of m with the description set.
+func (m Manifest) WithDescription(description string) Manifest {
+	m.Description = description
+	return m
+}
+
+// WithLogo returns a copy of m with the logo URL set.
+func (m Manifest) WithLogo(logoURL string) Manifest {
+	m.Logo = logoURL
+	return m
+}
+
+// WithBackground returns a copy of m with the background URL set.
+func (m Manifest) WithBackground(backgroundURL string) Manifest {
+	m.Background = backgroundURL
+	return m
+}
+
+// WithContactEmail returns a copy of m with the contact email set.
+func (m Manifest) WithContactEmail(email string) Manifest {
+	m.ContactEmail = email
+	return m
+}
+
+// WithIDPrefixes returns a copy of m with the given ID prefixes (like "tt") added.
+func (m Manifest) WithIDPrefixes(prefixes ...string) Manifest {
+	m.IDprefixes = appendUnique(m.IDprefixes, prefixes...)
+	return m
+}
+
+// WithBehaviorHints returns a copy of m with the behavior hints set.
+func (m Manifest) WithBehaviorHints(hints ManifestBehaviorHints) Manifest {
+	m.BehaviorHints = hints
+	return m
+}
+
+// WithConfig returns a copy of m with the given config items added.
+func (m Manifest) WithConfig(items ...ConfigItem) Manifest {
+	m.Config = append(slices.Clip(m.Config), items...)
+	return m
+}
+
+// WithResource returns a copy of m with the named resource (like "stream") added for the given types.
+// If the resource already exists, the types are merged into it.
+// The types are also added to the manifest's top level types.
+func (m Manifest) WithResource(name string, types ...string) Manifest {
+	resourceItems := slices.Clone(m.ResourceItems)
+	i := slices.IndexFunc(resourceItems, func(ri ResourceItem) bool { return ri.Name == name })
+	if i == -1 {
+		resourceItems = append(resourceItems, ResourceItem{Name: name, Types: appendUnique(nil, types...)})
+	} else {
+		resourceItems[i].Types = appendUnique(resourceItems[i].Types, types...)
+	}
+	m.ResourceItems = resourceItems
+	m.Types = appendUnique(m.Types, types...)
+	return m
+}
+
+// WithStreamResource returns a copy of m with the "stream" resource added for the given types.
+func (m Manifest) WithStreamResource(types ...string) Manifest {
+	return m.WithResource("stream", types...)
+}
+
+// WithMetaResource returns a copy of m with the "meta" resource added for the given types.
+func (m Manifest) WithMetaResource(types ...string) Manifest {
+	return m.WithResource("meta", types...)
+}
+
+// WithSubtitlesResource returns a copy of m with the "subtitles" resource added for the given types.
+func (m Manifest) WithSubtitlesResource(types ...string) Manifest {
+	return m.WithResource("subtitles", types...)
+}
+
+// WithCatalog returns a copy of m with the given catalogs added.
+// The "catalog" resource and the catalogs' types are added automatically.
+func (m Manifest) WithCatalog(catalogs ...CatalogItem) Manifest {
+	m.Catalogs = append(slices.Clip(m.Catalogs), catalogs...)
+	for _, catalog := range catalogs {
+		m = m.WithResource("catalog", catalog.Type)
+	}
+	return m
+}
+
+// Validate checks that m contains everything Stremio and NewAddon require.
+// All violations are returned together.
+func (m Manifest) Validate() error {
+	var errs []error
+	if m.ID == "" {
+		errs = append(errs, errors.New("manifest ID is empty"))
+	}
+	if m.Name == "" {
+		errs = append(errs, errors.New("manifest name is empty"))
+	}
+	if m.Description == "" {
+		errs = append(errs, errors.New("manifest description is empty"))
+	}
+	if m.Version == "" {
+		errs = append(errs, errors.New("manifest version is empty"))
+	}
+	if len(m.ResourceItems) == 0 {
+		errs = append(errs, errors.New("manifest has no resources"))
+	}
+	if m.Types == nil {
+		errs = append(errs, errors.New("manifest types must not be nil"))
+	}
+	if m.Catalogs == nil {
+		errs = append(errs, errors.New("manifest catalogs must not be nil"))
+	}
+	for _, catalog := range m.Catalogs {
+		if catalog.Type == "" || catalog.ID == "" {
+			errs = append(errs, fmt.Errorf("catalog %q requires both a type and an ID", catalog.Name))
+		}
+	}
+	if m.BehaviorHints.ConfigurationRequired && !m.BehaviorHints.Configurable {
+		errs = append(errs, errors.New("requiring a configuration only makes sense when also making the addon configurable"))
+	}
+	return errors.Join(errs...)
+}
+
+// appendUnique appends the values that aren't in s yet, without modifying the backing array of s.
+func appendUnique(s []string, values ...string) []string {
+	s = slices.Clip(s)
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
+
+// Names of the catalog extra properties that Stremio knows.
+// See https://github.com/Stremio/stremio-addon-sdk/blob/master/docs/api/responses/manifest.md#extra-properties
+const (
+	// ExtraSearch is the name of the extra for searching the catalog.
+	ExtraSearch = "search"
+	// ExtraGenre is the name of the extra for filtering the catalog by genre.
+	ExtraGenre = "genre"
+	// ExtraSkip is the name of the extra for catalog pagination.
+	ExtraSkip = "skip"
+)
+
+// NewCatalog creates a new CatalogItem without any extras.
+// Use the With* methods to add extras, for example:
+//
+//	catalog := types.NewCatalog("movie", "top", "Top").WithSearch().WithGenres("Action", "Drama").WithSkip()
+//
+// Each method returns a modified copy, just like the Manifest builder methods.
+func NewCatalog(catalogType, id, name string) CatalogItem {
+	return CatalogItem{
+		Type: catalogType,
+		ID:   id,
+		Name: name,
+	}
+}
+
+// WithExtra returns a copy of ci with the given extra added.
+// An existing extra with the same name is replaced.
+func (ci CatalogItem) WithExtra(extra ExtraItem) CatalogItem {
+	extras := slices.Clone(ci.Extra)
+	if i := slices.IndexFunc(extras, func(ei ExtraItem) bool { return ei.Name == extra.Name }); i != -1 {
+		extras[i] = extra
+	} else {
+		extras = append(extras, extra)
+	}
+	ci.Extra = extras
+	return ci
+}
+
+// WithSearch returns a copy of ci that supports searching.
+// Stremio then also queries the catalog when the user searches for something.
+func (ci CatalogItem) WithSearch() CatalogItem {
+	return ci.WithExtra(ExtraItem{Name: ExtraSearch})
+}
+
+// WithRequiredSearch returns a copy of ci that can *only* be searched.
+// Stremio then doesn't show the catalog on the board or in Discover, but only in search results.
+func (ci CatalogItem) WithRequiredSearch() CatalogItem {
+	return ci.WithExtra(ExtraItem{Name: ExtraSearch, IsRequired: true})
+}
+
+// WithGenres returns a copy of ci that can be filtered by the given genres.
+// They're shown in the Discover sidebar and one at a time is passed as "genre" extra to the CatalogHandler.
+func (ci CatalogItem) WithGenres(genres ...string) CatalogItem {
+	return ci.WithExtra(ExtraItem{Name: ExtraGenre, Options: slices.Clone(genres), OptionsLimit: 1})
+}
+
+// Genres returns the distinct genres of the metas, sorted alphabetically, for the options of WithGenres.
+// They're taken from both the Genres field and the links with the category "Genres".
+// Deriving them from a catalog's items keeps the Discover sidebar in line with the data, see also Addon.UpdateCatalogGenres.
+func Genres(metas []MetaPreviewItem) []string {
+	var genres []string
+	add := func(genre string) {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	for _, meta := range metas {
+		for _, genre := range meta.Genres {
+			add(genre)
+		}
+		for _, link := range meta.Links {
+			if link.Category == "Genres" {
+				add(link.Name)
+			}
+		}
+	}
+	slices.Sort(genres)
+	return slices.Compact(genres)
+}
+
+// WithRequiredGenre returns a copy of ci that requires one of the given genres to be selected.
+// Stremio then only shows the catalog in Discover, where the first genre is selected by default.
+func (ci CatalogItem) WithRequiredGenre(genres ...string) CatalogItem {
+	return ci.WithExtra(ExtraItem{Name: ExtraGenre, IsRequired: true, Options: slices.Clone(genres), OptionsLimit: 1})
+}
+
+// WithSkip returns a copy of ci that supports pagination.
+// Stremio then passes the number of already loaded items as "skip" extra to the CatalogHandler.
+func (ci CatalogItem) WithSkip() CatalogItem {
+	return ci.WithExtra(ExtraItem{Name: ExtraSkip})
+}
+
+// CatalogLanguageSeparator separates the ID of a catalog from its language in the IDs of per-language catalogs, see CatalogItem.ForLanguages.
+const CatalogLanguageSeparator = "."
+
+// ForLanguages returns a copy of ci per language, like "de" or "pt-BR", with the language appended to the ID, like "top.de".
+// Stremio caches catalogs by URL, so per-language catalogs are more reliable than localizing one catalog by request headers.
+// With the Languages option the language from the catalog ID takes precedence, so handlers get it with stremio.LanguageFromContext,
+// and the ID without the language with SplitCatalogLanguage. Change the names of the copies for localized catalog rows.
+func (ci CatalogItem) ForLanguages(languages ...string) []CatalogItem {
+	catalogs := make([]CatalogItem, len(languages))
+	for i, lang := range languages {
+		catalogs[i] = ci.Clone()
+		catalogs[i].ID = ci.ID + CatalogLanguageSeparator + lang
+	}
+	return catalogs
+}
+
+// SplitCatalogLanguage splits the ID of a per-language catalog (see CatalogItem.ForLanguages) into the catalog ID and the language.
+// The language is empty for IDs without a separator. It's not checked whether it's an actual language.
+func SplitCatalogLanguage(id string) (catalogID, lang string) {
+	i := strings.LastIndex(id, CatalogLanguageSeparator)
+	if i == -1 {
+		return id, ""
+	}
+	return id[:i], id[i+len(CatalogLanguageSeparator):]
+}
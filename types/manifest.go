@@ -90,8 +90,8 @@ func (m Manifest) Clone() Manifest {
 		Logo:          m.Logo,
 		ContactEmail:  m.ContactEmail,
 		BehaviorHints: m.BehaviorHints,
-		AddonCatalogs: m.AddonCatalogs,
-		Config:        m.Config,
+		AddonCatalogs: addonCatalogs,
+		Config:        configs,
 	}
 }
 
@@ -193,11 +193,13 @@ type ConfigItem struct {
 	ConfTitle    string   `json:"title,omitempty"`    // the title of the setting
 	ConfOptions  []string `json:"options,omitempty"`  // the list of (string) choices for type: "select"
 	ConfRequired bool     `json:"required,omitempty"` // if the value is required or not, only applies to the following types: "string", "number" (default is false)
+	ConfMin      *float64 `json:"min,omitempty"`      // the minimum value for type: "number", not part of Stremio's spec but used by go-stremio's configure page
+	ConfMax      *float64 `json:"max,omitempty"`      // the maximum value for type: "number", not part of Stremio's spec but used by go-stremio's configure page
 }
 
 func (ci ConfigItem) Clone() ConfigItem {
 	var options []string
-	for ci.ConfOptions != nil {
+	if ci.ConfOptions != nil {
 		options = make([]string, len(ci.ConfOptions))
 		copy(options, ci.ConfOptions)
 	}
@@ -209,5 +211,15 @@ func (ci ConfigItem) Clone() ConfigItem {
 		ConfTitle:    ci.ConfTitle,
 		ConfOptions:  options,
 		ConfRequired: ci.ConfRequired,
+		ConfMin:      cloneFloat(ci.ConfMin),
+		ConfMax:      cloneFloat(ci.ConfMax),
 	}
 }
+
+func cloneFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
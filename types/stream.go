@@ -17,6 +17,9 @@ type StreamItem struct {
 	Subtitles     []SubtitleItem      `json:"subtitles,omitempty"`
 	Sources       []string            `json:"sources,omitempty"`
 	BehaviorHints StreamBehaviorHints `json:"behaviorHints,omitempty"`
+
+	// Not sent to Stremio, but formatted into Name, Title and Description by helpers like WithQuality.
+	Details StreamDetails `json:"-"`
 }
 
 type StreamBehaviorHints struct {
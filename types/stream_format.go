@@ -0,0 +1,192 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// StreamDetails contains the properties of a stream that the StreamItem helpers
+// (WithQuality, WithSize, WithSeeders etc.) format into its Name, Title and Description.
+type StreamDetails struct {
+	Addon    string // Name of the addon or provider, usually shown above the quality
+	Quality  string // E.g. "1080p" or "4K HDR"
+	Filename string
+	Size     int64 // In bytes
+	Seeders  int
+	Source   string // E.g. the torrent site or hoster
+}
+
+// StreamFormat contains the templates for rendering StreamDetails into a StreamItem.
+// The templates are executed with a StreamDetails value and can use the "size" function to format a number of bytes.
+// A nil template leaves the corresponding field untouched.
+type StreamFormat struct {
+	Name        *template.Template
+	Title       *template.Template
+	Description *template.Template
+}
+
+// DefaultStreamFormat is the format the StreamItem helpers use.
+// It shows the addon and quality as name (in two lines) and the filename, seeders, size and source as title, like many popular addons do.
+// You can overwrite it at startup, before any handler is called, to change the format for all helpers.
+var DefaultStreamFormat = MustStreamFormat(
+	"{{.Addon}}\n{{.Quality}}",
+	"{{.Filename}}\n{{if .Seeders}}👤 {{.Seeders}} {{end}}{{if .Size}}💾 {{size .Size}} {{end}}{{with .Source}}⚙️ {{.}}{{end}}",
+	"",
+)
+
+var streamFormatFuncs = template.FuncMap{
+	"size": FormatSize,
+}
+
+// NewStreamFormat parses the given templates into a StreamFormat.
+// An empty string leads to a nil template, so the corresponding field isn't touched when formatting.
+// The templates are test-executed so that errors (like accessing unknown fields) are returned here and not when formatting a stream.
+func NewStreamFormat(name, title, description string) (StreamFormat, error) {
+	var f StreamFormat
+	var err error
+	if f.Name, err = parseStreamTemplate("name", name); err != nil {
+		return StreamFormat{}, err
+	}
+	if f.Title, err = parseStreamTemplate("title", title); err != nil {
+		return StreamFormat{}, err
+	}
+	if f.Description, err = parseStreamTemplate("description", description); err != nil {
+		return StreamFormat{}, err
+	}
+	return f, nil
+}
+
+// MustStreamFormat is like NewStreamFormat, but panics if a template is invalid.
+func MustStreamFormat(name, title, description string) StreamFormat {
+	f, err := NewStreamFormat(name, title, description)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func parseStreamTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Funcs(streamFormatFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %v template: %w", name, err)
+	}
+	if err = t.Execute(&bytes.Buffer{}, StreamDetails{}); err != nil {
+		return nil, fmt.Errorf("couldn't execute %v template: %w", name, err)
+	}
+	return t, nil
+}
+
+// FormatSize formats a number of bytes in a human readable way, like "1.4 GB".
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return strconv.FormatInt(bytes, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(bytes)/float64(div), 'f', 1, 64) + " " + string("KMGTP"[exp]) + "B"
+}
+
+// Format returns a copy of s with Name, Title and Description rendered from s.Details with the given format.
+// Fields whose template renders to nothing keep their previous value.
+// The helpers like WithQuality already format with DefaultStreamFormat, so you only need this for a custom format:
+//
+//	stream = stream.WithQuality("1080p").WithSeeders(42).Format(myFormat)
+func (s StreamItem) Format(f StreamFormat) StreamItem {
+	s.Name = renderStreamTemplate(f.Name, s.Details, s.Name)
+	s.Title = renderStreamTemplate(f.Title, s.Details, s.Title)
+	s.Description = renderStreamTemplate(f.Description, s.Details, s.Description)
+	return s
+}
+
+// renderStreamTemplate executes t and returns the result without empty lines and surrounding whitespace.
+// Templates are test-executed when parsed, so if it still fails, the previous value is kept. The same goes for empty results.
+func renderStreamTemplate(t *template.Template, details StreamDetails, previous string) string {
+	if t == nil {
+		return previous
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, details); err != nil {
+		return previous
+	}
+	lines := strings.Split(buf.String(), "\n")
+	nonEmpty := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			nonEmpty = append(nonEmpty, line)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return previous
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+// seedDetails fills the details from the Name and Title (or the filename hint) when the stream wasn't formatted before,
+// so the first helper keeps them instead of replacing them with only the new detail.
+func (s StreamItem) seedDetails() StreamItem {
+	if s.Details != (StreamDetails{}) {
+		return s
+	}
+	s.Details.Addon = s.Name
+	s.Details.Filename = s.Title
+	if s.Details.Filename == "" {
+		s.Details.Filename = s.BehaviorHints.Filename
+	}
+	return s
+}
+
+// WithAddonName returns a copy of s with the addon or provider name set and the stream formatted with DefaultStreamFormat.
+func (s StreamItem) WithAddonName(name string) StreamItem {
+	s = s.seedDetails()
+	s.Details.Addon = name
+	return s.Format(DefaultStreamFormat)
+}
+
+// WithQuality returns a copy of s with the quality (like "1080p") set and the stream formatted with DefaultStreamFormat.
+func (s StreamItem) WithQuality(quality string) StreamItem {
+	s = s.seedDetails()
+	s.Details.Quality = quality
+	return s.Format(DefaultStreamFormat)
+}
+
+// WithFilename returns a copy of s with the filename set and the stream formatted with DefaultStreamFormat.
+// The filename is also set as behavior hint, which Stremio uses for subtitle matching.
+func (s StreamItem) WithFilename(filename string) StreamItem {
+	s = s.seedDetails()
+	s.Details.Filename = filename
+	s.BehaviorHints.Filename = filename
+	return s.Format(DefaultStreamFormat)
+}
+
+// WithSize returns a copy of s with the size in bytes set and the stream formatted with DefaultStreamFormat.
+// The size is also set as behavior hint, which Stremio uses for subtitle matching.
+func (s StreamItem) WithSize(bytes int64) StreamItem {
+	s = s.seedDetails()
+	s.Details.Size = bytes
+	s.BehaviorHints.VideoSize = int(bytes)
+	return s.Format(DefaultStreamFormat)
+}
+
+// WithSeeders returns a copy of s with the number of seeders set and the stream formatted with DefaultStreamFormat.
+func (s StreamItem) WithSeeders(n int) StreamItem {
+	s = s.seedDetails()
+	s.Details.Seeders = n
+	return s.Format(DefaultStreamFormat)
+}
+
+// WithSource returns a copy of s with the source (like the torrent site) set and the stream formatted with DefaultStreamFormat.
+func (s StreamItem) WithSource(source string) StreamItem {
+	s = s.seedDetails()
+	s.Details.Source = source
+	return s.Format(DefaultStreamFormat)
+}
@@ -0,0 +1,84 @@
+package stremio
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// userDataCache is an LRU cache of decoded user data, keyed by the encoded user data from the URL.
+// It's safe for concurrent use.
+type userDataCache struct {
+	cache map[string]*list.Element
+	// Elements are of type *userDataCacheEntry, with the most recently used one at the front.
+	order      *list.List
+	maxEntries int
+	lock       *sync.Mutex
+	// For the admin dashboard
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type userDataCacheEntry struct {
+	key      string
+	userData any
+}
+
+func newUserDataCache(maxEntries int) *userDataCache {
+	return &userDataCache{
+		cache:      map[string]*list.Element{},
+		order:      list.New(),
+		maxEntries: maxEntries,
+		lock:       &sync.Mutex{},
+	}
+}
+
+func (c *userDataCache) get(key string) (any, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.cache[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(elem)
+	return elem.Value.(*userDataCacheEntry).userData, true
+}
+
+func (c *userDataCache) set(key string, userData any) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.cache[key]; ok {
+		elem.Value.(*userDataCacheEntry).userData = userData
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.cache[key] = c.order.PushFront(&userDataCacheEntry{key: key, userData: userData})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.cache, oldest.Value.(*userDataCacheEntry).key)
+	}
+}
+
+// decodeUserDataCached is like decodeUserData, but returns the cached user data if the cache has it.
+// Only successfully decoded user data is cached. The cache can be nil, for not caching.
+func decodeUserDataCached(cache *userDataCache, data string, unmarshalUserData userDataUnmarshaler, logger *zap.Logger, userDataIsBase64 bool) (any, error) {
+	if cache == nil {
+		return decodeUserData(data, unmarshalUserData, logger, userDataIsBase64)
+	}
+	if userData, ok := cache.get(data); ok {
+		return userData, nil
+	}
+	userData, err := decodeUserData(data, unmarshalUserData, logger, userDataIsBase64)
+	if err != nil {
+		return nil, err
+	}
+	// Fiber's params point into the request buffer, which is reused for other requests.
+	cache.set(strings.Clone(data), userData)
+	return userData, nil
+}
@@ -0,0 +1,118 @@
+package stremio
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrUserDataExpired signals that the user data JWT was valid, but is expired or was issued before JWTOptions.IssuedAfter.
+var ErrUserDataExpired = errors.New("user data expired")
+
+// JWTOptions are the options for issuing user data as JWT, see Options.UserDataJWT.
+type JWTOptions struct {
+	// Keys for signing and verifying the tokens with HMAC-SHA256 ("HS256").
+	// The first key signs new tokens, and all keys are accepted when verifying. To rotate keys, put the new key first,
+	// and remove the old one once the tokens signed with it should be invalid.
+	// Each key must be at least MinUserDataSigningKeyLength bytes long.
+	Keys [][]byte
+	// Issuer that's put into the "iss" claim. Tokens with a different issuer are rejected.
+	// Default "".
+	Issuer string
+	// How long tokens are valid after they're issued. Users need to reconfigure the addon after that.
+	// Default 0 (meaning tokens don't expire).
+	TTL time.Duration
+	// Tokens issued before this time are rejected, for invalidating all existing configurations at once.
+	// Default zero time.
+	IssuedAfter time.Time
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss,omitempty"`
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp,omitempty"`
+	Config    json.RawMessage `json:"cfg"`
+}
+
+func jwtSignature(signingInput string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+// issueUserDataJWT marshals the user data and returns it as compact JWT, with the user data in the "cfg" claim.
+func issueUserDataJWT(userData any, opts *JWTOptions) (string, error) {
+	userDataJSON, err := json.Marshal(userData)
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal user data: %w", err)
+	}
+	now := time.Now()
+	claims := jwtClaims{
+		Issuer:   opts.Issuer,
+		IssuedAt: now.Unix(),
+		Config:   userDataJSON,
+	}
+	if opts.TTL != 0 {
+		claims.ExpiresAt = now.Add(opts.TTL).Unix()
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal claims: %w", err)
+	}
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(signingInput, opts.Keys[0])), nil
+}
+
+// verifyUserDataJWT verifies the JWT and returns the user data of its "cfg" claim, URL-escaped like user data without Base64 encoding.
+func verifyUserDataJWT(token string, opts *JWTOptions) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidUserDataSignature
+	}
+	// Only accept our own header, so the algorithm can't be changed by the client.
+	if parts[0] != jwtHeader {
+		return "", ErrInvalidUserDataSignature
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidUserDataSignature
+	}
+	signingInput := parts[0] + "." + parts[1]
+	valid := false
+	for _, key := range opts.Keys {
+		if hmac.Equal(signature, jwtSignature(signingInput, key)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrInvalidUserDataSignature
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidUserDataSignature
+	}
+	var claims jwtClaims
+	if err = json.Unmarshal(claimsJSON, &claims); err != nil {
+		return "", ErrInvalidUserDataSignature
+	}
+	now := time.Now()
+	switch {
+	case claims.Issuer != opts.Issuer:
+		return "", ErrInvalidUserDataSignature
+	case claims.ExpiresAt != 0 && now.Unix() >= claims.ExpiresAt:
+		return "", ErrUserDataExpired
+	case !opts.IssuedAfter.IsZero() && claims.IssuedAt < opts.IssuedAfter.Unix():
+		return "", ErrUserDataExpired
+	}
+	return url.PathEscape(string(claims.Config)), nil
+}
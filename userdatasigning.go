@@ -0,0 +1,78 @@
+package stremio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// MinUserDataSigningKeyLength is the minimum length of the UserDataSigningKey option in bytes.
+const MinUserDataSigningKeyLength = 32
+
+// ErrInvalidUserDataSignature signals that the signature of the user data is missing or doesn't match,
+// for example because the user modified the user data.
+var ErrInvalidUserDataSignature = errors.New("invalid user data signature")
+
+// Routes with user data that are verified when signing user data or using JWTs, and whose user data length is limited by MaxUserDataLength.
+// Custom endpoints use DecodeUserData, which does both as well.
+var userDataRoutes = []string{"manifest.json", "catalog", "meta", "stream", "subtitles", "configure", "feed"}
+
+// signUserData appends the Base64URL-encoded HMAC-SHA256 signature of the encoded user data, separated by a dot.
+// Neither Base64URL nor URL-escaping escape dots, but the signature doesn't contain any, so the last dot always separates it.
+func signUserData(encoded string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyUserData checks the signature of the signed user data and returns the encoded user data without it.
+func verifyUserData(signed string, key []byte) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i == -1 {
+		return "", ErrInvalidUserDataSignature
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if err != nil {
+		return "", ErrInvalidUserDataSignature
+	}
+	encoded := signed[:i]
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", ErrInvalidUserDataSignature
+	}
+	return encoded, nil
+}
+
+// createUserDataVerificationMiddleware creates a middleware that rejects requests whose user data can't be verified,
+// like when it isn't signed with the UserDataSigningKey, is an invalid JWT or an unknown config token, and puts the verified user data into the context
+// for the handlers (see userDataParam).
+func createUserDataVerificationMiddleware(verify func(ctx context.Context, userData string) (string, error), logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userData, err := verify(c.Context(), c.Params("userData"))
+		if errors.Is(err, ErrInvalidUserDataSignature) || errors.Is(err, ErrUserDataExpired) || errors.Is(err, ErrUnknownConfigToken) {
+			logger.Warn("Rejecting request with unverifiable user data", zap.Error(err))
+			return c.SendStatus(fiber.StatusBadRequest)
+		} else if err != nil {
+			logger.Error("Couldn't verify user data", zap.Error(err))
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.RequestCtx().SetUserValue("verifiedUserData", userData)
+		return c.Next()
+	}
+}
+
+// userDataParam returns the user data of the request, without the signature when signing user data
+// and as URL-escaped JSON when using JWTs or a ConfigStore.
+func userDataParam(c fiber.Ctx) string {
+	if userData, ok := c.RequestCtx().UserValue("verifiedUserData").(string); ok {
+		return userData
+	}
+	return c.Params("userData")
+}
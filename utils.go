@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"path"
 
+	"github.com/xybydy/go-stremio/internal/metactx"
 	"github.com/xybydy/go-stremio/types"
 )
 
@@ -28,11 +29,22 @@ func (fs *PrefixedFS) Open(name string) (fs.File, error) {
 	return fs.FS.Open(name)
 }
 
+// WithMeta returns a copy of the context with the meta object, which can be read with GetMetaFromContext.
+// go-stremio uses it when PutMetaInContext is set in the options, but it's also useful for tests and custom middlewares.
+func WithMeta(ctx context.Context, meta types.MetaItem) context.Context {
+	return context.WithValue(ctx, metactx.Key, meta)
+}
+
 // GetMetaFromContext returns the Meta object that's stored in the context.
 // It returns an error if no meta was found in the context or the value found isn't of type Meta.
 // The former one is ErrNoMeta which acts as sentinel error so you can check for it.
+// For backward compatibility it also reads meta objects that were stored under the string key "meta",
+// but new code should use WithMeta for storing them.
 func GetMetaFromContext(ctx context.Context) (types.MetaItem, error) {
-	metaIface := ctx.Value("meta")
+	metaIface := ctx.Value(metactx.Key)
+	if metaIface == nil {
+		metaIface = ctx.Value(metactx.LegacyKey)
+	}
 	if metaIface == nil {
 		return types.MetaItem{}, ErrNoMeta
 	} else if meta, ok := metaIface.(types.MetaItem); ok {
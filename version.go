@@ -0,0 +1,219 @@
+package stremio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// latestVersionCheckInterval is how often the LatestVersionURL is checked while the addon is running.
+const latestVersionCheckInterval = 6 * time.Hour
+
+// VersionInfo is the response of the "/version" endpoint, see Options.VersionEndpoint.
+type VersionInfo struct {
+	// Version from the manifest
+	Version string `json:"version"`
+	// Go version the addon was built with, like "go1.23.4"
+	GoVersion string `json:"goVersion"`
+	// Path and version of the main module, the version is "(devel)" for builds from a local checkout
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"moduleVersion,omitempty"`
+	// VCS info that the Go toolchain stamps into builds from a repository, like the Git commit SHA
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revisionTime,omitempty"`
+	Modified     bool   `json:"modified,omitempty"`
+	// Latest version from the LatestVersionURL and whether the running version is older, only set when it was checked successfully
+	LatestVersion string `json:"latestVersion,omitempty"`
+	Outdated      bool   `json:"outdated,omitempty"`
+}
+
+// buildVersionInfo returns the version info of the build, without the manifest and latest version.
+func buildVersionInfo() VersionInfo {
+	info := VersionInfo{GoVersion: runtime.Version()}
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Module = buildInfo.Main.Path
+	info.ModuleVersion = buildInfo.Main.Version
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.RevisionTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// versionChecker checks the LatestVersionURL for newer versions of the addon.
+type versionChecker struct {
+	url        string
+	manifest   *atomic.Pointer[manifestSnapshot]
+	httpClient *http.Client
+	lock       sync.Mutex
+	latest     string
+	logger     *zap.Logger
+}
+
+func newVersionChecker(url, addonID string, manifest *atomic.Pointer[manifestSnapshot], logger *zap.Logger) *versionChecker {
+	v := &versionChecker{
+		url:        url,
+		manifest:   manifest,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+	metrics.GetOrCreateGauge(`addon_outdated{addon="`+addonID+`"}`, func() float64 {
+		if _, outdated := v.status(); outdated {
+			return 1
+		}
+		return 0
+	})
+	return v
+}
+
+// status returns the latest version, "" if it wasn't checked successfully yet, and whether the running version is older.
+func (v *versionChecker) status() (string, bool) {
+	v.lock.Lock()
+	latest := v.latest
+	v.lock.Unlock()
+	snapshot := v.manifest.Load()
+	if latest == "" || snapshot == nil {
+		return latest, false
+	}
+	return latest, compareVersions(snapshot.bodies.manifest.Version, latest) < 0
+}
+
+// run checks the latest version now and then periodically until the context is canceled.
+func (v *versionChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(latestVersionCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := v.check(ctx); err != nil && ctx.Err() == nil {
+			v.logger.Warn("Couldn't check the latest version", zap.String("url", v.url), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (v *versionChecker) check(ctx context.Context) error {
+	latest, err := v.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	v.lock.Lock()
+	v.latest = latest
+	v.lock.Unlock()
+	if _, outdated := v.status(); outdated {
+		v.logger.Warn("A newer version of the addon is available", zap.String("version", v.manifest.Load().bodies.manifest.Version), zap.String("latestVersion", latest))
+	}
+	return nil
+}
+
+// fetch returns the version from the LatestVersionURL, which responds with plain text or JSON with a "version" or "tag_name" field.
+func (v *versionChecker) fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create request: %w", err)
+	}
+	res, err := v.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch latest version: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status code fetching latest version: %v", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("couldn't read latest version: %w", err)
+	}
+	latest := strings.TrimSpace(string(body))
+	if strings.HasPrefix(latest, "{") {
+		var release struct {
+			Version string `json:"version"`
+			TagName string `json:"tag_name"`
+		}
+		if err := json.Unmarshal(body, &release); err != nil {
+			return "", fmt.Errorf("couldn't unmarshal latest version: %w", err)
+		}
+		latest = release.Version
+		if latest == "" {
+			latest = release.TagName
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no latest version in response")
+	}
+	return latest, nil
+}
+
+// compareVersions compares versions like "1.2.3", "v1.10.0" and "2.0.0-beta.1" by their numeric parts,
+// returning -1 if a is older than b, 1 if it's newer and 0 if they're the same.
+// A pre-release is older than the release with the same numbers.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := range max(len(aParts), len(bParts)) {
+		var aNum, bNum int
+		if i < len(aParts) {
+			aNum, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bNum, _ = strconv.Atoi(bParts[i])
+		}
+		if aNum != bNum {
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func createVersionHandler(manifest *atomic.Pointer[manifestSnapshot], checker *versionChecker, logger *zap.Logger) fiber.Handler {
+	build := buildVersionInfo()
+	return func(c fiber.Ctx) error {
+		logger.Debug("versionHandler called")
+		info := build
+		info.Version = manifest.Load().bodies.manifest.Version
+		if checker != nil {
+			info.LatestVersion, info.Outdated = checker.status()
+		}
+		return c.JSON(info)
+	}
+}
@@ -0,0 +1,97 @@
+package stremio
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+// VirtualHosts serves different addons depending on the Host header from one process,
+// like a movie addon at "movies.example.com" and a series addon at "series.example.com".
+// Each addon keeps its own manifest, handlers and options, except for the ones about the server like BindAddr and Port.
+type VirtualHosts struct {
+	hosts    map[string]*Addon
+	fallback *Addon
+	logger   *zap.Logger
+}
+
+// NewVirtualHosts creates a new VirtualHosts object that can be started with Run().
+// The hosts are matched case-insensitively and without port. Requests for other hosts are handled by the fallback addon,
+// or rejected with "404 Not Found" when it's nil. The logger is for the server, the addons keep using their own loggers.
+func NewVirtualHosts(hosts map[string]*Addon, fallback *Addon, logger *zap.Logger) (*VirtualHosts, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("no host was passed")
+	}
+	lowerHosts := make(map[string]*Addon, len(hosts))
+	for host, addon := range hosts {
+		if addon == nil {
+			return nil, errors.New("an addon for host " + host + " is nil")
+		}
+		lowerHosts[strings.ToLower(host)] = addon
+	}
+	return &VirtualHosts{
+		hosts:    lowerHosts,
+		fallback: fallback,
+		logger:   logger,
+	}, nil
+}
+
+// App returns a Fiber app that dispatches the requests to the apps of the addons (see Addon.App) by their Host header.
+// The Fiber config is used for all apps.
+func (v *VirtualHosts) App(fiberConf *fiber.Config) *fiber.App {
+	handlers := make(map[string]fasthttp.RequestHandler, len(v.hosts))
+	for host, addon := range v.hosts {
+		handlers[host] = addon.App(fiberConf).Handler()
+	}
+	var fallback fasthttp.RequestHandler
+	if v.fallback != nil {
+		fallback = v.fallback.App(fiberConf).Handler()
+	}
+
+	var app *fiber.App
+	if fiberConf != nil {
+		app = fiber.New(*fiberConf)
+	} else {
+		app = fiber.New()
+	}
+	app.Use(func(c fiber.Ctx) error {
+		handler, ok := handlers[strings.ToLower(c.Hostname())]
+		if !ok {
+			handler = fallback
+		}
+		if handler == nil {
+			v.logger.Debug("Rejecting request for unknown host", zap.String("host", c.Hostname()))
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		handler(c.RequestCtx())
+		return nil
+	})
+	return app
+}
+
+// Run starts the server for all addons at the address, like "0.0.0.0:8080", and gracefully handles shutdowns like Addon.Run.
+// The lifecycle hooks in the options of all addons, like OnStart, are called once per addon.
+// The longest ShutdownTimeout of the addons is used, or none if one of them has none, and ReusePort when one of them sets it.
+func (v *VirtualHosts) Run(addr string, stoppingChan chan bool, fiberConf *fiber.Config) {
+	if stoppingChan != nil && cap(stoppingChan) < 1 {
+		v.logger.Fatal("The passed stopping channel isn't buffered")
+	}
+
+	addons := make([]*Addon, 0, len(v.hosts)+1)
+	for _, addon := range v.hosts {
+		if !slices.Contains(addons, addon) {
+			addons = append(addons, addon)
+		}
+	}
+	if v.fallback != nil && !slices.Contains(addons, v.fallback) {
+		addons = append(addons, v.fallback)
+	}
+	if _, err := serve(v.App(fiberConf), addr, stoppingChan, v.logger, addons, nil, nil); err != nil {
+		v.logger.Fatal("Couldn't start server", zap.Error(err))
+	}
+	v.logger.Info("Finished shutting down server")
+}
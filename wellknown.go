@@ -0,0 +1,75 @@
+package stremio
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+)
+
+// DefaultRobotsTxt is a robots.txt for Options.RobotsTxt that disallows crawling the whole addon,
+// as the manifest, catalog and stream responses aren't meant for search engines.
+const DefaultRobotsTxt = "User-agent: *\nDisallow: /\n"
+
+// securityTxtDefaultExpiry is the time after a request that the security.txt expires when SecurityTxt.Expires isn't set.
+// RFC 9116 recommends less than a year, so crawlers don't keep stale contact information.
+const securityTxtDefaultExpiry = 180 * 24 * time.Hour
+
+// SecurityTxt is the content of the security.txt (RFC 9116) at "/.well-known/security.txt", see Options.SecurityTxt.
+type SecurityTxt struct {
+	// URIs for reporting security issues, like "mailto:security@example.com" or "https://example.com/security".
+	// At least one is required.
+	Contact []string
+	// Date and time after which the content shouldn't be considered current anymore.
+	// Default zero (meaning 180 days after each request, so it never expires while the addon is running).
+	Expires time.Time
+	// URI of a key for encrypted communication, like "https://example.com/pgp-key.txt".
+	// Default "".
+	Encryption string
+	// URI of the security policy, like "https://example.com/security-policy.html".
+	// Default "".
+	Policy string
+	// Language tags of the languages that reports can be written in, like "en" or "de".
+	// Default nil.
+	PreferredLanguages []string
+}
+
+// String returns the security.txt content as of now.
+func (s SecurityTxt) String() string {
+	expires := s.Expires
+	if expires.IsZero() {
+		expires = time.Now().Add(securityTxtDefaultExpiry)
+	}
+	var sb strings.Builder
+	for _, contact := range s.Contact {
+		sb.WriteString("Contact: " + contact + "\n")
+	}
+	sb.WriteString("Expires: " + expires.UTC().Format(time.RFC3339) + "\n")
+	if s.Encryption != "" {
+		sb.WriteString("Encryption: " + s.Encryption + "\n")
+	}
+	if s.Policy != "" {
+		sb.WriteString("Policy: " + s.Policy + "\n")
+	}
+	if len(s.PreferredLanguages) > 0 {
+		sb.WriteString("Preferred-Languages: " + strings.Join(s.PreferredLanguages, ", ") + "\n")
+	}
+	return sb.String()
+}
+
+func createRobotsTxtHandler(robotsTxt string, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("robotsTxtHandler called")
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(robotsTxt)
+	}
+}
+
+func createSecurityTxtHandler(securityTxt SecurityTxt, logger *zap.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		logger.Debug("securityTxtHandler called")
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(securityTxt.String())
+	}
+}